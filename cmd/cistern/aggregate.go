@@ -0,0 +1,394 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// aggregator computes an aggregate over the values of a column.
+//
+// Aggregators are mergeable: cluster nodes return their aggregator
+// state as JSON and the coordinator merges the states of each group
+// before computing the results.
+type aggregator interface {
+	// add adds the value of the column in an event with timestamp ts.
+	// value is nil if the event doesn't have the column.
+	add(value interface{}, ts int64)
+	merge(other aggregator)
+	// result returns the aggregate, or nil if it has no value.
+	result() interface{}
+}
+
+// newAggregator returns an aggregator for the aggregate named name.
+//
+// The aggregates are sum, count, min, max, avg, stddev and variance
+// (of the sample), count_distinct, approx_count_distinct, median
+// and percentiles p1 to p99, and first and last by _ts. Aggregates
+// other than count, count_distinct, first and last ignore values that
+// aren't numbers.
+func newAggregator(name string) (aggregator, error) {
+	switch name {
+	case "sum":
+		return &sumAggregator{}, nil
+	case "count":
+		return &countAggregator{}, nil
+	case "min":
+		return &extremeAggregator{}, nil
+	case "max":
+		return &extremeAggregator{max: true}, nil
+	case "avg":
+		return &sumAggregator{avg: true}, nil
+	case "stddev":
+		return &varianceAggregator{stddev: true}, nil
+	case "variance":
+		return &varianceAggregator{}, nil
+	case "count_distinct":
+		return &distinctAggregator{Values: map[string]bool{}}, nil
+	case "approx_count_distinct":
+		return &approxDistinctAggregator{Registers: make([]byte, cardinalityRegisters)}, nil
+	case "median":
+		return &quantileAggregator{quantile: 0.5}, nil
+	case "first":
+		return &firstAggregator{}, nil
+	case "last":
+		return &firstAggregator{last: true}, nil
+	case "":
+		return nil, fmt.Errorf("missing aggregate")
+	}
+	if strings.HasPrefix(name, "p") {
+		p, err := strconv.Atoi(name[1:])
+		if err == nil && p >= 1 && p <= 99 {
+			return &quantileAggregator{quantile: float64(p) / 100}, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown aggregate %s", name)
+}
+
+// decodeAggregator decodes the JSON state of a partial aggregate.
+func decodeAggregator(name string, state interface{}) (aggregator, error) {
+	agg, err := newAggregator(name)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(b, agg)
+	return agg, err
+}
+
+type countAggregator struct {
+	Count int64 `json:"count"`
+}
+
+func (a *countAggregator) add(value interface{}, ts int64) { a.Count++ }
+func (a *countAggregator) merge(other aggregator)          { a.Count += other.(*countAggregator).Count }
+func (a *countAggregator) result() interface{}             { return float64(a.Count) }
+
+// sumAggregator computes sums and averages.
+type sumAggregator struct {
+	Sum   float64 `json:"sum"`
+	Count int64   `json:"count"`
+	avg   bool
+}
+
+func (a *sumAggregator) add(value interface{}, ts int64) {
+	if f, ok := valueOf(value).number(); ok {
+		a.Sum += f
+		a.Count++
+	}
+}
+
+func (a *sumAggregator) merge(other aggregator) {
+	o := other.(*sumAggregator)
+	a.Sum += o.Sum
+	a.Count += o.Count
+}
+
+func (a *sumAggregator) result() interface{} {
+	if a.Count == 0 {
+		return nil
+	}
+	if a.avg {
+		return a.Sum / float64(a.Count)
+	}
+	return a.Sum
+}
+
+// extremeAggregator computes minimums and maximums.
+type extremeAggregator struct {
+	Value float64 `json:"value"`
+	Count int64   `json:"count"`
+	max   bool
+}
+
+func (a *extremeAggregator) add(value interface{}, ts int64) {
+	if f, ok := valueOf(value).number(); ok {
+		a.addNumber(f, 1)
+	}
+}
+
+func (a *extremeAggregator) addNumber(f float64, count int64) {
+	if a.Count == 0 || (a.max && f > a.Value) || (!a.max && f < a.Value) {
+		a.Value = f
+	}
+	a.Count += count
+}
+
+func (a *extremeAggregator) merge(other aggregator) {
+	if o := other.(*extremeAggregator); o.Count > 0 {
+		a.addNumber(o.Value, o.Count)
+	}
+}
+
+func (a *extremeAggregator) result() interface{} {
+	if a.Count == 0 {
+		return nil
+	}
+	return a.Value
+}
+
+// varianceAggregator computes the sample variance or standard
+// deviation with Welford's algorithm.
+type varianceAggregator struct {
+	Count  int64   `json:"count"`
+	Mean   float64 `json:"mean"`
+	M2     float64 `json:"m2"`
+	stddev bool
+}
+
+func (a *varianceAggregator) add(value interface{}, ts int64) {
+	f, ok := valueOf(value).number()
+	if !ok {
+		return
+	}
+	a.Count++
+	delta := f - a.Mean
+	a.Mean += delta / float64(a.Count)
+	a.M2 += delta * (f - a.Mean)
+}
+
+func (a *varianceAggregator) merge(other aggregator) {
+	o := other.(*varianceAggregator)
+	if o.Count == 0 {
+		return
+	}
+	count := a.Count + o.Count
+	delta := o.Mean - a.Mean
+	a.M2 += o.M2 + delta*delta*float64(a.Count)*float64(o.Count)/float64(count)
+	a.Mean += delta * float64(o.Count) / float64(count)
+	a.Count = count
+}
+
+func (a *varianceAggregator) result() interface{} {
+	if a.Count < 2 {
+		return nil
+	}
+	variance := a.M2 / float64(a.Count-1)
+	if a.stddev {
+		return math.Sqrt(variance)
+	}
+	return variance
+}
+
+// distinctAggregator counts distinct values exactly.
+type distinctAggregator struct {
+	Values map[string]bool `json:"values"`
+}
+
+func (a *distinctAggregator) add(value interface{}, ts int64) {
+	if value != nil {
+		a.Values[stringValue(value)] = true
+	}
+}
+
+func (a *distinctAggregator) merge(other aggregator) {
+	for v := range other.(*distinctAggregator).Values {
+		a.Values[v] = true
+	}
+}
+
+func (a *distinctAggregator) result() interface{} { return float64(len(a.Values)) }
+
+// approxDistinctAggregator estimates the number of distinct values
+// with HyperLogLog in constant memory.
+type approxDistinctAggregator struct {
+	Registers []byte `json:"registers"`
+}
+
+func (a *approxDistinctAggregator) add(value interface{}, ts int64) {
+	if value != nil {
+		addCardinality(a.Registers, stringValue(value))
+	}
+}
+
+func (a *approxDistinctAggregator) merge(other aggregator) {
+	o := other.(*approxDistinctAggregator)
+	if len(o.Registers) != len(a.Registers) {
+		return
+	}
+	for i, r := range o.Registers {
+		if r > a.Registers[i] {
+			a.Registers[i] = r
+		}
+	}
+}
+
+func (a *approxDistinctAggregator) result() interface{} {
+	return float64(estimateCardinality(a.Registers))
+}
+
+// quantileAggregator estimates a quantile with a quantileSketch.
+type quantileAggregator struct {
+	quantileSketch
+	quantile float64
+}
+
+func (a *quantileAggregator) add(value interface{}, ts int64) {
+	if f, ok := valueOf(value).number(); ok {
+		a.quantileSketch.add(f)
+	}
+}
+
+func (a *quantileAggregator) merge(other aggregator) {
+	a.quantileSketch.merge(&other.(*quantileAggregator).quantileSketch)
+}
+
+func (a *quantileAggregator) result() interface{} {
+	if a.Count == 0 {
+		return nil
+	}
+	return a.quantileSketch.quantile(a.quantile)
+}
+
+// firstAggregator keeps the value of the column in the event with the
+// lowest or, for last, the highest timestamp.
+type firstAggregator struct {
+	Ts    int64       `json:"ts"`
+	Value interface{} `json:"value"`
+	Set   bool        `json:"set"`
+	last  bool
+}
+
+func (a *firstAggregator) add(value interface{}, ts int64) {
+	if value == nil {
+		return
+	}
+	// Events are added in timestamp order, so later events with the
+	// same timestamp are last.
+	if !a.Set || (a.last && ts >= a.Ts) || (!a.last && ts < a.Ts) {
+		a.Ts, a.Value, a.Set = ts, value, true
+	}
+}
+
+func (a *firstAggregator) merge(other aggregator) {
+	o := other.(*firstAggregator)
+	if o.Set && (!a.Set || (a.last && o.Ts > a.Ts) || (!a.last && o.Ts < a.Ts)) {
+		a.Ts, a.Value, a.Set = o.Ts, o.Value, true
+	}
+}
+
+func (a *firstAggregator) result() interface{} { return a.Value }
+
+// sketchAccuracy is the relative accuracy of quantile estimates.
+const sketchAccuracy = 0.01
+
+var sketchLogGamma = math.Log((1 + sketchAccuracy) / (1 - sketchAccuracy))
+
+// quantileSketch is a mergeable quantile sketch (DDSketch). Values are
+// counted in buckets whose bounds grow exponentially, so any quantile
+// is estimated within sketchAccuracy of a value of the data, using
+// memory logarithmic in the range of the values.
+type quantileSketch struct {
+	Positive map[int]int64 `json:"positive,omitempty"`
+	Negative map[int]int64 `json:"negative,omitempty"`
+	Zero     int64         `json:"zero,omitempty"`
+	Count    int64         `json:"count"`
+}
+
+func sketchKey(f float64) int {
+	return int(math.Ceil(math.Log(f) / sketchLogGamma))
+}
+
+// sketchValue returns the estimate for values in the bucket of key.
+func sketchValue(key int) float64 {
+	gamma := math.Exp(sketchLogGamma)
+	return 2 * math.Pow(gamma, float64(key)) / (gamma + 1)
+}
+
+func (s *quantileSketch) add(f float64) {
+	switch {
+	case math.IsNaN(f) || math.IsInf(f, 0):
+		return
+	case f > 0:
+		if s.Positive == nil {
+			s.Positive = map[int]int64{}
+		}
+		s.Positive[sketchKey(f)]++
+	case f < 0:
+		if s.Negative == nil {
+			s.Negative = map[int]int64{}
+		}
+		s.Negative[sketchKey(-f)]++
+	default:
+		s.Zero++
+	}
+	s.Count++
+}
+
+func (s *quantileSketch) merge(other *quantileSketch) {
+	for key, n := range other.Positive {
+		if s.Positive == nil {
+			s.Positive = map[int]int64{}
+		}
+		s.Positive[key] += n
+	}
+	for key, n := range other.Negative {
+		if s.Negative == nil {
+			s.Negative = map[int]int64{}
+		}
+		s.Negative[key] += n
+	}
+	s.Zero += other.Zero
+	s.Count += other.Count
+}
+
+// quantile returns the estimated q-quantile of a non-empty sketch.
+func (s *quantileSketch) quantile(q float64) float64 {
+	rank := int64(q * float64(s.Count-1))
+
+	negative := sortedKeys(s.Negative)
+	for i := len(negative) - 1; i >= 0; i-- {
+		rank -= s.Negative[negative[i]]
+		if rank < 0 {
+			return -sketchValue(negative[i])
+		}
+	}
+	rank -= s.Zero
+	if rank < 0 {
+		return 0
+	}
+	value := 0.0
+	for _, key := range sortedKeys(s.Positive) {
+		value = sketchValue(key)
+		rank -= s.Positive[key]
+		if rank < 0 {
+			break
+		}
+	}
+	return value
+}
+
+func sortedKeys(buckets map[int]int64) []int {
+	keys := make([]int, 0, len(buckets))
+	for key := range buckets {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	return keys
+}
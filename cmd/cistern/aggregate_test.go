@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/Cistern/cistern/internal/query"
+)
+
+func TestAggregates(t *testing.T) {
+	ec, err := CreateEventCollection("/tmp/test_cistern_aggregate.lm2", defaultCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { ec.col.Destroy() }()
+	err = ec.StoreEvents(testEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	desc, err := query.Parse("SELECT avg(packets), variance(packets), stddev(packets), count_distinct(dest_port), " +
+		"approx_count_distinct(dest_address), median(packets), p99(packets), first(source_port), last(source_port)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ec.Query(*desc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Summary) != 1 {
+		t.Fatalf("expected one summary row but got %v", result.Summary)
+	}
+	summary := result.Summary[0]
+
+	packets := []float64{}
+	for _, event := range testEvents {
+		packets = append(packets, float64(event["packets"].(int)))
+	}
+	mean, variance := 0.0, 0.0
+	for _, p := range packets {
+		mean += p / float64(len(packets))
+	}
+	for _, p := range packets {
+		variance += (p - mean) * (p - mean) / float64(len(packets)-1)
+	}
+
+	expected := map[string]float64{
+		"avg(packets)":                        mean,
+		"variance(packets)":                   variance,
+		"stddev(packets)":                     math.Sqrt(variance),
+		"count_distinct(dest_port)":           4,
+		"approx_count_distinct(dest_address)": 4,
+	}
+	for field, value := range expected {
+		got, _ := summary[field].(float64)
+		if math.Abs(got-value) > 1e-9 {
+			t.Errorf("%s: expected %v but got %v", field, value, summary[field])
+		}
+	}
+	for _, field := range []string{"median(packets)", "p99(packets)"} {
+		got, _ := summary[field].(float64)
+		if got < 5 || got > 160*(1+sketchAccuracy) {
+			t.Errorf("%s: expected a value in the range of packets but got %v", field, summary[field])
+		}
+	}
+	if summary["first(source_port)"] != 52310.0 || summary["last(source_port)"] != 443.0 {
+		t.Errorf("expected the first and last source ports but got %v and %v",
+			summary["first(source_port)"], summary["last(source_port)"])
+	}
+
+	for _, q := range []string{"SELECT avgg(packets)", "SELECT p100(packets)", "SELECT packets GROUP BY protocol"} {
+		desc, err := query.Parse(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = ec.Query(*desc)
+		if err == nil {
+			t.Errorf("%s: expected an error", q)
+		}
+	}
+}
+
+func TestQuantileSketch(t *testing.T) {
+	a, b := &quantileAggregator{quantile: 0.95}, &quantileAggregator{quantile: 0.95}
+	for i := 1; i <= 10000; i++ {
+		if i%2 == 0 {
+			a.add(float64(i), 0)
+		} else {
+			b.add(float64(-i), 0)
+		}
+	}
+	a.add(0, 0)
+
+	// The state of partial aggregates goes through JSON.
+	data, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state interface{}
+	json.Unmarshal(data, &state)
+	decoded, err := decodeAggregator("p95", state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.merge(decoded)
+
+	for _, c := range []struct {
+		q, expected float64
+	}{
+		{0.95, 9000},
+		{0.5, 0},
+		{0.25, -5000},
+		{0, -9999},
+		{1, 10000},
+	} {
+		got := a.quantileSketch.quantile(c.q)
+		if math.Abs(got-c.expected) > math.Abs(c.expected)*sketchAccuracy+1 {
+			t.Errorf("q=%v: expected about %v but got %v", c.q, c.expected, got)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"time"
@@ -12,6 +13,177 @@ import (
 
 func service() *siesta.Service {
 	service := siesta.NewService("/api")
+	service.Route("GET", "/collections", "lists collections", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(CollectionNames())
+	})
+
+	service.Route("GET", "/collections/:collection", "returns collection details", func(w http.ResponseWriter, r *http.Request) {
+		var params siesta.Params
+		collectionName := params.String("collection", "", "collection name")
+		err := params.Parse(r.Form)
+		if err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		collectionsLock.Lock()
+		collection, present := Collections[*collectionName]
+		collectionsLock.Unlock()
+
+		if !present {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		info, err := collection.Info(*collectionName)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			log.Println(err)
+			return
+		}
+		json.NewEncoder(w).Encode(info)
+	})
+
+	service.Route("POST", "/collections/:collection", "creates an empty collection", func(w http.ResponseWriter, r *http.Request) {
+		var params siesta.Params
+		collectionName := params.String("collection", "", "collection name")
+		retention := params.Int("retention", 0, "retention in days")
+		maxBytes := params.Int64("max_bytes", 0, "maximum disk usage in bytes")
+		maxEvents := params.Int64("max_events", 0, "maximum number of events")
+		flatten := params.Bool("flatten", false, "flatten nested fields at ingest")
+		err := params.Parse(r.Form)
+		if err != nil || !collectionNameRegexp.MatchString(*collectionName) {
+			log.Println(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		collection, err := CreateCollection(*collectionName)
+		if err != nil {
+			if err == ErrExists {
+				w.WriteHeader(http.StatusConflict)
+			} else {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			log.Println(err)
+			return
+		}
+		if *retention > 0 {
+			collection.SetRetention(*retention)
+		}
+		collection.SetMaxBytes(*maxBytes)
+		collection.SetMaxEvents(*maxEvents)
+		collection.SetFlatten(*flatten)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	service.Route("POST", "/collections/:collection/rename", "renames a collection", func(w http.ResponseWriter, r *http.Request) {
+		var params siesta.Params
+		collectionName := params.String("collection", "", "collection name")
+		newName := params.String("name", "", "new collection name")
+		err := params.Parse(r.Form)
+		if err != nil || !collectionNameRegexp.MatchString(*newName) {
+			log.Println(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		err = RenameCollection(*collectionName, *newName)
+		if err != nil {
+			switch err {
+			case ErrDoesNotExist:
+				w.WriteHeader(http.StatusNotFound)
+			case ErrExists, ErrHasSources:
+				w.WriteHeader(http.StatusConflict)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			log.Println(err)
+		}
+	})
+
+	service.Route("DELETE", "/collections/:collection", "deletes a collection and its files", func(w http.ResponseWriter, r *http.Request) {
+		var params siesta.Params
+		collectionName := params.String("collection", "", "collection name")
+		err := params.Parse(r.Form)
+		if err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		err = DeleteCollection(*collectionName)
+		if err != nil {
+			switch err {
+			case ErrDoesNotExist:
+				w.WriteHeader(http.StatusNotFound)
+			case ErrHasSources:
+				w.WriteHeader(http.StatusConflict)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			log.Println(err)
+		}
+	})
+
+	service.Route("POST", "/collections/:collection/events", "stores a JSON array of events", func(w http.ResponseWriter, r *http.Request) {
+		var params siesta.Params
+		collectionName := params.String("collection", "", "collection name")
+		local := params.Bool("local", false, "store on this node instead of routing to shard owners")
+		err := params.Parse(r.Form)
+		if err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var store EventStore
+		if ClusterNode != nil && ClusterNode.IsSharded(*collectionName) && !*local {
+			store = shardStore{cluster: ClusterNode, collection: *collectionName}
+		} else {
+			collectionsLock.Lock()
+			collection, present := Collections[*collectionName]
+			collectionsLock.Unlock()
+
+			if !present {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			store = collection
+		}
+
+		events := []Event{}
+		err = json.NewDecoder(r.Body).Decode(&events)
+		if err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		stored, err := store.StoreNewEvents(events)
+		if err != nil {
+			switch err {
+			case ErrLowDisk:
+				w.WriteHeader(http.StatusServiceUnavailable)
+			case ErrReadOnly:
+				w.WriteHeader(http.StatusConflict)
+			default:
+				if _, ok := err.(invalidEventError); ok {
+					w.WriteHeader(http.StatusBadRequest)
+				} else {
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			}
+			log.Println(err)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]int{
+			"stored":     stored,
+			"duplicates": len(events) - stored,
+		})
+	})
+
 	service.Route("OPTIONS", "/collections/:collection/query", "preflight request", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Headers", r.Header.Get("Access-Control-Request-Headers"))
@@ -24,33 +196,36 @@ func service() *siesta.Service {
 		queryString := params.String("query", "", "query string")
 		start := params.Int64("start", 0, "Start Unix timestamp")
 		end := params.Int64("end", 0, "End Unix timestamp")
+		continuation := params.String("continuation", "", "continuation token from the previous page")
 		err := params.Parse(r.Form)
 		if err != nil {
 			log.Println(err)
-			w.WriteHeader(http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, errCodeInvalidRequest, err)
 			return
 		}
 
+		sharded := ClusterNode != nil && ClusterNode.IsSharded(*collectionName)
+
 		collectionsLock.Lock()
 		collection, present := Collections[*collectionName]
 		collectionsLock.Unlock()
 
-		if !present {
-			w.WriteHeader(http.StatusNotFound)
+		if !present && !sharded {
+			writeError(w, http.StatusNotFound, errCodeNotFound, ErrDoesNotExist)
 			return
 		}
 
 		queryDesc, err := query.Parse(*queryString)
 		if err != nil {
 			log.Println(err)
-			w.WriteHeader(http.StatusBadRequest)
+			writeQueryError(w, err)
 			return
 		}
 
 		log.Println("Got query", *queryString)
 
-		queryDesc.TimeRange.Start = time.Unix(*start, 0)
-		queryDesc.TimeRange.End = time.Unix(*end, 0)
+		setDefaultTimeRange(queryDesc, *start, *end)
+		queryDesc.Continuation = *continuation
 
 		if queryDesc.PointSize > 0 {
 			// Round off timestamps
@@ -58,30 +233,195 @@ func service() *siesta.Service {
 			queryDesc.TimeRange.End = queryDesc.TimeRange.End.Truncate(time.Duration(queryDesc.PointSize * 1000))
 		}
 
-		for i, filter := range queryDesc.Filters {
-			var v interface{}
-			err := json.Unmarshal([]byte(filter.Value.(json.RawMessage)), &v)
+		err = decodeFilterValues(queryDesc)
+		if err != nil {
+			log.Println(err)
+			writeError(w, http.StatusBadRequest, errCodeInvalidFilterValue, err)
+			return
+		}
+		_, err = newQueryPlan(*queryDesc)
+		if err != nil {
+			log.Println(err)
+			writeQueryError(w, err)
+			return
+		}
+
+		var result *QueryResult
+		if sharded {
+			result, err = ClusterNode.Query(*collectionName, *queryDesc)
+		} else {
+			result, err = collection.Query(*queryDesc)
+		}
+		if err != nil {
+			log.Println(err)
+			writeError(w, http.StatusInternalServerError, errCodeQueryFailed, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(result)
+	})
+
+	service.Route("POST", "/collections/:collection/partial_query", "runs a query from a cluster coordinator on the local shard", func(w http.ResponseWriter, r *http.Request) {
+		var params siesta.Params
+		collectionName := params.String("collection", "", "collection name")
+		err := params.Parse(r.Form)
+		if err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		collectionsLock.Lock()
+		collection, present := Collections[*collectionName]
+		collectionsLock.Unlock()
+
+		if !present {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		queryDesc := query.Desc{}
+		err = json.NewDecoder(r.Body).Decode(&queryDesc)
+		if err != nil {
+			log.Println(err)
+			writeError(w, http.StatusBadRequest, errCodeInvalidRequest, err)
+			return
+		}
+		_, err = newQueryPlan(queryDesc)
+		if err != nil {
+			log.Println(err)
+			writeQueryError(w, err)
+			return
+		}
+
+		result, err := collection.Query(queryDesc)
+		if err != nil {
+			log.Println(err)
+			writeError(w, http.StatusInternalServerError, errCodeQueryFailed, err)
+			return
+		}
+		json.NewEncoder(w).Encode(result)
+	})
+
+	service.Route("POST", "/collections/:collection/compact", "compacts a collection", func(w http.ResponseWriter, r *http.Request) {
+		var params siesta.Params
+		collectionName := params.String("collection", "", "collection name")
+		err := params.Parse(r.Form)
+		if err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		collectionsLock.Lock()
+		collection, present := Collections[*collectionName]
+		collectionsLock.Unlock()
+
+		if !present {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		err = collection.Compact()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			log.Println(err)
+		}
+	})
+
+	service.Route("GET", "/collections/:collection/export", "streams events of a collection as NDJSON, CSV or Parquet", func(w http.ResponseWriter, r *http.Request) {
+		var params siesta.Params
+		collectionName := params.String("collection", "", "collection name")
+		format := params.String("format", ExportNDJSON, "ndjson, csv or parquet")
+		filter := params.String("filter", "", "filter expression")
+		start := params.Int64("start", 0, "Start Unix timestamp")
+		end := params.Int64("end", 0, "End Unix timestamp")
+		err := params.Parse(r.Form)
+		if err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		collectionsLock.Lock()
+		collection, present := Collections[*collectionName]
+		collectionsLock.Unlock()
+
+		if !present {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		queryDesc := &query.Desc{}
+		if *filter != "" {
+			queryDesc, err = query.Parse("FILTER " + *filter)
+			if err != nil {
+				log.Println(err)
+				writeQueryError(w, err)
+				return
+			}
+			err = decodeFilterValues(queryDesc)
 			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
 				log.Println(err)
+				writeError(w, http.StatusBadRequest, errCodeInvalidFilterValue, err)
 				return
 			}
-			filter.Value = v
-			queryDesc.Filters[i] = filter
 		}
+		setDefaultTimeRange(queryDesc, *start, *end)
 
-		result, err := collection.Query(*queryDesc)
+		switch *format {
+		case ExportNDJSON, ExportCSV, ExportParquet:
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", ExportContentType(*format))
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", *collectionName+"."+*format))
+		err = collection.Export(*queryDesc, *format, w)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
+			// Headers are already sent if any events were written.
+			log.Println(err)
+		}
+	})
+
+	service.Route("GET", "/collections/:collection/verify", "compares the events of a source with the stored events", func(w http.ResponseWriter, r *http.Request) {
+		var params siesta.Params
+		collectionName := params.String("collection", "", "collection name")
+		source := params.String("source", "", "source name")
+		start := params.Int64("start", 0, "Start Unix timestamp")
+		end := params.Int64("end", 0, "End Unix timestamp (defaults to now)")
+		err := params.Parse(r.Form)
+		if err != nil || *source == "" {
 			log.Println(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
 		}
 
+		endTime := time.Now()
+		if *end > 0 {
+			endTime = time.Unix(*end, 0)
+		}
+		result, err := VerifySource(*collectionName, *source, time.Unix(*start, 0), endTime)
+		if err != nil {
+			switch err {
+			case ErrDoesNotExist, ErrUnknownSource:
+				w.WriteHeader(http.StatusNotFound)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			log.Println(err)
+			return
+		}
 		json.NewEncoder(w).Encode(result)
 	})
 
-	service.Route("POST", "/collections/:collection/compact", "compacts a collection", func(w http.ResponseWriter, r *http.Request) {
+	service.Route("GET", "/collections/:collection/replication", "returns write batches to a follower", func(w http.ResponseWriter, r *http.Request) {
 		var params siesta.Params
 		collectionName := params.String("collection", "", "collection name")
+		after := params.Int64("after", 0, "sequence number of the last batch the follower has")
+		wait := params.Int("wait", 0, "seconds to wait for new batches")
+		followerName := params.String("follower", "", "follower name")
 		err := params.Parse(r.Form)
 		if err != nil {
 			log.Println(err)
@@ -98,11 +438,335 @@ func service() *siesta.Service {
 			return
 		}
 
-		err = collection.Compact()
+		name := *followerName
+		if name == "" {
+			name = r.RemoteAddr
+		}
+		resp, err := collection.Replicate(name, *after, time.Duration(*wait)*time.Second)
+		if err != nil {
+			switch err {
+			case ErrNotLeader:
+				w.WriteHeader(http.StatusConflict)
+			case ErrResyncNeeded:
+				w.WriteHeader(http.StatusGone)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			log.Println(err)
+			return
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+
+	service.Route("POST", "/collections/:collection/promote", "promotes a follower to leader", func(w http.ResponseWriter, r *http.Request) {
+		var params siesta.Params
+		collectionName := params.String("collection", "", "collection name")
+		err := params.Parse(r.Form)
+		if err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		collectionsLock.Lock()
+		collection, present := Collections[*collectionName]
+		collectionsLock.Unlock()
+
+		if !present {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		err = collection.Promote()
+		if err != nil {
+			if err == ErrNotFollower {
+				w.WriteHeader(http.StatusConflict)
+			} else {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			log.Println(err)
+		}
+	})
+
+	service.Route("GET", "/collections/:collection/fields", "returns the fields seen in a collection", func(w http.ResponseWriter, r *http.Request) {
+		var params siesta.Params
+		collectionName := params.String("collection", "", "collection name")
+		prefix := params.String("prefix", "", "only return fields starting with prefix")
+		err := params.Parse(r.Form)
+		if err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		collectionsLock.Lock()
+		collection, present := Collections[*collectionName]
+		collectionsLock.Unlock()
+
+		if !present {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		json.NewEncoder(w).Encode(collection.Fields(*prefix))
+	})
+
+	service.Route("GET", "/collections/:collection/archive", "returns the archive catalog", func(w http.ResponseWriter, r *http.Request) {
+		var params siesta.Params
+		collectionName := params.String("collection", "", "collection name")
+		err := params.Parse(r.Form)
+		if err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		collectionsLock.Lock()
+		collection, present := Collections[*collectionName]
+		collectionsLock.Unlock()
+
+		if !present {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		segments := collection.ArchiveSegments()
+		if segments == nil {
+			segments = []ArchiveSegment{}
+		}
+		json.NewEncoder(w).Encode(segments)
+	})
+
+	service.Route("POST", "/collections/:collection/archive", "archives old events now", func(w http.ResponseWriter, r *http.Request) {
+		var params siesta.Params
+		collectionName := params.String("collection", "", "collection name")
+		err := params.Parse(r.Form)
+		if err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		collectionsLock.Lock()
+		collection, present := Collections[*collectionName]
+		collectionsLock.Unlock()
+
+		if !present {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		archived, err := collection.Archive(*collectionName, time.Now())
+		if err != nil {
+			switch err {
+			case ErrNotArchived, ErrReadOnly:
+				w.WriteHeader(http.StatusConflict)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			log.Println(err)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]int64{"archived": archived})
+	})
+
+	service.Route("GET", "/collections/:collection/snapshot", "streams a snapshot of a collection as a tar archive", func(w http.ResponseWriter, r *http.Request) {
+		var params siesta.Params
+		collectionName := params.String("collection", "", "collection name")
+		err := params.Parse(r.Form)
+		if err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		collectionsLock.Lock()
+		collection, present := Collections[*collectionName]
+		collectionsLock.Unlock()
+
+		if !present {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-tar")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", *collectionName+".tar"))
+		err = collection.SnapshotTar(*collectionName, w)
+		if err != nil {
+			// Headers are already sent if any part of the archive was written.
+			log.Println(err)
+		}
+	})
+
+	service.Route("POST", "/collections/:collection/snapshot", "writes a snapshot of a collection to a directory", func(w http.ResponseWriter, r *http.Request) {
+		var params siesta.Params
+		collectionName := params.String("collection", "", "collection name")
+		dir := params.String("dir", "", "snapshot directory, relative to the data directory")
+		err := params.Parse(r.Form)
+		if err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		snapshotPath, err := snapshotDir(*dir)
+		if err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		collectionsLock.Lock()
+		collection, present := Collections[*collectionName]
+		collectionsLock.Unlock()
+
+		if !present {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		err = collection.Snapshot(*collectionName, snapshotPath)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			log.Println(err)
 		}
 	})
+
+	service.Route("POST", "/collections/:collection/restore", "restores a snapshot from a tar archive body or a directory", func(w http.ResponseWriter, r *http.Request) {
+		var params siesta.Params
+		collectionName := params.String("collection", "", "collection name")
+		dir := params.String("dir", "", "snapshot directory, relative to the data directory")
+		err := params.Parse(r.Form)
+		if err != nil || !collectionNameRegexp.MatchString(*collectionName) {
+			log.Println(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if *dir != "" {
+			var snapshotPath string
+			snapshotPath, err = snapshotDir(*dir)
+			if err != nil {
+				log.Println(err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			err = RestoreSnapshot(snapshotPath, *collectionName)
+		} else {
+			err = RestoreSnapshotTar(r.Body, *collectionName)
+		}
+		if err != nil {
+			if err == ErrHasSources {
+				w.WriteHeader(http.StatusConflict)
+			} else {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			log.Println(err)
+		}
+	})
 	return service
 }
+
+// setDefaultTimeRange sets the start and end of a query from Unix
+// timestamps unless the query sets them itself.
+func setDefaultTimeRange(desc *query.Desc, start, end int64) {
+	if desc.TimeRange.Start.IsZero() {
+		desc.TimeRange.Start = time.Unix(start, 0)
+	}
+	if desc.TimeRange.End.IsZero() {
+		desc.TimeRange.End = time.Unix(end, 0)
+	}
+}
+
+// decodeFilterValues replaces the raw JSON filter values of a parsed
+// query with their decoded values.
+func decodeFilterValues(desc *query.Desc) error {
+	err := decodeFilterList(desc.Filters)
+	if err != nil {
+		return err
+	}
+	return decodeFilterList(desc.Having)
+}
+
+func decodeFilterList(filters []query.Filter) error {
+	for i := range filters {
+		err := decodeFilterValue(&filters[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeFilterValue(filter *query.Filter) error {
+	switch {
+	case filter.And != nil:
+		return decodeFilterList(filter.And)
+	case filter.Or != nil:
+		return decodeFilterList(filter.Or)
+	case filter.Not != nil:
+		return decodeFilterValue(filter.Not)
+	}
+	raw, ok := filter.Value.(json.RawMessage)
+	if !ok {
+		return nil
+	}
+	var v interface{}
+	err := json.Unmarshal([]byte(raw), &v)
+	if err != nil {
+		return err
+	}
+	filter.Value = v
+	return nil
+}
+
+// Error codes of API error responses.
+const (
+	errCodeInvalidRequest      = "invalid_request"
+	errCodeNotFound            = "not_found"
+	errCodeSyntax              = "syntax_error"
+	errCodeInvalidQuery        = "invalid_query"
+	errCodeInvalidFilterValue  = "invalid_filter_value"
+	errCodeInvalidContinuation = "invalid_continuation"
+	errCodeQueryFailed         = "query_failed"
+)
+
+// APIError is the body of an error response. Syntax errors also have
+// the position of the error in the query and the tokens expected there.
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	*query.SyntaxError
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// writeError writes an error response.
+func writeError(w http.ResponseWriter, status int, code string, err error) {
+	apiErr := &APIError{
+		Code:    code,
+		Message: err.Error(),
+	}
+	if syntaxErr, ok := err.(*query.SyntaxError); ok {
+		apiErr.SyntaxError = syntaxErr
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(apiErr)
+}
+
+// writeQueryError writes the error response for a query that can't be
+// parsed or planned.
+func writeQueryError(w http.ResponseWriter, err error) {
+	if _, ok := err.(*query.SyntaxError); ok {
+		writeError(w, http.StatusBadRequest, errCodeSyntax, err)
+		return
+	}
+	switch err {
+	case errInvalidContinuation, errContinuationMismatch, errContinuationUnordered:
+		writeError(w, http.StatusBadRequest, errCodeInvalidContinuation, err)
+	default:
+		writeError(w, http.StatusBadRequest, errCodeInvalidQuery, err)
+	}
+}
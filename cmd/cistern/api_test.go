@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/Cistern/cistern/internal/query"
+)
+
+func TestWriteQueryError(t *testing.T) {
+	_, parseErr := query.Parse("SELECT sum(bytes")
+	testCases := []struct {
+		err  error
+		code string
+	}{
+		{parseErr, errCodeSyntax},
+		{errContinuationMismatch, errCodeInvalidContinuation},
+		{errContinuationUnordered, errCodeInvalidContinuation},
+	}
+	for _, c := range testCases {
+		w := httptest.NewRecorder()
+		writeQueryError(w, c.err)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%v: expected status %d but got %d", c.err, http.StatusBadRequest, w.Code)
+		}
+		apiErr := APIError{}
+		err := json.Unmarshal(w.Body.Bytes(), &apiErr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if apiErr.Code != c.code || apiErr.Message != c.err.Error() {
+			t.Errorf("%v: unexpected error response %s", c.err, w.Body)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	writeQueryError(w, parseErr)
+	body := map[string]interface{}{}
+	err := json.Unmarshal(w.Body.Bytes(), &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body["line"] != 1.0 || body["column"] != 17.0 || body["expected"] == nil {
+		t.Errorf("expected the position of the syntax error but got %s", w.Body)
+	}
+}
+
+func TestInvalidFilters(t *testing.T) {
+	dataDir, err := ioutil.TempDir("", "cistern-api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataDir)
+	defer func(dir string) { DataDir = dir }(DataDir)
+	DataDir = dataDir
+
+	collection, err := CreateCollection("api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer DeleteCollection("api")
+	err = collection.StoreEvents(testEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests := []*http.Request{}
+	for _, queryString := range []string{
+		`FILTER source_address matches "(10"`,
+		`FILTER source_address IN CIDR ("10.0.0.0/33")`,
+		`FILTER source_address NOT IN CIDR ("10.0.0.0", "10.0.0.0/8")`,
+	} {
+		form := url.Values{"query": {queryString}}
+		requests = append(requests, httptest.NewRequest("POST", "/api/collections/api/query?"+form.Encode(), nil))
+	}
+	// Queries from a coordinator aren't parsed, so their filters can
+	// have any number of values.
+	for _, values := range [][]interface{}{{1.0}, {1.0, 2.0, 3.0}} {
+		body, err := json.Marshal(query.Desc{
+			Filters: []query.Filter{{Column: "bytes", Condition: "between", Value: values}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		requests = append(requests, httptest.NewRequest("POST", "/api/collections/api/partial_query", bytes.NewReader(body)))
+	}
+
+	for _, r := range requests {
+		w := httptest.NewRecorder()
+		service().ServeHTTP(w, r)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d but got %d", r.URL, http.StatusBadRequest, w.Code)
+		}
+		apiErr := APIError{}
+		err := json.Unmarshal(w.Body.Bytes(), &apiErr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if apiErr.Code != errCodeInvalidQuery {
+			t.Errorf("%s: expected error code %s but got %s", r.URL, errCodeInvalidQuery, w.Body)
+		}
+	}
+}
+
+func TestPushEventErrors(t *testing.T) {
+	dataDir, err := ioutil.TempDir("", "cistern-api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataDir)
+	defer func(dir string) { DataDir = dir }(DataDir)
+	DataDir = dataDir
+
+	collection, err := CreateCollection("api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer DeleteCollection("api")
+
+	push := func(body string) int {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/api/collections/api/events", bytes.NewReader([]byte(body)))
+		service().ServeHTTP(w, r)
+		return w.Code
+	}
+	for _, body := range []string{
+		`[{"_tag": "a b", "_ts": "2017-08-01T03:20:00Z"}]`,
+		`[{"_tag": "a", "_ts": "yesterday"}]`,
+		`[{"_tag": "a"}]`,
+		`[{"_tag": "a", "_ts": "2017-08-01T03:20:00Z", "_hash": "a|b"}]`,
+	} {
+		if code := push(body); code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d but got %d", body, http.StatusBadRequest, code)
+		}
+	}
+
+	// Failures of the server aren't blamed on the events.
+	collection.col.Close()
+	body := `[{"_tag": "a", "_ts": "2017-08-01T03:20:00Z"}]`
+	if code := push(body); code != http.StatusInternalServerError {
+		t.Errorf("expected status %d but got %d", http.StatusInternalServerError, code)
+	}
+}
@@ -0,0 +1,873 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Preetam/lm2"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const (
+	defaultArchiveAfter = 14 // days
+
+	// archiveCatalogSuffix is appended to the collection file name
+	// to get the file name of its archive catalog.
+	archiveCatalogSuffix = ".archive.json"
+
+	archiveInterval = time.Hour
+	day             = 24 * time.Hour
+)
+
+var (
+	ErrNotArchived     = errors.New("cistern: collection isn't archived")
+	ErrSegmentNotFound = errors.New("cistern: archive segment not found")
+)
+
+// archiveSegmentBytes is the approximate size of the records in a
+// segment before compression. Days with more events are split into
+// several segments.
+var archiveSegmentBytes = 64 << 20
+
+// ArchiveStore stores immutable archive segments by name.
+type ArchiveStore interface {
+	Put(name string, data []byte) error
+	Get(name string) ([]byte, error)
+	Delete(name string) error
+}
+
+// NewArchiveStore returns the archive store described by config.
+func NewArchiveStore(config ConfigArchive) (ArchiveStore, error) {
+	if config.Dir != "" && config.Bucket != "" {
+		return nil, errors.New("archive can't have both a dir and a bucket")
+	}
+	if config.Dir != "" {
+		return dirArchiveStore{dir: filepath.Join(config.Dir, config.Prefix)}, nil
+	}
+	if config.Bucket == "" {
+		return nil, errors.New("archive needs a dir or a bucket")
+	}
+
+	awsConfig := aws.NewConfig()
+	if config.Region != "" {
+		awsConfig = awsConfig.WithRegion(config.Region)
+	}
+	if config.Endpoint != "" {
+		// S3-compatible stores usually don't support virtual-hosted buckets.
+		awsConfig = awsConfig.WithEndpoint(config.Endpoint).WithS3ForcePathStyle(true)
+	}
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, err
+	}
+	return s3ArchiveStore{
+		svc:    s3.New(sess),
+		bucket: config.Bucket,
+		prefix: config.Prefix,
+	}, nil
+}
+
+// dirArchiveStore stores segments as files in a local directory.
+type dirArchiveStore struct {
+	dir string
+}
+
+func (s dirArchiveStore) Put(name string, data []byte) error {
+	filename := filepath.Join(s.dir, filepath.FromSlash(name))
+	err := os.MkdirAll(filepath.Dir(filename), 0700)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filename, data)
+}
+
+func (s dirArchiveStore) Get(name string) ([]byte, error) {
+	data, err := ioutil.ReadFile(filepath.Join(s.dir, filepath.FromSlash(name)))
+	if os.IsNotExist(err) {
+		return nil, ErrSegmentNotFound
+	}
+	return data, err
+}
+
+func (s dirArchiveStore) Delete(name string) error {
+	err := os.Remove(filepath.Join(s.dir, filepath.FromSlash(name)))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// s3ArchiveStore stores segments as objects in an S3 bucket.
+type s3ArchiveStore struct {
+	svc    *s3.S3
+	bucket string
+	prefix string
+}
+
+func (s s3ArchiveStore) Put(name string, data []byte) error {
+	_, err := s.svc.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.prefix + name),
+		Body:   bytes.NewReader(data),
+	})
+	return err
+}
+
+func (s s3ArchiveStore) Get(name string) ([]byte, error) {
+	resp, err := s.svc.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.prefix + name),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, ErrSegmentNotFound
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (s s3ArchiveStore) Delete(name string) error {
+	_, err := s.svc.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.prefix + name),
+	})
+	return err
+}
+
+// writeFileAtomic writes data to a temporary file and renames it
+// to filename so readers never see a partial file.
+func writeFileAtomic(filename string, data []byte) error {
+	tmp := filename + ".tmp"
+	err := ioutil.WriteFile(tmp, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+// ArchiveSegment is an immutable, compressed file of archived events.
+type ArchiveSegment struct {
+	Name    string    `json:"name"`
+	Start   time.Time `json:"start"` // oldest event
+	End     time.Time `json:"end"`   // newest event
+	Events  int64     `json:"events"`
+	Bytes   int64     `json:"bytes"` // compressed size
+	Created time.Time `json:"created"`
+}
+
+// ArchiveInfo summarizes the archive of a collection.
+type ArchiveInfo struct {
+	After    int        `json:"after"` // days events stay on local disk
+	Segments int        `json:"segments"`
+	Events   int64      `json:"events"`
+	Bytes    int64      `json:"bytes"`
+	Oldest   *time.Time `json:"oldest,omitempty"`
+	Newest   *time.Time `json:"newest,omitempty"`
+}
+
+// archiveCatalog lists the segments of a collection. It is stored
+// next to the collection file.
+type archiveCatalog struct {
+	Segments []ArchiveSegment `json:"segments"`
+}
+
+// archive is the archival tier of a collection. Events older than
+// after days are moved out of the collection, a UTC day at a time,
+// into segments in the store.
+type archive struct {
+	store  ArchiveStore
+	after  int
+	prefix string // segment name prefix
+
+	lock     sync.Mutex
+	segments []ArchiveSegment // ordered by start
+
+	runLock sync.Mutex // serializes archive runs
+}
+
+// archiveRecord is an event record in a segment.
+type archiveRecord struct {
+	key   string
+	value string
+}
+
+// SetArchive moves events older than after days from the collection
+// to segments in store. The names of segments start with prefix.
+// Only leaders archive events. Followers get the catalog of their
+// leader and read its segments from the same store.
+func (c *EventCollection) SetArchive(store ArchiveStore, after int, prefix string) error {
+	a := &archive{
+		store:  store,
+		after:  after,
+		prefix: prefix,
+	}
+	catalog := archiveCatalog{}
+	data, err := ioutil.ReadFile(c.filename + archiveCatalogSuffix)
+	if err == nil {
+		err = json.Unmarshal(data, &catalog)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	a.segments = catalog.Segments
+	c.archive = a
+	return nil
+}
+
+// ArchiveSegments returns the catalog of archived segments, oldest first.
+func (c *EventCollection) ArchiveSegments() []ArchiveSegment {
+	if c.archive == nil {
+		return nil
+	}
+	return c.archive.catalog()
+}
+
+// ArchiveInfo summarizes the archive of the collection. It returns nil
+// if the collection isn't archived.
+func (c *EventCollection) ArchiveInfo() *ArchiveInfo {
+	if c.archive == nil {
+		return nil
+	}
+	info := &ArchiveInfo{After: c.archive.after}
+	for _, segment := range c.archive.catalog() {
+		info.Segments++
+		info.Events += segment.Events
+		info.Bytes += segment.Bytes
+		start, end := segment.Start, segment.End
+		if info.Oldest == nil || start.Before(*info.Oldest) {
+			info.Oldest = &start
+		}
+		if info.Newest == nil || end.After(*info.Newest) {
+			info.Newest = &end
+		}
+	}
+	return info
+}
+
+func (a *archive) catalog() []ArchiveSegment {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return append([]ArchiveSegment(nil), a.segments...)
+}
+
+// saveCatalog replaces the segments in the catalog and writes it to disk.
+func (a *archive) saveCatalog(filename string, segments []ArchiveSegment) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	segments, err := writeCatalog(filename, segments)
+	if err != nil {
+		return err
+	}
+	a.segments = segments
+	return nil
+}
+
+// writeCatalog writes a catalog of segments to filename. It returns
+// the segments ordered by start.
+func writeCatalog(filename string, segments []ArchiveSegment) ([]ArchiveSegment, error) {
+	segments = append([]ArchiveSegment{}, segments...)
+	sort.SliceStable(segments, func(i, j int) bool {
+		return segments[i].Start.Before(segments[j].Start)
+	})
+	data, err := json.Marshal(archiveCatalog{Segments: segments})
+	if err != nil {
+		return nil, err
+	}
+	return segments, writeFileAtomic(filename, data)
+}
+
+// saveArchiveCatalog replaces the archive catalog of the collection.
+// The catalog is written even if the collection isn't archived, so
+// it's loaded once an archive is set.
+func (c *EventCollection) saveArchiveCatalog(segments []ArchiveSegment) error {
+	filename := c.filename + archiveCatalogSuffix
+	if c.archive != nil {
+		return c.archive.saveCatalog(filename, segments)
+	}
+	_, err := writeCatalog(filename, segments)
+	return err
+}
+
+// Archive moves the events of every UTC day that ended more than the
+// archive threshold ago to the archive, and removes segments that are
+// past the retention period. It returns the number of archived events.
+func (c *EventCollection) Archive(name string, now time.Time) (int64, error) {
+	a := c.archive
+	if a == nil {
+		return 0, ErrNotArchived
+	}
+	if c.isFollower() {
+		return 0, ErrReadOnly
+	}
+	a.runLock.Lock()
+	defer a.runLock.Unlock()
+
+	cutoff := now.UTC().Add(-time.Duration(a.after) * day).Truncate(day)
+	archived := int64(0)
+	for {
+		c.lock.RLock()
+		oldest, _, err := c.timeRange()
+		c.lock.RUnlock()
+		if err != nil {
+			return archived, err
+		}
+		if oldest < 0 || !fromMicrosecondTime(oldest).Before(cutoff) {
+			break
+		}
+		n, err := c.archiveDay(name, fromMicrosecondTime(oldest).UTC().Truncate(day))
+		archived += n
+		if err != nil {
+			return archived, err
+		}
+	}
+
+	err := c.expireArchive(now)
+	if err != nil {
+		return archived, err
+	}
+	if archived > 0 {
+		// Reclaim the space of the removed events.
+		err = c.Compact()
+	}
+	return archived, err
+}
+
+// archiveDay moves the events of the UTC day starting at start to new
+// segments and removes them from the collection. The day is read from
+// a snapshot, and a segment is written each time archiveSegmentBytes
+// of records are read, so only one segment is held in memory.
+func (c *EventCollection) archiveDay(name string, start time.Time) (int64, error) {
+	a := c.archive
+	end := start.Add(day)
+	startTs := formatTs(toMicrosecondTime(start))
+	endTs := formatTs(toMicrosecondTime(end))
+	startKey := string(eventKeyPrefix) + string(startTs[:])
+	endKey := string(eventKeyPrefix) + string(endTs[:])
+
+	c.lock.RLock()
+	cur, err := c.col.NewCursor()
+	c.lock.RUnlock()
+	if err != nil {
+		return 0, err
+	}
+	// Events can already be archived if an earlier run failed before
+	// removing them, or if they were captured again after archiving.
+	archivedCur := a.newCursor(startKey, endKey)
+	archivedOK := archivedCur.Next()
+
+	archived := int64(0)
+	records := []archiveRecord{}    // read from the collection
+	newRecords := []archiveRecord{} // not archived yet
+	size := 0
+	flush := func() error {
+		if len(newRecords) > 0 {
+			segment, err := c.writeSegment(name, start, newRecords)
+			if err != nil {
+				return err
+			}
+			archived += segment.Events
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		c.snapshotLock.Lock()
+		err := c.removeArchived(records)
+		c.snapshotLock.Unlock()
+		records, newRecords, size = records[:0], newRecords[:0], 0
+		return err
+	}
+
+	cur.Seek(startKey)
+	for cur.Next() {
+		if cur.Key() < startKey {
+			continue
+		}
+		if cur.Key() >= endKey {
+			break
+		}
+		for archivedOK && archivedCur.Key() < cur.Key() {
+			archivedOK = archivedCur.Next()
+		}
+		if err = archivedCur.Err(); err != nil {
+			return archived, err
+		}
+		record := archiveRecord{key: cur.Key(), value: cur.Value()}
+		records = append(records, record)
+		if !archivedOK || archivedCur.Key() != record.key {
+			newRecords = append(newRecords, record)
+		}
+		size += len(record.key) + len(record.value)
+		if size >= archiveSegmentBytes {
+			err = flush()
+			if err != nil {
+				return archived, err
+			}
+		}
+	}
+	if err = cur.Err(); err != nil {
+		return archived, err
+	}
+	return archived, flush()
+}
+
+// writeSegment stores records of the UTC day starting at start as a new
+// segment and adds it to the catalog.
+func (c *EventCollection) writeSegment(name string, start time.Time, records []archiveRecord) (ArchiveSegment, error) {
+	a := c.archive
+	data, err := encodeSegment(records)
+	if err != nil {
+		return ArchiveSegment{}, err
+	}
+	first, _, _, err := splitCollectionID(records[0].key)
+	if err != nil {
+		return ArchiveSegment{}, err
+	}
+	last, _, _, err := splitCollectionID(records[len(records)-1].key)
+	if err != nil {
+		return ArchiveSegment{}, err
+	}
+	created := time.Now().UTC()
+	segment := ArchiveSegment{
+		Name:    a.prefix + start.Format("2006-01-02") + "-" + strconv.FormatInt(created.UnixNano(), 10) + ".seg.gz",
+		Start:   fromMicrosecondTime(first).UTC(),
+		End:     fromMicrosecondTime(last).UTC(),
+		Events:  int64(len(records)),
+		Bytes:   int64(len(data)),
+		Created: created,
+	}
+	err = a.store.Put(segment.Name, data)
+	if err != nil {
+		return ArchiveSegment{}, err
+	}
+	err = a.saveCatalog(c.filename+archiveCatalogSuffix, append(a.catalog(), segment))
+	if err != nil {
+		return ArchiveSegment{}, err
+	}
+	log.Printf("Archived %d events of %s from %s to %s", len(records), name,
+		start.Format("2006-01-02"), segment.Name)
+	return segment, nil
+}
+
+// removeArchived deletes archived events and their index entries
+// from the collection. The deletes are sent to followers along with
+// the archive catalog, which followers save before deleting events.
+// The caller must hold the snapshot lock.
+func (c *EventCollection) removeArchived(records []archiveRecord) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.countLock.Lock()
+	defer c.countLock.Unlock()
+
+	wb := lm2.NewWriteBatch()
+	replRecords := []ReplicationRecord{}
+	fch := fieldChanges{}
+	for _, record := range records {
+		wb.Delete(record.key)
+		event := Event{}
+		if json.Unmarshal([]byte(record.value), &event) == nil {
+			for _, indexKey := range indexEntries(c.indexes, event, record.key) {
+				wb.Delete(indexKey)
+			}
+			fch.remove(event)
+		}
+		replRecords = append(replRecords, ReplicationRecord{Key: []byte(record.key), Deleted: true})
+	}
+	count := c.EventCount() - int64(len(records))
+	if count < 0 {
+		count = 0
+	}
+	wb.Set(eventCountKey, strconv.FormatInt(count, 10))
+	if len(records) > 0 {
+		invalidateFieldCatalog(wb)
+	}
+	replBatch := ReplicationBatch{Sequence: c.ReplicationSequence() + 1, Records: replRecords}
+	if c.archive != nil {
+		replBatch.Archive = &archiveCatalog{Segments: c.archive.catalog()}
+	}
+	err := c.logBatch(wb, replBatch)
+	if err != nil {
+		return err
+	}
+	_, err = c.col.Update(wb)
+	if err != nil {
+		return err
+	}
+	atomic.StoreInt64(&c.eventCount, count)
+	c.commitBatch(replBatch)
+	c.applyFieldChanges(fch)
+	return nil
+}
+
+// expireArchive deletes segments whose events are all outside
+// of the retention period.
+func (c *EventCollection) expireArchive(now time.Time) error {
+	a := c.archive
+	if c.retention <= 0 {
+		return nil
+	}
+	minTs := now.Add(-time.Duration(c.retention) * day)
+	segments := a.catalog()
+	kept := []ArchiveSegment{}
+	expired := []ArchiveSegment{}
+	for _, segment := range segments {
+		if segment.End.Before(minTs) {
+			expired = append(expired, segment)
+		} else {
+			kept = append(kept, segment)
+		}
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+	c.snapshotLock.Lock()
+	defer c.snapshotLock.Unlock()
+
+	// Update the catalog first so queries never read deleted segments.
+	err := a.saveCatalog(c.filename+archiveCatalogSuffix, kept)
+	if err != nil {
+		return err
+	}
+	// Followers stop reading the expired segments too.
+	err = c.removeArchived(nil)
+	if err != nil {
+		return err
+	}
+	for _, segment := range expired {
+		err = a.store.Delete(segment.Name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteArchive deletes every segment and the catalog of the collection.
+func (c *EventCollection) deleteArchive() error {
+	if c.archive != nil {
+		for _, segment := range c.archive.catalog() {
+			err := c.archive.store.Delete(segment.Name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	err := os.Remove(c.filename + archiveCatalogSuffix)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// encodeSegment returns the gzip-compressed records as a sequence
+// of length-prefixed keys and values.
+func encodeSegment(records []archiveRecord) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	lenBuf := make([]byte, binary.MaxVarintLen64)
+	for _, record := range records {
+		for _, s := range []string{record.key, record.value} {
+			n := binary.PutUvarint(lenBuf, uint64(len(s)))
+			_, err := zw.Write(lenBuf[:n])
+			if err == nil {
+				_, err = io.WriteString(zw, s)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	err := zw.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeSegment(data []byte) ([]archiveRecord, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(zr)
+	readString := func() (string, error) {
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return "", err
+		}
+		b := make([]byte, n)
+		_, err = io.ReadFull(r, b)
+		return string(b), err
+	}
+
+	records := []archiveRecord{}
+	for {
+		key, err := readString()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		value, err := readString()
+		if err != nil {
+			return nil, fmt.Errorf("truncated segment: %v", err)
+		}
+		records = append(records, archiveRecord{key: key, value: value})
+	}
+}
+
+func (a *archive) readSegment(name string) ([]archiveRecord, error) {
+	data, err := a.store.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("reading archive segment %s: %v", name, err)
+	}
+	return decodeSegment(data)
+}
+
+// archiveCursor iterates over archived events between two event keys,
+// in key order, or in reverse key order if reverse is set. Segments
+// are read as the cursor reaches them.
+type archiveCursor struct {
+	archive  *archive
+	groups   [][]ArchiveSegment // segments not read yet, see groupSegments
+	startKey string
+	endKey   string
+	reverse  bool
+	records  []archiveRecord
+	pos      int
+	err      error
+}
+
+func (a *archive) newCursor(startKey string, endKey string) *archiveCursor {
+	return &archiveCursor{
+		archive:  a,
+		groups:   groupSegments(a.segmentsBetween(startKey, endKey)),
+		startKey: startKey,
+		endKey:   endKey,
+	}
+}
+
+// newReverseCursor returns an archiveCursor that starts at endKey and
+// iterates in reverse key order.
+func (a *archive) newReverseCursor(startKey string, endKey string) *archiveCursor {
+	ac := a.newCursor(startKey, endKey)
+	ac.reverse = true
+	return ac
+}
+
+// segmentsBetween returns the segments with events between two event
+// keys, ordered by start.
+func (a *archive) segmentsBetween(startKey string, endKey string) []ArchiveSegment {
+	segments := []ArchiveSegment{}
+	for _, segment := range a.catalog() {
+		startTs := formatTs(toMicrosecondTime(segment.Start))
+		endTs := formatTs(toMicrosecondTime(segment.End))
+		if string(eventKeyPrefix)+string(endTs[:])+"\xff" < startKey ||
+			string(eventKeyPrefix)+string(startTs[:]) > endKey {
+			continue
+		}
+		segments = append(segments, segment)
+	}
+	return segments
+}
+
+// groupSegments splits segments ordered by start into groups whose time
+// ranges overlap. The records of a group are merged, and groups don't
+// overlap each other.
+func groupSegments(segments []ArchiveSegment) [][]ArchiveSegment {
+	groups := [][]ArchiveSegment{}
+	for len(segments) > 0 {
+		end := segments[0].End
+		n := 1
+		for n < len(segments) && !segments[n].Start.After(end) {
+			if segments[n].End.After(end) {
+				end = segments[n].End
+			}
+			n++
+		}
+		groups = append(groups, segments[:n])
+		segments = segments[n:]
+	}
+	return groups
+}
+
+func (ac *archiveCursor) Next() bool {
+	if ac.reverse {
+		ac.pos--
+	} else {
+		ac.pos++
+	}
+	for ac.pos < 0 || ac.pos >= len(ac.records) {
+		if ac.err != nil || len(ac.groups) == 0 {
+			ac.records = nil
+			return false
+		}
+		ac.err = ac.readGroup()
+	}
+	return ac.err == nil
+}
+
+// readGroup reads the next group of segments and merges their records.
+// Each segment is decoded once.
+func (ac *archiveCursor) readGroup() error {
+	var group []ArchiveSegment
+	if ac.reverse {
+		group = ac.groups[len(ac.groups)-1]
+		ac.groups = ac.groups[:len(ac.groups)-1]
+	} else {
+		group = ac.groups[0]
+		ac.groups = ac.groups[1:]
+	}
+
+	records := []archiveRecord{}
+	for _, segment := range group {
+		segmentRecords, err := ac.archive.readSegment(segment.Name)
+		if err != nil {
+			return err
+		}
+		for _, record := range segmentRecords {
+			if record.key >= ac.startKey && record.key <= ac.endKey {
+				records = append(records, record)
+			}
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].key < records[j].key
+	})
+	ac.records = records[:0]
+	for i, record := range records {
+		if i == 0 || record.key != records[i-1].key {
+			ac.records = append(ac.records, record)
+		}
+	}
+	ac.pos = 0
+	if ac.reverse {
+		ac.pos = len(ac.records) - 1
+	}
+	return nil
+}
+
+func (ac *archiveCursor) Key() string {
+	if ac.pos < 0 || ac.pos >= len(ac.records) {
+		return ""
+	}
+	return ac.records[ac.pos].key
+}
+
+func (ac *archiveCursor) Value() string {
+	if ac.pos < 0 || ac.pos >= len(ac.records) {
+		return ""
+	}
+	return ac.records[ac.pos].value
+}
+
+func (ac *archiveCursor) Err() error {
+	return ac.err
+}
+
+// mergeCursor merges two cursors in key order, or in reverse key order
+// if both cursors are reversed. Keys in both cursors are returned once.
+type mergeCursor struct {
+	a, b     eventCursor
+	reverse  bool
+	aOK, bOK bool
+	started  bool
+	current  eventCursor
+}
+
+func (mc *mergeCursor) Next() bool {
+	if !mc.started {
+		mc.aOK = mc.a.Next()
+		mc.bOK = mc.b.Next()
+		mc.started = true
+	} else if mc.current == mc.a {
+		mc.aOK = mc.a.Next()
+	} else if mc.current == mc.b {
+		mc.bOK = mc.b.Next()
+	}
+	if mc.aOK && mc.bOK && mc.a.Key() == mc.b.Key() {
+		mc.bOK = mc.b.Next()
+	}
+
+	switch {
+	case mc.aOK && (!mc.bOK || (mc.a.Key() < mc.b.Key()) != mc.reverse):
+		mc.current = mc.a
+	case mc.bOK:
+		mc.current = mc.b
+	default:
+		mc.current = nil
+		return false
+	}
+	return true
+}
+
+func (mc *mergeCursor) Key() string {
+	if mc.current == nil {
+		return ""
+	}
+	return mc.current.Key()
+}
+
+func (mc *mergeCursor) Value() string {
+	if mc.current == nil {
+		return ""
+	}
+	return mc.current.Value()
+}
+
+func (mc *mergeCursor) Err() error {
+	if err := mc.a.Err(); err != nil {
+		return err
+	}
+	return mc.b.Err()
+}
+
+// archiveCollections periodically archives old events of collections.
+func archiveCollections(done chan struct{}) {
+	ticker := time.NewTicker(archiveInterval)
+	defer ticker.Stop()
+	for {
+		collectionsLock.Lock()
+		collections := map[string]*EventCollection{}
+		for name, collection := range Collections {
+			collections[name] = collection
+		}
+		collectionsLock.Unlock()
+
+		for name, collection := range collections {
+			if collection.archive == nil || collection.isFollower() {
+				continue
+			}
+			_, err := collection.Archive(name, time.Now())
+			if err != nil {
+				log.Printf("Couldn't archive collection %s: %v", name, err)
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-done:
+			return
+		}
+	}
+}
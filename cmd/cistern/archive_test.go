@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Cistern/cistern/internal/query"
+)
+
+func TestArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cistern-archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ec, err := CreateEventCollection("/tmp/test_cistern_archive.lm2", defaultCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(ec.filename + archiveCatalogSuffix)
+	defer func() { ec.col.Destroy() }()
+	store := &countingArchiveStore{ArchiveStore: dirArchiveStore{dir: dir}}
+	err = ec.SetArchive(store, 14, "test/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ec.StoreEvents(testEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archived, err := ec.Archive("test", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if archived != int64(len(testEvents)) || ec.EventCount() != 0 {
+		t.Fatalf("expected %d archived events and none left but got %d and %d",
+			len(testEvents), archived, ec.EventCount())
+	}
+	info := ec.ArchiveInfo()
+	if info.Segments != 1 || info.Events != int64(len(testEvents)) {
+		t.Errorf("expected one segment with %d events but got %+v", len(testEvents), info)
+	}
+
+	// Events captured again after archiving aren't archived twice.
+	err = ec.StoreEvents(testEvents[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	archived, err = ec.Archive("test", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if archived != 0 || ec.EventCount() != 0 {
+		t.Errorf("expected no new archived events and none left but got %d and %d", archived, ec.EventCount())
+	}
+
+	// Queries read archived and local events in order.
+	recent := Event{"_tag": "recent", "_ts": time.Now().UTC().Format(time.RFC3339), "source_port": 443}
+	err = ec.StoreEvents([]Event{recent})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ec.Query(query.Desc{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Events) != len(testEvents)+1 {
+		t.Fatalf("expected %d events but got %d", len(testEvents)+1, len(result.Events))
+	}
+	if result.Events[len(result.Events)-1]["_tag"] != "recent" {
+		t.Errorf("expected the local event last but got %v", result.Events[len(result.Events)-1])
+	}
+	result, err = ec.Query(query.Desc{
+		Filters: []query.Filter{{Column: "source_port", Condition: "=", Value: 443.0}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Events) != 4 {
+		t.Errorf("expected 4 events but got %d", len(result.Events))
+	}
+
+	// Reverse scans read each segment once.
+	store.gets = 0
+	timeRange := `SINCE "2017-08-01T03:00:00Z" UNTIL "2017-08-01T05:00:00Z"`
+	desc, err := query.Parse(timeRange + " ORDER BY _ts DESC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reversed, err := ec.Query(*desc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	desc, err = query.Parse(timeRange)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err = ec.Query(*desc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reversed.Events) != len(result.Events) {
+		t.Fatalf("expected %d events in reverse but got %d", len(result.Events), len(reversed.Events))
+	}
+	for i, event := range reversed.Events {
+		if event["_id"] != result.Events[len(result.Events)-1-i]["_id"] {
+			t.Errorf("expected %v at %d in reverse but got %v", result.Events[len(result.Events)-1-i], i, event)
+		}
+	}
+	if store.gets != 2 {
+		t.Errorf("expected each scan to read the segment once but got %d reads", store.gets)
+	}
+
+	// Segments past the retention period are deleted.
+	ec.SetRetention(30)
+	err = ec.expireArchive(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if segments := ec.ArchiveSegments(); len(segments) != 0 {
+		t.Errorf("expected expired segments to be deleted but got %+v", segments)
+	}
+	files, _ := ioutil.ReadDir(dir + "/test")
+	if len(files) != 0 {
+		t.Errorf("expected no segment files but got %d", len(files))
+	}
+}
+
+// countingArchiveStore counts the segments read from an ArchiveStore.
+type countingArchiveStore struct {
+	ArchiveStore
+	gets int
+}
+
+func (s *countingArchiveStore) Get(name string) ([]byte, error) {
+	s.gets++
+	return s.ArchiveStore.Get(name)
+}
+
+func TestArchiveReplicas(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cistern-archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(dataDir string) { DataDir = dataDir }(DataDir)
+	DataDir = dir
+	store := dirArchiveStore{dir: filepath.Join(dir, "segments")}
+
+	leader, err := CreateEventCollection(filepath.Join(dir, "leader.lm2"), defaultCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer leader.Close()
+	err = leader.SetArchive(store, 14, "test/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	follower, err := CreateEventCollection(filepath.Join(dir, "follower.lm2"), defaultCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer follower.Close()
+	err = follower.SetArchive(store, 14, "test/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	follower.Follow("follower", "127.0.0.1:1")
+	defer follower.StopFollowing()
+
+	err = leader.StoreEvents(testEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Days are split into segments as they're read.
+	defer func(n int) { archiveSegmentBytes = n }(archiveSegmentBytes)
+	archiveSegmentBytes = 1
+	archived, err := leader.Archive("leader", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if archived != int64(len(testEvents)) || len(leader.ArchiveSegments()) != len(testEvents) {
+		t.Fatalf("expected %d events in as many segments but got %d in %d",
+			len(testEvents), archived, len(leader.ArchiveSegments()))
+	}
+
+	// Followers read the segments of the leader.
+	resp, err := leader.Replicate("follower", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, batch := range resp.Batches {
+		err = follower.applyBatch(batch)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	result, err := follower.Query(query.Desc{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if follower.EventCount() != 0 || len(result.Events) != len(testEvents) {
+		t.Errorf("expected %d archived events on the follower but got %d, and %d local",
+			len(testEvents), len(result.Events), follower.EventCount())
+	}
+
+	// Snapshots include the archive catalog.
+	buf := &bytes.Buffer{}
+	err = leader.SnapshotTar("leader", buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = RestoreSnapshotTar(buf, "restored")
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := Collections["restored"]
+	defer delete(Collections, "restored")
+	defer restored.Close()
+	err = restored.SetArchive(store, 14, "test/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err = restored.Query(query.Desc{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Events) != len(testEvents) {
+		t.Errorf("expected %d restored events but got %d", len(testEvents), len(result.Events))
+	}
+}
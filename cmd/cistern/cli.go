@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// commands are the subcommands of the cistern binary. Each returns
+// the process exit code.
+var commands = map[string]func(args []string) int{
+	"snapshot": snapshotCommand,
+	"restore":  restoreCommand,
+	"export":   exportCommand,
+	"verify":   verifyCommand,
+}
+
+func collectionURL(apiAddr, collection, action string) string {
+	return fmt.Sprintf("http://%s/api/collections/%s/%s", apiAddr, url.PathEscape(collection), action)
+}
+
+// parseTime parses an RFC 3339 time or a Unix timestamp. An empty
+// string is the zero Unix timestamp.
+func parseTime(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if ts, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return ts, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Unix(), nil
+}
+
+func snapshotCommand(args []string) int {
+	flags := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	apiAddr := flags.String("api-addr", "localhost:2020", "API address of the running Cistern")
+	collection := flags.String("collection", "", "Collection to snapshot")
+	out := flags.String("out", "", "Output tar file (defaults to stdout)")
+	dir := flags.String("dir", "", "Directory under the server's data directory to write the snapshot to instead")
+	flags.Parse(args)
+
+	if *collection == "" {
+		flags.Usage()
+		return 2
+	}
+
+	if *dir != "" {
+		resp, err := http.PostForm(collectionURL(*apiAddr, *collection, "snapshot"), url.Values{"dir": {*dir}})
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			log.Println("Snapshot failed:", resp.Status)
+			return 1
+		}
+		return 0
+	}
+
+	resp, err := http.Get(collectionURL(*apiAddr, *collection, "snapshot"))
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Println("Snapshot failed:", resp.Status)
+		return 1
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+	_, err = io.Copy(w, resp.Body)
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+	return 0
+}
+
+func restoreCommand(args []string) int {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	apiAddr := flags.String("api-addr", "localhost:2020", "API address of the running Cistern")
+	collection := flags.String("collection", "", "Collection to restore into")
+	in := flags.String("in", "", "Input tar file (defaults to stdin)")
+	offline := flags.Bool("offline", false, "Restore directly into the data directory while Cistern is stopped")
+	flags.StringVar(&DataDir, "data-dir", DataDir, "Data directory (with -offline)")
+	flags.Parse(args)
+
+	if !collectionNameRegexp.MatchString(*collection) {
+		flags.Usage()
+		return 2
+	}
+
+	var r io.Reader = os.Stdin
+	if *in != "" {
+		f, err := os.Open(*in)
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		defer f.Close()
+		r = f
+	}
+
+	if *offline {
+		err := RestoreSnapshotTar(r, *collection)
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		Collections[*collection].Close()
+		return 0
+	}
+
+	resp, err := http.Post(collectionURL(*apiAddr, *collection, "restore"), "application/x-tar", r)
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Println("Restore failed:", resp.Status)
+		return 1
+	}
+	return 0
+}
+
+func exportCommand(args []string) int {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	apiAddr := flags.String("api-addr", "localhost:2020", "API address of the running Cistern")
+	collection := flags.String("collection", "", "Collection to export")
+	format := flags.String("format", ExportNDJSON, "Output format: ndjson, csv or parquet")
+	filter := flags.String("filter", "", "Filter expression, e.g. 'dest_port = 22'")
+	start := flags.String("start", "", "Start time (RFC 3339 or Unix timestamp)")
+	end := flags.String("end", "", "End time (RFC 3339 or Unix timestamp)")
+	out := flags.String("out", "", "Output file (defaults to stdout)")
+	flags.Parse(args)
+
+	if *collection == "" {
+		flags.Usage()
+		return 2
+	}
+	startTs, err := parseTime(*start)
+	if err != nil {
+		log.Println(err)
+		return 2
+	}
+	endTs, err := parseTime(*end)
+	if err != nil {
+		log.Println(err)
+		return 2
+	}
+
+	params := url.Values{
+		"format": {*format},
+		"start":  {strconv.FormatInt(startTs, 10)},
+		"end":    {strconv.FormatInt(endTs, 10)},
+	}
+	if *filter != "" {
+		params.Set("filter", *filter)
+	}
+	resp, err := http.Get(collectionURL(*apiAddr, *collection, "export") + "?" + params.Encode())
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Println("Export failed:", resp.Status)
+		return 1
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+	_, err = io.Copy(w, resp.Body)
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+	return 0
+}
+
+func verifyCommand(args []string) int {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	apiAddr := flags.String("api-addr", "localhost:2020", "API address of the running Cistern")
+	collection := flags.String("collection", "", "Collection to verify")
+	source := flags.String("source", "", "Log group feeding the collection")
+	start := flags.String("start", "", "Start time (RFC 3339 or Unix timestamp)")
+	end := flags.String("end", "", "End time (RFC 3339 or Unix timestamp, defaults to now)")
+	flags.Parse(args)
+
+	if *collection == "" || *source == "" {
+		flags.Usage()
+		return 2
+	}
+	startTs, err := parseTime(*start)
+	if err != nil {
+		log.Println(err)
+		return 2
+	}
+	endTs, err := parseTime(*end)
+	if err != nil {
+		log.Println(err)
+		return 2
+	}
+
+	params := url.Values{
+		"source": {*source},
+		"start":  {strconv.FormatInt(startTs, 10)},
+		"end":    {strconv.FormatInt(endTs, 10)},
+	}
+	resp, err := http.Get(collectionURL(*apiAddr, *collection, "verify") + "?" + params.Encode())
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Println("Verify failed:", resp.Status)
+		return 1
+	}
+
+	result := VerifyResult{}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+	fmt.Printf("Source events:  %d\n", result.SourceEvents)
+	fmt.Printf("Stored:         %d\n", result.Stored)
+	fmt.Printf("Missing:        %d\n", result.Missing)
+	fmt.Printf("Invalid:        %d\n", result.Invalid)
+	fmt.Printf("Checkpoint:     %s\n", result.Checkpoint.Format(time.RFC3339))
+	for _, id := range result.MissingIDs {
+		fmt.Println("Missing event", id)
+	}
+	if result.Missing > 0 {
+		return 1
+	}
+	return 0
+}
@@ -4,13 +4,18 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"path/filepath"
+	"sync"
 
 	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
 )
 
 type LogState struct {
 	LastTimestamp int64 `json:"last_timestamp"`
-	filename      string
+	// MessageHashUntil is the checkpoint of the source when it was
+	// first captured with CloudWatch event IDs. JSON log events up to
+	// it were stored with a hash of their message instead.
+	MessageHashUntil *int64 `json:"message_hash_until,omitempty"`
+	filename         string
 }
 
 func (s *LogState) Store() error {
@@ -36,9 +41,54 @@ func NewLogState(filename string) (*LogState, error) {
 		filename: filename,
 	}
 	state.Load()
+	if state.MessageHashUntil == nil {
+		until := state.messageHashUntil()
+		state.MessageHashUntil = &until
+	}
 	return state, state.Store()
 }
 
+// messageHashUntil returns the timestamp up to which JSON log events
+// were stored with a hash of their message. States stored before event
+// IDs were used don't have it, so it's their checkpoint.
+func (s *LogState) messageHashUntil() int64 {
+	if s.MessageHashUntil == nil {
+		return s.LastTimestamp
+	}
+	return *s.MessageHashUntil
+}
+
+// sourceStateFile returns the path of the checkpoint file of a source.
+func sourceStateFile(name string) string {
+	return filepath.Join(DataDir, name+".state")
+}
+
+// logEventConverter converts a CloudWatch Logs event to a Cistern event.
+type logEventConverter func(e *cloudwatchlogs.FilteredLogEvent) (Event, error)
+
+// newLogEventConverter returns the converter of a log group with the
+// checkpoint state s.
+type newLogEventConverter func(s *LogState) logEventConverter
+
+var (
+	// logSources holds the converters of the log groups being captured.
+	logSources     = map[string]newLogEventConverter{}
+	logSourcesLock sync.Mutex
+)
+
+func registerLogSource(name string, newConverter newLogEventConverter) {
+	logSourcesLock.Lock()
+	defer logSourcesLock.Unlock()
+	logSources[name] = newConverter
+}
+
+func logSourceConverter(name string) (newLogEventConverter, bool) {
+	logSourcesLock.Lock()
+	defer logSourcesLock.Unlock()
+	newConverter, ok := logSources[name]
+	return newConverter, ok
+}
+
 // CloudWatchLog is a CloudWatch Logs log group.
 type CloudWatchLog struct {
 	svc          *cloudwatchlogs.CloudWatchLogs
@@ -48,7 +98,7 @@ type CloudWatchLog struct {
 
 // NewCloudWatchLog returns a CloudWatchLog for the given log group name.
 func NewCloudWatchLog(svc *cloudwatchlogs.CloudWatchLogs, logGroupName string) (*CloudWatchLog, error) {
-	logState, err := NewLogState(filepath.Join(DataDir, logGroupName+".state"))
+	logState, err := NewLogState(sourceStateFile(logGroupName))
 	if err != nil {
 		return nil, err
 	}
@@ -61,9 +111,19 @@ func NewCloudWatchLog(svc *cloudwatchlogs.CloudWatchLogs, logGroupName string) (
 
 // GetLogEvents gets log events from the log group.
 func (cwl *CloudWatchLog) GetLogEvents(start int64) ([]*cloudwatchlogs.FilteredLogEvent, error) {
+	return cwl.GetLogEventsRange(start, 0)
+}
+
+// GetLogEventsRange gets log events from the log group with timestamps
+// between start and end in milliseconds. An end of 0 means no end.
+func (cwl *CloudWatchLog) GetLogEventsRange(start int64, end int64) ([]*cloudwatchlogs.FilteredLogEvent, error) {
 	result := []*cloudwatchlogs.FilteredLogEvent{}
 	limit := int64(10000)
 	var token *string
+	var endTime *int64
+	if end > 0 {
+		endTime = &end
+	}
 	interleaved := true
 	for {
 		output, err := cwl.svc.FilterLogEvents(&cloudwatchlogs.FilterLogEventsInput{
@@ -71,6 +131,7 @@ func (cwl *CloudWatchLog) GetLogEvents(start int64) ([]*cloudwatchlogs.FilteredL
 			Limit:        &limit,
 			NextToken:    token,
 			StartTime:    &start,
+			EndTime:      endTime,
 			Interleaved:  &interleaved,
 		})
 		if err != nil {
@@ -0,0 +1,440 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Cistern/cistern/internal/query"
+)
+
+// ClusterNode is the node this process runs as. It is nil unless
+// cluster mode is configured.
+var ClusterNode *Cluster
+
+// Cluster routes events and queries of sharded collections between nodes.
+//
+// A sharded collection is split by event tag across the nodes that own it,
+// so all events of a log stream end up on the same node. Any node can
+// coordinate: it forwards events to their owners and fans queries out to
+// all owners, merging the partial results.
+type Cluster struct {
+	self   string
+	nodes  map[string]string   // node name to API address
+	shards map[string][]string // collection name to owner nodes
+	client *http.Client
+}
+
+// NewCluster returns the cluster described by config as seen from
+// the node named in it.
+func NewCluster(config ConfigCluster) (*Cluster, error) {
+	cl := &Cluster{
+		self:   config.Node,
+		nodes:  map[string]string{},
+		shards: map[string][]string{},
+		client: &http.Client{Timeout: time.Minute},
+	}
+	for _, node := range config.Nodes {
+		cl.nodes[node.Name] = node.Addr
+	}
+	if _, ok := cl.nodes[cl.self]; !ok {
+		return nil, fmt.Errorf("node %q is not in the list of cluster nodes", cl.self)
+	}
+	for collection, owners := range config.Shards {
+		if len(owners) == 0 {
+			return nil, fmt.Errorf("collection %s has no owners", collection)
+		}
+		for _, owner := range owners {
+			if _, ok := cl.nodes[owner]; !ok {
+				return nil, fmt.Errorf("unknown node %q owns collection %s", owner, collection)
+			}
+		}
+		cl.shards[collection] = owners
+	}
+	return cl, nil
+}
+
+// IsSharded returns true if the collection is split across nodes.
+func (cl *Cluster) IsSharded(collection string) bool {
+	_, ok := cl.shards[collection]
+	return ok
+}
+
+// OwnsShard returns true if this node stores a shard of the collection.
+// Collections that aren't sharded are local to every node.
+func (cl *Cluster) OwnsShard(collection string) bool {
+	owners, ok := cl.shards[collection]
+	if !ok {
+		return true
+	}
+	for _, owner := range owners {
+		if owner == cl.self {
+			return true
+		}
+	}
+	return false
+}
+
+// owner returns the node that stores events with the given tag.
+func (cl *Cluster) owner(collection string, tag string) string {
+	owners := cl.shards[collection]
+	h := fnv.New32a()
+	h.Write([]byte(tag))
+	return owners[h.Sum32()%uint32(len(owners))]
+}
+
+// CapturesLogGroup returns true if this node captures the log group
+// feeding the collection. Each log group of a sharded collection is
+// captured by one of the owners, picked like the owner of a tag, which
+// routes its events to their owners. Collections that aren't sharded
+// capture on every node.
+func (cl *Cluster) CapturesLogGroup(collection string, group string) bool {
+	if !cl.IsSharded(collection) {
+		return true
+	}
+	return cl.owner(collection, group) == cl.self
+}
+
+func (cl *Cluster) url(node string, collection string, action string, params url.Values) string {
+	u := collectionURL(cl.nodes[node], collection, action)
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+	return u
+}
+
+// StoreEvents routes events of a sharded collection to their owners and
+// returns the number of events that weren't already stored.
+func (cl *Cluster) StoreEvents(collection string, events []Event) (int, error) {
+	byNode := map[string][]Event{}
+	for _, event := range events {
+		tag, ok := event["_tag"].(string)
+		if !ok || !eventIDTagRegexp.MatchString(tag) {
+			return 0, errInvalidTag
+		}
+		node := cl.owner(collection, tag)
+		byNode[node] = append(byNode[node], event)
+	}
+
+	stored := 0
+	for node, nodeEvents := range byNode {
+		n, err := cl.storeOnNode(node, collection, nodeEvents)
+		if err != nil {
+			return stored, err
+		}
+		stored += n
+	}
+	return stored, nil
+}
+
+func (cl *Cluster) storeOnNode(node string, collection string, events []Event) (int, error) {
+	if node == cl.self {
+		collectionsLock.Lock()
+		local := Collections[collection]
+		collectionsLock.Unlock()
+		if local == nil {
+			return 0, ErrDoesNotExist
+		}
+		return local.StoreNewEvents(events)
+	}
+
+	body, err := json.Marshal(events)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := cl.client.Post(cl.url(node, collection, "events", url.Values{"local": {"true"}}),
+		"application/json", bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusServiceUnavailable {
+		// The node is low on disk space.
+		return 0, ErrLowDisk
+	}
+	if resp.StatusCode == http.StatusBadRequest {
+		return 0, invalidEventError("node " + node + " rejected the events")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("storing events on node %s: %s", node, resp.Status)
+	}
+	result := map[string]int{}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	return result["stored"], err
+}
+
+// shardStore stores events of a sharded collection through the cluster.
+type shardStore struct {
+	cluster    *Cluster
+	collection string
+}
+
+func (s shardStore) StoreNewEvents(events []Event) (int, error) {
+	return s.cluster.StoreEvents(s.collection, events)
+}
+
+// Query runs a query on every owner of a sharded collection and
+// merges the results.
+func (cl *Cluster) Query(collection string, desc query.Desc) (*QueryResult, error) {
+	partial := partialDesc(desc)
+	owners := cl.shards[collection]
+	results := make([]*QueryResult, len(owners))
+	errs := make([]error, len(owners))
+	wg := sync.WaitGroup{}
+	for i, node := range owners {
+		wg.Add(1)
+		go func(i int, node string) {
+			defer wg.Done()
+			results[i], errs[i] = cl.queryNode(node, collection, partial)
+			if errs[i] != nil {
+				errs[i] = fmt.Errorf("querying node %s: %v", node, errs[i])
+			}
+		}(i, node)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return mergeQueryResults(desc, results)
+}
+
+func (cl *Cluster) queryNode(node string, collection string, desc query.Desc) (*QueryResult, error) {
+	var body []byte
+	if node == cl.self {
+		collectionsLock.Lock()
+		local := Collections[collection]
+		collectionsLock.Unlock()
+		if local == nil {
+			return nil, ErrDoesNotExist
+		}
+		result, err := local.Query(desc)
+		if err != nil {
+			return nil, err
+		}
+		// Round trip local results through JSON so they have the
+		// same types as remote results.
+		body, err = json.Marshal(result)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		reqBody, err := json.Marshal(desc)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := cl.client.Post(cl.url(node, collection, "partial_query", nil),
+			"application/json", bytes.NewReader(reqBody))
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			apiErr := &APIError{}
+			if json.NewDecoder(resp.Body).Decode(apiErr) == nil && apiErr.Message != "" {
+				return nil, fmt.Errorf("%s: %s", resp.Status, apiErr.Message)
+			}
+			return nil, errors.New(resp.Status)
+		}
+		body, err = ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return decodeQueryResult(body)
+}
+
+// decodeQueryResult decodes a marshaled query result, restoring
+// event timestamps.
+func decodeQueryResult(body []byte) (*QueryResult, error) {
+	result := &QueryResult{}
+	err := json.Unmarshal(body, result)
+	if err != nil {
+		return nil, err
+	}
+	for _, events := range [][]Event{result.Summary, result.Series, result.Events} {
+		for _, event := range events {
+			if s, ok := event["_ts"].(string); ok {
+				ts, err := time.Parse(time.RFC3339Nano, s)
+				if err != nil {
+					return nil, err
+				}
+				event["_ts"] = ts
+			}
+		}
+	}
+	return result, nil
+}
+
+// partialDesc returns the query each node runs for desc. Aggregates
+// are ordered and limited after merging, so nodes return all groups.
+func partialDesc(desc query.Desc) query.Desc {
+	plan, err := newQueryPlan(desc)
+	if err == nil && plan.aggregated {
+		desc.OrderBy = nil
+		desc.Limit = 0
+		desc.Top = 0
+		desc.Partial = true
+	}
+	return desc
+}
+
+// mergeQueryResults merges the partial results of nodes for desc.
+func mergeQueryResults(desc query.Desc, results []*QueryResult) (*QueryResult, error) {
+	start := time.Now()
+	merged := &QueryResult{
+		Summary: []Event{},
+		Series:  []Event{},
+		Events:  []Event{},
+		Query:   desc,
+		Stats:   &QueryStats{Phases: map[string]float64{}},
+	}
+	defer merged.Stats.phase("merge", start)
+
+	for _, result := range results {
+		merged.Stats.add(result.Stats)
+	}
+	if desc.Explain {
+		merged.Plan = mergeExplain(results)
+		return merged, nil
+	}
+
+	for _, result := range results {
+		merged.Events = append(merged.Events, result.Events...)
+	}
+	if pagedInKeyOrder(desc) {
+		err := sortByKey(merged.Events, newestFirst(desc))
+		if err != nil {
+			return nil, err
+		}
+		if desc.Limit > 0 && len(merged.Events) > desc.Limit {
+			merged.Events = merged.Events[:desc.Limit]
+		}
+		if desc.Limit > 0 && len(merged.Events) == desc.Limit {
+			merged.Continuation, err = encodeContinuation(desc, merged.Events[desc.Limit-1])
+			if err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		merged.Events = orderEvents(desc, merged.Events)
+	}
+
+	summaries := [][]Event{}
+	series := [][]Event{}
+	for _, result := range results {
+		summaries = append(summaries, result.Summary)
+		series = append(series, result.Series)
+	}
+	plan, err := newQueryPlan(desc)
+	if err != nil {
+		return nil, err
+	}
+	var summaryAggregators [][]aggregator
+	merged.Summary, summaryAggregators, err = mergeGroups(plan, summaries, func(event Event) string {
+		return event["_group_id"].(string)
+	}, true)
+	if err != nil {
+		return nil, err
+	}
+	groupAggregators := map[string][]aggregator{}
+	for i, event := range merged.Summary {
+		groupAggregators[event["_group_id"].(string)] = summaryAggregators[i]
+	}
+	// Keep the order of groups stable across queries before sorting.
+	sort.SliceStable(merged.Summary, func(i, j int) bool {
+		return merged.Summary[i]["_group_id"].(string) < merged.Summary[j]["_group_id"].(string)
+	})
+	var folded map[string]bool
+	merged.Summary = orderAndLimit(desc, merged.Summary)
+	merged.Summary, folded = plan.top(merged.Summary, groupAggregators)
+
+	validGroupIDs := map[string]bool{}
+	for _, e := range merged.Summary {
+		validGroupIDs[e["_group_id"].(string)] = true
+	}
+	var seriesAggregators [][]aggregator
+	merged.Series, seriesAggregators, err = mergeGroups(plan, series, func(event Event) string {
+		return event["_ts"].(time.Time).String() + "\x00" + event["_group_id"].(string)
+	}, false)
+	if err != nil {
+		return nil, err
+	}
+	seriesEvents := []Event{}
+	keptAggregators := [][]aggregator{}
+	for i, event := range merged.Series {
+		if validGroupIDs[event["_group_id"].(string)] || folded[event["_group_id"].(string)] {
+			seriesEvents = append(seriesEvents, event)
+			keptAggregators = append(keptAggregators, seriesAggregators[i])
+		}
+	}
+	seriesEvents = plan.foldSeries(seriesEvents, keptAggregators, folded)
+	sort.Stable(ByTimestamp(seriesEvents))
+	merged.Series = seriesEvents
+	return merged, nil
+}
+
+// mergeExplain merges the plans of nodes. Nodes run the same plan on
+// their own segments.
+func mergeExplain(results []*QueryResult) *QueryExplain {
+	var merged *QueryExplain
+	for _, result := range results {
+		if result.Plan == nil {
+			continue
+		}
+		if merged == nil {
+			plan := *result.Plan
+			merged = &plan
+			continue
+		}
+		merged.Segments += result.Plan.Segments
+	}
+	return merged
+}
+
+// mergeGroups combines the partial aggregates of events with the same
+// key and replaces them with the values of the columns. Groups that
+// don't match the HAVING filters are dropped if having is true. It
+// also returns the merged aggregates of each group.
+func mergeGroups(plan *queryPlan, partials [][]Event, key func(Event) string, having bool) ([]Event, [][]aggregator, error) {
+	groups := map[string]Event{}
+	aggregators := map[string][]aggregator{}
+	keys := []string{}
+	for _, events := range partials {
+		for _, event := range events {
+			k := key(event)
+			if _, ok := groups[k]; !ok {
+				groups[k] = event
+				aggregators[k] = plan.newAggregators()
+				keys = append(keys, k)
+			}
+			for i, call := range plan.aggregates {
+				agg, err := decodeAggregator(call.aggregate, event[call.key])
+				if err != nil {
+					return nil, nil, err
+				}
+				aggregators[k][i].merge(agg)
+			}
+		}
+	}
+	merged := []Event{}
+	mergedAggregators := [][]aggregator{}
+	for _, k := range keys {
+		group := groups[k]
+		if !plan.setColumns(group, aggregators[k]) && having {
+			continue
+		}
+		merged = append(merged, group)
+		mergedAggregators = append(mergedAggregators, aggregators[k])
+	}
+	return merged, mergedAggregators, nil
+}
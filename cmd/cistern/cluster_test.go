@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/Cistern/cistern/internal/query"
+)
+
+func TestMergeQueryResults(t *testing.T) {
+	whole, err := CreateEventCollection("/tmp/test_cistern_cluster_whole.lm2", defaultCacheSize)
+	defer whole.col.Destroy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := []*EventCollection{}
+	for _, filename := range []string{"/tmp/test_cistern_cluster_1.lm2", "/tmp/test_cistern_cluster_2.lm2"} {
+		shard, err := CreateEventCollection(filename, defaultCacheSize)
+		defer shard.col.Destroy()
+		if err != nil {
+			t.Fatal(err)
+		}
+		shards = append(shards, shard)
+	}
+
+	err = whole.StoreEvents(testEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, event := range testEvents {
+		err = shards[i%2].StoreEvents([]Event{event})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	queries := []string{
+		"LIMIT 3",
+		"ORDER BY source_address, bytes DESC LIMIT 4",
+		"ORDER BY _ts DESC LIMIT 3",
+		"SELECT sum(bytes), count(bytes), min(packets), max(packets) GROUP BY source_address",
+		"SELECT sum(bytes) GROUP BY dest_port ORDER BY sum(bytes) DESC LIMIT 2",
+		"SELECT count(bytes) GROUP BY source_address POINT SIZE 20m",
+		"SELECT avg(bytes), stddev(bytes), count_distinct(dest_port), approx_count_distinct(dest_port), p90(bytes), first(dest_port), last(dest_port) GROUP BY protocol",
+		"SELECT sum(bytes) / count(_id) AS avg_size, max(packets) - min(packets) GROUP BY source_address ORDER BY avg_size DESC LIMIT 1",
+		"SELECT sum(bytes) GROUP BY dest_port HAVING count(_id) > 1 AND p50(bytes) > 1000 POINT SIZE 20m",
+		"SELECT day, sum(bytes) GROUP BY bucket(_ts, 1h) AS day, cidr(dest_address, 16) ORDER BY sum(bytes) DESC",
+		"SELECT sum(bytes), p50(packets) GROUP BY dest_port TOP 2 POINT SIZE 20m",
+		"SELECT bytes / packets AS bpp ORDER BY _ts DESC LIMIT 3",
+	}
+	for _, queryString := range queries {
+		desc, err := query.Parse(queryString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Page through raw event queries with a limit.
+		for page := 0; page < 3; page++ {
+			expected, err := whole.Query(*desc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			partials := []*QueryResult{}
+			for _, shard := range shards {
+				result, err := shard.Query(partialDesc(*desc))
+				if err != nil {
+					t.Fatal(err)
+				}
+				body, err := json.Marshal(result)
+				if err != nil {
+					t.Fatal(err)
+				}
+				result, err = decodeQueryResult(body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				partials = append(partials, result)
+			}
+			merged, err := mergeQueryResults(*desc, partials)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !sameJSON(expected.Events, merged.Events) {
+				t.Errorf("%s: expected events %v but got %v", queryString, expected.Events, merged.Events)
+			}
+			if len(desc.OrderBy) > 0 && !sameJSON(expected.Summary, merged.Summary) {
+				t.Errorf("%s: expected summary %v but got %v", queryString, expected.Summary, merged.Summary)
+			}
+			if !sameJSON(byGroupID(expected.Summary), byGroupID(merged.Summary)) {
+				t.Errorf("%s: expected summary %v but got %v", queryString, expected.Summary, merged.Summary)
+			}
+			if expected.Continuation != merged.Continuation {
+				t.Errorf("%s: expected continuation %q but got %q", queryString, expected.Continuation, merged.Continuation)
+			}
+			if len(desc.GroupBy) > 0 && expected.Stats.EventsMatched != merged.Stats.EventsMatched {
+				t.Errorf("%s: expected %d events matched but got %d", queryString, expected.Stats.EventsMatched, merged.Stats.EventsMatched)
+			}
+			if len(expected.Series) != len(merged.Series) {
+				t.Errorf("%s: expected %d series points but got %d", queryString, len(expected.Series), len(merged.Series))
+			}
+			if merged.Continuation == "" {
+				break
+			}
+			desc.Continuation = merged.Continuation
+		}
+	}
+}
+
+func TestPartialDesc(t *testing.T) {
+	desc, err := query.Parse("SELECT bytes / packets AS bpp ORDER BY _ts DESC LIMIT 10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Raw events are limited and ordered on the nodes.
+	partial := partialDesc(*desc)
+	if partial.Partial || partial.Limit != 10 || len(partial.OrderBy) != 1 ||
+		queryFingerprint(partial) != queryFingerprint(*desc) {
+		t.Errorf("expected the query of a projection unchanged but got %+v", partial)
+	}
+
+	desc, err = query.Parse("SELECT sum(bytes) GROUP BY dest_port ORDER BY sum(bytes) DESC LIMIT 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	partial = partialDesc(*desc)
+	if !partial.Partial || partial.Limit != 0 || partial.OrderBy != nil {
+		t.Errorf("expected all groups from the nodes but got %+v", partial)
+	}
+}
+
+func TestCapturesLogGroup(t *testing.T) {
+	config := ConfigCluster{
+		Nodes: []ConfigNode{
+			{Name: "a", Addr: "a:2020"},
+			{Name: "b", Addr: "b:2020"},
+			{Name: "c", Addr: "c:2020"},
+		},
+		Shards: map[string][]string{"flowlogs": {"a", "b"}},
+	}
+	groups := []string{"vpc-1", "vpc-2", "vpc-3", "vpc-4", "vpc-5", "vpc-6"}
+	captures := map[string]int{}
+	for _, node := range config.Nodes {
+		config.Node = node.Name
+		cl, err := NewCluster(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, group := range groups {
+			if cl.CapturesLogGroup("flowlogs", group) {
+				captures[group]++
+				if !cl.OwnsShard("flowlogs") {
+					t.Errorf("expected only owners to capture but %s captures %s", node.Name, group)
+				}
+			}
+		}
+		if !cl.CapturesLogGroup("local", "app") {
+			t.Errorf("expected %s to capture the log group of a collection that isn't sharded", node.Name)
+		}
+	}
+	for _, group := range groups {
+		if captures[group] != 1 {
+			t.Errorf("expected one node to capture %s but got %d", group, captures[group])
+		}
+	}
+}
+
+func byGroupID(events []Event) map[string]Event {
+	groups := map[string]Event{}
+	for _, event := range events {
+		groups[event["_group_id"].(string)] = event
+	}
+	return groups
+}
+
+// sameJSON compares values by their JSON encoding, since merged results
+// have been decoded from JSON.
+func sameJSON(a, b interface{}) bool {
+	var aDecoded, bDecoded interface{}
+	aJSON, _ := json.Marshal(a)
+	bJSON, _ := json.Marshal(b)
+	json.Unmarshal(aJSON, &aDecoded)
+	json.Unmarshal(bJSON, &bDecoded)
+	return reflect.DeepEqual(aDecoded, bDecoded)
+}
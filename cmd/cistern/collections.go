@@ -0,0 +1,312 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Preetam/lm2"
+)
+
+var ErrExists = errors.New("cistern: already exists")
+
+// DefaultRetention is the retention in days of collections that
+// aren't in the config file.
+var DefaultRetention = defaultRetention
+
+// CollectionInfo describes a collection.
+type CollectionInfo struct {
+	Name      string     `json:"name"`
+	FileSize  int64      `json:"file_size"`
+	Stats     lm2.Stats  `json:"stats"`
+	Events    int64      `json:"events"`
+	Oldest    *time.Time `json:"oldest,omitempty"`
+	Newest    *time.Time `json:"newest,omitempty"`
+	Retention int        `json:"retention"`
+	MaxBytes  int64      `json:"max_bytes,omitempty"`
+	MaxEvents int64      `json:"max_events,omitempty"`
+	Indexes   []string   `json:"indexes,omitempty"`
+	Flatten   bool       `json:"flatten,omitempty"`
+	Sources   []string   `json:"sources,omitempty"`
+
+	Replication ReplicationInfo `json:"replication"`
+	Archive     *ArchiveInfo    `json:"archive,omitempty"`
+}
+
+// Info returns the details of the collection.
+func (c *EventCollection) Info(name string) (CollectionInfo, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	info := CollectionInfo{
+		Name:      name,
+		Stats:     c.col.Stats(),
+		Events:    c.EventCount(),
+		Retention: c.retention,
+		MaxBytes:  c.maxBytes,
+		MaxEvents: c.maxEvents,
+		Indexes:   c.indexes,
+		Flatten:   c.flatten,
+		Sources:   c.Sources(),
+
+		Replication: c.ReplicationInfo(),
+		Archive:     c.ArchiveInfo(),
+	}
+	size, err := c.DiskUsage()
+	if err != nil {
+		return info, err
+	}
+	info.FileSize = size
+
+	oldest, newest, err := c.timeRange()
+	if err != nil {
+		return info, err
+	}
+	if oldest >= 0 {
+		oldestTime := fromMicrosecondTime(oldest).UTC()
+		newestTime := fromMicrosecondTime(newest).UTC()
+		info.Oldest, info.Newest = &oldestTime, &newestTime
+	}
+	return info, nil
+}
+
+// timeRange returns the timestamps of the oldest and newest events,
+// or -1 if there are no events. The caller must hold the collection lock.
+func (c *EventCollection) timeRange() (int64, int64, error) {
+	cur, err := c.col.NewCursor()
+	if err != nil {
+		return 0, 0, err
+	}
+	oldest := int64(-1)
+	cur.Seek(string(eventKeyPrefix))
+	for cur.Next() {
+		if cur.Key()[0] < eventKeyPrefix {
+			continue
+		}
+		if cur.Key()[0] == eventKeyPrefix {
+			oldest, _, _, err = splitCollectionID(cur.Key())
+			if err != nil {
+				return 0, 0, err
+			}
+		}
+		break
+	}
+	if err = cur.Err(); err != nil || oldest < 0 {
+		return -1, -1, err
+	}
+
+	// Seek lands on the last key less than or equal to the given key,
+	// which is the newest event since no timestamp starts with 0xff.
+	cur, err = c.col.NewCursor()
+	if err != nil {
+		return 0, 0, err
+	}
+	cur.Seek(string(eventKeyPrefix) + "\xff")
+	if !cur.Next() {
+		return 0, 0, cur.Err()
+	}
+	newest, _, _, err := splitCollectionID(cur.Key())
+	if err != nil {
+		return 0, 0, err
+	}
+	return oldest, newest, nil
+}
+
+// CollectionNames returns the names of all open collections in order.
+func CollectionNames() []string {
+	collectionsLock.Lock()
+	defer collectionsLock.Unlock()
+	names := []string{}
+	for name := range Collections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CreateCollection creates an empty collection in the data directory.
+func CreateCollection(name string) (*EventCollection, error) {
+	if !collectionNameRegexp.MatchString(name) {
+		return nil, errors.New("invalid collection name")
+	}
+
+	collectionsLock.Lock()
+	defer collectionsLock.Unlock()
+	if Collections[name] != nil {
+		return nil, ErrExists
+	}
+
+	filename := filepath.Join(DataDir, name+".lm2")
+	if _, err := os.Stat(filename); err == nil {
+		return nil, ErrExists
+	}
+	// A leftover WAL would be replayed onto the new file.
+	os.Remove(filename + ".wal")
+	collection, err := CreateEventCollection(filename, defaultCacheSize)
+	if err != nil {
+		return nil, err
+	}
+	collection.SetRetention(DefaultRetention)
+	Collections[name] = collection
+	return collection, nil
+}
+
+// RenameCollection renames a collection and its files.
+// Collections with attached sources can't be renamed.
+func RenameCollection(name string, newName string) error {
+	if !collectionNameRegexp.MatchString(newName) {
+		return errors.New("invalid collection name")
+	}
+
+	collectionsLock.Lock()
+	defer collectionsLock.Unlock()
+	collection := Collections[name]
+	if collection == nil {
+		return ErrDoesNotExist
+	}
+	if Collections[newName] != nil {
+		return ErrExists
+	}
+	if len(collection.Sources()) > 0 {
+		return ErrHasSources
+	}
+
+	filename := filepath.Join(filepath.Dir(collection.filename), newName+".lm2")
+	if _, err := os.Stat(filename); err == nil {
+		return ErrExists
+	}
+	err := collection.rename(filename)
+	if err != nil {
+		return err
+	}
+	delete(Collections, name)
+	Collections[newName] = collection
+	return nil
+}
+
+// rename moves the collection files to filename. If that fails, the
+// files are moved back and the collection is reopened under its old name.
+func (c *EventCollection) rename(filename string) error {
+	c.snapshotLock.Lock()
+	defer c.snapshotLock.Unlock()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.col.Close()
+	moved := []string{}
+	var err error
+	for _, suffix := range []string{"", ".wal", archiveCatalogSuffix} {
+		if suffix != "" {
+			os.Remove(filename + suffix)
+		}
+		err = os.Rename(c.filename+suffix, filename+suffix)
+		if err == nil {
+			moved = append(moved, suffix)
+		} else if suffix != "" && os.IsNotExist(err) {
+			err = nil
+		} else {
+			break
+		}
+	}
+	var col *lm2.Collection
+	if err == nil {
+		col, err = lm2.OpenCollection(filename, c.cacheSize)
+	}
+	if err == nil {
+		c.filename = filename
+		c.col = col
+		return nil
+	}
+
+	for _, suffix := range moved {
+		os.Rename(filename+suffix, c.filename+suffix)
+	}
+	col, openErr := lm2.OpenCollection(c.filename, c.cacheSize)
+	if openErr != nil {
+		log.Printf("Couldn't reopen %s after a failed rename: %v", c.filename, openErr)
+		return err
+	}
+	c.col = col
+	return err
+}
+
+// DeleteCollection closes a collection and removes its files
+// and archived events.
+// Collections with attached sources can't be deleted.
+func DeleteCollection(name string) error {
+	collectionsLock.Lock()
+	collection := Collections[name]
+	if collection == nil {
+		collectionsLock.Unlock()
+		return ErrDoesNotExist
+	}
+	if len(collection.Sources()) > 0 {
+		collectionsLock.Unlock()
+		return ErrHasSources
+	}
+	delete(Collections, name)
+	collectionsLock.Unlock()
+
+	// Closing waits for writes in progress, so it's done without
+	// holding up lookups of other collections.
+	collection.Close()
+	err := collection.deleteArchive()
+	if err != nil {
+		return err
+	}
+	for _, filename := range []string{collection.filename, collection.filename + ".wal"} {
+		err := os.Remove(filename)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// openDataDirCollections opens collections in the data directory that
+// aren't in the config file, such as collections created through the API.
+// The files in skip belong to configured collections that this node
+// doesn't store. Sharded collections are only opened from the config.
+func openDataDirCollections(skip []string) error {
+	filenames, err := filepath.Glob(filepath.Join(DataDir, "*.lm2"))
+	if err != nil {
+		return err
+	}
+	skipped := map[string]bool{}
+	for _, filename := range skip {
+		skipped[filepath.Clean(filename)] = true
+	}
+	collectionsLock.Lock()
+	defer collectionsLock.Unlock()
+	for _, filename := range filenames {
+		name := strings.TrimSuffix(filepath.Base(filename), ".lm2")
+		if Collections[name] != nil || !collectionNameRegexp.MatchString(name) {
+			continue
+		}
+		if skipped[filepath.Clean(filename)] || (ClusterNode != nil && ClusterNode.IsSharded(name)) {
+			continue
+		}
+		configured := false
+		for _, collection := range Collections {
+			if filepath.Clean(collection.filename) == filepath.Clean(filename) {
+				configured = true
+				break
+			}
+		}
+		if configured {
+			continue
+		}
+		collection, err := OpenEventCollection(filename, defaultCacheSize)
+		if err != nil {
+			return err
+		}
+		collection.SetRetention(DefaultRetention)
+		Collections[name] = collection
+		log.Printf("Opened collection %s from the data directory", name)
+	}
+	return nil
+}
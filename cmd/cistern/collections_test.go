@@ -0,0 +1,171 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCollectionManagement(t *testing.T) {
+	dataDir, err := ioutil.TempDir("", "cistern-collections")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataDir)
+	defer func(dir string) { DataDir = dir }(DataDir)
+	DataDir = dataDir
+
+	collection, err := CreateCollection("pushed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer DeleteCollection("pushed")
+	defer DeleteCollection("renamed")
+	if _, err = CreateCollection("pushed"); err != ErrExists {
+		t.Errorf("expected ErrExists but got %v", err)
+	}
+
+	info, err := collection.Info("pushed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Oldest != nil || info.Events != 0 {
+		t.Errorf("expected an empty collection but got %+v", info)
+	}
+
+	err = collection.StoreEvents(testEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A failed rename leaves the collection usable under its old name.
+	err = os.MkdirAll(filepath.Join(dataDir, "blocked.lm2.wal", "file"), 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = RenameCollection("pushed", "blocked"); err == nil {
+		t.Fatal("expected renaming onto a directory to fail")
+	}
+	if _, err = os.Stat(filepath.Join(dataDir, "blocked.lm2")); !os.IsNotExist(err) {
+		t.Errorf("expected the data file to be moved back but got %v", err)
+	}
+	err = collection.StoreEvents(testEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = RenameCollection("pushed", "renamed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(filepath.Join(dataDir, "renamed.lm2")); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err = Collections["renamed"].Info("renamed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Events != int64(len(testEvents)) {
+		t.Errorf("expected %d events but got %d", len(testEvents), info.Events)
+	}
+	oldest, _ := time.Parse(time.RFC3339, "2017-08-01T03:20:00Z")
+	newest, _ := time.Parse(time.RFC3339, "2017-08-01T04:20:00Z")
+	if info.Oldest == nil || !info.Oldest.Equal(oldest) || !info.Newest.Equal(newest) {
+		t.Errorf("expected events from %v to %v but got %v to %v", oldest, newest, info.Oldest, info.Newest)
+	}
+
+	// Collections with sources can't be renamed or deleted.
+	attached, err := CreateCollection("attached")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer delete(Collections, "attached")
+	defer attached.Close()
+	attached.AttachSource("source")
+	if err = RenameCollection("attached", "detached"); err != ErrHasSources {
+		t.Errorf("expected ErrHasSources but got %v", err)
+	}
+	if err = DeleteCollection("attached"); err != ErrHasSources {
+		t.Errorf("expected ErrHasSources but got %v", err)
+	}
+
+	err = DeleteCollection("renamed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, present := Collections["renamed"]; present {
+		t.Error("expected deleted collection to be removed")
+	}
+	if _, err = os.Stat(filepath.Join(dataDir, "renamed.lm2")); !os.IsNotExist(err) {
+		t.Errorf("expected collection file to be removed but got %v", err)
+	}
+}
+
+func TestDiskDirs(t *testing.T) {
+	defer func(dir string) { DataDir = dir }(DataDir)
+	DataDir = "/var/lib/cistern"
+	defer func(collections map[string]*EventCollection) { Collections = collections }(Collections)
+	Collections = map[string]*EventCollection{
+		"flowlogs": {filename: "/var/lib/cistern/flowlogs.lm2"},
+		"archive":  {filename: "/mnt/archive/archive.lm2"},
+		"audit":    {filename: "/mnt/archive/audit.lm2"},
+		"app":      {filename: "/mnt/app/app.lm2"},
+	}
+
+	expected := []string{"/var/lib/cistern", "/mnt/app", "/mnt/archive"}
+	if dirs := diskDirs(); !reflect.DeepEqual(dirs, expected) {
+		t.Errorf("expected %v but got %v", expected, dirs)
+	}
+}
+
+func TestOpenDataDirCollections(t *testing.T) {
+	dataDir, err := ioutil.TempDir("", "cistern-collections")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataDir)
+	defer func(dir string) { DataDir = dir }(DataDir)
+	DataDir = dataDir
+	defer func(collections map[string]*EventCollection) { Collections = collections }(Collections)
+	Collections = map[string]*EventCollection{}
+	defer func(cl *Cluster) { ClusterNode = cl }(ClusterNode)
+	ClusterNode, err = NewCluster(ConfigCluster{
+		Node: "c",
+		Nodes: []ConfigNode{
+			{Name: "a", Addr: "a:2020"},
+			{Name: "b", Addr: "b:2020"},
+			{Name: "c", Addr: "c:2020"},
+		},
+		Shards: map[string][]string{"flowlogs": {"a", "b"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A shard of other nodes, a configured collection of other nodes
+	// and a collection created through the API.
+	for _, name := range []string{"flowlogs", "audit-file", "pushed"} {
+		ec, err := CreateEventCollection(filepath.Join(dataDir, name+".lm2"), defaultCacheSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ec.Close()
+	}
+
+	err = openDataDirCollections([]string{filepath.Join(dataDir, "audit-file.lm2")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		for _, collection := range Collections {
+			collection.Close()
+		}
+	}()
+	if len(Collections) != 1 || Collections["pushed"] == nil {
+		t.Errorf("expected only the pushed collection but got %v", Collections)
+	}
+}
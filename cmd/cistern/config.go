@@ -1,11 +1,114 @@
 package main
 
+import (
+	"log"
+	"path/filepath"
+)
+
+const defaultRetention = 7 // days
+
+type ConfigCollection struct {
+	Name      string   `json:"name"`
+	Retention int      `json:"retention"` // days
+	MaxBytes  int64    `json:"max_bytes"`
+	MaxEvents int64    `json:"max_events"`
+	Indexes   []string `json:"indexes"`
+	File      string   `json:"file"`
+	CacheSize int      `json:"cache_size"`
+	Flatten   bool     `json:"flatten"` // flatten nested fields at ingest
+	Leader    string   `json:"leader"`  // API address of the leader to follow
+
+	Archive *ConfigArchive `json:"archive"`
+}
+
+// ConfigArchive configures the archival tier of a collection.
+// Segments are stored in either a local directory or an S3 bucket.
+type ConfigArchive struct {
+	After    int    `json:"after"` // days events stay on local disk
+	Dir      string `json:"dir"`
+	Bucket   string `json:"bucket"`
+	Prefix   string `json:"prefix"` // prepended to segment names
+	Region   string `json:"region"`
+	Endpoint string `json:"endpoint"` // for S3-compatible stores
+}
+
 type ConfigCloudWatchLogGroup struct {
-	Name    string `json:"name"`
-	FlowLog bool   `json:"flowlog"`
+	Name       string `json:"name"`
+	FlowLog    bool   `json:"flowlog"`
+	Collection string `json:"collection"`
+}
+
+type ConfigNode struct {
+	Name string `json:"name"`
+	Addr string `json:"addr"` // API address
+}
+
+type ConfigCluster struct {
+	Node   string              `json:"node"` // name of this node
+	Nodes  []ConfigNode        `json:"nodes"`
+	Shards map[string][]string `json:"shards"` // collection name to owner nodes
 }
 
 type Config struct {
+	Collections    []ConfigCollection         `json:"collections"`
 	CloudWatchLogs []ConfigCloudWatchLogGroup `json:"cloudwatch_logs"`
-	Retention      int                        `json:"retention"`
+	Retention      int                        `json:"retention"`     // default retention in days
+	MinFreeDisk    uint64                     `json:"min_free_disk"` // bytes
+	Cluster        *ConfigCluster             `json:"cluster"`
+}
+
+// CollectionName returns the name of the collection the log group
+// feeds into. It defaults to the log group name.
+func (g ConfigCloudWatchLogGroup) CollectionName() string {
+	if g.Collection != "" {
+		return g.Collection
+	}
+	return g.Name
+}
+
+// CollectionConfigs returns the configuration of every collection,
+// including collections that are only referenced by sources.
+// Missing settings are filled in with defaults.
+func (c Config) CollectionConfigs() []ConfigCollection {
+	collections := []ConfigCollection{}
+	declared := map[string]bool{}
+	for _, collection := range c.Collections {
+		declared[collection.Name] = true
+		collections = append(collections, collection)
+	}
+	for _, group := range c.CloudWatchLogs {
+		name := group.CollectionName()
+		if declared[name] {
+			continue
+		}
+		declared[name] = true
+		collections = append(collections, ConfigCollection{Name: name})
+	}
+
+	for i, collection := range collections {
+		if collection.Retention == 0 {
+			collection.Retention = c.Retention
+		}
+		if collection.Retention == 0 {
+			collection.Retention = defaultRetention
+			log.Printf("Missing retention for %s; defaulting to %d days", collection.Name, collection.Retention)
+		}
+		if collection.File == "" {
+			collection.File = filepath.Join(DataDir, collection.Name+".lm2")
+		}
+		if collection.CacheSize == 0 {
+			collection.CacheSize = defaultCacheSize
+		}
+		if collection.Archive != nil && collection.Archive.After == 0 {
+			archive := *collection.Archive
+			archive.After = defaultArchiveAfter
+			if archive.After >= collection.Retention {
+				// Archive events before they're evicted.
+				archive.After = collection.Retention - 1
+			}
+			collection.Archive = &archive
+		}
+		collections[i] = collection
+	}
+	return collections
 }
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestCollectionConfigs(t *testing.T) {
+	config := Config{
+		Collections: []ConfigCollection{
+			{Name: "flowlogs", Retention: 30, Indexes: []string{"source_address"}, Archive: &ConfigArchive{Dir: "/archive"}},
+			{Name: "recent", Archive: &ConfigArchive{Dir: "/archive"}},
+		},
+		CloudWatchLogs: []ConfigCloudWatchLogGroup{
+			{Name: "vpc-a", FlowLog: true, Collection: "flowlogs"},
+			{Name: "vpc-b", FlowLog: true, Collection: "flowlogs"},
+			{Name: "app"},
+		},
+		Retention: 3,
+	}
+
+	collections := config.CollectionConfigs()
+	if len(collections) != 3 {
+		t.Fatalf("expected %d collections but got %d", 3, len(collections))
+	}
+
+	flowLogs := collections[0]
+	if flowLogs.Name != "flowlogs" || flowLogs.Retention != 30 {
+		t.Errorf("unexpected collection config %+v", flowLogs)
+	}
+	if flowLogs.File != filepath.Join(DataDir, "flowlogs.lm2") {
+		t.Errorf("unexpected file %s", flowLogs.File)
+	}
+	if flowLogs.CacheSize != defaultCacheSize {
+		t.Errorf("expected cache size %d but got %d", defaultCacheSize, flowLogs.CacheSize)
+	}
+	if flowLogs.Archive.After != defaultArchiveAfter {
+		t.Errorf("expected events archived after %d days but got %d", defaultArchiveAfter, flowLogs.Archive.After)
+	}
+
+	// The default archive threshold is below a shorter retention.
+	recent := collections[1]
+	if recent.Retention != 3 || recent.Archive.After != 2 {
+		t.Errorf("expected events archived after 2 of 3 days but got %d of %d",
+			recent.Archive.After, recent.Retention)
+	}
+
+	app := collections[2]
+	if app.Name != "app" || app.Retention != 3 {
+		t.Errorf("unexpected collection config %+v", app)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Cistern/cistern/internal/query"
+)
+
+var (
+	errInvalidContinuation   = errors.New("invalid continuation token")
+	errContinuationMismatch  = errors.New("continuation token is for a different query")
+	errContinuationUnordered = errors.New("continuation tokens require raw events in time order")
+)
+
+// continuation is the content of a continuation token, which continues
+// a raw event query after the last event of a page of results.
+//
+// Tokens are opaque to clients: they're base64 encoded JSON.
+type continuation struct {
+	// After is the key of the last event of the page. Keys are
+	// binary, so it's a []byte to survive JSON encoding.
+	After []byte `json:"after"`
+	// Fingerprint identifies the query the token continues.
+	Fingerprint string `json:"fingerprint"`
+}
+
+// queryFingerprint identifies the events a query pages through. The
+// time range and limit aren't part of it, so relative time ranges and
+// the page size can change between pages.
+func queryFingerprint(desc query.Desc) string {
+	desc.TimeRange = query.TimeRange{}
+	desc.Limit = 0
+	desc.Continuation = ""
+	desc.Partial = false
+	b, _ := json.Marshal(desc)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:8])
+}
+
+// pagedInKeyOrder returns true if the raw events of desc come in key
+// order, or reverse key order for newest first, so they can be paged
+// with continuation tokens.
+func pagedInKeyOrder(desc query.Desc) bool {
+	return len(desc.OrderBy) == 0 || newestFirst(desc)
+}
+
+// newestFirst returns true if desc orders events by _ts DESC only.
+func newestFirst(desc query.Desc) bool {
+	return len(desc.OrderBy) == 1 && isTsColumn(desc.OrderBy[0]) && desc.OrderBy[0].Descending
+}
+
+// encodeContinuation returns the token that continues desc after
+// lastEvent.
+func encodeContinuation(desc query.Desc, lastEvent Event) (string, error) {
+	key, err := idKey(lastEvent["_id"])
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(continuation{After: []byte(key), Fingerprint: queryFingerprint(desc)})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeContinuation returns the key of the event desc continues
+// after, or "" if desc has no continuation token.
+func decodeContinuation(desc query.Desc) (string, error) {
+	if desc.Continuation == "" {
+		return "", nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(desc.Continuation)
+	if err != nil {
+		return "", errInvalidContinuation
+	}
+	c := continuation{}
+	err = json.Unmarshal(b, &c)
+	if err != nil || len(c.After) == 0 || c.After[0] != eventKeyPrefix {
+		return "", errInvalidContinuation
+	}
+	if c.Fingerprint != queryFingerprint(desc) {
+		return "", errContinuationMismatch
+	}
+	return string(c.After), nil
+}
+
+// idKey returns the key of the event with an _id.
+func idKey(id interface{}) (string, error) {
+	s, _ := id.(string)
+	parts := strings.Split(s, "|")
+	if len(parts) < 2 || len(parts) > 3 {
+		return "", fmt.Errorf("invalid event ID %v", id)
+	}
+	ts, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid event ID %v", id)
+	}
+	hash := ""
+	if len(parts) == 3 {
+		hash = parts[2]
+	}
+	formattedTs := formatTs(ts)
+	return string(eventKeyPrefix) + string(formattedTs[:]) + "|" + parts[1] + "|" + hash, nil
+}
+
+// sortByKey sorts events in key order, or in reverse key order if
+// reverse is true.
+func sortByKey(events []Event, reverse bool) error {
+	keys := make(map[string]string, len(events))
+	for _, event := range events {
+		id, _ := event["_id"].(string)
+		key, err := idKey(id)
+		if err != nil {
+			return err
+		}
+		keys[id] = key
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		a, b := keys[events[i]["_id"].(string)], keys[events[j]["_id"].(string)]
+		if reverse {
+			return a > b
+		}
+		return a < b
+	})
+	return nil
+}
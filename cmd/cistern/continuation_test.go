@@ -0,0 +1,111 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Cistern/cistern/internal/query"
+)
+
+func TestContinuation(t *testing.T) {
+	ec, err := CreateEventCollection("/tmp/test_cistern_continuation.lm2", defaultCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { ec.col.Destroy() }()
+
+	// Events with the same timestamp are paged in key order too.
+	start := time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC)
+	events := []Event{}
+	for i := 0; i < 25; i++ {
+		events = append(events, Event{
+			"_tag": "a",
+			"_ts":  start.Add(time.Duration(i/2) * time.Minute).Format(time.RFC3339),
+			"i":    i,
+		})
+	}
+	err = ec.StoreEvents(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, q := range []string{
+		"FILTER i >= 3 LIMIT 5",
+		"FILTER i >= 3 ORDER BY _ts DESC LIMIT 4",
+	} {
+		desc, err := query.Parse(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = decodeFilterValues(desc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		all := *desc
+		all.Limit = 0
+		expected, err := ec.Query(all)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected.Continuation != "" {
+			t.Errorf("%s: expected no continuation without a limit", q)
+		}
+
+		ids := []interface{}{}
+		pages := 0
+		for {
+			result, err := ec.Query(*desc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pages++
+			for _, event := range result.Events {
+				ids = append(ids, event["_id"])
+			}
+			if result.Continuation == "" {
+				break
+			}
+			desc.Continuation = result.Continuation
+		}
+		expectedIDs := []interface{}{}
+		for _, event := range expected.Events {
+			expectedIDs = append(expectedIDs, event["_id"])
+		}
+		if len(expectedIDs) != 22 || !reflect.DeepEqual(ids, expectedIDs) {
+			t.Errorf("%s: expected pages of %v but got %v", q, expectedIDs, ids)
+		}
+		if pages < 5 {
+			t.Errorf("%s: expected at least 5 pages but got %d", q, pages)
+		}
+	}
+
+	desc, err := query.Parse("LIMIT 5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ec.Query(*desc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []string{
+		"FILTER i > 1 LIMIT 5",
+		"ORDER BY i LIMIT 5",
+		"SELECT count(i)",
+	} {
+		other, err := query.Parse(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		other.Continuation = result.Continuation
+		_, err = ec.Query(*other)
+		if err == nil {
+			t.Errorf("%s: expected an error for a continuation of another query", q)
+		}
+	}
+	desc.Continuation = "bm90IGEgdG9rZW4"
+	_, err = ec.Query(*desc)
+	if err != errInvalidContinuation {
+		t.Errorf("expected an invalid token error but got %v", err)
+	}
+}
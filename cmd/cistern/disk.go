@@ -0,0 +1,111 @@
+package main
+
+import (
+	"log"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const defaultMinFreeDisk = 100 * 1024 * 1024
+
+// diskFree returns the number of bytes available to unprivileged
+// users on the file system containing path.
+func diskFree(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	err := syscall.Statfs(path, &stat)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
+
+// diskDirs returns the data directory and the other directories that
+// hold collection files.
+func diskDirs() []string {
+	dirs := []string{DataDir}
+	seen := map[string]bool{filepath.Clean(DataDir): true}
+	collectionsLock.Lock()
+	defer collectionsLock.Unlock()
+	for _, collection := range Collections {
+		dir := filepath.Dir(collection.filename)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs[1:])
+	return dirs
+}
+
+// monitorDisk pauses ingestion when the data directory, or another
+// directory with collection files, has less than minFree bytes available
+// and resumes it once space is freed up.
+func monitorDisk(minFree uint64, done chan struct{}) {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+	for {
+		lowDir, lowFree, failed := "", uint64(0), false
+		for _, dir := range diskDirs() {
+			free, err := diskFree(dir)
+			if err != nil {
+				log.Printf("Couldn't check free disk space of %s: %v", dir, err)
+				failed = true
+				continue
+			}
+			if free < minFree {
+				lowDir, lowFree = dir, free
+				break
+			}
+		}
+		if lowDir != "" {
+			if atomic.SwapInt32(&lowDisk, 1) == 0 {
+				log.Printf("Low disk space in %s (%d bytes free); pausing ingestion", lowDir, lowFree)
+			}
+		} else if !failed {
+			if atomic.SwapInt32(&lowDisk, 0) == 1 {
+				log.Printf("Disk space recovered; resuming ingestion")
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-done:
+			return
+		}
+	}
+}
+
+// enforceLimits periodically compacts collections that exceed
+// their size or event count limits.
+func enforceLimits(done chan struct{}) {
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-done:
+			return
+		}
+
+		collectionsLock.Lock()
+		collections := map[string]*EventCollection{}
+		for name, collection := range Collections {
+			collections[name] = collection
+		}
+		collectionsLock.Unlock()
+
+		for name, collection := range collections {
+			if !collection.ExceedsLimits() {
+				continue
+			}
+			log.Printf("Collection %s exceeds its limits; evicting oldest events", name)
+			err := collection.Compact()
+			if err != nil {
+				log.Printf("Couldn't compact collection %s: %v", name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Cistern/cistern/internal/query"
+)
+
+// Export formats.
+const (
+	ExportNDJSON  = "ndjson"
+	ExportCSV     = "csv"
+	ExportParquet = "parquet"
+)
+
+var ErrUnknownFormat = errors.New("cistern: unknown export format")
+
+// exportKind is the type of an exported column.
+type exportKind int
+
+const (
+	kindNone exportKind = iota
+	kindNumber
+	kindBool
+	kindString
+)
+
+// exportColumn is a column discovered in the exported events.
+type exportColumn struct {
+	name string
+	kind exportKind
+}
+
+// leadingColumns are exported before all other fields, in this order.
+var leadingColumns = []string{"_ts", "_tag", "_hash", "_id"}
+
+// ExportContentType returns the MIME type of an export format.
+func ExportContentType(format string) string {
+	switch format {
+	case ExportCSV:
+		return "text/csv"
+	case ExportParquet:
+		return "application/octet-stream"
+	}
+	return "application/x-ndjson"
+}
+
+// Export writes the events in the time range of desc that match its
+// filters to w in the given format. Events are streamed as they are read.
+// CSV and Parquet need the set of fields up front, so events are scanned
+// twice; fields first seen during the second scan are left out. The
+// collection isn't locked while events are written to w.
+func (c *EventCollection) Export(desc query.Desc, format string, w io.Writer) error {
+	switch format {
+	case ExportNDJSON, ExportCSV, ExportParquet:
+	default:
+		return ErrUnknownFormat
+	}
+
+	normalizeTimeRange(&desc)
+
+	if format == ExportNDJSON {
+		bw := bufio.NewWriter(w)
+		enc := json.NewEncoder(bw)
+		err := c.scanEvents(desc, scanOptions{unlocked: true}, func(ts int64, event Event) (bool, error) {
+			event["_ts"] = fromMicrosecondTime(ts)
+			return true, enc.Encode(event)
+		})
+		if err != nil {
+			return err
+		}
+		return bw.Flush()
+	}
+
+	columns, err := c.exportColumns(desc)
+	if err != nil {
+		return err
+	}
+
+	if format == ExportCSV {
+		return c.exportCSV(desc, columns, w)
+	}
+	return c.exportParquet(desc, columns, w)
+}
+
+// exportColumns returns the fields of the matching events. A field with
+// values of different types is exported as a string.
+func (c *EventCollection) exportColumns(desc query.Desc) ([]exportColumn, error) {
+	kinds := map[string]exportKind{}
+	err := c.scanEvents(desc, scanOptions{unlocked: true}, func(ts int64, event Event) (bool, error) {
+		for field, value := range event {
+			kind := valueKind(value)
+			if kind == kindNone {
+				if _, ok := kinds[field]; !ok {
+					kinds[field] = kindNone
+				}
+				continue
+			}
+			switch kinds[field] {
+			case kindNone:
+				kinds[field] = kind
+			case kind:
+			default:
+				kinds[field] = kindString
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	columns := []exportColumn{}
+	for _, name := range leadingColumns {
+		if kind, ok := kinds[name]; ok {
+			columns = append(columns, exportColumn{name: name, kind: kind})
+			delete(kinds, name)
+		}
+	}
+	names := []string{}
+	for name := range kinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		kind := kinds[name]
+		if kind == kindNone {
+			// Only nulls.
+			kind = kindString
+		}
+		columns = append(columns, exportColumn{name: name, kind: kind})
+	}
+	return columns, nil
+}
+
+func valueKind(value interface{}) exportKind {
+	switch value.(type) {
+	case nil:
+		return kindNone
+	case float64, int64, int:
+		return kindNumber
+	case bool:
+		return kindBool
+	}
+	return kindString
+}
+
+// stringValue formats a non-string value as JSON.
+func stringValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (c *EventCollection) exportCSV(desc query.Desc, columns []exportColumn, w io.Writer) error {
+	cw := csv.NewWriter(w)
+	record := make([]string, len(columns))
+	for i, column := range columns {
+		record[i] = column.name
+	}
+	err := cw.Write(record)
+	if err != nil {
+		return err
+	}
+
+	err = c.scanEvents(desc, scanOptions{unlocked: true}, func(ts int64, event Event) (bool, error) {
+		for i, column := range columns {
+			value := event[column.name]
+			switch v := value.(type) {
+			case nil:
+				record[i] = ""
+			case float64:
+				record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+			case bool:
+				record[i] = strconv.FormatBool(v)
+			default:
+				record[i] = stringValue(v)
+			}
+			if column.name == "_ts" {
+				record[i] = fromMicrosecondTime(ts).UTC().Format(time.RFC3339Nano)
+			}
+		}
+		return true, cw.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (c *EventCollection) exportParquet(desc query.Desc, columns []exportColumn, w io.Writer) error {
+	pw, err := newParquetWriter(w, columns)
+	if err != nil {
+		return err
+	}
+	err = c.scanEvents(desc, scanOptions{unlocked: true}, func(ts int64, event Event) (bool, error) {
+		event["_ts"] = ts
+		return true, pw.Write(event)
+	})
+	if err != nil {
+		return err
+	}
+	return pw.Close()
+}
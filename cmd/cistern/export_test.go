@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cistern/cistern/internal/query"
+)
+
+func TestExport(t *testing.T) {
+	ec, err := CreateEventCollection("/tmp/test_cistern_export.lm2", defaultCacheSize)
+	defer ec.col.Destroy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ec.StoreEvents(testEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	desc, err := query.Parse("FILTER source_port = 443")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = decodeFilterValues(desc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	err = ec.Export(*desc, ExportNDJSON, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 NDJSON lines but got %d", len(lines))
+	}
+	event := Event{}
+	err = json.Unmarshal([]byte(lines[0]), &event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event["_ts"] != "2017-08-01T03:30:00Z" {
+		t.Errorf("expected RFC 3339 _ts but got %v", event["_ts"])
+	}
+
+	buf.Reset()
+	err = ec.Export(query.Desc{}, ExportCSV, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(testEvents)+1 {
+		t.Fatalf("expected %d CSV records but got %d", len(testEvents)+1, len(records))
+	}
+	header := strings.Join(records[0], ",")
+	expectedHeader := "_ts,_tag,_hash,_id,bytes,dest_address,dest_port,packets,protocol,source_address,source_port"
+	if header != expectedHeader {
+		t.Errorf("expected header %q but got %q", expectedHeader, header)
+	}
+	if records[1][4] != "192500" {
+		t.Errorf("expected bytes 192500 but got %q", records[1][4])
+	}
+
+	buf.Reset()
+	err = ec.Export(query.Desc{}, ExportParquet, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	if !bytes.HasPrefix(b, []byte(parquetMagic)) || !bytes.HasSuffix(b, []byte(parquetMagic)) {
+		t.Fatal("missing Parquet magic")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(b[len(b)-8:]))
+	if footerLen <= 0 || footerLen > len(b)-12 {
+		t.Errorf("invalid Parquet footer length %d", footerLen)
+	}
+
+	err = ec.Export(query.Desc{}, "xml", buf)
+	if err != ErrUnknownFormat {
+		t.Errorf("expected ErrUnknownFormat but got %v", err)
+	}
+}
+
+// blockingWriter blocks its first write until it's released.
+type blockingWriter struct {
+	bytes.Buffer
+	blocked chan struct{}
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	if w.blocked != nil {
+		close(w.blocked)
+		w.blocked = nil
+		<-w.release
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestExportUnlocked(t *testing.T) {
+	ec, err := CreateEventCollection("/tmp/test_cistern_export_unlocked.lm2", defaultCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { ec.col.Destroy() }()
+	start := time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC)
+	events := []Event{}
+	for i := 0; i < 500; i++ {
+		events = append(events, Event{
+			"_tag":  "test",
+			"_ts":   start.Add(time.Duration(i) * time.Second).Format(time.RFC3339),
+			"value": fmt.Sprintf("event %d", i),
+		})
+	}
+	err = ec.StoreEvents(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := &blockingWriter{blocked: make(chan struct{}), release: make(chan struct{})}
+	blocked := w.blocked
+	done := make(chan error)
+	go func() {
+		done <- ec.Export(query.Desc{}, ExportNDJSON, w)
+	}()
+	<-blocked
+
+	// Writes and compaction go ahead while the client is stalled.
+	finished := make(chan error)
+	go func() {
+		err := ec.StoreEvents([]Event{{"_tag": "test", "_ts": "2017-08-02T00:00:00Z"}})
+		if err == nil {
+			err = ec.Compact()
+		}
+		finished <- err
+	}()
+	select {
+	case err = <-finished:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected writes not to wait for the export")
+	}
+
+	close(w.release)
+	err = <-done
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(w.String()), "\n")
+	if len(lines) < len(events) {
+		t.Fatalf("expected at least %d events but got %d", len(events), len(lines))
+	}
+	for i, event := range events {
+		exported := Event{}
+		err = json.Unmarshal([]byte(lines[i]), &exported)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exported["value"] != event["value"] {
+			t.Fatalf("expected %v but got %v", event["value"], exported["value"])
+		}
+	}
+}
@@ -0,0 +1,613 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/Cistern/cistern/internal/query"
+)
+
+// scalarFunctions maps the scalar functions of expressions to their
+// number of arguments.
+var scalarFunctions = map[string]int{
+	"abs":    1,
+	"ceil":   1,
+	"floor":  1,
+	"round":  -1, // round(x) or round(x, digits)
+	"sqrt":   1,
+	"log":    1,
+	"log10":  1,
+	"exp":    1,
+	"pow":    2,
+	"lower":  1,
+	"upper":  1,
+	"length": 1,
+
+	"bucket":      2,
+	"hour_of_day": 1,
+	"day_of_week": 1,
+	"cidr":        2,
+	"port_class":  1,
+}
+
+// columnName returns the name of a column in results.
+func columnName(column query.ColumnDesc) string {
+	switch {
+	case column.Alias != "":
+		return column.Alias
+	case column.Expr != nil:
+		return column.Name
+	case column.Aggregate != "":
+		return column.Aggregate + "(" + column.Name + ")"
+	}
+	return column.Name
+}
+
+// columnExpr returns the expression of a column.
+func columnExpr(column query.ColumnDesc) query.Expr {
+	switch {
+	case column.Expr != nil:
+		return *column.Expr
+	case column.Aggregate != "":
+		return query.Expr{Func: column.Aggregate, Operands: []query.Expr{{Column: column.Name}}}
+	}
+	return query.Expr{Column: column.Name}
+}
+
+// exprString returns the canonical text of an expression. Operations
+// nested in other operations are parenthesized.
+func exprString(e query.Expr) string {
+	switch {
+	case e.Op != "" && len(e.Operands) == 1:
+		return e.Op + operandString(e.Operands[0])
+	case e.Op != "":
+		return operandString(e.Operands[0]) + e.Op + operandString(e.Operands[1])
+	case e.Func != "":
+		args := []string{}
+		for _, operand := range e.Operands {
+			args = append(args, exprString(operand))
+		}
+		return e.Func + "(" + strings.Join(args, ",") + ")"
+	case e.Column != "":
+		return e.Column
+	}
+	return stringValue(e.Value)
+}
+
+func operandString(e query.Expr) string {
+	if e.Op != "" {
+		return "(" + exprString(e) + ")"
+	}
+	return exprString(e)
+}
+
+// isTsColumn returns true if column is the _ts field itself, whose
+// values are microsecond timestamps while scanning.
+func isTsColumn(column query.ColumnDesc) bool {
+	return column.Name == "_ts" && column.Aggregate == "" && column.Expr == nil
+}
+
+func isFieldExpr(e query.Expr) bool {
+	return e.Column != "" && e.Op == "" && e.Func == ""
+}
+
+// hasAggregate returns true if an expression computes an aggregate.
+func hasAggregate(e query.Expr) bool {
+	if e.Func != "" && isAggregate(e.Func) {
+		return true
+	}
+	for _, operand := range e.Operands {
+		if hasAggregate(operand) {
+			return true
+		}
+	}
+	return false
+}
+
+func isAggregate(name string) bool {
+	_, err := newAggregator(name)
+	return err == nil
+}
+
+// aggregateCall is an aggregate of an expression used by a query.
+type aggregateCall struct {
+	key       string // canonical text, like sum(bytes)
+	aggregate string
+	arg       query.Expr
+}
+
+// otherGroupID is the _group_id of the group TOP folds the groups
+// after the first n into.
+const otherGroupID = "_other"
+
+// queryPlan is a checked query with the aggregates it computes.
+type queryPlan struct {
+	desc       query.Desc
+	aggregates []aggregateCall
+	// aggregated is true if the query returns summary rows instead
+	// of events.
+	aggregated bool
+	// grouped maps the canonical text and aliases of GROUP BY
+	// columns to their names in results.
+	grouped map[string]string
+	filters []Filter
+	having  []Filter
+}
+
+// newQueryPlan checks the columns of desc and collects their aggregates.
+func newQueryPlan(desc query.Desc) (*queryPlan, error) {
+	p := &queryPlan{
+		desc:       desc,
+		aggregated: len(desc.GroupBy) > 0 || desc.PointSize > 0,
+		grouped:    map[string]string{},
+	}
+	for _, column := range desc.GroupBy {
+		p.grouped[exprString(columnExpr(column))] = columnName(column)
+		p.grouped[columnName(column)] = columnName(column)
+		err := p.check(columnExpr(column), false, false)
+		if err != nil {
+			return nil, fmt.Errorf("group by %s: %v", column.Name, err)
+		}
+	}
+	for _, column := range desc.Columns {
+		err := p.check(columnExpr(column), true, false)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %v", column.Name, err)
+		}
+	}
+	err := p.checkFilters(desc.Filters, false)
+	if err != nil {
+		return nil, err
+	}
+	p.filters, err = buildFilters(desc.Filters)
+	if err != nil {
+		return nil, err
+	}
+	err = p.checkFilters(desc.Having, true)
+	if err != nil {
+		return nil, fmt.Errorf("having: %v", err)
+	}
+	p.having, err = buildFilters(desc.Having)
+	if err != nil {
+		return nil, err
+	}
+	if len(p.aggregates) > 0 {
+		p.aggregated = true
+	}
+	if len(desc.Having) > 0 && !p.aggregated {
+		return nil, errors.New("having requires aggregates or GROUP BY")
+	}
+	if desc.Top > 0 && len(desc.GroupBy) == 0 {
+		return nil, errors.New("top requires GROUP BY")
+	}
+	if desc.Continuation != "" {
+		if p.aggregated || !pagedInKeyOrder(desc) {
+			return nil, errContinuationUnordered
+		}
+		_, err := decodeContinuation(desc)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if p.aggregated {
+		for _, column := range desc.Columns {
+			err := p.checkGrouped(columnExpr(column))
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %v", column.Name, err)
+			}
+		}
+	}
+	return p, nil
+}
+
+// check checks an expression and adds its aggregates to the plan.
+func (p *queryPlan) check(e query.Expr, allowAggregates, inAggregate bool) error {
+	switch {
+	case e.Op != "":
+		if len(e.Operands) != 2 && !(e.Op == "-" && len(e.Operands) == 1) {
+			return fmt.Errorf("invalid operation %s", e.Op)
+		}
+		switch e.Op {
+		case "+", "-", "*", "/", "%":
+		default:
+			return fmt.Errorf("unknown operator %s", e.Op)
+		}
+	case e.Func != "" && isAggregate(e.Func):
+		if !allowAggregates {
+			return fmt.Errorf("aggregate %s not allowed here", e.Func)
+		}
+		if inAggregate {
+			return fmt.Errorf("aggregate %s inside an aggregate", e.Func)
+		}
+		if len(e.Operands) != 1 {
+			return fmt.Errorf("aggregate %s takes one argument", e.Func)
+		}
+		key := exprString(e)
+		for _, call := range p.aggregates {
+			if call.key == key {
+				return nil
+			}
+		}
+		p.aggregates = append(p.aggregates, aggregateCall{key: key, aggregate: e.Func, arg: e.Operands[0]})
+		return p.check(e.Operands[0], allowAggregates, true)
+	case e.Func != "":
+		arity, ok := scalarFunctions[e.Func]
+		if !ok {
+			return fmt.Errorf("unknown function %s", e.Func)
+		}
+		if (arity >= 0 && len(e.Operands) != arity) || (arity < 0 && (len(e.Operands) < 1 || len(e.Operands) > 2)) {
+			return fmt.Errorf("wrong number of arguments to %s", e.Func)
+		}
+	}
+	for _, operand := range e.Operands {
+		err := p.check(operand, allowAggregates, inAggregate)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkFilters checks the expressions of filters.
+func (p *queryPlan) checkFilters(filters []query.Filter, allowAggregates bool) error {
+	for _, f := range filters {
+		operands := append(append([]query.Filter{}, f.And...), f.Or...)
+		if f.Not != nil {
+			operands = append(operands, *f.Not)
+		}
+		err := p.checkFilters(operands, allowAggregates)
+		if err != nil {
+			return err
+		}
+		if f.Expr != nil {
+			err = p.check(*f.Expr, allowAggregates, false)
+			if err != nil {
+				return fmt.Errorf("filter %s: %v", f.Column, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkGrouped checks that fields outside aggregates are grouped.
+func (p *queryPlan) checkGrouped(e query.Expr) error {
+	if _, ok := p.grouped[exprString(e)]; ok {
+		return nil
+	}
+	switch {
+	case e.Func != "" && isAggregate(e.Func):
+		return nil
+	case isFieldExpr(e):
+		if _, ok := p.grouped[e.Column]; !ok {
+			return fmt.Errorf("%s must be aggregated or in GROUP BY", e.Column)
+		}
+	}
+	for _, operand := range e.Operands {
+		err := p.checkGrouped(operand)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *queryPlan) newAggregators() []aggregator {
+	aggregators := make([]aggregator, len(p.aggregates))
+	for i, call := range p.aggregates {
+		aggregators[i], _ = newAggregator(call.aggregate)
+	}
+	return aggregators
+}
+
+// addEvent adds an event to the aggregators of a row.
+func (p *queryPlan) addEvent(aggregators []aggregator, event Event, ts int64) {
+	for i, call := range p.aggregates {
+		aggregators[i].add(evalExpr(call.arg, event, nil), ts)
+	}
+}
+
+// setPartial sets the aggregator states of a row for partial queries.
+func (p *queryPlan) setPartial(row Event, aggregators []aggregator) {
+	for i, call := range p.aggregates {
+		row[call.key] = aggregators[i]
+	}
+}
+
+// setColumns replaces aggregator states of a row with the values
+// of the columns. It returns false if the row doesn't match the
+// HAVING filters.
+func (p *queryPlan) setColumns(row Event, aggregators []aggregator) bool {
+	results := map[string]interface{}{}
+	for i, call := range p.aggregates {
+		results[call.key] = aggregators[i].result()
+		delete(row, call.key)
+	}
+	// Grouped values can be referred to by expression or alias.
+	groupValues := Event{}
+	for name, resultName := range p.grouped {
+		groupValues[name] = row[resultName]
+		results[name] = row[resultName]
+	}
+	for _, column := range p.desc.Columns {
+		row[columnName(column)] = evalExpr(columnExpr(column), groupValues, results)
+	}
+
+	if len(p.having) == 0 {
+		return true
+	}
+	// HAVING filters see the columns and all aggregates of the row.
+	havingRow := Event{}
+	for name, value := range results {
+		havingRow[name] = value
+	}
+	for name, value := range groupValues {
+		havingRow[name] = value
+	}
+	for name, value := range row {
+		havingRow[name] = value
+	}
+	for _, filter := range p.having {
+		if !filter.Filter(havingRow) {
+			return false
+		}
+	}
+	return true
+}
+
+// top keeps the first TOP rows of ordered summary rows and merges the
+// aggregates of the others into an _other row, which it appends.
+// aggregators has the aggregates of rows by _group_id. It returns the
+// rows and the IDs of the folded groups.
+func (p *queryPlan) top(rows []Event, aggregators map[string][]aggregator) ([]Event, map[string]bool) {
+	folded := map[string]bool{}
+	if p.desc.Top <= 0 || len(rows) <= p.desc.Top {
+		return rows, folded
+	}
+	other := p.newAggregators()
+	for _, row := range rows[p.desc.Top:] {
+		id := row["_group_id"].(string)
+		folded[id] = true
+		for i, agg := range aggregators[id] {
+			other[i].merge(agg)
+		}
+	}
+	return append(rows[:p.desc.Top:p.desc.Top], p.otherRow(other)), folded
+}
+
+// foldSeries replaces the series rows of folded groups with an _other
+// row for each timestamp. aggregators has the aggregates of each row.
+func (p *queryPlan) foldSeries(rows []Event, aggregators [][]aggregator, folded map[string]bool) []Event {
+	kept := []Event{}
+	others := map[int64][]aggregator{}
+	for i, row := range rows {
+		if !folded[row["_group_id"].(string)] {
+			kept = append(kept, row)
+			continue
+		}
+		ts := row["_ts"].(time.Time).UnixNano()
+		if _, ok := others[ts]; !ok {
+			others[ts] = p.newAggregators()
+		}
+		for j, agg := range aggregators[i] {
+			others[ts][j].merge(agg)
+		}
+	}
+	for ts, other := range others {
+		row := p.otherRow(other)
+		row["_ts"] = time.Unix(0, ts).UTC()
+		kept = append(kept, row)
+	}
+	return kept
+}
+
+// otherRow returns the summary row of the _other group, which has no
+// GROUP BY values.
+func (p *queryPlan) otherRow(aggregators []aggregator) Event {
+	row := Event{}
+	p.setColumns(row, aggregators)
+	row["_group_id"] = otherGroupID
+	return row
+}
+
+// evalExpr evaluates an expression for an event or summary row.
+// Aggregates and grouped expressions are looked up in results by
+// their canonical text. The value is nil if it's undefined, e.g. if a
+// field is missing or not a number.
+func evalExpr(e query.Expr, event Event, results map[string]interface{}) interface{} {
+	if results != nil {
+		if value, ok := results[exprString(e)]; ok {
+			return value
+		}
+	}
+	switch {
+	case e.Op != "":
+		if len(e.Operands) == 1 {
+			a, ok := evalNumber(e.Operands[0], event, results)
+			if !ok {
+				return nil
+			}
+			return -a
+		}
+		a, aOK := evalNumber(e.Operands[0], event, results)
+		b, bOK := evalNumber(e.Operands[1], event, results)
+		if !aOK || !bOK {
+			return nil
+		}
+		return arithmetic(e.Op, a, b)
+	case e.Func != "" && isAggregate(e.Func):
+		return results[exprString(e)]
+	case e.Func != "":
+		args := make([]interface{}, len(e.Operands))
+		for i, operand := range e.Operands {
+			args[i] = evalExpr(operand, event, results)
+		}
+		return callFunction(e.Func, args)
+	case e.Column != "":
+		value, _ := event.Lookup(e.Column)
+		if ts, ok := value.(int64); ok && e.Column == "_ts" {
+			// Event timestamps are in microseconds while scanning.
+			value = fromMicrosecondTime(ts)
+		}
+		return value
+	}
+	return e.Value
+}
+
+func evalNumber(e query.Expr, event Event, results map[string]interface{}) (float64, bool) {
+	return valueOf(evalExpr(e, event, results)).number()
+}
+
+func arithmetic(op string, a, b float64) interface{} {
+	var result float64
+	switch op {
+	case "+":
+		result = a + b
+	case "-":
+		result = a - b
+	case "*":
+		result = a * b
+	case "/":
+		result = a / b
+	case "%":
+		result = math.Mod(a, b)
+	}
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		// Like division by zero
+		return nil
+	}
+	return result
+}
+
+func callFunction(name string, args []interface{}) interface{} {
+	switch name {
+	case "bucket":
+		return bucket(args[0], args[1])
+	case "hour_of_day", "day_of_week":
+		t, ok := inferValue(args[0]).coerce(TypeTimestamp)
+		if !ok {
+			return nil
+		}
+		if name == "hour_of_day" {
+			return float64(t.t.UTC().Hour())
+		}
+		return float64(t.t.UTC().Weekday())
+	case "cidr":
+		return cidr(args[0], args[1])
+	case "port_class":
+		return portClass(args[0])
+	case "lower", "upper", "length":
+		s, ok := args[0].(string)
+		if !ok {
+			return nil
+		}
+		switch name {
+		case "lower":
+			return strings.ToLower(s)
+		case "upper":
+			return strings.ToUpper(s)
+		}
+		return float64(len(s))
+	}
+
+	numbers := make([]float64, len(args))
+	for i, arg := range args {
+		f, ok := valueOf(arg).number()
+		if !ok {
+			return nil
+		}
+		numbers[i] = f
+	}
+	x := numbers[0]
+	var result float64
+	switch name {
+	case "abs":
+		result = math.Abs(x)
+	case "ceil":
+		result = math.Ceil(x)
+	case "floor":
+		result = math.Floor(x)
+	case "round":
+		digits := 0.0
+		if len(numbers) > 1 {
+			digits = numbers[1]
+		}
+		scale := math.Pow(10, math.Trunc(digits))
+		result = math.Round(x*scale) / scale
+	case "sqrt":
+		result = math.Sqrt(x)
+	case "log":
+		result = math.Log(x)
+	case "log10":
+		result = math.Log10(x)
+	case "exp":
+		result = math.Exp(x)
+	case "pow":
+		result = math.Pow(x, numbers[1])
+	}
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return nil
+	}
+	return result
+}
+
+// bucket rounds a timestamp down to a multiple of a duration since the
+// Unix epoch, or a number down to a multiple of a number.
+func bucket(v, size interface{}) interface{} {
+	if t, ok := inferValue(v).coerce(TypeTimestamp); ok {
+		d, ok := inferValue(size).coerce(TypeDuration)
+		if !ok || d.i <= 0 {
+			return nil
+		}
+		return t.t.UTC().Truncate(time.Duration(d.i))
+	}
+	f, fOK := valueOf(v).number()
+	step, stepOK := valueOf(size).number()
+	if !fOK || !stepOK || step <= 0 {
+		return nil
+	}
+	return math.Floor(f/step) * step
+}
+
+// cidr returns the network with a prefix length of bits that contains
+// an IP address, like "10.1.2.0/24".
+func cidr(v, bits interface{}) interface{} {
+	ip, ok := inferValue(v).coerce(TypeIP)
+	if !ok {
+		return nil
+	}
+	n, ok := valueOf(bits).number()
+	if !ok {
+		return nil
+	}
+	addr := ip.ip.To4()
+	if addr == nil {
+		addr = ip.ip.To16()
+	}
+	if n < 0 || int(n) > len(addr)*8 {
+		return nil
+	}
+	network := &net.IPNet{
+		IP:   addr.Mask(net.CIDRMask(int(n), len(addr)*8)),
+		Mask: net.CIDRMask(int(n), len(addr)*8),
+	}
+	return network.String()
+}
+
+// portClass returns the IANA range of a port number: well-known
+// (0-1023), registered (1024-49151) or dynamic (49152-65535).
+func portClass(v interface{}) interface{} {
+	f, ok := valueOf(v).number()
+	switch {
+	case !ok || f < 0 || f > 65535 || f != math.Trunc(f):
+		return nil
+	case f < 1024:
+		return "well-known"
+	case f < 49152:
+		return "registered"
+	}
+	return "dynamic"
+}
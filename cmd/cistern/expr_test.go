@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Cistern/cistern/internal/query"
+)
+
+func TestComputedColumns(t *testing.T) {
+	ec, err := CreateEventCollection("/tmp/test_cistern_expr.lm2", defaultCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { ec.col.Destroy() }()
+	err = ec.StoreEvents(testEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(q string) *QueryResult {
+		desc, err := query.Parse(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = decodeFilterValues(desc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := ec.Query(*desc)
+		if err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+		return result
+	}
+
+	result := run("SELECT bytes / packets AS bpp, abs(-source_port), bytes / 0 FILTER source_port = 22")
+	if len(result.Events) != 1 {
+		t.Fatalf("expected one event but got %d", len(result.Events))
+	}
+	event := result.Events[0]
+	if event["bpp"] != 13297.0/72 || event["abs(-source_port)"] != 22.0 || event["bytes/0"] != nil {
+		t.Errorf("expected computed fields but got %v", event)
+	}
+	if event["bytes"] == nil {
+		t.Errorf("expected the event's fields to be kept but got %v", event)
+	}
+
+	result = run("SELECT source_address, sum(bytes) / count(_id) AS avg_size, round(sum(bytes * 8) / 1000, 1) " +
+		"GROUP BY source_address ORDER BY avg_size DESC")
+	if len(result.Summary) != 2 {
+		t.Fatalf("expected 2 groups but got %v", result.Summary)
+	}
+	first := result.Summary[0]
+	if first["source_address"] != "172.31.31.192" || first["avg_size"] != 55525.0 ||
+		first["round(sum(bytes*8)/1000,1)"] != 1776.8 {
+		t.Errorf("expected computed columns of the largest group but got %v", first)
+	}
+	if _, ok := first["sum(bytes)"]; ok {
+		t.Errorf("expected only the columns of the query but got %v", first)
+	}
+	for i := 1; i < len(result.Summary); i++ {
+		if result.Summary[i]["avg_size"].(float64) > result.Summary[i-1]["avg_size"].(float64) {
+			t.Errorf("expected groups ordered by avg_size but got %v", result.Summary)
+		}
+	}
+
+	result = run("SELECT sqrt(variance(bytes)) - stddev(bytes) AS zero")
+	if zero, _ := result.Summary[0]["zero"].(float64); math.Abs(zero) > 1e-6 {
+		t.Errorf("expected 0 but got %v", result.Summary[0]["zero"])
+	}
+
+	for _, q := range []string{
+		"SELECT foo(bytes)",
+		"SELECT sum(sum(bytes))",
+		"SELECT bytes + sum(packets)",
+		"SELECT pow(bytes)",
+		"SELECT sum(bytes) GROUP BY count(bytes)",
+	} {
+		desc, err := query.Parse(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = ec.Query(*desc)
+		if err == nil {
+			t.Errorf("%s: expected an error", q)
+		}
+	}
+}
+
+func TestGroupByExpressions(t *testing.T) {
+	ec, err := CreateEventCollection("/tmp/test_cistern_group_expr.lm2", defaultCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { ec.col.Destroy() }()
+	err = ec.StoreEvents(testEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		query    string
+		column   string
+		expected map[string]float64 // counts by group
+	}{
+		{
+			"SELECT hour, count(_id) GROUP BY bucket(_ts, 1h) AS hour",
+			"hour",
+			map[string]float64{"2017-08-01T03:00:00Z": 4, "2017-08-01T04:00:00Z": 3},
+		},
+		{
+			"SELECT count(_id) GROUP BY bucket(_ts, 1d)",
+			"bucket(_ts,1d)",
+			map[string]float64{"2017-08-01T00:00:00Z": 7},
+		},
+		{
+			"SELECT hour_of_day(_ts) + 1 AS next, count(_id) GROUP BY hour_of_day(_ts)",
+			"next",
+			map[string]float64{"4": 4, "5": 3},
+		},
+		{
+			"SELECT count(_id) GROUP BY cidr(dest_address, 16)",
+			"cidr(dest_address,16)",
+			map[string]float64{"52.54.0.0/16": 3, "172.31.0.0/16": 3, "73.31.0.0/16": 1},
+		},
+		{
+			"SELECT count(_id) GROUP BY port_class(dest_port) AS class",
+			"class",
+			map[string]float64{"well-known": 3, "registered": 3, "dynamic": 1},
+		},
+		{
+			"SELECT count(_id) GROUP BY bucket(bytes, 5000)",
+			"bucket(bytes,5000)",
+			map[string]float64{"0": 1, "5000": 4, "10000": 1, "190000": 1},
+		},
+	}
+	for _, c := range testCases {
+		desc, err := query.Parse(c.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := ec.Query(*desc)
+		if err != nil {
+			t.Fatalf("%s: %v", c.query, err)
+		}
+		got := map[string]float64{}
+		for _, row := range result.Summary {
+			got[stringValue(row[c.column])] = row["count(_id)"].(float64)
+		}
+		if !reflect.DeepEqual(got, c.expected) {
+			t.Errorf("%s: expected %v but got %v", c.query, c.expected, got)
+		}
+	}
+
+	for _, q := range []string{
+		"SELECT _ts, count(_id) GROUP BY hour_of_day(_ts)",
+		"SELECT count(_id) GROUP BY bucket(_ts)",
+		"SELECT count(_id) GROUP BY cidr(sum(bytes), 8)",
+	} {
+		desc, err := query.Parse(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = ec.Query(*desc)
+		if err == nil {
+			t.Errorf("%s: expected an error", q)
+		}
+	}
+}
+
+func TestGroupingFunctions(t *testing.T) {
+	ts := time.Date(2017, 8, 1, 3, 20, 0, 0, time.UTC)
+	testCases := []struct {
+		name     string
+		args     []interface{}
+		expected interface{}
+	}{
+		{"bucket", []interface{}{ts, "24h0m0s"}, time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC)},
+		{"bucket", []interface{}{"2017-08-01T03:20:00Z", "15m0s"}, time.Date(2017, 8, 1, 3, 15, 0, 0, time.UTC)},
+		{"bucket", []interface{}{-5.0, 10.0}, -10.0},
+		{"bucket", []interface{}{ts, 0.0}, nil},
+		{"hour_of_day", []interface{}{"2017-08-01T03:20:00+02:00"}, 1.0},
+		{"day_of_week", []interface{}{ts}, 2.0},
+		{"cidr", []interface{}{"10.1.2.3", 24.0}, "10.1.2.0/24"},
+		{"cidr", []interface{}{"2001:db8::1", 32.0}, "2001:db8::/32"},
+		{"cidr", []interface{}{"10.1.2.3", 33.0}, nil},
+		{"cidr", []interface{}{"-", 8.0}, nil},
+		{"port_class", []interface{}{json.Number("22")}, "well-known"},
+		{"port_class", []interface{}{"8080"}, "registered"},
+		{"port_class", []interface{}{60000}, "dynamic"},
+		{"port_class", []interface{}{70000}, nil},
+	}
+	for _, c := range testCases {
+		got := callFunction(c.name, c.args)
+		if !reflect.DeepEqual(got, c.expected) {
+			t.Errorf("%s%v: expected %v but got %v", c.name, c.args, c.expected, got)
+		}
+	}
+}
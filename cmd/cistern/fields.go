@@ -0,0 +1,467 @@
+package main
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"log"
+	"math"
+	"math/bits"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Preetam/lm2"
+)
+
+const (
+	// fieldCatalogKeyPrefix is the prefix of the keys holding the
+	// catalog entry of each field. It lives in the reserved '_' key space.
+	fieldCatalogKeyPrefix = "_field\x00"
+	// fieldCatalogBuiltKey is set when the stored catalog covers all
+	// events. Writes of events remove it until the catalog is written
+	// again, so a catalog that missed writes is rebuilt on open.
+	fieldCatalogBuiltKey = "_fieldsbuilt"
+
+	// maxCatalogFields limits the number of cataloged fields so events
+	// with generated field names can't grow the catalog without bound.
+	maxCatalogFields = 1000
+	maxFieldSamples  = 5
+	maxSampleLength  = 100
+
+	// fieldCatalogFlushInterval is how often changed catalog entries are
+	// written. lm2 appends a record for every update, so writing them with
+	// every batch would mostly fill the file with stale catalog entries.
+	// If the collection isn't closed cleanly, the catalog is rebuilt.
+	fieldCatalogFlushInterval = time.Minute
+
+	// cardinalityRegisters is the number of HyperLogLog registers kept
+	// per field. 256 registers estimate cardinality within about 7%.
+	cardinalityRegisters = 256
+)
+
+// FieldInfo describes a field observed in the events of a collection.
+type FieldInfo struct {
+	Name        string    `json:"name"` // path of the field
+	Types       []string  `json:"types"`
+	Count       int64     `json:"count"`       // events with the field
+	Cardinality int64     `json:"cardinality"` // approximate number of distinct values
+	FirstSeen   time.Time `json:"first_seen"`  // timestamp of the oldest event with the field
+	LastSeen    time.Time `json:"last_seen"`   // timestamp of the newest event with the field
+	Samples     []string  `json:"samples,omitempty"`
+}
+
+// fieldStats is the catalog entry of a field.
+type fieldStats struct {
+	FieldInfo
+	Registers []byte `json:"registers"`
+}
+
+// fieldCatalog keeps track of the fields of a collection.
+type fieldCatalog struct {
+	lock      sync.Mutex
+	fields    map[string]*fieldStats
+	changed   map[string]bool // fields changed since the last write
+	removed   map[string]bool // fields removed since the last write
+	lastWrite time.Time
+}
+
+func newFieldCatalog() *fieldCatalog {
+	return &fieldCatalog{
+		fields:    map[string]*fieldStats{},
+		changed:   map[string]bool{},
+		removed:   map[string]bool{},
+		lastWrite: time.Now(),
+	}
+}
+
+// fieldChanges are the events a write batch stores and removes. They're
+// applied to the field catalog once the batch is written.
+type fieldChanges struct {
+	stored   []Event
+	storedTs []int64
+	removed  []Event
+}
+
+func (fch *fieldChanges) store(event Event, ts int64) {
+	fch.stored = append(fch.stored, event)
+	fch.storedTs = append(fch.storedTs, ts)
+}
+
+func (fch *fieldChanges) remove(event Event) {
+	fch.removed = append(fch.removed, event)
+}
+
+// invalidateFieldCatalog removes the key that marks the stored catalog
+// as complete in a write batch that changes events.
+func invalidateFieldCatalog(wb *lm2.WriteBatch) {
+	wb.Delete(fieldCatalogBuiltKey)
+}
+
+// applyFieldChanges updates the field catalog with the changes of a
+// written batch, and writes the catalog if it's due. The caller must
+// hold the collection lock and serialize writes.
+func (c *EventCollection) applyFieldChanges(fch fieldChanges) {
+	fc := c.fieldCatalog
+	for i, event := range fch.stored {
+		fc.observe(event, fch.storedTs[i])
+	}
+	for _, event := range fch.removed {
+		fc.forget(event)
+	}
+	if !fc.flushDue() {
+		return
+	}
+	err := c.flushFieldCatalog()
+	if err != nil {
+		// The catalog stays invalid, so it's rebuilt on open.
+		log.Printf("Couldn't write the field catalog of %s: %v", c.filename, err)
+	}
+}
+
+// Fields returns the cataloged fields whose names start with prefix,
+// ordered by name.
+func (c *EventCollection) Fields(prefix string) []FieldInfo {
+	fc := c.fieldCatalog
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+
+	fields := []FieldInfo{}
+	for name, stats := range fc.fields {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		info := stats.FieldInfo
+		info.Cardinality = estimateCardinality(stats.Registers)
+		fields = append(fields, info)
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Name < fields[j].Name
+	})
+	return fields
+}
+
+// observe adds the fields of an event stored with the given timestamp
+// to the catalog. Nested objects are cataloged by path; arrays are
+// cataloged by their first element.
+func (fc *fieldCatalog) observe(event Event, ts int64) {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+	seen := fromMicrosecondTime(ts).UTC()
+	for name, value := range event {
+		if strings.HasPrefix(name, "_") && name != "_tag" {
+			continue
+		}
+		fc.observeValue(name, value, seen)
+	}
+}
+
+func (fc *fieldCatalog) observeValue(path string, value interface{}, seen time.Time) {
+	stats, ok := fc.fields[path]
+	if !ok {
+		if len(fc.fields) >= maxCatalogFields {
+			return
+		}
+		delete(fc.removed, path)
+		stats = &fieldStats{
+			FieldInfo: FieldInfo{
+				Name:      path,
+				FirstSeen: seen,
+				LastSeen:  seen,
+			},
+			Registers: make([]byte, cardinalityRegisters),
+		}
+		fc.fields[path] = stats
+	}
+	fc.changed[path] = true
+
+	stats.Count++
+	if seen.Before(stats.FirstSeen) {
+		stats.FirstSeen = seen
+	}
+	if seen.After(stats.LastSeen) {
+		stats.LastSeen = seen
+	}
+	typ := fieldType(value)
+	if !containsString(stats.Types, typ) {
+		stats.Types = append(stats.Types, typ)
+		sort.Strings(stats.Types)
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for name, fieldValue := range v {
+			fc.observeValue(path+"."+name, fieldValue, seen)
+		}
+		return
+	case []interface{}:
+		if len(v) > 0 {
+			fc.observeValue(path+"[0]", v[0], seen)
+		}
+		return
+	}
+
+	sample := stringValue(value)
+	addCardinality(stats.Registers, sample)
+	if len(stats.Samples) < maxFieldSamples && len(sample) <= maxSampleLength &&
+		!containsString(stats.Samples, sample) {
+		stats.Samples = append(stats.Samples, sample)
+	}
+}
+
+// forget removes an event from the counts of its fields. Fields that
+// no event has anymore are removed. The other statistics of a field
+// only shrink when the catalog is rebuilt.
+func (fc *fieldCatalog) forget(event Event) {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+	for name, value := range event {
+		if strings.HasPrefix(name, "_") && name != "_tag" {
+			continue
+		}
+		fc.forgetValue(name, value)
+	}
+}
+
+func (fc *fieldCatalog) forgetValue(path string, value interface{}) {
+	stats, ok := fc.fields[path]
+	if !ok {
+		return
+	}
+	stats.Count--
+	if stats.Count <= 0 {
+		delete(fc.fields, path)
+		delete(fc.changed, path)
+		fc.removed[path] = true
+	} else {
+		fc.changed[path] = true
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for name, fieldValue := range v {
+			fc.forgetValue(path+"."+name, fieldValue)
+		}
+	case []interface{}:
+		if len(v) > 0 {
+			fc.forgetValue(path+"[0]", v[0])
+		}
+	}
+}
+
+// replace replaces the fields of the catalog with the fields of other,
+// which aren't written yet.
+func (fc *fieldCatalog) replace(other *fieldCatalog) {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+	for name := range fc.fields {
+		fc.removed[name] = true
+	}
+	fc.fields = other.fields
+	fc.changed = map[string]bool{}
+	for name := range fc.fields {
+		fc.changed[name] = true
+		delete(fc.removed, name)
+	}
+}
+
+// flushDue returns true if the changed entries should be written.
+func (fc *fieldCatalog) flushDue() bool {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+	return len(fc.changed)+len(fc.removed) > 0 && time.Since(fc.lastWrite) >= fieldCatalogFlushInterval
+}
+
+// write adds the changed and removed catalog entries to wb, and marks
+// the stored catalog as complete.
+func (fc *fieldCatalog) write(wb *lm2.WriteBatch) error {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+	fc.lastWrite = time.Now()
+	for name := range fc.changed {
+		data, err := json.Marshal(fc.fields[name])
+		if err != nil {
+			return err
+		}
+		wb.Set(fieldCatalogKeyPrefix+name, string(data))
+	}
+	for name := range fc.removed {
+		wb.Delete(fieldCatalogKeyPrefix + name)
+	}
+	fc.changed = map[string]bool{}
+	fc.removed = map[string]bool{}
+	wb.Set(fieldCatalogBuiltKey, "")
+	return nil
+}
+
+// flushFieldCatalog writes the changed catalog entries. The caller
+// must hold the collection lock.
+func (c *EventCollection) flushFieldCatalog() error {
+	wb := lm2.NewWriteBatch()
+	err := c.fieldCatalog.write(wb)
+	if err != nil {
+		return err
+	}
+	_, err = c.col.Update(wb)
+	return err
+}
+
+// loadFieldCatalog reads the field catalog, building it from the stored
+// events if the collection predates it or the stored catalog missed
+// writes of events.
+func (c *EventCollection) loadFieldCatalog() error {
+	fc := newFieldCatalog()
+	c.fieldCatalog = fc
+
+	cur, err := c.col.NewCursor()
+	if err != nil {
+		return err
+	}
+	_, err = cur.Get(fieldCatalogBuiltKey)
+	if err == lm2.ErrKeyNotFound {
+		return c.buildFieldCatalog()
+	}
+	if err != nil {
+		return err
+	}
+
+	cur.Seek(fieldCatalogKeyPrefix)
+	for cur.Next() {
+		if cur.Key() < fieldCatalogKeyPrefix {
+			continue
+		}
+		if !strings.HasPrefix(cur.Key(), fieldCatalogKeyPrefix) {
+			break
+		}
+		stats := &fieldStats{}
+		err = json.Unmarshal([]byte(cur.Value()), stats)
+		if err != nil {
+			return err
+		}
+		if len(stats.Registers) != cardinalityRegisters {
+			stats.Registers = make([]byte, cardinalityRegisters)
+		}
+		fc.fields[stats.Name] = stats
+	}
+	return cur.Err()
+}
+
+func (c *EventCollection) buildFieldCatalog() error {
+	cur, err := c.col.NewCursor()
+	if err != nil {
+		return err
+	}
+	// Stored entries of fields no event has anymore are removed.
+	cur.Seek(fieldCatalogKeyPrefix)
+	for cur.Next() {
+		if cur.Key() < fieldCatalogKeyPrefix {
+			continue
+		}
+		if !strings.HasPrefix(cur.Key(), fieldCatalogKeyPrefix) {
+			break
+		}
+		c.fieldCatalog.removed[cur.Key()[len(fieldCatalogKeyPrefix):]] = true
+	}
+	if err = cur.Err(); err != nil {
+		return err
+	}
+
+	cur, err = c.col.NewCursor()
+	if err != nil {
+		return err
+	}
+	cur.Seek(string(eventKeyPrefix))
+	for cur.Next() {
+		if cur.Key()[0] != eventKeyPrefix {
+			if cur.Key()[0] > eventKeyPrefix {
+				break
+			}
+			continue
+		}
+		ts, tag, _, err := splitCollectionID(cur.Key())
+		if err != nil {
+			return err
+		}
+		event := Event{}
+		err = json.Unmarshal([]byte(cur.Value()), &event)
+		if err != nil {
+			return err
+		}
+		event["_tag"] = tag
+		c.fieldCatalog.observe(event, ts)
+	}
+	if err = cur.Err(); err != nil {
+		return err
+	}
+	return c.flushFieldCatalog()
+}
+
+// fieldType returns the JSON type of a value.
+func fieldType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case float64, int64, int:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	}
+	return "object"
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// addCardinality adds a value to HyperLogLog registers.
+func addCardinality(registers []byte, value string) {
+	h := fnv.New64a()
+	h.Write([]byte(value))
+	x := mix64(h.Sum64())
+	// The top 8 bits pick the register; the rank of the rest is stored.
+	index := x >> 56
+	rank := byte(bits.LeadingZeros64(x<<8|1<<7) + 1)
+	if rank > registers[index] {
+		registers[index] = rank
+	}
+}
+
+// mix64 scrambles the bits of an FNV hash, whose high bits
+// are poorly distributed for short inputs.
+func mix64(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// estimateCardinality returns the HyperLogLog estimate of the number
+// of distinct values added to the registers.
+func estimateCardinality(registers []byte) int64 {
+	m := float64(len(registers))
+	sum := 0.0
+	zeros := 0
+	for _, r := range registers {
+		sum += math.Pow(2, -float64(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		// Linear counting is more accurate for small cardinalities.
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return int64(estimate + 0.5)
+}
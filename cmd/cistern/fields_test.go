@@ -0,0 +1,147 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFieldCatalog(t *testing.T) {
+	const filename = "/tmp/test_cistern_fields.lm2"
+	ec, err := CreateEventCollection(filename, defaultCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(filename + ".wal")
+	defer os.Remove(filename)
+	err = ec.StoreEvents(testEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields := ec.Fields("")
+	names := []string{}
+	for _, field := range fields {
+		names = append(names, field.Name)
+	}
+	expectedNames := []string{"_tag", "bytes", "dest_address", "dest_port", "packets",
+		"protocol", "source_address", "source_port"}
+	if !reflect.DeepEqual(names, expectedNames) {
+		t.Fatalf("expected fields %v but got %v", expectedNames, names)
+	}
+
+	// The catalog is written when the collection is closed.
+	ec.Close()
+	ec, err = OpenEventCollection(filename, defaultCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ec.Close()
+
+	fields = ec.Fields("dest_")
+	if len(fields) != 2 {
+		t.Fatalf("expected 2 dest_ fields but got %+v", fields)
+	}
+	port := fields[1]
+	first, _ := time.Parse(time.RFC3339, "2017-08-01T03:20:00Z")
+	last, _ := time.Parse(time.RFC3339, "2017-08-01T04:20:00Z")
+	if port.Name != "dest_port" || port.Count != int64(len(testEvents)) ||
+		!reflect.DeepEqual(port.Types, []string{"number"}) ||
+		!port.FirstSeen.Equal(first) || !port.LastSeen.Equal(last) {
+		t.Errorf("unexpected catalog entry %+v", port)
+	}
+	if port.Cardinality != 4 {
+		t.Errorf("expected 4 distinct dest_port values but got %d", port.Cardinality)
+	}
+	if len(port.Samples) != 4 || port.Samples[0] != "443" {
+		t.Errorf("expected 4 samples but got %v", port.Samples)
+	}
+}
+
+func TestFieldCatalogChanges(t *testing.T) {
+	const filename = "/tmp/test_cistern_field_changes.lm2"
+	ec, err := CreateEventCollection(filename, defaultCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { ec.col.Destroy() }()
+	err = ec.StoreEvents(testEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Events of a failed write aren't cataloged.
+	_, err = ec.StoreNewEvents([]Event{
+		{"_tag": "a", "_ts": "2017-08-01T05:00:00Z", "failed": 1},
+		{"_tag": "a"},
+	})
+	if err == nil {
+		t.Fatal("expected an event without a timestamp to fail")
+	}
+	if fields := ec.Fields("failed"); len(fields) != 0 {
+		t.Errorf("expected no fields of a failed write but got %+v", fields)
+	}
+
+	// Writes that aren't in the stored catalog yet survive a crash.
+	ec.Close()
+	ec, err = OpenEventCollection(filename, defaultCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ec.StoreNewEvents([]Event{{"_tag": "a", "_ts": "2017-08-01T05:00:00Z", "stored": 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec.col.Close()
+	ec, err = OpenEventCollection(filename, defaultCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields := ec.Fields("stored"); len(fields) != 1 || fields[0].Count != 1 {
+		t.Errorf("expected the field of the last write but got %+v", fields)
+	}
+
+	// Removed events aren't counted.
+	cur, err := ec.col.NewCursor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := []archiveRecord{}
+	for cur.Next() {
+		if cur.Key()[0] == eventKeyPrefix && strings.Contains(cur.Value(), `"stored"`) {
+			records = append(records, archiveRecord{key: cur.Key(), value: cur.Value()})
+		}
+	}
+	err = ec.removeArchived(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields := ec.Fields("stored"); len(fields) != 0 {
+		t.Errorf("expected the field of removed events to be removed but got %+v", fields)
+	}
+
+	// Compacting rebuilds the catalog from the kept events.
+	ec.SetMaxEvents(2)
+	err = ec.Compact()
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := time.Parse(time.RFC3339, testEvents[len(testEvents)-2]["_ts"].(string))
+	fields := ec.Fields("dest_port")
+	if len(fields) != 1 || fields[0].Count != 2 || !fields[0].FirstSeen.Equal(first) {
+		t.Errorf("expected 2 dest_port values from %v but got %+v", first, fields)
+	}
+}
+
+func TestEstimateCardinality(t *testing.T) {
+	registers := make([]byte, cardinalityRegisters)
+	for i := 0; i < 100000; i++ {
+		addCardinality(registers, time.Duration(i).String())
+	}
+	estimate := estimateCardinality(registers)
+	if estimate < 85000 || estimate > 115000 {
+		t.Errorf("expected about 100000 but got %d", estimate)
+	}
+}
@@ -1,10 +1,9 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
+	"net"
 	"regexp"
-	"strconv"
 
 	"github.com/Cistern/cistern/internal/query"
 )
@@ -20,6 +19,11 @@ const (
 	FilterGreaterThan
 	FilterGreaterThanOrEqual
 	FilterMatches
+	FilterIn
+	FilterNotIn
+	FilterInCIDR
+	FilterNotInCIDR
+	FilterBetween
 )
 
 func (f FilterType) String() string {
@@ -31,6 +35,11 @@ func (f FilterType) String() string {
 		FilterGreaterThan:        ">",
 		FilterGreaterThanOrEqual: ">=",
 		FilterMatches:            "matches",
+		FilterIn:                 "in",
+		FilterNotIn:              "not in",
+		FilterInCIDR:             "in cidr",
+		FilterNotInCIDR:          "not in cidr",
+		FilterBetween:            "between",
 	}
 	if str, ok := rep[f]; ok {
 		return str
@@ -41,13 +50,18 @@ func (f FilterType) String() string {
 func stringToFilterType(s string) FilterType {
 	ft := FilterUnknown
 	rep := map[string]FilterType{
-		"=":       FilterEquals,
-		"!=":      FilterNotEquals,
-		"<":       FilterLessThan,
-		"<=":      FilterLessThanOrEqual,
-		">":       FilterGreaterThan,
-		">=":      FilterGreaterThanOrEqual,
-		"matches": FilterMatches,
+		"=":           FilterEquals,
+		"!=":          FilterNotEquals,
+		"<":           FilterLessThan,
+		"<=":          FilterLessThanOrEqual,
+		">":           FilterGreaterThan,
+		">=":          FilterGreaterThanOrEqual,
+		"matches":     FilterMatches,
+		"in":          FilterIn,
+		"not in":      FilterNotIn,
+		"in cidr":     FilterInCIDR,
+		"not in cidr": FilterNotInCIDR,
+		"between":     FilterBetween,
 	}
 	if f, ok := rep[s]; ok {
 		ft = f
@@ -59,126 +73,287 @@ func buildFilters(queryFilters []query.Filter) ([]Filter, error) {
 	filters := []Filter{}
 
 	for _, f := range queryFilters {
-		filterType := stringToFilterType(f.Condition)
-		switch filterType {
-		case FilterUnknown:
-			return nil, fmt.Errorf("unknown filter %s", f.Condition)
-
-		case FilterEquals:
-			filters = append(filters, EqualsFilter(f.Column, f.Value))
-		case FilterNotEquals:
-			filters = append(filters, NotEqualsFilter(f.Column, f.Value))
-		case FilterLessThan:
-			filters = append(filters, LessThanFilter(f.Column, f.Value))
-		case FilterLessThanOrEqual:
-			filters = append(filters, LessThanOrEqualFilter(f.Column, f.Value))
-		case FilterGreaterThan:
-			filters = append(filters, GreaterThanFilter(f.Column, f.Value))
-		case FilterGreaterThanOrEqual:
-			filters = append(filters, GreaterThanOrEqualFilter(f.Column, f.Value))
-		case FilterMatches:
-			str, ok := f.Value.(string)
+		filter, err := buildFilter(f)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+
+	return filters, nil
+}
+
+func buildFilter(f query.Filter) (Filter, error) {
+	switch {
+	case f.And != nil:
+		operands, err := buildFilters(f.And)
+		if err != nil {
+			return Filter{}, err
+		}
+		return AndFilter(operands...), nil
+	case f.Or != nil:
+		operands, err := buildFilters(f.Or)
+		if err != nil {
+			return Filter{}, err
+		}
+		return OrFilter(operands...), nil
+	case f.Not != nil:
+		operand, err := buildFilter(*f.Not)
+		if err != nil {
+			return Filter{}, err
+		}
+		return NotFilter(operand), nil
+	}
+
+	filter, err := buildComparison(f)
+	if err != nil {
+		return Filter{}, err
+	}
+	filter.expr = f.Expr
+	return filter, nil
+}
+
+func buildComparison(f query.Filter) (Filter, error) {
+	filterType := stringToFilterType(f.Condition)
+	switch filterType {
+	case FilterEquals:
+		return EqualsFilter(f.Column, f.Value), nil
+	case FilterNotEquals:
+		return NotEqualsFilter(f.Column, f.Value), nil
+	case FilterLessThan:
+		return LessThanFilter(f.Column, f.Value), nil
+	case FilterLessThanOrEqual:
+		return LessThanOrEqualFilter(f.Column, f.Value), nil
+	case FilterGreaterThan:
+		return GreaterThanFilter(f.Column, f.Value), nil
+	case FilterGreaterThanOrEqual:
+		return GreaterThanOrEqualFilter(f.Column, f.Value), nil
+	case FilterMatches:
+		str, ok := f.Value.(string)
+		if !ok {
+			return Filter{}, fmt.Errorf("expected string value for matches filter")
+		}
+		r, err := regexp.Compile(str)
+		if err != nil {
+			return Filter{}, err
+		}
+		return MatchesFilter(f.Column, r), nil
+	case FilterIn:
+		return InFilter(f.Column, filterValues(f.Value)), nil
+	case FilterNotIn:
+		return NotInFilter(f.Column, filterValues(f.Value)), nil
+	case FilterInCIDR, FilterNotInCIDR:
+		nets := []*net.IPNet{}
+		for _, v := range filterValues(f.Value) {
+			str, ok := v.(string)
 			if !ok {
-				return nil, fmt.Errorf("expected string value for matches filter")
+				return Filter{}, fmt.Errorf("expected string values for %s filter", filterType)
 			}
-			r, err := regexp.Compile(str)
+			_, n, err := net.ParseCIDR(str)
 			if err != nil {
-				return nil, err
+				return Filter{}, err
 			}
-			filters = append(filters, MatchesFilter(f.Column, r))
+			nets = append(nets, n)
+		}
+		if filterType == FilterNotInCIDR {
+			return NotInCIDRFilter(f.Column, nets), nil
 		}
+		return InCIDRFilter(f.Column, nets), nil
+	case FilterBetween:
+		values, ok := f.Value.([]interface{})
+		if !ok || len(values) != 2 {
+			return Filter{}, fmt.Errorf("expected two values for between filter")
+		}
+		return BetweenFilter(f.Column, values[0], values[1]), nil
 	}
+	return Filter{}, fmt.Errorf("unknown filter %s", f.Condition)
+}
 
-	return filters, nil
+// filterValues returns the values of a list filter. A single value
+// is a list of one.
+func filterValues(value interface{}) []interface{} {
+	if values, ok := value.([]interface{}); ok {
+		return values
+	}
+	return []interface{}{value}
 }
 
+type filterOp int
+
+const (
+	filterCompare filterOp = iota
+	filterAnd
+	filterOr
+	filterNot
+)
+
 type Filter struct {
 	column     string
-	value      interface{}
-	filterFunc func(a, b interface{}) bool
+	value      Value
+	filterFunc func(a, b Value) bool
+	// expr computes the compared value instead of column, from
+	// the fields of an event and the aggregates of a summary row.
+	expr *query.Expr
+
+	// Boolean filters combine the results of their operands.
+	op       filterOp
+	operands []Filter
 }
 
 func (f Filter) Filter(e Event) bool {
-	if v, ok := e[f.column]; !ok {
+	switch f.op {
+	case filterAnd:
+		for _, operand := range f.operands {
+			if !operand.Filter(e) {
+				return false
+			}
+		}
+		return true
+	case filterOr:
+		for _, operand := range f.operands {
+			if operand.Filter(e) {
+				return true
+			}
+		}
 		return false
-	} else {
-		return f.filterFunc(v, f.value)
+	case filterNot:
+		return !f.operands[0].Filter(e)
 	}
-}
 
-func EqualsFilter(column string, value interface{}) Filter {
-	filterFunc := func(a, b interface{}) bool {
-		return compareInterfaces(a, b) == 0
+	if f.expr != nil {
+		v := evalExpr(*f.expr, e, e)
+		if v == nil {
+			return false
+		}
+		return f.filterFunc(valueOf(v), f.value)
 	}
+	v, ok := e.Lookup(f.column)
+	if !ok {
+		return false
+	}
+	if ts, ok := v.(int64); ok && f.column == "_ts" {
+		// Event timestamps are in microseconds while scanning.
+		v = fromMicrosecondTime(ts)
+	}
+	return f.filterFunc(valueOf(v), f.value)
+}
+
+// compareFilter returns a filter that compares values of the column
+// with value. The type of value is inferred.
+func compareFilter(column string, value interface{}, test func(int) bool) Filter {
 	return Filter{
-		column:     column,
-		value:      value,
-		filterFunc: filterFunc,
+		column: column,
+		value:  inferValue(value),
+		filterFunc: func(a, b Value) bool {
+			return test(compareValues(a, b))
+		},
 	}
 }
 
+func EqualsFilter(column string, value interface{}) Filter {
+	return compareFilter(column, value, func(c int) bool { return c == 0 })
+}
+
 func NotEqualsFilter(column string, value interface{}) Filter {
-	filterFunc := func(a, b interface{}) bool {
-		return compareInterfaces(a, b) != 0
+	return compareFilter(column, value, func(c int) bool { return c != 0 })
+}
+
+func LessThanFilter(column string, value interface{}) Filter {
+	return compareFilter(column, value, func(c int) bool { return c < 0 })
+}
+
+func LessThanOrEqualFilter(column string, value interface{}) Filter {
+	return compareFilter(column, value, func(c int) bool { return c <= 0 })
+}
+
+func GreaterThanFilter(column string, value interface{}) Filter {
+	return compareFilter(column, value, func(c int) bool { return c > 0 })
+}
+
+func GreaterThanOrEqualFilter(column string, value interface{}) Filter {
+	return compareFilter(column, value, func(c int) bool { return c >= 0 })
+}
+
+func MatchesFilter(column string, r *regexp.Regexp) Filter {
+	filterFunc := func(a, b Value) bool {
+		if a.typ != TypeString {
+			return false
+		}
+		return r.MatchString(a.s)
 	}
 	return Filter{
 		column:     column,
-		value:      value,
 		filterFunc: filterFunc,
 	}
 }
 
-func LessThanFilter(column string, value interface{}) Filter {
-	filterFunc := func(a, b interface{}) bool {
-		return compareInterfaces(a, b) < 0
+func InFilter(column string, values []interface{}) Filter {
+	inferred := make([]Value, len(values))
+	for i, v := range values {
+		inferred[i] = inferValue(v)
+	}
+	filterFunc := func(a, b Value) bool {
+		for _, v := range inferred {
+			if compareValues(a, v) == 0 {
+				return true
+			}
+		}
+		return false
 	}
 	return Filter{
 		column:     column,
-		value:      value,
 		filterFunc: filterFunc,
 	}
 }
 
-func LessThanOrEqualFilter(column string, value interface{}) Filter {
-	filterFunc := func(a, b interface{}) bool {
-		return compareInterfaces(a, b) <= 0
-	}
+func NotInFilter(column string, values []interface{}) Filter {
+	in := InFilter(column, values).filterFunc
 	return Filter{
 		column:     column,
-		value:      value,
-		filterFunc: filterFunc,
+		filterFunc: func(a, b Value) bool { return !in(a, b) },
 	}
 }
 
-func GreaterThanFilter(column string, value interface{}) Filter {
-	filterFunc := func(a, b interface{}) bool {
-		return compareInterfaces(a, b) > 0
+// InCIDRFilter matches IPv4 and IPv6 addresses in any of the networks.
+// IPv4-mapped IPv6 addresses match IPv4 networks.
+func InCIDRFilter(column string, nets []*net.IPNet) Filter {
+	filterFunc := func(a, b Value) bool {
+		ip, ok := a.coerce(TypeIP)
+		if !ok {
+			return false
+		}
+		for _, n := range nets {
+			if n.Contains(ip.ip) {
+				return true
+			}
+		}
+		return false
 	}
 	return Filter{
 		column:     column,
-		value:      value,
 		filterFunc: filterFunc,
 	}
 }
 
-func GreaterThanOrEqualFilter(column string, value interface{}) Filter {
-	filterFunc := func(a, b interface{}) bool {
-		return compareInterfaces(a, b) >= 0
+// NotInCIDRFilter matches addresses outside all of the networks.
+// Values that aren't addresses don't match.
+func NotInCIDRFilter(column string, nets []*net.IPNet) Filter {
+	in := InCIDRFilter(column, nets).filterFunc
+	filterFunc := func(a, b Value) bool {
+		_, ok := a.coerce(TypeIP)
+		return ok && !in(a, b)
 	}
 	return Filter{
 		column:     column,
-		value:      value,
 		filterFunc: filterFunc,
 	}
 }
 
-func MatchesFilter(column string, r *regexp.Regexp) Filter {
-	filterFunc := func(a, b interface{}) bool {
-		aString, ok := a.(string)
-		if !ok {
-			return false
-		}
-		return r.MatchString(aString)
+// BetweenFilter matches values from low to high, inclusive.
+func BetweenFilter(column string, low, high interface{}) Filter {
+	lowValue, highValue := inferValue(low), inferValue(high)
+	filterFunc := func(a, b Value) bool {
+		return compareValues(a, lowValue) >= 0 && compareValues(a, highValue) <= 0
 	}
 	return Filter{
 		column:     column,
@@ -186,51 +361,16 @@ func MatchesFilter(column string, r *regexp.Regexp) Filter {
 	}
 }
 
-func checkEquals(a, b interface{}) bool {
-	return compareInterfaces(a, b) == 0
+func AndFilter(operands ...Filter) Filter {
+	return Filter{op: filterAnd, operands: operands}
 }
 
-func compareInterfaces(a, b interface{}) int {
-	switch a.(type) {
-	case int:
-		aInt := a.(int)
-		if bInt, ok := b.(int); ok {
-			return aInt - bInt
-		}
-	case float64:
-		aFloat := a.(float64)
-		if bFloat, ok := b.(float64); ok {
-			if aFloat == bFloat {
-				return 0
-			} else if aFloat < bFloat {
-				return -1
-			} else {
-				return 1
-			}
-		}
-	case string:
-		aString := a.(string)
-		if bString, ok := b.(string); ok {
-			if aString == bString {
-				return 0
-			} else if aString < bString {
-				return -1
-			} else {
-				return 1
-			}
-		}
-	case json.Number:
-		aFloat, _ := strconv.ParseFloat(string(a.(json.Number)), 64)
-		if bNumber, ok := b.(json.Number); ok {
-			bFloat, _ := strconv.ParseFloat(string(bNumber), 64)
-			if aFloat == bFloat {
-				return 0
-			} else if aFloat < bFloat {
-				return -1
-			} else {
-				return 1
-			}
-		}
-	}
-	return -1
+func OrFilter(operands ...Filter) Filter {
+	return Filter{op: filterOr, operands: operands}
+}
+
+// NotFilter matches the events f doesn't match, including events
+// without the column of f.
+func NotFilter(f Filter) Filter {
+	return Filter{op: filterNot, operands: []Filter{f}}
 }
@@ -5,9 +5,9 @@ import (
 	"fmt"
 	"log"
 	"net"
-	"path/filepath"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -35,6 +35,7 @@ type FlowLogRecord struct {
 	Timestamp  time.Time `json:"_ts"`
 	Duration   float64   `json:"_duration"`
 	StreamName string    `json:"stream_name"`
+	EventID    string    `json:"_hash"`
 }
 
 func (r *FlowLogRecord) Parse(s string) error {
@@ -117,14 +118,35 @@ func (r *FlowLogRecord) ToEvent() Event {
 		"log_status":     r.LogStatus,
 		"_ts":            r.Timestamp.Format(time.RFC3339Nano),
 		"_tag":           r.StreamName,
+		"_hash":          r.EventID,
 	}
 }
 
-func captureFlowLogs(groupName string, retention int, done chan struct{}) error {
-	if retention == 0 {
-		retention = 7
-		log.Printf("Missing retention for %s; defaulting to %d days", groupName, retention)
+// flowLogConverter returns the converter of flow log groups, which
+// doesn't depend on their state.
+func flowLogConverter(*LogState) logEventConverter {
+	return flowLogEvent
+}
+
+// flowLogEvent converts a CloudWatch Logs event of a flow log group.
+// The CloudWatch event ID identifies the event, since records of the
+// same stream often share a start time.
+func flowLogEvent(e *cloudwatchlogs.FilteredLogEvent) (Event, error) {
+	rec := &FlowLogRecord{}
+	err := rec.Parse(*e.Message)
+	if err != nil {
+		return nil, err
 	}
+	rec.Timestamp = rec.Start
+	rec.Duration = rec.End.Sub(rec.Start).Seconds()
+	rec.StreamName = *e.LogStreamName
+	if e.EventId != nil {
+		rec.EventID = *e.EventId
+	}
+	return rec.ToEvent(), nil
+}
+
+func captureFlowLogs(groupName string, store EventStore, done chan struct{}) error {
 	stop := make(chan struct{}, 1)
 
 	go func() {
@@ -137,27 +159,8 @@ func captureFlowLogs(groupName string, retention int, done chan struct{}) error
 		return err
 	}
 
-	collectionsLock.Lock()
-	eventCollection := Collections[groupName]
-	if eventCollection == nil {
-		eventCollection, err = OpenEventCollection(filepath.Join(DataDir, groupName+".lm2"))
-		if err != nil {
-			if err == ErrDoesNotExist {
-				eventCollection, err = CreateEventCollection(filepath.Join(DataDir, groupName+".lm2"))
-			}
-			if err != nil {
-				collectionsLock.Unlock()
-				return err
-			}
-		}
-		Collections[groupName] = eventCollection
-	}
-	collectionsLock.Unlock()
-
-	eventCollection.SetRetention(retention)
-
 	nextBatchStart := cwl.LastTimestamp()
-	currentBatch := []*FlowLogRecord{}
+	currentBatch := []Event{}
 	timer := time.NewTimer(0)
 
 	log.Println("Starting poll of flow log group", groupName)
@@ -177,23 +180,23 @@ func captureFlowLogs(groupName string, retention int, done chan struct{}) error
 		case <-timer.C:
 		case <-stop:
 			log.Println("Stopping poll of flow log group", groupName)
-			eventCollection.col.Close()
 			return nil
 		}
 
+		if atomic.LoadInt32(&lowDisk) == 1 {
+			// Don't fetch anything until there's room to store it.
+			continue
+		}
+
 		logEvents, err := cwl.GetLogEvents(nextBatchStart)
 		if err != nil {
 			return err
 		}
 
 		for _, e := range logEvents {
-			rec := &FlowLogRecord{}
-			err = rec.Parse(*e.Message)
+			event, err := flowLogEvent(e)
 			if err == nil {
-				rec.Timestamp = rec.Start
-				rec.Duration = rec.End.Sub(rec.Start).Seconds()
-				rec.StreamName = *e.LogStreamName
-				currentBatch = append(currentBatch, rec)
+				currentBatch = append(currentBatch, event)
 			}
 
 			if nextBatchStart < *e.Timestamp {
@@ -201,20 +204,24 @@ func captureFlowLogs(groupName string, retention int, done chan struct{}) error
 			}
 		}
 
-		events := []Event{}
-		for _, rec := range currentBatch {
-			event := rec.ToEvent()
-			event["_tag"] = rec.StreamName
-			events = append(events, event)
-		}
-
+		events := currentBatch
 		if len(logEvents) > 0 {
-			log.Printf("Logs group %s: aggregated %d events", groupName, len(logEvents))
-			err = eventCollection.StoreEvents(events)
+			stored, err := store.StoreNewEvents(events)
+			if err == ErrLowDisk {
+				// The disk filled up during the fetch. Fetch the
+				// batch again from the checkpoint once there's room.
+				log.Printf("Logs group %s: pausing, %v", groupName, err)
+				nextBatchStart = cwl.LastTimestamp()
+				currentBatch = currentBatch[:0]
+				continue
+			}
 			if err != nil {
 				return err
 			}
-			nextBatchStart += 1
+			log.Printf("Logs group %s: aggregated %d events, %d new", groupName, len(logEvents), stored)
+			// The next poll starts at the last timestamp again so that events
+			// with the same timestamp that arrive late aren't skipped.
+			// Events that were already stored are skipped by StoreNewEvents.
 			err = cwl.SetLastTimestamp(nextBatchStart)
 			if err != nil {
 				return err
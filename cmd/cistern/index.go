@@ -0,0 +1,292 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Preetam/lm2"
+)
+
+const (
+	indexKeyPrefix byte = 'x'
+
+	// indexBuiltKeyPrefix marks an index as built for all existing events.
+	indexBuiltKeyPrefix = "_index2\x00"
+
+	// Index entries used to be keyed by the JSON of values. Compaction
+	// drops the entries and markers of those indexes.
+	legacyIndexKeyPrefix      byte = 'i'
+	legacyIndexBuiltKeyPrefix      = "_index\x00"
+)
+
+var errNotIndexable = errors.New("value can't be looked up in an index")
+
+// eventCursor iterates over event keys and values in key order.
+// *lm2.Cursor implements it for full scans.
+type eventCursor interface {
+	Next() bool
+	Key() string
+	Value() string
+	Err() error
+}
+
+// seekCursor is an eventCursor that can be moved back to an earlier
+// key. Cursors keep reading the snapshot they were created with.
+type seekCursor interface {
+	eventCursor
+	Seek(key string)
+}
+
+// indexPrefix returns the key prefix of index entries for events
+// whose field has the given value.
+func indexPrefix(field string, value interface{}) (string, error) {
+	text, ok := indexValue(value)
+	if !ok {
+		return "", errNotIndexable
+	}
+	return string(indexKeyPrefix) + field + "\x00" + text + "\x00", nil
+}
+
+// indexValue returns the text of a value in index keys. Values that
+// equality filters coerce to the same value, like 22 and "22" or two
+// spellings of an IP, have the same text. Some values that aren't
+// equal do too, so filters are applied to the events of an index.
+func indexValue(value interface{}) (string, bool) {
+	v := inferValue(value)
+	if f, ok := v.coerce(TypeFloat); ok && v.typ == TypeString {
+		v = f
+	}
+	switch v.typ {
+	case TypeBool:
+		return "b" + strconv.FormatBool(v.b), true
+	case TypeInt:
+		return "n" + formatIndexNumber(float64(v.i)), true
+	case TypeFloat:
+		return "n" + formatIndexNumber(v.f), true
+	case TypeString:
+		return "s" + v.s, true
+	case TypeIP:
+		return "a" + v.ip.To16().String(), true
+	case TypeTimestamp:
+		return "t" + v.t.UTC().Format(time.RFC3339Nano), true
+	case TypeDuration:
+		return "d" + strconv.FormatInt(v.i, 10), true
+	}
+	return "", false
+}
+
+// indexedValue returns true if the events equal to value are all in
+// its index entries. Booleans are also equal to strings like "1" that
+// are indexed as numbers, so they and the strings aren't.
+func indexedValue(value interface{}) bool {
+	v := inferValue(value)
+	if v.typ == TypeString {
+		_, err := strconv.ParseBool(v.s)
+		return err != nil
+	}
+	return v.typ != TypeBool && v.typ != TypeNull
+}
+
+func formatIndexNumber(f float64) string {
+	if f == 0 {
+		// -0 equals 0.
+		f = 0
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// indexEntries returns the index keys for an event stored at eventKey.
+func indexEntries(fields []string, event Event, eventKey string) []string {
+	keys := []string{}
+	for _, field := range fields {
+		value, ok := event.Lookup(field)
+		if !ok || value == nil {
+			continue
+		}
+		prefix, err := indexPrefix(field, value)
+		if err != nil {
+			// Events with these values are found by scanning.
+			continue
+		}
+		keys = append(keys, prefix+eventKey)
+	}
+	return keys
+}
+
+// splitIndexKey returns the field and event key of an index key.
+func splitIndexKey(key string) (string, string, bool) {
+	if len(key) < 1 || key[0] != indexKeyPrefix {
+		return "", "", false
+	}
+	parts := strings.SplitN(key[1:], "\x00", 3)
+	if len(parts) != 3 {
+		return "", "", false
+	}
+	return parts[0], parts[2], true
+}
+
+// SetIndexes sets the fields that are indexed. Indexes that don't
+// exist yet are built from the stored events.
+func (c *EventCollection) SetIndexes(fields []string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.indexes = fields
+
+	for _, field := range fields {
+		cur, err := c.col.NewCursor()
+		if err != nil {
+			return err
+		}
+		_, err = cur.Get(indexBuiltKeyPrefix + field)
+		if err == nil {
+			continue
+		}
+		if err != lm2.ErrKeyNotFound {
+			return err
+		}
+		err = c.buildIndex(field)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *EventCollection) isIndexed(field string) bool {
+	for _, f := range c.indexes {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *EventCollection) buildIndex(field string) error {
+	cur, err := c.col.NewCursor()
+	if err != nil {
+		return err
+	}
+
+	const batchSize = 1000
+	wb := lm2.NewWriteBatch()
+	pending := 0
+	cur.Seek(string(eventKeyPrefix))
+	for cur.Next() {
+		if cur.Key()[0] != eventKeyPrefix {
+			if cur.Key()[0] > eventKeyPrefix {
+				break
+			}
+			continue
+		}
+		event := Event{}
+		err = json.Unmarshal([]byte(cur.Value()), &event)
+		if err != nil {
+			return err
+		}
+		for _, key := range indexEntries([]string{field}, event, cur.Key()) {
+			wb.Set(key, "")
+			pending++
+		}
+		if pending >= batchSize {
+			_, err = c.col.Update(wb)
+			if err != nil {
+				return err
+			}
+			wb = lm2.NewWriteBatch()
+			pending = 0
+		}
+	}
+	if err = cur.Err(); err != nil {
+		return err
+	}
+	wb.Set(indexBuiltKeyPrefix+field, "")
+	_, err = c.col.Update(wb)
+	return err
+}
+
+// indexCursor iterates over the events referenced by an index,
+// in event key order.
+type indexCursor struct {
+	cur    *lm2.Cursor
+	lookup *lm2.Cursor
+	prefix string
+	start  string
+	key    string
+	value  string
+	err    error
+}
+
+// newIndexCursor returns a cursor over events whose field equals value,
+// starting at the event key start.
+func (c *EventCollection) newIndexCursor(field string, value interface{}, start string) (*indexCursor, error) {
+	prefix, err := indexPrefix(field, value)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := c.col.NewCursor()
+	if err != nil {
+		return nil, err
+	}
+	lookup, err := c.col.NewCursor()
+	if err != nil {
+		return nil, err
+	}
+	ic := &indexCursor{
+		cur:    cur,
+		lookup: lookup,
+		prefix: prefix,
+	}
+	ic.Seek(start)
+	return ic, nil
+}
+
+// Seek positions the cursor before the first event from the event key
+// start on. The cursor keeps reading the same snapshot.
+func (ic *indexCursor) Seek(start string) {
+	ic.start = ic.prefix + start
+	ic.cur.Seek(ic.start)
+}
+
+func (ic *indexCursor) Next() bool {
+	for ic.cur.Next() {
+		key := ic.cur.Key()
+		if key < ic.start {
+			continue
+		}
+		if !strings.HasPrefix(key, ic.prefix) {
+			break
+		}
+		eventKey := key[len(ic.prefix):]
+		value, err := ic.lookup.Get(eventKey)
+		if err != nil {
+			if err == lm2.ErrKeyNotFound {
+				// Stale index entry.
+				continue
+			}
+			ic.err = err
+			break
+		}
+		ic.key, ic.value = eventKey, value
+		return true
+	}
+	if ic.err == nil {
+		ic.err = ic.cur.Err()
+	}
+	ic.key, ic.value = "", ""
+	return false
+}
+
+func (ic *indexCursor) Key() string {
+	return ic.key
+}
+
+func (ic *indexCursor) Value() string {
+	return ic.value
+}
+
+func (ic *indexCursor) Err() error {
+	return ic.err
+}
@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"hash/crc32"
 	"log"
-	"path/filepath"
+	"sync/atomic"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -16,11 +16,37 @@ func hashMessage(message string) string {
 	return fmt.Sprintf("%x", crc32.ChecksumIEEE([]byte(message)))
 }
 
-func captureJSONLogs(groupName string, retention int, done chan struct{}) error {
-	if retention == 0 {
-		retention = 7
-		log.Printf("Missing retention for %s; defaulting to %d days", groupName, retention)
+// jsonLogConverter returns the converter of a JSON log group with the
+// checkpoint state s.
+func jsonLogConverter(s *LogState) logEventConverter {
+	messageHashUntil := s.messageHashUntil()
+	return func(e *cloudwatchlogs.FilteredLogEvent) (Event, error) {
+		return jsonLogEvent(e, messageHashUntil)
 	}
+}
+
+// jsonLogEvent converts a CloudWatch Logs event with a JSON object message.
+// The CloudWatch event ID identifies the event. Events up to
+// messageHashUntil keep the hash of their message they were stored
+// with, so they aren't stored again when they're fetched again.
+func jsonLogEvent(e *cloudwatchlogs.FilteredLogEvent, messageHashUntil int64) (Event, error) {
+	event := Event{}
+	err := json.Unmarshal([]byte(*e.Message), &event)
+	if err != nil {
+		return nil, err
+	}
+	timestamp := time.Unix(*e.Timestamp/1000, (*e.Timestamp%1000)*1000000)
+	event["_ts"] = timestamp.Format(time.RFC3339Nano)
+	event["_tag"] = *e.LogStreamName
+	if *e.Timestamp <= messageHashUntil {
+		event["_hash"] = hashMessage(*e.Message)
+	} else if e.EventId != nil {
+		event["_hash"] = *e.EventId
+	}
+	return event, nil
+}
+
+func captureJSONLogs(groupName string, store EventStore, done chan struct{}) error {
 	stop := make(chan struct{}, 1)
 
 	go func() {
@@ -33,25 +59,7 @@ func captureJSONLogs(groupName string, retention int, done chan struct{}) error
 		return err
 	}
 
-	collectionsLock.Lock()
-	eventCollection := Collections[groupName]
-	if eventCollection == nil {
-		eventCollection, err = OpenEventCollection(filepath.Join(DataDir, groupName+".lm2"))
-		if err != nil {
-			if err == ErrDoesNotExist {
-				eventCollection, err = CreateEventCollection(filepath.Join(DataDir, groupName+".lm2"))
-			}
-			if err != nil {
-				collectionsLock.Unlock()
-				return err
-			}
-		}
-		Collections[groupName] = eventCollection
-	}
-	collectionsLock.Unlock()
-
-	eventCollection.SetRetention(retention)
-
+	convert := jsonLogConverter(cwl.logState)
 	nextBatchStart := cwl.LastTimestamp()
 	currentBatch := []Event{}
 	timer := time.NewTimer(0)
@@ -73,24 +81,22 @@ func captureJSONLogs(groupName string, retention int, done chan struct{}) error
 		case <-timer.C:
 		case <-stop:
 			log.Println("Stopping poll of JSON log group", groupName)
-			eventCollection.col.Close()
 			return nil
 		}
 
+		if atomic.LoadInt32(&lowDisk) == 1 {
+			// Don't fetch anything until there's room to store it.
+			continue
+		}
+
 		logEvents, err := cwl.GetLogEvents(nextBatchStart)
 		if err != nil {
 			return err
 		}
 
 		for _, e := range logEvents {
-			event := Event{}
-			err = json.Unmarshal([]byte(*e.Message), &event)
+			event, err := convert(e)
 			if err == nil {
-				timestamp := time.Unix(*e.Timestamp/1000, (*e.Timestamp%1000)*1000000)
-				event["_ts"] = timestamp.Format(time.RFC3339Nano)
-				event["_tag"] = *e.LogStreamName
-				event["_hash"] = hashMessage(*e.Message)
-
 				currentBatch = append(currentBatch, event)
 
 				if nextBatchStart < *e.Timestamp {
@@ -101,12 +107,19 @@ func captureJSONLogs(groupName string, retention int, done chan struct{}) error
 
 		events := currentBatch
 		if len(logEvents) > 0 {
-			log.Printf("Logs group %s: aggregated %d events", groupName, len(logEvents))
-			err = eventCollection.StoreEvents(events)
+			stored, err := store.StoreNewEvents(events)
+			if err == ErrLowDisk {
+				// See captureFlowLogs.
+				log.Printf("Logs group %s: pausing, %v", groupName, err)
+				nextBatchStart = cwl.LastTimestamp()
+				currentBatch = currentBatch[:0]
+				continue
+			}
 			if err != nil {
 				return err
 			}
-			nextBatchStart += 1
+			log.Printf("Logs group %s: aggregated %d events, %d new", groupName, len(logEvents), stored)
+			// See captureFlowLogs.
 			err = cwl.SetLastTimestamp(nextBatchStart)
 			if err != nil {
 				return err
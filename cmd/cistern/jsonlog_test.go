@@ -0,0 +1,108 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+func TestJSONLogUpgrade(t *testing.T) {
+	dataDir, err := ioutil.TempDir("", "cistern-jsonlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataDir)
+	defer func(dir string) { DataDir = dir }(DataDir)
+	DataDir = dataDir
+
+	// The checkpoint of a group captured before event IDs were used.
+	const checkpoint = 1500182400000
+	err = ioutil.WriteFile(sourceStateFile("app"), []byte(`{"last_timestamp":1500182400000}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := NewLogState(sourceStateFile("app"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The boundary stays put as the checkpoint moves.
+	state.LastTimestamp = checkpoint + 1000
+	err = state.Store()
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err = NewLogState(sourceStateFile("app"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if until := state.messageHashUntil(); until != checkpoint {
+		t.Fatalf("expected message hashes until %d but got %d", checkpoint, until)
+	}
+
+	logEvents := []*cloudwatchlogs.FilteredLogEvent{}
+	for i, ts := range []int64{checkpoint, checkpoint + 1} {
+		logEvents = append(logEvents, &cloudwatchlogs.FilteredLogEvent{
+			EventId:       aws.String("event" + strconv.Itoa(i)),
+			LogStreamName: aws.String("stream"),
+			Message:       aws.String(`{"status": 200}`),
+			Timestamp:     aws.Int64(ts),
+		})
+	}
+
+	ec, err := CreateEventCollection(filepath.Join(dataDir, "app.lm2"), defaultCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ec.Close()
+	// The event at the checkpoint was stored with a hash of its message.
+	stored, err := jsonLogEvent(logEvents[0], checkpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored["_hash"] != hashMessage(*logEvents[0].Message) {
+		t.Fatalf("expected a message hash but got %v", stored["_hash"])
+	}
+	err = ec.StoreEvents([]Event{stored})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Fetching from the checkpoint again only stores the new event.
+	convert := jsonLogConverter(state)
+	events := []Event{}
+	for _, e := range logEvents {
+		event, err := convert(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event)
+	}
+	if events[1]["_hash"] != *logEvents[1].EventId {
+		t.Errorf("expected the event ID as the hash but got %v", events[1]["_hash"])
+	}
+	n, err := ec.StoreNewEvents(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 || ec.EventCount() != 2 {
+		t.Errorf("expected 1 new event and 2 in total but got %d and %d", n, ec.EventCount())
+	}
+
+	// New groups use event IDs from the start.
+	state, err = NewLogState(sourceStateFile("new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	event, err := jsonLogConverter(state)(logEvents[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event["_hash"] != *logEvents[0].EventId {
+		t.Errorf("expected the event ID as the hash but got %v", event["_hash"])
+	}
+}
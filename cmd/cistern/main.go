@@ -22,6 +22,12 @@ var (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			os.Exit(command(os.Args[2:]))
+		}
+	}
+
 	configFilePath := flag.String("config", "./cistern.json", "Path to config file")
 	apiAddr := flag.String("api-addr", "localhost:2020", "API listen address")
 	uiContentPath := flag.String("ui-content", "", "Path to static UI content (enables UI)")
@@ -29,6 +35,7 @@ func main() {
 	flag.Parse()
 
 	log.Printf("Cistern v%s starting", version)
+	ReplicationNodeID = *apiAddr
 
 	configFileData, err := ioutil.ReadFile(*configFilePath)
 	if err != nil {
@@ -50,22 +57,101 @@ func main() {
 		close(done)
 	}()
 
+	if config.MinFreeDisk == 0 {
+		config.MinFreeDisk = defaultMinFreeDisk
+	}
+	go monitorDisk(config.MinFreeDisk, done)
+	go enforceLimits(done)
+
+	if config.Cluster != nil {
+		ClusterNode, err = NewCluster(*config.Cluster)
+		if err != nil {
+			log.Fatal("Invalid cluster config:", err)
+		}
+		log.Printf("Running as cluster node %s", config.Cluster.Node)
+	}
+
+	notOwned := []string{} // files of shards of other nodes
+	for _, collectionConfig := range config.CollectionConfigs() {
+		if ClusterNode != nil && !ClusterNode.OwnsShard(collectionConfig.Name) {
+			notOwned = append(notOwned, collectionConfig.File)
+			continue
+		}
+		collection, err := OpenOrCreateEventCollection(collectionConfig.File, collectionConfig.CacheSize)
+		if err != nil {
+			log.Fatalf("Couldn't open collection %s: %v", collectionConfig.Name, err)
+		}
+		collection.SetRetention(collectionConfig.Retention)
+		collection.SetFlatten(collectionConfig.Flatten)
+		collection.SetMaxBytes(collectionConfig.MaxBytes)
+		collection.SetMaxEvents(collectionConfig.MaxEvents)
+		err = collection.SetIndexes(collectionConfig.Indexes)
+		if err != nil {
+			log.Fatalf("Couldn't build indexes for collection %s: %v", collectionConfig.Name, err)
+		}
+		if collectionConfig.Archive != nil {
+			store, err := NewArchiveStore(*collectionConfig.Archive)
+			if err != nil {
+				log.Fatalf("Invalid archive for collection %s: %v", collectionConfig.Name, err)
+			}
+			if collectionConfig.Archive.After >= collectionConfig.Retention {
+				// Events would be evicted before they're archived.
+				log.Fatalf("Invalid archive for collection %s: events must be archived after fewer days than the %d day retention",
+					collectionConfig.Name, collectionConfig.Retention)
+			}
+			err = collection.SetArchive(store, collectionConfig.Archive.After, collectionConfig.Name+"/")
+			if err != nil {
+				log.Fatalf("Couldn't load archive catalog of collection %s: %v", collectionConfig.Name, err)
+			}
+		}
+		if collectionConfig.Leader != "" {
+			collection.Follow(collectionConfig.Name, collectionConfig.Leader)
+		}
+		Collections[collectionConfig.Name] = collection
+	}
+
+	if config.Retention > 0 {
+		DefaultRetention = config.Retention
+	}
+	err = openDataDirCollections(notOwned)
+	if err != nil {
+		log.Fatal("Couldn't open collections in the data directory:", err)
+	}
+	go archiveCollections(done)
+
 	for _, group := range config.CloudWatchLogs {
+		capture := captureJSONLogs
+		newConverter := jsonLogConverter
 		if group.FlowLog {
-			go func(group ConfigCloudWatchLogGroup) {
-				err := captureFlowLogs(group.Name, config.Retention, done)
-				if err != nil {
-					log.Fatal(err)
-				}
-			}(group)
-		} else {
-			go func(group ConfigCloudWatchLogGroup) {
-				err := captureJSONLogs(group.Name, config.Retention, done)
-				if err != nil {
-					log.Fatal(err)
-				}
-			}(group)
+			capture = captureFlowLogs
+			newConverter = flowLogConverter
+		}
+		registerLogSource(group.Name, newConverter)
+
+		if ClusterNode != nil && !ClusterNode.CapturesLogGroup(group.CollectionName(), group.Name) {
+			continue
+		}
+		var store EventStore
+		if ClusterNode != nil && ClusterNode.IsSharded(group.CollectionName()) {
+			store = shardStore{cluster: ClusterNode, collection: group.CollectionName()}
 		}
+		collection := Collections[group.CollectionName()]
+		if collection != nil && store == nil {
+			store = collection
+		}
+		go func(group ConfigCloudWatchLogGroup) {
+			if collection != nil {
+				// Followers start capturing once they're promoted.
+				if !collection.WaitLeader(done) {
+					return
+				}
+				collection.AttachSource(group.Name)
+			}
+			err := capture(group.Name, store, done)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}(group)
 	}
 
 	if *uiContentPath != "" {
@@ -92,5 +178,10 @@ func main() {
 	<-done
 	log.Println("Waiting for things to get cleaned up...")
 	time.Sleep(250 * time.Millisecond)
+	collectionsLock.Lock()
+	for _, collection := range Collections {
+		collection.Close()
+	}
+	collectionsLock.Unlock()
 	log.Println("Exiting.")
 }
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// A minimal Parquet writer for exports. Every column is optional and
+// written as a single uncompressed, PLAIN-encoded data page per row group.
+// Numbers are DOUBLE, booleans BOOLEAN, and everything else UTF-8
+// BYTE_ARRAY. _ts is an INT64 microsecond timestamp.
+
+const (
+	parquetMagic        = "PAR1"
+	parquetRowGroupSize = 10000
+
+	// Physical types
+	parquetBoolean   = 0
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	// Converted types
+	parquetUTF8            = 0
+	parquetTimestampMicros = 10
+
+	parquetOptional = 1
+
+	parquetEncodingPlain = 0
+	parquetEncodingRLE   = 3
+
+	parquetDataPage = 0
+)
+
+type parquetColumn struct {
+	exportColumn
+	physicalType int32
+	defLevels    []bool
+	values       bytes.Buffer
+	bools        []bool
+}
+
+type parquetRowGroup struct {
+	columns   []parquetChunk
+	totalSize int64
+	numRows   int64
+}
+
+type parquetChunk struct {
+	offset int64
+	size   int64
+	values int64
+}
+
+type parquetWriter struct {
+	w         io.Writer
+	offset    int64
+	columns   []*parquetColumn
+	rows      int64
+	numRows   int64
+	rowGroups []parquetRowGroup
+}
+
+func newParquetWriter(w io.Writer, columns []exportColumn) (*parquetWriter, error) {
+	pw := &parquetWriter{w: w}
+	for _, column := range columns {
+		pc := &parquetColumn{exportColumn: column}
+		switch {
+		case column.name == "_ts":
+			pc.physicalType = parquetInt64
+		case column.kind == kindNumber:
+			pc.physicalType = parquetDouble
+		case column.kind == kindBool:
+			pc.physicalType = parquetBoolean
+		default:
+			pc.physicalType = parquetByteArray
+		}
+		pw.columns = append(pw.columns, pc)
+	}
+	return pw, pw.write([]byte(parquetMagic))
+}
+
+func (pw *parquetWriter) write(b []byte) error {
+	n, err := pw.w.Write(b)
+	pw.offset += int64(n)
+	return err
+}
+
+// Write adds an event to the current row group. Values that don't match
+// the column type are written as nulls.
+func (pw *parquetWriter) Write(event Event) error {
+	for _, pc := range pw.columns {
+		present := true
+		switch pc.physicalType {
+		case parquetInt64:
+			ts, ok := event[pc.name].(int64)
+			present = ok
+			if ok {
+				binary.Write(&pc.values, binary.LittleEndian, ts)
+			}
+		case parquetDouble:
+			f, ok := event[pc.name].(float64)
+			present = ok
+			if ok {
+				binary.Write(&pc.values, binary.LittleEndian, math.Float64bits(f))
+			}
+		case parquetBoolean:
+			b, ok := event[pc.name].(bool)
+			present = ok
+			if ok {
+				pc.bools = append(pc.bools, b)
+			}
+		default:
+			value := event[pc.name]
+			present = value != nil
+			if present {
+				s := stringValue(value)
+				binary.Write(&pc.values, binary.LittleEndian, uint32(len(s)))
+				pc.values.WriteString(s)
+			}
+		}
+		pc.defLevels = append(pc.defLevels, present)
+	}
+	pw.rows++
+	if pw.rows == parquetRowGroupSize {
+		return pw.flush()
+	}
+	return nil
+}
+
+// flush writes the current row group.
+func (pw *parquetWriter) flush() error {
+	if pw.rows == 0 {
+		return nil
+	}
+	rowGroup := parquetRowGroup{numRows: pw.rows}
+	for _, pc := range pw.columns {
+		page := bytes.Buffer{}
+		levels := encodeLevels(pc.defLevels)
+		binary.Write(&page, binary.LittleEndian, uint32(len(levels)))
+		page.Write(levels)
+		if pc.physicalType == parquetBoolean {
+			page.Write(packBools(pc.bools))
+		} else {
+			page.Write(pc.values.Bytes())
+		}
+
+		header := thriftWriter{}
+		header.i32(1, parquetDataPage)
+		header.i32(2, int32(page.Len()))
+		header.i32(3, int32(page.Len()))
+		header.beginStruct(5)
+		header.i32(1, int32(len(pc.defLevels)))
+		header.i32(2, parquetEncodingPlain)
+		header.i32(3, parquetEncodingRLE)
+		header.i32(4, parquetEncodingRLE)
+		header.endStruct()
+		header.stop()
+
+		chunk := parquetChunk{
+			offset: pw.offset,
+			size:   int64(header.buf.Len() + page.Len()),
+			values: int64(len(pc.defLevels)),
+		}
+		err := pw.write(header.buf.Bytes())
+		if err != nil {
+			return err
+		}
+		err = pw.write(page.Bytes())
+		if err != nil {
+			return err
+		}
+		rowGroup.columns = append(rowGroup.columns, chunk)
+		rowGroup.totalSize += chunk.size
+
+		pc.defLevels = pc.defLevels[:0]
+		pc.bools = pc.bools[:0]
+		pc.values.Reset()
+	}
+	pw.rowGroups = append(pw.rowGroups, rowGroup)
+	pw.numRows += pw.rows
+	pw.rows = 0
+	return nil
+}
+
+// Close writes the last row group and the file footer.
+func (pw *parquetWriter) Close() error {
+	err := pw.flush()
+	if err != nil {
+		return err
+	}
+
+	meta := thriftWriter{}
+	meta.i32(1, 1)
+	meta.beginList(2, thriftStruct, len(pw.columns)+1)
+	meta.beginElem()
+	meta.binary(4, "schema")
+	meta.i32(5, int32(len(pw.columns)))
+	meta.endElem()
+	for _, pc := range pw.columns {
+		meta.beginElem()
+		meta.i32(1, pc.physicalType)
+		meta.i32(3, parquetOptional)
+		meta.binary(4, pc.name)
+		switch pc.physicalType {
+		case parquetInt64:
+			meta.i32(6, parquetTimestampMicros)
+		case parquetByteArray:
+			meta.i32(6, parquetUTF8)
+		}
+		meta.endElem()
+	}
+	meta.i64(3, pw.numRows)
+	meta.beginList(4, thriftStruct, len(pw.rowGroups))
+	for _, rowGroup := range pw.rowGroups {
+		meta.beginElem()
+		meta.beginList(1, thriftStruct, len(rowGroup.columns))
+		for i, chunk := range rowGroup.columns {
+			pc := pw.columns[i]
+			meta.beginElem()
+			meta.i64(2, chunk.offset)
+			meta.beginStruct(3)
+			meta.i32(1, pc.physicalType)
+			meta.beginList(2, thriftI32, 2)
+			meta.varint(parquetEncodingPlain)
+			meta.varint(parquetEncodingRLE)
+			meta.beginList(3, thriftBinary, 1)
+			meta.rawBinary(pc.name)
+			meta.i32(4, 0) // uncompressed
+			meta.i64(5, chunk.values)
+			meta.i64(6, chunk.size)
+			meta.i64(7, chunk.size)
+			meta.i64(9, chunk.offset)
+			meta.endStruct()
+			meta.endElem()
+		}
+		meta.i64(2, rowGroup.totalSize)
+		meta.i64(3, rowGroup.numRows)
+		meta.endElem()
+	}
+	meta.binary(6, "cistern")
+	meta.stop()
+
+	err = pw.write(meta.buf.Bytes())
+	if err != nil {
+		return err
+	}
+	footer := make([]byte, 4, 8)
+	binary.LittleEndian.PutUint32(footer, uint32(meta.buf.Len()))
+	return pw.write(append(footer, parquetMagic...))
+}
+
+// encodeLevels encodes definition levels with a bit width of 1 using
+// RLE runs of the RLE/bit-packing hybrid encoding.
+func encodeLevels(levels []bool) []byte {
+	buf := []byte{}
+	for i := 0; i < len(levels); {
+		j := i
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		buf = appendUvarint(buf, uint64(j-i)<<1)
+		if levels[i] {
+			buf = append(buf, 1)
+		} else {
+			buf = append(buf, 0)
+		}
+		i = j
+	}
+	return buf
+}
+
+// packBools packs booleans into bits, least significant bit first.
+func packBools(values []bool) []byte {
+	buf := make([]byte, (len(values)+7)/8)
+	for i, v := range values {
+		if v {
+			buf[i/8] |= 1 << uint(i%8)
+		}
+	}
+	return buf
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	return append(buf, tmp[:n]...)
+}
+
+// Thrift compact protocol types
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter writes structs in the Thrift compact protocol.
+type thriftWriter struct {
+	buf     bytes.Buffer
+	lastID  int16
+	idStack []int16
+}
+
+func (t *thriftWriter) fieldHeader(id int16, typ byte) {
+	if delta := id - t.lastID; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.varint(int64(id))
+	}
+	t.lastID = id
+}
+
+func (t *thriftWriter) varint(v int64) {
+	t.buf.Write(appendUvarint(nil, uint64((v<<1)^(v>>63))))
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.fieldHeader(id, thriftI32)
+	t.varint(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.fieldHeader(id, thriftI64)
+	t.varint(v)
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.fieldHeader(id, thriftBinary)
+	t.rawBinary(s)
+}
+
+func (t *thriftWriter) rawBinary(s string) {
+	t.buf.Write(appendUvarint(nil, uint64(len(s))))
+	t.buf.WriteString(s)
+}
+
+func (t *thriftWriter) beginList(id int16, elemType byte, size int) {
+	t.fieldHeader(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elemType)
+	} else {
+		t.buf.WriteByte(0xf0 | elemType)
+		t.buf.Write(appendUvarint(nil, uint64(size)))
+	}
+}
+
+// beginStruct starts a struct field.
+func (t *thriftWriter) beginStruct(id int16) {
+	t.fieldHeader(id, thriftStruct)
+	t.beginElem()
+}
+
+func (t *thriftWriter) endStruct() {
+	t.endElem()
+}
+
+// beginElem starts a struct list element.
+func (t *thriftWriter) beginElem() {
+	t.idStack = append(t.idStack, t.lastID)
+	t.lastID = 0
+}
+
+func (t *thriftWriter) endElem() {
+	t.stop()
+	t.lastID = t.idStack[len(t.idStack)-1]
+	t.idStack = t.idStack[:len(t.idStack)-1]
+}
+
+func (t *thriftWriter) stop() {
+	t.buf.WriteByte(0)
+}
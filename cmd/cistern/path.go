@@ -0,0 +1,83 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Lookup returns the value of a field. Fields of nested objects and
+// elements of arrays are addressed with dotted paths and indexes,
+// like userIdentity.arn or resources[0].ARN. A top-level field named
+// like the path, such as one created by flattening, takes precedence.
+func (e Event) Lookup(path string) (interface{}, bool) {
+	if value, ok := e[path]; ok {
+		return value, true
+	}
+	if !strings.ContainsAny(path, ".[") {
+		return nil, false
+	}
+
+	var value interface{} = map[string]interface{}(e)
+	for len(path) > 0 {
+		var name string
+		switch path[0] {
+		case '.':
+			path = path[1:]
+			continue
+		case '[':
+			end := strings.IndexByte(path, ']')
+			if end < 0 {
+				return nil, false
+			}
+			index, err := strconv.Atoi(path[1:end])
+			path = path[end+1:]
+			array, ok := value.([]interface{})
+			if err != nil || !ok || index < 0 || index >= len(array) {
+				return nil, false
+			}
+			value = array[index]
+			continue
+		}
+
+		end := strings.IndexAny(path, ".[")
+		if end < 0 {
+			end = len(path)
+		}
+		name, path = path[:end], path[end:]
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		value, ok = object[name]
+		if !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// flattenEvent returns the event with nested objects and arrays
+// replaced by top-level fields named by their paths. Empty objects
+// and arrays are dropped.
+func flattenEvent(event Event) Event {
+	flat := Event{}
+	for name, value := range event {
+		flattenValue(flat, name, value)
+	}
+	return flat
+}
+
+func flattenValue(flat Event, path string, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for name, fieldValue := range v {
+			flattenValue(flat, path+"."+name, fieldValue)
+		}
+	case []interface{}:
+		for i, element := range v {
+			flattenValue(flat, path+"["+strconv.Itoa(i)+"]", element)
+		}
+	default:
+		flat[path] = value
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/Cistern/cistern/internal/query"
+)
+
+var nestedEvent = `{
+	"eventName": "GetObject",
+	"userIdentity": {"type": "IAMUser", "arn": "arn:aws:iam::123:user/alice"},
+	"requestParameters": {"bucketName": "logs", "key": "a.gz"},
+	"resources": [{"ARN": "arn:aws:s3:::logs"}, {"ARN": "arn:aws:s3:::logs/a.gz", "size": 1200}],
+	"user.name": "flat"
+}`
+
+func TestLookup(t *testing.T) {
+	event := Event{}
+	err := json.Unmarshal([]byte(nestedEvent), &event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		path     string
+		expected interface{}
+	}{
+		{"eventName", "GetObject"},
+		{"userIdentity.arn", "arn:aws:iam::123:user/alice"},
+		{"resources[1].ARN", "arn:aws:s3:::logs/a.gz"},
+		{"resources[1].size", 1200.0},
+		{"user.name", "flat"},
+		{"userIdentity.missing", nil},
+		{"resources[2].ARN", nil},
+		{"eventName.length", nil},
+	}
+	for _, c := range testCases {
+		value, ok := event.Lookup(c.path)
+		if ok != (c.expected != nil) || value != c.expected {
+			t.Errorf("%s: expected %v but got %v (%v)", c.path, c.expected, value, ok)
+		}
+	}
+
+	flat := flattenEvent(event)
+	for _, c := range testCases {
+		if c.expected == nil {
+			continue
+		}
+		if flat[c.path] != c.expected {
+			t.Errorf("%s: expected flattened %v but got %v", c.path, c.expected, flat[c.path])
+		}
+	}
+	if _, ok := flat["resources"]; ok {
+		t.Error("expected arrays to be flattened")
+	}
+}
+
+func TestNestedQuery(t *testing.T) {
+	for _, flatten := range []bool{false, true} {
+		ec, err := CreateEventCollection("/tmp/test_cistern_nested.lm2", defaultCacheSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ec.SetFlatten(flatten)
+
+		events := []Event{}
+		for i, bucket := range []string{"logs", "logs", "backups"} {
+			event := Event{}
+			err = json.Unmarshal([]byte(nestedEvent), &event)
+			if err != nil {
+				t.Fatal(err)
+			}
+			event["_tag"] = "trail"
+			event["_ts"] = fmt.Sprintf("2017-08-01T03:2%d:00Z", i)
+			event["requestParameters"].(map[string]interface{})["bucketName"] = bucket
+			events = append(events, event)
+		}
+		err = ec.StoreEvents(events)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		desc, err := query.Parse(`SELECT sum(resources[1].size) GROUP BY requestParameters.bucketName FILTER userIdentity.type = "IAMUser"`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = decodeFilterValues(desc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := ec.Query(*desc)
+		ec.col.Destroy()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sums := map[interface{}]interface{}{}
+		for _, event := range result.Summary {
+			sums[event["requestParameters.bucketName"]] = event["sum(resources[1].size)"]
+		}
+		if sums["logs"] != 2400.0 || sums["backups"] != 1200.0 {
+			t.Errorf("flatten=%v: expected sums per bucket but got %v", flatten, result.Summary)
+		}
+	}
+}
@@ -1,18 +1,19 @@
 package main
 
 import (
+	"container/heap"
 	"crypto/md5"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
-	"math"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Cistern/cistern/internal/query"
+	"github.com/Preetam/lm2"
 )
 
 type QueryResult struct {
@@ -20,6 +21,12 @@ type QueryResult struct {
 	Series  []Event     `json:"series,omitempty"`
 	Events  []Event     `json:"events,omitempty"`
 	Query   interface{} `json:"query"`
+	// Continuation continues a raw event query with a limit after
+	// the last of its events.
+	Continuation string `json:"continuation,omitempty"`
+	// Plan is set instead of results for EXPLAIN queries.
+	Plan  *QueryExplain `json:"plan,omitempty"`
+	Stats *QueryStats   `json:"stats"`
 }
 
 type ByTimestamp []Event
@@ -30,48 +37,417 @@ func (t ByTimestamp) Less(i, j int) bool {
 	return t[i]["_ts"].(time.Time).Before(t[j]["_ts"].(time.Time))
 }
 
+// OrderBy sorts events by the values of the ORDER BY columns. Equal
+// events keep the order they were added in.
 type OrderBy struct {
-	columns []string
-	events  []Event
+	columns []query.ColumnDesc
+	rows    []orderRow
+	seq     int
 }
 
-func (o OrderBy) Len() int      { return len(o.events) }
-func (o OrderBy) Swap(i, j int) { o.events[i], o.events[j] = o.events[j], o.events[i] }
-func (o OrderBy) Less(i, j int) bool {
-	for _, col := range o.columns {
-		if compareInterfaces(o.events[i][col], o.events[j][col]) >= 0 {
-			return false
+type orderRow struct {
+	event  Event
+	values []interface{}
+	seq    int
+}
+
+// add adds an event with the values of its ORDER BY columns.
+func (o *OrderBy) add(event Event, values []interface{}) {
+	o.rows = append(o.rows, o.row(event, values))
+}
+
+func (o *OrderBy) row(event Event, values []interface{}) orderRow {
+	o.seq++
+	return orderRow{event: event, values: values, seq: o.seq}
+}
+
+func (o *OrderBy) Len() int           { return len(o.rows) }
+func (o *OrderBy) Swap(i, j int)      { o.rows[i], o.rows[j] = o.rows[j], o.rows[i] }
+func (o *OrderBy) Less(i, j int) bool { return o.less(o.rows[i], o.rows[j]) }
+
+func (o *OrderBy) less(a, b orderRow) bool {
+	for k, column := range o.columns {
+		c := compareInterfaces(a.values[k], b.values[k])
+		if column.Descending {
+			c = -c
+		}
+		if c != 0 {
+			return c < 0
 		}
 	}
-	return true
+	return a.seq < b.seq
+}
+
+// sorted returns the events in order.
+func (o *OrderBy) sorted() []Event {
+	sort.Sort(o)
+	events := make([]Event, len(o.rows))
+	for i, row := range o.rows {
+		events[i] = row.event
+	}
+	return events
+}
+
+// topEvents keeps the first n events in the order of an OrderBy. The
+// rows are a heap with the last of them at the root.
+type topEvents struct {
+	OrderBy
+	n int
+}
+
+func (t *topEvents) Less(i, j int) bool { return t.OrderBy.Less(j, i) }
+func (t *topEvents) Push(x interface{}) { t.rows = append(t.rows, x.(orderRow)) }
+func (t *topEvents) Pop() interface{} {
+	last := t.rows[len(t.rows)-1]
+	t.rows = t.rows[:len(t.rows)-1]
+	return last
+}
+
+// add adds an event if it's among the first n so far.
+func (t *topEvents) add(event Event, values []interface{}) {
+	row := t.row(event, values)
+	switch {
+	case len(t.rows) < t.n:
+		heap.Push(t, row)
+	case t.less(row, t.rows[0]):
+		t.rows[0] = row
+		heap.Fix(t, 0)
+	}
+}
+
+// orderValues returns the values of the ORDER BY columns of desc for
+// an event. Columns that aren't fields of the event are evaluated.
+func orderValues(desc query.Desc, event Event) []interface{} {
+	values := make([]interface{}, len(desc.OrderBy))
+	for i, column := range desc.OrderBy {
+		if value, ok := event[columnName(column)]; ok {
+			values[i] = value
+			continue
+		}
+		values[i] = evalExpr(columnExpr(column), event, nil)
+	}
+	return values
+}
+
+// orderEvents sorts raw events by the ORDER BY columns of desc and
+// applies its limit.
+func orderEvents(desc query.Desc, events []Event) []Event {
+	ordered := &OrderBy{columns: desc.OrderBy}
+	for _, event := range events {
+		ordered.add(event, orderValues(desc, event))
+	}
+	events = ordered.sorted()
+	if desc.Limit > 0 && len(events) > desc.Limit {
+		events = events[:desc.Limit]
+	}
+	return events
 }
 
 func (c *EventCollection) Query(desc query.Desc) (*QueryResult, error) {
-	log.Printf("%#v", c.col.Stats())
-	defer log.Printf("%#v", c.col.Stats())
+	phaseStart := time.Now()
 	c.lock.RLock()
 	defer c.lock.RUnlock()
+	stats := newQueryStats(c.col.Stats())
 
-	if desc.TimeRange.Start.Before(minTimestamp) {
-		desc.TimeRange.Start = minTimestamp
-	}
-	if desc.TimeRange.End.Before(minTimestamp) {
-		desc.TimeRange.End = minTimestamp
+	normalizeTimeRange(&desc)
+
+	plan, err := newQueryPlan(desc)
+	if err != nil {
+		return nil, err
 	}
 
-	if desc.TimeRange.Start == minTimestamp && desc.TimeRange.End == minTimestamp {
-		desc.TimeRange.End = fromMicrosecondTime(math.MaxInt64)
+	summaryRows := map[string][]aggregator{}
+	summaryRowsByTime := map[int64]map[string][]aggregator{}
+	resultEvents := []Event{}
+
+	// Raw events are ordered after scanning, keeping only the first
+	// LIMIT events if there's a limit.
+	var ordered interface {
+		add(event Event, values []interface{})
+		sorted() []Event
+	}
+	// Events ordered by _ts DESC are scanned newest first instead.
+	reverse := !plan.aggregated && newestFirst(desc)
+	switch {
+	case reverse:
+	case len(desc.OrderBy) > 0 && desc.Limit > 0:
+		ordered = &topEvents{OrderBy: OrderBy{columns: desc.OrderBy}, n: desc.Limit}
+	case len(desc.OrderBy) > 0:
+		ordered = &OrderBy{columns: desc.OrderBy}
 	}
 
-	filters, err := buildFilters(desc.Filters)
+	after, err := decodeContinuation(desc)
 	if err != nil {
 		return nil, err
 	}
+	if desc.Explain {
+		return &QueryResult{
+			Query: desc,
+			Plan:  c.explain(desc, plan, reverse),
+			Stats: stats.finish(c.col.Stats(), "plan", phaseStart),
+		}, nil
+	}
+	phaseStart = stats.phase("plan", phaseStart)
 
-	cur, err := c.col.NewCursor()
+	scan := scanOptions{reverse: reverse, after: after, stats: stats, filters: plan.filters}
+	err = c.scanEvents(desc, scan, func(ts int64, event Event) (bool, error) {
+		if !plan.aggregated {
+			// No group by or aggregates
+			for _, column := range desc.Columns {
+				if column.Expr != nil || column.Alias != "" {
+					event[columnName(column)] = evalExpr(columnExpr(column), event, nil)
+				}
+			}
+			event["_ts"] = fromMicrosecondTime(ts)
+			if ordered != nil {
+				ordered.add(event, orderValues(desc, event))
+				return true, nil
+			}
+			resultEvents = append(resultEvents, event)
+			if desc.Limit > 0 && len(resultEvents) == desc.Limit {
+				return false, nil
+			}
+			return true, nil
+		}
+
+		// Figure out the row key for grouping
+		rowKey := ""
+		if len(desc.GroupBy) > 0 {
+			rowKeyParts := []string{}
+			for _, groupCol := range desc.GroupBy {
+				groupColVal := evalExpr(columnExpr(groupCol), event, nil)
+				if isTsColumn(groupCol) {
+					// Keep the microsecond timestamp.
+					groupColVal = ts
+				}
+				if groupColVal == nil {
+					return true, nil
+				}
+				marshaledColVal, err := json.Marshal(groupColVal)
+				if err != nil {
+					return true, nil
+				}
+				rowKeyParts = append(rowKeyParts, string(marshaledColVal))
+			}
+			rowKey = strings.Join(rowKeyParts, "\x00")
+		}
+
+		// Do the aggregations.
+
+		updateRows := func(rowKey string, rows map[string][]aggregator) bool {
+			rowAggregates, ok := rows[rowKey]
+			if !ok {
+				rowAggregates = plan.newAggregators()
+				rows[rowKey] = rowAggregates
+			}
+			plan.addEvent(rowAggregates, event, ts)
+			return !ok
+		}
+
+		if len(desc.Columns) > 0 && updateRows(rowKey, summaryRows) {
+			stats.Groups++
+		}
+
+		if desc.PointSize > 0 {
+			timeGroup := ts / desc.PointSize
+			var rows map[string][]aggregator
+			var ok bool
+			if rows, ok = summaryRowsByTime[timeGroup]; !ok {
+				rows = map[string][]aggregator{}
+				summaryRowsByTime[timeGroup] = rows
+			}
+			updateRows(rowKey, rows)
+		}
+		return true, nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	phaseStart = stats.phase("scan", phaseStart)
+	if ordered != nil {
+		resultEvents = ordered.sorted()
+	}
+	continuation := ""
+	if ordered == nil && desc.Limit > 0 && len(resultEvents) == desc.Limit {
+		continuation, err = encodeContinuation(desc, resultEvents[len(resultEvents)-1])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	summaryEvents := []Event{}
+	groupAggregators := map[string][]aggregator{}
+	for rowKey, rowAggregates := range summaryRows {
+		event := Event{}
+		setGroupValues(desc, event, rowKey, true)
+		if !plan.setRow(event, rowAggregates) {
+			continue
+		}
+		rowKeyHash := md5.Sum([]byte(rowKey))
+		groupID := fmt.Sprintf("%x", rowKeyHash[:8])
+		event["_group_id"] = groupID
+		groupAggregators[groupID] = rowAggregates
+		summaryEvents = append(summaryEvents, event)
+	}
+
+	phaseStart = stats.phase("summarize", phaseStart)
+	summaryEvents = orderAndLimit(desc, summaryEvents)
+	summaryEvents, folded := plan.top(summaryEvents, groupAggregators)
+	phaseStart = stats.phase("order", phaseStart)
+	validGroupIDs := map[string]bool{}
+	for _, e := range summaryEvents {
+		validGroupIDs[e["_group_id"].(string)] = true
+	}
+
+	seriesEvents := []Event{}
+	if desc.PointSize > 0 {
+		seriesAggregators := [][]aggregator{}
+		for ts, rows := range summaryRowsByTime {
+			for rowKey, rowAggregates := range rows {
+				rowKeyHash := md5.Sum([]byte(rowKey))
+				groupID := fmt.Sprintf("%x", rowKeyHash[:8])
+				if !validGroupIDs[groupID] && !folded[groupID] {
+					continue
+				}
+				event := Event{
+					"_ts": fromMicrosecondTime(ts * desc.PointSize),
+				}
+				setGroupValues(desc, event, rowKey, false)
+				plan.setRow(event, rowAggregates)
+				event["_group_id"] = groupID
+				seriesEvents = append(seriesEvents, event)
+				seriesAggregators = append(seriesAggregators, rowAggregates)
+			}
+		}
+		seriesEvents = plan.foldSeries(seriesEvents, seriesAggregators, folded)
+
+		sort.Sort(ByTimestamp(seriesEvents))
+	}
+
+	return &QueryResult{
+		Summary:      summaryEvents,
+		Series:       seriesEvents,
+		Events:       resultEvents,
+		Query:        desc,
+		Continuation: continuation,
+		Stats:        stats.finish(c.col.Stats(), "series", phaseStart),
+	}, nil
+}
+
+// setGroupValues sets the GROUP BY columns of a summary row from its
+// row key. Grouping by _ts sets the row's timestamp if withTs is true.
+func setGroupValues(desc query.Desc, event Event, rowKey string, withTs bool) {
+	if len(desc.GroupBy) == 0 {
+		return
+	}
+	parts := strings.Split(rowKey, "\x00")
+	for i, part := range parts {
+		groupCol := desc.GroupBy[i]
+		if isTsColumn(groupCol) {
+			if withTs {
+				ts, _ := strconv.ParseInt(part, 10, 64)
+				event["_ts"] = fromMicrosecondTime(ts)
+			}
+			continue
+		}
+		var val interface{}
+		dec := json.NewDecoder(strings.NewReader(part))
+		dec.UseNumber()
+		dec.Decode(&val)
+		event[columnName(groupCol)] = val
+	}
+}
+
+// setRow sets the columns of a summary row, or the state of its
+// aggregates for partial queries. It returns false if the row is
+// filtered out by HAVING, which coordinators apply to partial rows
+// once they are merged.
+func (p *queryPlan) setRow(row Event, aggregators []aggregator) bool {
+	if p.desc.Partial {
+		p.setPartial(row, aggregators)
+		return true
+	}
+	return p.setColumns(row, aggregators)
+}
+
+// orderAndLimit sorts summary events by the ORDER BY columns of desc
+// and applies its limit. Without ORDER BY, TOP ranks groups by their
+// first aggregate column, largest first.
+func orderAndLimit(desc query.Desc, summaryEvents []Event) []Event {
+	orderBy := desc.OrderBy
+	if len(orderBy) == 0 && desc.Top > 0 {
+		for _, column := range desc.Columns {
+			if hasAggregate(columnExpr(column)) {
+				column.Descending = true
+				orderBy = []query.ColumnDesc{column}
+				break
+			}
+		}
+	}
+	if len(orderBy) != 0 {
+		ordered := &OrderBy{columns: orderBy}
+		for _, event := range summaryEvents {
+			values := make([]interface{}, len(orderBy))
+			for i, column := range orderBy {
+				values[i] = event[columnName(column)]
+			}
+			ordered.add(event, values)
+		}
+		summaryEvents = ordered.sorted()
+	}
+
+	if desc.Limit > 0 && len(summaryEvents) > desc.Limit {
+		summaryEvents = summaryEvents[:desc.Limit]
+	}
+	return summaryEvents
+}
+
+// normalizeTimeRange clamps the time range to the Unix epoch. A time
+// range without an end covers all events up to maxTimestamp.
+func normalizeTimeRange(desc *query.Desc) {
+	if desc.TimeRange.Start.Before(minTimestamp) {
+		desc.TimeRange.Start = minTimestamp
+	}
+	if !desc.TimeRange.End.After(minTimestamp) {
+		// No end, e.g. SINCE 6h
+		desc.TimeRange.End = maxTimestamp
+	}
+}
+
+// scanOptions controls how scanEvents reads events.
+type scanOptions struct {
+	reverse bool        // newest first, in reverse key order
+	after   string      // start after the event with this key
+	stats   *QueryStats // counts the work done, if set
+	filters []Filter    // built from the filters of desc, if set
+	// unlocked scans take the collection lock only to open cursors,
+	// so fn can block without holding up writes. They read forward.
+	unlocked bool
+}
+
+// scanEvents calls fn with every event in the time range of desc that
+// matches its filters, in key order unless opts say otherwise. The
+// event's _ts is set to its microsecond timestamp. Scanning stops when
+// fn returns false or an error. The caller must hold the collection
+// lock, unless the scan is unlocked.
+func (c *EventCollection) scanEvents(desc query.Desc, opts scanOptions, fn func(ts int64, event Event) (bool, error)) error {
+	stats := opts.stats
+	if stats == nil {
+		stats = &QueryStats{}
+	}
+	reverse, after := opts.reverse, opts.after
+	filters := opts.filters
+	var err error
+	if filters == nil {
+		filters, err = buildFilters(desc.Filters)
+		if err != nil {
+			return err
+		}
+	}
+	if opts.unlocked {
+		c.lock.RLock()
+	}
+	col := c.col
 
 	formattedStartTs := formatTs(toMicrosecondTime(desc.TimeRange.Start))
 	formattedEndTs := formatTs(toMicrosecondTime(desc.TimeRange.End))
@@ -79,29 +455,152 @@ func (c *EventCollection) Query(desc query.Desc) (*QueryResult, error) {
 	startKey := string(eventKeyPrefix) + string(formattedStartTs[:])
 	endKey := string(eventKeyPrefix) + string(formattedEndTs[:]) + "\xff"
 
-	summaryRows := map[string][]float64{}
-	summaryRowsByTime := map[int64]map[string][]float64{}
-	resultEvents := []Event{}
+	// openLive opens a cursor over the events in the collection from
+	// startKey on, leaving out the archive.
+	openLive := func(startKey string) (seekCursor, error) {
+		if filter, ok := c.indexedFilter(desc.Filters); ok {
+			indexCur, err := c.newIndexCursor(filter.Column, filter.Value, startKey)
+			if err != nil {
+				return nil, err
+			}
+			return indexCur, nil
+		}
+		scanCur, err := c.col.NewCursor()
+		if err != nil {
+			return nil, err
+		}
+		scanCur.Seek(startKey)
+		return scanCur, nil
+	}
+	open := func(startKey, endKey string) (eventCursor, error) {
+		cur, err := openLive(startKey)
+		if err != nil {
+			return nil, err
+		}
+		if c.archive != nil {
+			return &mergeCursor{a: c.archive.newCursor(startKey, endKey), b: cur}, nil
+		}
+		return cur, nil
+	}
+	if filter, ok := c.indexedFilter(desc.Filters); ok {
+		stats.Index = filter.Column
+	}
+	if c.archive != nil {
+		stats.Segments = len(c.archive.segmentsBetween(startKey, endKey))
+	}
+	var cur eventCursor
+	if reverse {
+		end := toMicrosecondTime(desc.TimeRange.End)
+		if after != "" {
+			afterTs, _, _, err := splitCollectionID(after)
+			if err != nil {
+				return err
+			}
+			if afterTs < end {
+				end = afterTs
+			}
+		}
+		// Start at the newest event rather than at an open end.
+		liveEnd := end
+		if last, ok := lastEventTs(c.col, end); ok && last < end {
+			liveEnd = last
+		}
+		var liveCur seekCursor
+		liveCur, err = openLive(startKey)
+		if err == nil {
+			cur = newReverseCursor(liveCur, toMicrosecondTime(desc.TimeRange.Start), liveEnd)
+			if c.archive != nil {
+				endTs := formatTs(end)
+				archiveCur := c.archive.newReverseCursor(startKey, string(eventKeyPrefix)+string(endTs[:])+"\xff")
+				cur = &mergeCursor{a: archiveCur, b: cur, reverse: true}
+			}
+		}
+	} else {
+		if after > startKey {
+			startKey = after
+		}
+		cur, err = open(startKey, endKey)
+	}
+	if opts.unlocked {
+		c.lock.RUnlock()
+	}
+	if err != nil {
+		return err
+	}
 
-	cur.Seek(startKey)
+	scan := &eventScan{
+		desc:    desc,
+		filters: filters,
+		reverse: reverse,
+		after:   after,
+		endKey:  endKey,
+		stats:   stats,
+		fn:      fn,
+	}
+	for {
+		stopped, err := scan.run(cur)
+		if stopped || !opts.unlocked {
+			return err
+		}
 
+		// Compacting replaces the collection, which ends cursors on the
+		// old one. Unlocked scans carry on after the last key read.
+		c.lock.RLock()
+		if c.col == col {
+			c.lock.RUnlock()
+			return err
+		}
+		col = c.col
+		if scan.lastKey > startKey {
+			startKey, scan.after = scan.lastKey, scan.lastKey
+		}
+		cur, err = open(startKey, endKey)
+		c.lock.RUnlock()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// eventScan is the state of a scan by scanEvents.
+type eventScan struct {
+	desc    query.Desc
+	filters []Filter
+	reverse bool
+	after   string // events up to this key are skipped
+	endKey  string
+	stats   *QueryStats
+	lastKey string // the key of the last event read
+	fn      func(ts int64, event Event) (bool, error)
+}
+
+// run calls fn with the matching events of cur. It returns true if the
+// scan stopped before the end of cur.
+func (s *eventScan) run(cur eventCursor) (bool, error) {
+	desc, stats, after, reverse := s.desc, s.stats, s.after, s.reverse
 CursorLoop:
 	for cur.Next() {
-		if cur.Key() > endKey {
-			break
+		if cur.Key() > s.endKey {
+			return true, nil
 		}
 
 		if (cur.Key())[0] == '_' {
 			continue
 		}
+		if after != "" && ((!reverse && cur.Key() <= after) || (reverse && cur.Key() >= after)) {
+			continue
+		}
 
 		// Extract event
 		id := cur.Key()
 		val := cur.Value()
+		s.lastKey = id
+		stats.KeysScanned++
+		stats.BytesRead += int64(len(id) + len(val))
 		ts, keyTag, hash, err := splitCollectionID(id)
 		if err != nil {
 			log.Println(err)
-			return nil, err
+			return true, err
 		}
 
 		if ts < toMicrosecondTime(desc.TimeRange.Start) {
@@ -113,8 +612,9 @@ CursorLoop:
 		err = json.Unmarshal(valBytes, &event)
 		if err != nil {
 			log.Println(err)
-			return nil, err
+			return true, err
 		}
+		stats.EventsDecoded++
 
 		eventID := strconv.FormatInt(ts, 10) + "|" + keyTag
 		event["_ts"] = ts
@@ -126,197 +626,177 @@ CursorLoop:
 		event["_id"] = eventID
 
 		// Apply filters
-		for _, filter := range filters {
+		for _, filter := range s.filters {
 			if !filter.Filter(event) {
 				continue CursorLoop
 			}
 		}
+		stats.EventsMatched++
 
-		if len(desc.GroupBy) == 0 && len(desc.Columns) == 0 && desc.PointSize <= 0 {
-			// No group by or aggregates
-			event["_ts"] = fromMicrosecondTime(ts)
-			resultEvents = append(resultEvents, event)
-			if desc.Limit > 0 && len(resultEvents) == desc.Limit {
-				break
-			}
-			continue
-		}
-
-		// Figure out the row key for grouping
-		rowKey := ""
-		if len(desc.GroupBy) > 0 {
-			rowKeyParts := []string{}
-			for _, groupCol := range desc.GroupBy {
-				groupColVal := event[groupCol.Name] // TODO: support aggregates on grouped columns
-				if groupColVal == nil {
-					continue CursorLoop
-				}
-				marshaledColVal, err := json.Marshal(groupColVal)
-				if err != nil {
-					continue CursorLoop
-				}
-				rowKeyParts = append(rowKeyParts, string(marshaledColVal))
-			}
-			rowKey = strings.Join(rowKeyParts, "\x00")
+		more, err := s.fn(ts, event)
+		if err != nil || !more {
+			return true, err
 		}
+	}
 
-		// Do the aggregations.
+	return false, cur.Err()
+}
 
-		updateRows := func(rowKey string, rows map[string][]float64) {
-			rowAggregates, ok := rows[rowKey]
-			if !ok {
-				rowAggregates = make([]float64, len(desc.Columns))
-				for i := range rowAggregates {
-					rowAggregates[i] = math.NaN()
-				}
-			}
+// indexedFilter returns an equality filter on an indexed column, if any.
+func (c *EventCollection) indexedFilter(filters []query.Filter) (query.Filter, bool) {
+	for _, filter := range filters {
+		if stringToFilterType(filter.Condition) == FilterEquals && filter.Expr == nil && c.isIndexed(filter.Column) &&
+			indexedValue(filter.Value) {
+			return filter, true
+		}
+	}
+	return query.Filter{}, false
+}
 
-			for i, columnDesc := range desc.Columns {
-				floatVal := 0.0
-				columnVal := event[columnDesc.Name]
-				switch columnVal.(type) {
-				case int:
-					floatVal = float64(columnVal.(int))
-				case float64:
-					floatVal = columnVal.(float64)
-				}
-				switch columnDesc.Aggregate {
-				case "sum":
-					if math.IsNaN(rowAggregates[i]) {
-						rowAggregates[i] = 0
-					}
-					rowAggregates[i] += floatVal
-				case "count":
-					if math.IsNaN(rowAggregates[i]) {
-						rowAggregates[i] = 0
-					}
-					rowAggregates[i] += 1
-				case "min":
-					if rowAggregates[i] > floatVal || math.IsNaN(rowAggregates[i]) {
-						rowAggregates[i] = floatVal
-					}
-				case "max":
-					if rowAggregates[i] < floatVal || math.IsNaN(rowAggregates[i]) {
-						rowAggregates[i] = floatVal
-					}
-				}
-			}
+const (
+	// reverseWindowEvents is the number of events below which the
+	// windows of a reverseCursor grow, and above twice which they
+	// shrink.
+	reverseWindowEvents = 1000
+	// reverseLoadEvents is the most events a reverseCursor loads at
+	// once, unless they are all at the same microsecond.
+	reverseLoadEvents = 4 * reverseWindowEvents
+	// maxReverseWindow is the longest window in microseconds.
+	maxReverseWindow = int64(366 * 24 * time.Hour / time.Microsecond)
+)
 
-			rows[rowKey] = rowAggregates
+// lastEventTs returns the microsecond timestamp of the newest event in
+// col up to end, if it finds one.
+func lastEventTs(col *lm2.Collection, end int64) (int64, bool) {
+	cur, err := col.NewCursor()
+	if err != nil {
+		return 0, false
+	}
+	endTs := formatTs(end)
+	endKey := string(eventKeyPrefix) + string(endTs[:]) + "\xff"
+	// Seeking lands on the last key before endKey.
+	cur.Seek(endKey)
+	last := ""
+	for cur.Next() {
+		key := cur.Key()
+		if key > endKey {
+			break
 		}
-
-		if len(desc.Columns) > 0 {
-			updateRows(rowKey, summaryRows)
+		if key[0] == eventKeyPrefix {
+			last = key
 		}
+	}
+	if last == "" {
+		return 0, false
+	}
+	ts, _, _, err := splitCollectionID(last)
+	if err != nil {
+		return 0, false
+	}
+	return ts, true
+}
 
-		if desc.PointSize > 0 {
-			timeGroup := ts / desc.PointSize
-			var rows map[string][]float64
-			var ok bool
-			if rows, ok = summaryRowsByTime[timeGroup]; !ok {
-				rows = map[string][]float64{}
-				summaryRowsByTime[timeGroup] = rows
-			}
-			updateRows(rowKey, rows)
-		}
-	} // Event cursor loop
+// reverseCursor iterates over the events between two microsecond
+// timestamps in reverse key order. Cursors only move forward, so it
+// seeks one cursor back to read windows of events forward, starting
+// from the end. All windows come from the snapshot of that cursor.
+// Windows start at a minute, double while they have few events and
+// halve when they have many. A window with too many events to load is
+// read again in a quarter of its length.
+type reverseCursor struct {
+	cur          seekCursor
+	start, end   int64 // the events left are from start to end
+	window       int64
+	keys, values []string
+	pos          int
+	err          error
+}
 
-	if err = cur.Err(); err != nil {
-		return nil, err
+func newReverseCursor(cur seekCursor, start, end int64) *reverseCursor {
+	return &reverseCursor{
+		cur:    cur,
+		start:  start,
+		end:    end,
+		window: int64(time.Minute / time.Microsecond),
 	}
+}
 
-	summaryEvents := []Event{}
-	for rowKey, rowAggregates := range summaryRows {
-		event := Event{}
-		if len(desc.GroupBy) > 0 {
-			parts := strings.Split(rowKey, "\x00")
-			for i, part := range parts {
-				if desc.GroupBy[i].Name == "_ts" { // TODO: support aggregates on grouped columns
-					ts, _ := strconv.Atoi(part)
-					event["_ts"] = fromMicrosecondTime(int64(ts))
-					continue
-				}
-				var val interface{}
-				dec := json.NewDecoder(strings.NewReader(part))
-				dec.UseNumber()
-				dec.Decode(&val)
-				event[desc.GroupBy[i].Name] = val // TODO: support aggregates on grouped columns
+func (rc *reverseCursor) Next() bool {
+	rc.pos--
+	for rc.pos < 0 {
+		if rc.err != nil || rc.end < rc.start {
+			rc.keys, rc.values = nil, nil
+			return false
+		}
+		windowStart := rc.start
+		if rc.end-rc.start >= rc.window {
+			windowStart = rc.end - rc.window + 1
+		}
+		if !rc.load(windowStart, rc.end) {
+			rc.window = (rc.end - windowStart + 1) / 4
+			if rc.window < 1 {
+				rc.window = 1
 			}
+			continue
 		}
-		for i, columnDesc := range desc.Columns {
-			fieldName := columnDesc.Aggregate + "(" + columnDesc.Name + ")"
-			event[fieldName] = rowAggregates[i]
+		switch {
+		case len(rc.keys) < reverseWindowEvents && rc.window < rc.end-rc.start && rc.window <= maxReverseWindow/2:
+			rc.window *= 2
+		case len(rc.keys) > 2*reverseWindowEvents && rc.window > 1:
+			rc.window /= 2
 		}
-		rowKeyHash := md5.Sum([]byte(rowKey))
-		event["_group_id"] = fmt.Sprintf("%x", rowKeyHash[:8])
-		summaryEvents = append(summaryEvents, event)
+		rc.end = windowStart - 1
+		rc.pos = len(rc.keys) - 1
 	}
+	return true
+}
 
-	if len(desc.OrderBy) != 0 {
-		orderByColumns := []string{}
-		for _, desc := range desc.OrderBy {
-			name := desc.Name
-			if desc.Aggregate != "" {
-				name = desc.Aggregate + "(" + name + ")"
-			}
-			orderByColumns = append(orderByColumns, name)
+// load reads the events from start to end. It returns false if there
+// are more than reverseLoadEvents of them in a window longer than a
+// microsecond.
+func (rc *reverseCursor) load(start, end int64) bool {
+	startTs, endTs := formatTs(start), formatTs(end)
+	startKey := string(eventKeyPrefix) + string(startTs[:])
+	endKey := string(eventKeyPrefix) + string(endTs[:]) + "\xff"
+	rc.keys, rc.values = rc.keys[:0], rc.values[:0]
+	cur := rc.cur
+	cur.Seek(startKey)
+	for cur.Next() {
+		key := cur.Key()
+		if key > endKey {
+			break
 		}
-		var ordering sort.Interface = OrderBy{
-			columns: orderByColumns,
-			events:  summaryEvents,
+		if key < startKey {
+			// Seeking lands on the last key before startKey.
+			continue
 		}
-		if desc.Descending {
-			ordering = sort.Reverse(ordering)
+		if len(rc.keys) == reverseLoadEvents && start < end {
+			rc.keys, rc.values = rc.keys[:0], rc.values[:0]
+			return false
 		}
-		sort.Stable(ordering)
+		rc.keys = append(rc.keys, key)
+		rc.values = append(rc.values, cur.Value())
 	}
+	rc.err = cur.Err()
+	return true
+}
 
-	if desc.Limit > 0 && len(summaryEvents) > desc.Limit {
-		summaryEvents = summaryEvents[:desc.Limit]
+func (rc *reverseCursor) Key() string {
+	if rc.pos < 0 || rc.pos >= len(rc.keys) {
+		return ""
 	}
-	validGroupIDs := map[string]bool{}
-	for _, e := range summaryEvents {
-		validGroupIDs[e["_group_id"].(string)] = true
-	}
-
-	seriesEvents := []Event{}
-	if desc.PointSize > 0 {
-		for ts, rows := range summaryRowsByTime {
-			for rowKey, rowAggregates := range rows {
-				rowKeyHash := md5.Sum([]byte(rowKey))
-				groupID := fmt.Sprintf("%x", rowKeyHash[:8])
-				if !validGroupIDs[groupID] {
-					continue
-				}
-				event := Event{
-					"_ts": fromMicrosecondTime(ts * desc.PointSize),
-				}
-				if len(desc.GroupBy) > 0 {
-					parts := strings.Split(rowKey, "\x00")
-					for i, part := range parts {
-						if desc.GroupBy[i].Name == "_ts" { // TODO: support aggregates on grouped columns
-							continue
-						}
-						var val interface{}
-						dec := json.NewDecoder(strings.NewReader(part))
-						dec.UseNumber()
-						dec.Decode(&val)
-						event[desc.GroupBy[i].Name] = val // TODO: support aggregates on grouped columns
-					}
-				}
-				for i, columnDesc := range desc.Columns {
-					fieldName := columnDesc.Aggregate + "(" + columnDesc.Name + ")"
-					event[fieldName] = rowAggregates[i]
-				}
-				event["_group_id"] = groupID
-				seriesEvents = append(seriesEvents, event)
-			}
-		}
+	return rc.keys[rc.pos]
+}
 
-		sort.Sort(ByTimestamp(seriesEvents))
+func (rc *reverseCursor) Value() string {
+	if rc.pos < 0 || rc.pos >= len(rc.values) {
+		return ""
 	}
+	return rc.values[rc.pos]
+}
 
-	return &QueryResult{Summary: summaryEvents, Series: seriesEvents, Events: resultEvents, Query: desc}, nil
+func (rc *reverseCursor) Err() error {
+	return rc.err
 }
 
 func splitCollectionID(id string) (int64, string, string, error) {
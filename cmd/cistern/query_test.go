@@ -1,13 +1,16 @@
 package main
 
 import (
+	"encoding/json"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/Cistern/cistern/internal/query"
 )
 
 func TestLimit(t *testing.T) {
-	ec, err := CreateEventCollection("/tmp/test_cistern_limit.lm2")
+	ec, err := CreateEventCollection("/tmp/test_cistern_limit.lm2", defaultCacheSize)
 	defer ec.col.Destroy()
 	if err != nil {
 		t.Fatal(err)
@@ -31,7 +34,7 @@ func TestLimit(t *testing.T) {
 }
 
 func TestFilter(t *testing.T) {
-	ec, err := CreateEventCollection("/tmp/test_cistern_filter.lm2")
+	ec, err := CreateEventCollection("/tmp/test_cistern_filter.lm2", defaultCacheSize)
 	defer ec.col.Destroy()
 	if err != nil {
 		t.Fatal(err)
@@ -117,6 +120,74 @@ func TestFilter(t *testing.T) {
 			},
 			ExpectedMatches: 3,
 		},
+		{
+			Filters: []query.Filter{
+				{
+					Or: []query.Filter{
+						{Column: "source_port", Condition: "=", Value: 443.0},
+						{Column: "dest_port", Condition: "=", Value: 443.0},
+					},
+				},
+			},
+			ExpectedMatches: 6,
+		},
+		{
+			Filters: []query.Filter{
+				{
+					Not: &query.Filter{Column: "source_port", Condition: "=", Value: 443.0},
+				},
+			},
+			ExpectedMatches: 4,
+		},
+		{
+			Filters: []query.Filter{
+				{
+					Or: []query.Filter{
+						{
+							And: []query.Filter{
+								{Column: "source_port", Condition: "=", Value: 443.0},
+								{Column: "dest_address", Condition: "matches", Value: "^172"},
+							},
+						},
+						{Column: "source_port", Condition: "=", Value: 22.0},
+					},
+				},
+				{
+					Not: &query.Filter{Column: "bytes", Condition: ">", Value: 10000.0},
+				},
+			},
+			ExpectedMatches: 3,
+		},
+		{
+			Filters: []query.Filter{
+				{
+					Column:    "source_port",
+					Condition: "in",
+					Value:     []interface{}{22.0, 52310.0},
+				},
+			},
+			ExpectedMatches: 2,
+		},
+		{
+			Filters: []query.Filter{
+				{
+					Column:    "source_address",
+					Condition: "in cidr",
+					Value:     "172.16.0.0/12",
+				},
+			},
+			ExpectedMatches: 4,
+		},
+		{
+			Filters: []query.Filter{
+				{
+					Column:    "bytes",
+					Condition: "between",
+					Value:     []interface{}{5318.0, 9673.0},
+				},
+			},
+			ExpectedMatches: 4,
+		},
 	}
 
 	for i, tc := range testCases {
@@ -132,3 +203,591 @@ func TestFilter(t *testing.T) {
 		}
 	}
 }
+
+func TestIndexedFilter(t *testing.T) {
+	ec, err := CreateEventCollection("/tmp/test_cistern_indexed_filter.lm2", defaultCacheSize)
+	defer ec.col.Destroy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ec.StoreEvents(testEvents[:3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Building the index should pick up existing events.
+	err = ec.SetIndexes([]string{"source_address"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ec.StoreEvents(testEvents[3:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filters := []query.Filter{
+		{
+			Column:    "source_address",
+			Condition: "=",
+			Value:     "172.31.31.192",
+		},
+	}
+	if _, ok := ec.indexedFilter(filters); !ok {
+		t.Fatal("expected filter to use the index")
+	}
+	result, err := ec.Query(query.Desc{
+		Filters: filters,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Events) != 4 {
+		t.Errorf("expected %d events but got %d", 4, len(result.Events))
+	}
+}
+
+func TestIndexedFilterCoercion(t *testing.T) {
+	indexed, err := CreateEventCollection("/tmp/test_cistern_indexed_coercion.lm2", defaultCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { indexed.col.Destroy() }()
+	scanned, err := CreateEventCollection("/tmp/test_cistern_scanned_coercion.lm2", defaultCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { scanned.col.Destroy() }()
+	err = indexed.SetIndexes([]string{"port", "address", "seen", "flag"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := []Event{
+		{"_tag": "a", "_ts": "2017-08-01T00:00:01Z", "port": 22, "address": "10.0.0.1", "seen": "2017-08-01T00:00:00Z", "flag": true},
+		{"_tag": "a", "_ts": "2017-08-01T00:00:02Z", "port": "22", "address": "::ffff:10.0.0.1", "seen": "2017-08-01T02:00:00+02:00", "flag": "1"},
+		{"_tag": "a", "_ts": "2017-08-01T00:00:03Z", "port": 22.0, "address": "10.0.0.2", "seen": "2017-08-01T00:00:00.5Z", "flag": 1},
+		{"_tag": "a", "_ts": "2017-08-01T00:00:04Z", "port": "ssh", "address": "fe80::1", "seen": "yesterday", "flag": false},
+	}
+	for _, ec := range []*EventCollection{indexed, scanned} {
+		err = ec.StoreEvents(events)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, filter := range []string{
+		`port = 22`,
+		`port = "22"`,
+		`port = 22.0`,
+		`port = "ssh"`,
+		`address = "10.0.0.1"`,
+		`address = "::ffff:10.0.0.1"`,
+		`address = "fe80:0:0:0:0:0:0:1"`,
+		`seen = "2017-08-01T00:00:00.000Z"`,
+		`flag = "1"`,
+		`flag = 1`,
+	} {
+		desc, err := query.Parse("FILTER " + filter + " SINCE \"2017-08-01\" UNTIL \"2017-08-02\"")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = decodeFilterValues(desc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, err := scanned.Query(*desc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := indexed.Query(*desc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(expected.Events) == 0 {
+			t.Errorf("%s: expected events", filter)
+		}
+		if !sameJSON(expected.Events, result.Events) {
+			t.Errorf("%s: expected %v but got %v through the index", filter, expected.Events, result.Events)
+		}
+	}
+}
+
+func TestNetworkFilters(t *testing.T) {
+	ec, err := CreateEventCollection("/tmp/test_cistern_network.lm2", defaultCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { ec.col.Destroy() }()
+
+	err = ec.StoreEvents([]Event{
+		{"_tag": "a", "_ts": "2017-08-01T03:20:00Z", "source_address": "10.1.2.3", "dest_port": 22},
+		{"_tag": "a", "_ts": "2017-08-01T03:21:00Z", "source_address": "10.200.0.1", "dest_port": 443},
+		{"_tag": "a", "_ts": "2017-08-01T03:22:00Z", "source_address": "192.168.1.1", "dest_port": 3389},
+		{"_tag": "a", "_ts": "2017-08-01T03:23:00Z", "source_address": "fd00::1", "dest_port": 5900},
+		{"_tag": "a", "_ts": "2017-08-01T03:24:00Z", "source_address": "2001:db8::1", "dest_port": 80},
+		{"_tag": "a", "_ts": "2017-08-01T03:25:00Z", "source_address": "::ffff:10.9.9.9", "dest_port": 22},
+		{"_tag": "a", "_ts": "2017-08-01T03:26:00Z", "source_address": "-", "dest_port": 22},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		query    string
+		expected int
+	}{
+		{`FILTER source_address in cidr "10.0.0.0/8"`, 3},
+		{`FILTER source_address in cidr ("10.1.0.0/16", "fc00::/7")`, 2},
+		{`FILTER source_address not in cidr ("10.0.0.0/8", "fc00::/7")`, 2},
+		{`FILTER dest_port in (22, 3389, 5900)`, 5},
+		{`FILTER dest_port not in (22, 3389, 5900)`, 2},
+		{`FILTER dest_port between 80 and 443`, 2},
+		{`FILTER source_address between "10.0.0.0" and "10.255.255.255"`, 3},
+		{`FILTER dest_port in (22) AND NOT source_address in cidr "10.0.0.0/8"`, 1},
+	}
+	for _, c := range testCases {
+		desc, err := query.Parse(c.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = decodeFilterValues(desc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := ec.Query(*desc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Events) != c.expected {
+			t.Errorf("%s: expected %d events but got %d", c.query, c.expected, len(result.Events))
+		}
+	}
+
+	_, err = ec.Query(query.Desc{
+		Filters: []query.Filter{{Column: "source_address", Condition: "in cidr", Value: "10.0.0.0"}},
+	})
+	if err == nil {
+		t.Error("expected an error for an invalid network")
+	}
+}
+
+func TestHaving(t *testing.T) {
+	ec, err := CreateEventCollection("/tmp/test_cistern_having.lm2", defaultCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { ec.col.Destroy() }()
+	err = ec.StoreEvents(testEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		query    string
+		expected string // source_address of the only matching group
+	}{
+		{"SELECT count(_id) GROUP BY source_address HAVING count(_id) > 3 POINT SIZE 20m", "172.31.31.192"},
+		{"SELECT count(_id) GROUP BY source_address HAVING count_distinct(dest_port) >= 2 AND sum(bytes) < 100000", "52.54.154.173"},
+		{"SELECT sum(bytes) AS total GROUP BY source_address HAVING total > 100000", "172.31.31.192"},
+	}
+	for _, c := range testCases {
+		desc, err := query.Parse(c.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = decodeFilterValues(desc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := ec.Query(*desc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Summary) != 1 || result.Summary[0]["source_address"] != c.expected {
+			t.Errorf("%s: expected only %s but got %v", c.query, c.expected, result.Summary)
+			continue
+		}
+		for _, event := range result.Series {
+			if event["source_address"] != c.expected {
+				t.Errorf("%s: expected series of %s but got %v", c.query, c.expected, event)
+			}
+		}
+	}
+
+	_, err = ec.Query(query.Desc{
+		Having: []query.Filter{{Column: "bytes", Condition: ">", Value: 1.0}},
+	})
+	if err == nil {
+		t.Error("expected an error for HAVING without aggregates")
+	}
+}
+
+func TestQueryTimeRange(t *testing.T) {
+	ec, err := CreateEventCollection("/tmp/test_cistern_time_range.lm2", defaultCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { ec.col.Destroy() }()
+	err = ec.StoreEvents(testEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		query    string
+		expected int
+	}{
+		{`SINCE "2017-08-01T04:00:00Z"`, 3},
+		{`UNTIL "2017-08-01T03:40:00Z"`, 3},
+		{`FILTER protocol = 6 BETWEEN "2017-08-01T03:30:00Z" AND "2017-08-01T04:00:00Z"`, 4},
+		{`SINCE "2017-08-01"`, 7},
+		{`SINCE 1h`, 0},
+	}
+	for _, c := range testCases {
+		desc, err := query.Parse(c.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = decodeFilterValues(desc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := ec.Query(*desc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Events) != c.expected {
+			t.Errorf("%s: expected %d events but got %d", c.query, c.expected, len(result.Events))
+		}
+	}
+}
+
+func TestTop(t *testing.T) {
+	ec, err := CreateEventCollection("/tmp/test_cistern_top.lm2", defaultCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { ec.col.Destroy() }()
+	err = ec.StoreEvents(testEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	desc, err := query.Parse("SELECT dest_port, sum(bytes) GROUP BY dest_port TOP 2 POINT SIZE 20m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ec.Query(*desc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Summary) != 3 {
+		t.Fatalf("expected 2 groups and _other but got %v", result.Summary)
+	}
+	top, second, other := result.Summary[0], result.Summary[1], result.Summary[2]
+	if top["dest_port"] != json.Number("443") || top["sum(bytes)"] != 208803.0 ||
+		second["dest_port"] != json.Number("56598") || second["sum(bytes)"] != 13297.0 {
+		t.Errorf("expected the groups with the most bytes but got %v and %v", top, second)
+	}
+	if other["_group_id"] != otherGroupID || other["sum(bytes)"] != 17518.0 || other["dest_port"] != nil {
+		t.Errorf("expected the other groups to be folded but got %v", other)
+	}
+
+	// Series points add up to the totals of the whole collection.
+	totals := map[time.Time]float64{}
+	for _, event := range testEvents {
+		ts, _ := time.Parse(time.RFC3339, event["_ts"].(string))
+		totals[ts.Truncate(20*time.Minute)] += float64(event["bytes"].(int))
+	}
+	got := map[time.Time]float64{}
+	others := 0
+	for _, event := range result.Series {
+		got[event["_ts"].(time.Time)] += event["sum(bytes)"].(float64)
+		if event["_group_id"] == otherGroupID {
+			others++
+		}
+	}
+	if !reflect.DeepEqual(got, totals) || others == 0 {
+		t.Errorf("expected series totals %v but got %v", totals, got)
+	}
+
+	desc, err = query.Parse("SELECT sum(bytes) TOP 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ec.Query(*desc)
+	if err == nil {
+		t.Error("expected an error for TOP without GROUP BY")
+	}
+}
+
+func TestOrderBy(t *testing.T) {
+	ec, err := CreateEventCollection("/tmp/test_cistern_order_by.lm2", defaultCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { ec.col.Destroy() }()
+	err = ec.StoreEvents(testEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		query    string
+		column   string
+		expected []string
+	}{
+		{"ORDER BY bytes DESC LIMIT 3", "bytes", []string{"192500", "13297", "9673"}},
+		{"ORDER BY bytes LIMIT 2", "bytes", []string{"2941", "5318"}},
+		{"ORDER BY bytes / packets DESC LIMIT 1", "bytes", []string{"192500"}},
+		{
+			"ORDER BY source_address, bytes DESC", "bytes", // IPs sort as addresses
+			[]string{"9259", "5318", "2941", "192500", "13297", "9673", "6630"},
+		},
+		{
+			"SELECT bytes * 2 AS double ORDER BY double ASC LIMIT 2", "double",
+			[]string{"5882", "10636"},
+		},
+		{
+			"SELECT dest_port, count(_id), sum(bytes) GROUP BY dest_port ORDER BY count(_id) DESC, sum(bytes) ASC", "dest_port",
+			[]string{"443", "46986", "46960", "56598"},
+		},
+	}
+	for _, c := range testCases {
+		desc, err := query.Parse(c.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := ec.Query(*desc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		events := result.Events
+		if len(desc.GroupBy) > 0 {
+			events = result.Summary
+		}
+		got := []string{}
+		for _, event := range events {
+			got = append(got, stringValue(event[c.column]))
+		}
+		if !reflect.DeepEqual(got, c.expected) {
+			t.Errorf("%s: expected %v but got %v", c.query, c.expected, got)
+		}
+	}
+}
+
+func TestReverseScan(t *testing.T) {
+	ec, err := CreateEventCollection("/tmp/test_cistern_reverse.lm2", defaultCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { ec.col.Destroy() }()
+	err = ec.SetIndexes([]string{"parity"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Events every 10 seconds span many windows of the reverse cursor.
+	const n = 3000
+	start := time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC)
+	events := []Event{}
+	for i := 0; i < n; i++ {
+		events = append(events, Event{
+			"_tag":   "a",
+			"_ts":    start.Add(time.Duration(i) * 10 * time.Second).Format(time.RFC3339),
+			"i":      i,
+			"parity": i % 2,
+		})
+	}
+	err = ec.StoreEvents(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		query    string
+		expected []int
+	}{
+		{"ORDER BY _ts DESC LIMIT 3", []int{2999, 2998, 2997}},
+		{"FILTER parity = 0 ORDER BY _ts DESC LIMIT 2", []int{2998, 2996}},
+		{"FILTER i < 1000 ORDER BY _ts DESC LIMIT 2", []int{999, 998}},
+		{`UNTIL "2017-08-01T00:00:20Z" ORDER BY _ts DESC`, []int{2, 1, 0}},
+	}
+	for _, c := range testCases {
+		desc, err := query.Parse(c.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = decodeFilterValues(desc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := ec.Query(*desc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []int{}
+		for _, event := range result.Events {
+			i, _ := valueOf(event["i"]).number()
+			got = append(got, int(i))
+		}
+		if !reflect.DeepEqual(got, c.expected) {
+			t.Errorf("%s: expected %v but got %v", c.query, c.expected, got)
+		}
+	}
+
+	desc, err := query.Parse("ORDER BY _ts DESC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ec.Query(*desc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Events) != n {
+		t.Fatalf("expected %d events but got %d", n, len(result.Events))
+	}
+	for i := 1; i < n; i++ {
+		if !result.Events[i]["_ts"].(time.Time).Before(result.Events[i-1]["_ts"].(time.Time)) {
+			t.Fatalf("expected events newest first but got %v after %v", result.Events[i], result.Events[i-1])
+		}
+	}
+}
+
+func TestReverseCursorLoads(t *testing.T) {
+	ec, err := CreateEventCollection("/tmp/test_cistern_reverse_loads.lm2", defaultCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { ec.col.Destroy() }()
+
+	// A few old events, then a dense minute of events a year later.
+	const old, dense = 10, 10000
+	start := time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC)
+	events := []Event{}
+	for i := 0; i < old; i++ {
+		events = append(events, Event{
+			"_tag": "a",
+			"_ts":  start.Add(time.Duration(i) * time.Hour).Format(time.RFC3339Nano),
+		})
+	}
+	for i := 0; i < dense; i++ {
+		events = append(events, Event{
+			"_tag": "a",
+			"_ts":  start.AddDate(1, 0, 0).Add(time.Duration(i) * 5 * time.Millisecond).Format(time.RFC3339Nano),
+		})
+	}
+	err = ec.StoreEvents(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	last, ok := lastEventTs(ec.col, toMicrosecondTime(maxTimestamp))
+	expected := toMicrosecondTime(start.AddDate(1, 0, 0).Add((dense - 1) * 5 * time.Millisecond))
+	if !ok || last != expected {
+		t.Errorf("expected the newest event at %d but got %d, %v", expected, last, ok)
+	}
+
+	// From an open end, windows grow across the empty years, but no
+	// load buffers more than reverseLoadEvents.
+	cur, err := ec.col.NewCursor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc := newReverseCursor(cur, toMicrosecondTime(minTimestamp), toMicrosecondTime(maxTimestamp))
+	n, maxLoad, prev := 0, 0, ""
+	for rc.Next() {
+		if len(rc.keys) > maxLoad {
+			maxLoad = len(rc.keys)
+		}
+		if rc.window <= 0 || rc.window > maxReverseWindow {
+			t.Fatalf("unexpected window %d", rc.window)
+		}
+		if prev != "" && rc.Key() >= prev {
+			t.Fatalf("expected keys in reverse order but got %s after %s", rc.Key(), prev)
+		}
+		prev = rc.Key()
+		n++
+	}
+	if rc.Err() != nil {
+		t.Fatal(rc.Err())
+	}
+	if n != old+dense {
+		t.Errorf("expected %d events but got %d", old+dense, n)
+	}
+	if maxLoad > reverseLoadEvents {
+		t.Errorf("expected loads of at most %d events but got %d", reverseLoadEvents, maxLoad)
+	}
+}
+
+func TestQueryStats(t *testing.T) {
+	ec, err := CreateEventCollection("/tmp/test_cistern_query_stats.lm2", defaultCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { ec.col.Destroy() }()
+	err = ec.SetIndexes([]string{"source_address"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ec.StoreEvents(testEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	desc, err := query.Parse("SELECT count(_id) GROUP BY protocol FILTER bytes > 1000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ec.Query(*desc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := result.Stats
+	matched := 0
+	for _, event := range testEvents {
+		if event["bytes"].(int) > 1000 {
+			matched++
+		}
+	}
+	if stats.KeysScanned != int64(len(testEvents)) || stats.EventsDecoded != int64(len(testEvents)) ||
+		stats.EventsMatched != int64(matched) || stats.Groups != 1 || stats.BytesRead == 0 || stats.Index != "" {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	for _, phase := range []string{"plan", "scan", "summarize", "order", "series"} {
+		if _, ok := stats.Phases[phase]; !ok {
+			t.Errorf("expected the %s phase in %v", phase, stats.Phases)
+		}
+	}
+
+	// Series points are not counted as groups.
+	desc, err = query.Parse("SELECT count(_id) GROUP BY protocol FILTER bytes > 1000 POINT SIZE 1m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err = ec.Query(*desc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Stats.Groups != 1 {
+		t.Errorf("expected 1 group, got %d", result.Stats.Groups)
+	}
+
+	// EXPLAIN returns the plan without scanning.
+	desc, err = query.Parse(`EXPLAIN SELECT _id FILTER source_address = "172.31.31.192" ORDER BY _ts DESC LIMIT 2`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err = ec.Query(*desc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Events) != 0 || result.Stats.KeysScanned != 0 {
+		t.Errorf("expected EXPLAIN not to scan but got %v and %+v", result.Events, result.Stats)
+	}
+	plan := result.Plan
+	if plan == nil || plan.Scan != "index" || plan.Index != "source_address" || !plan.Reverse ||
+		plan.Order != "reverse key" || plan.Filters != 1 {
+		t.Errorf("unexpected plan %+v", plan)
+	}
+}
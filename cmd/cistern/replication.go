@@ -0,0 +1,707 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Preetam/lm2"
+)
+
+const (
+	// replicationSeqKey holds the sequence number of the last write batch
+	// stored or applied. It is written in the same batch as the events.
+	replicationSeqKey = "_replseq"
+	// replicationLogKeyPrefix prefixes the keys of the write batches
+	// kept for followers, which are followed by the sequence number.
+	// Batches are stored with their events so the log survives restarts.
+	// Only their keys are stored; values are read from the events.
+	replicationLogKeyPrefix = "_repllog\x00"
+
+	// replicationLogSize is the number of event records leaders keep
+	// for followers. Each batch counts as a record too. Followers that
+	// fall further behind resync from a snapshot.
+	replicationLogSize = 100000
+	// replicationMaxRecords limits the number of records in a response.
+	replicationMaxRecords = 10000
+
+	replicationWait       = 30 * time.Second
+	replicationRetryDelay = 5 * time.Second
+)
+
+var (
+	ErrReadOnly     = errors.New("cistern: collection is a read-only follower")
+	ErrNotLeader    = errors.New("cistern: collection is not a leader")
+	ErrNotFollower  = errors.New("cistern: collection is not a follower")
+	ErrResyncNeeded = errors.New("cistern: follower is too far behind")
+
+	followerClient = &http.Client{Timeout: replicationWait + time.Minute}
+
+	// ReplicationNodeID identifies this node to leaders.
+	ReplicationNodeID = ""
+)
+
+// ReplicationRecord is an event record shipped to followers. Keys
+// contain binary timestamps, so they're sent as bytes.
+type ReplicationRecord struct {
+	Key     []byte `json:"key"`
+	Value   string `json:"value,omitempty"`
+	Deleted bool   `json:"deleted,omitempty"` // the event was removed
+}
+
+// ReplicationBatch is a write batch of a leader.
+type ReplicationBatch struct {
+	Sequence int64               `json:"sequence"`
+	Records  []ReplicationRecord `json:"records"`
+	// DeleteBefore is set by compactions of the leader. Events with
+	// keys before it were removed.
+	DeleteBefore []byte `json:"delete_before,omitempty"`
+	// Archive is set by archive runs of the leader to its archive
+	// catalog. Followers save it before applying the batch.
+	Archive *archiveCatalog `json:"archive,omitempty"`
+}
+
+// ReplicationResponse is the response of a leader to a follower.
+type ReplicationResponse struct {
+	Sequence int64              `json:"sequence"` // leader sequence
+	Batches  []ReplicationBatch `json:"batches"`
+	// Checkpoints are the source checkpoints of the leader. They are only
+	// sent once the follower has caught up so they're never ahead of the data.
+	Checkpoints map[string]json.RawMessage `json:"checkpoints,omitempty"`
+}
+
+// ReplicationInfo describes the replication state of a collection.
+type ReplicationInfo struct {
+	Role           string                  `json:"role"` // leader or follower
+	Sequence       int64                   `json:"sequence"`
+	Leader         string                  `json:"leader,omitempty"`
+	LeaderSequence int64                   `json:"leader_sequence,omitempty"`
+	Lag            int64                   `json:"lag"` // write batches behind the leader
+	LastContact    *time.Time              `json:"last_contact,omitempty"`
+	LastError      string                  `json:"last_error,omitempty"`
+	Followers      map[string]FollowerInfo `json:"followers,omitempty"`
+}
+
+// FollowerInfo describes a follower as seen by its leader.
+type FollowerInfo struct {
+	Sequence int64     `json:"sequence"`
+	Lag      int64     `json:"lag"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// replicationLog keeps the most recent write batches of a leader.
+type replicationLog struct {
+	lock    sync.Mutex
+	batches []ReplicationBatch
+	records int // see logSize
+	// updated is closed and replaced when a batch is added.
+	updated chan struct{}
+
+	followers map[string]FollowerInfo
+}
+
+func newReplicationLog() *replicationLog {
+	return &replicationLog{
+		updated:   make(chan struct{}),
+		followers: map[string]FollowerInfo{},
+	}
+}
+
+// logSize is the size of a batch in the log, in records.
+func logSize(batch ReplicationBatch) int {
+	return len(batch.Records) + 1
+}
+
+// overflow returns the sequence numbers of the batches that adding
+// batch drops from the log.
+func (l *replicationLog) overflow(batch ReplicationBatch) []int64 {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	dropped := []int64{}
+	records := l.records + logSize(batch)
+	for _, logged := range l.batches {
+		if records <= replicationLogSize {
+			break
+		}
+		records -= logSize(logged)
+		dropped = append(dropped, logged.Sequence)
+	}
+	return dropped
+}
+
+// storedBytes returns the approximate number of bytes the stored log
+// takes for its batches, and for the records of each event key.
+func (l *replicationLog) storedBytes() (int64, map[string]int64) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	batchBytes := int64(0)
+	recordBytes := map[string]int64{}
+	for _, batch := range l.batches {
+		// The key, sequence number and JSON of the batch.
+		batchBytes += int64(len(replicationLogKeyPrefix) + 20 + 60 + recordOverhead)
+		for _, record := range batch.Records {
+			// The base64 key and its JSON.
+			recordBytes[string(record.Key)] += int64((len(record.Key)+2)/3*4 + 12)
+		}
+	}
+	return batchBytes, recordBytes
+}
+
+// removeBefore removes the records of events with keys before minKey,
+// which a compaction removed, from the batches in the log.
+func (l *replicationLog) removeBefore(minKey string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.records = 0
+	for i, batch := range l.batches {
+		l.batches[i].Records = recordsFrom(batch.Records, minKey)
+		l.records += logSize(l.batches[i])
+	}
+}
+
+// recordsFrom returns the records with keys from minKey on.
+func recordsFrom(records []ReplicationRecord, minKey string) []ReplicationRecord {
+	kept := []ReplicationRecord{}
+	for _, record := range records {
+		if string(record.Key) >= minKey {
+			kept = append(kept, record)
+		}
+	}
+	return kept
+}
+
+// reset replaces the batches in the log.
+func (l *replicationLog) reset(batches []ReplicationBatch) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.batches = batches
+	l.records = 0
+	for _, batch := range batches {
+		l.records += logSize(batch)
+	}
+	close(l.updated)
+	l.updated = make(chan struct{})
+}
+
+func (l *replicationLog) add(batch ReplicationBatch) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.batches = append(l.batches, batch)
+	l.records += logSize(batch)
+	for l.records > replicationLogSize && len(l.batches) > 1 {
+		l.records -= logSize(l.batches[0])
+		l.batches = l.batches[1:]
+	}
+	close(l.updated)
+	l.updated = make(chan struct{})
+}
+
+// since returns the batches after the given sequence, and a channel
+// that is closed when another batch is added.
+func (l *replicationLog) since(after int64, seq int64) ([]ReplicationBatch, <-chan struct{}, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if after == seq {
+		return nil, l.updated, nil
+	}
+	if after > seq || len(l.batches) == 0 || l.batches[0].Sequence > after+1 {
+		return nil, l.updated, ErrResyncNeeded
+	}
+	batches := []ReplicationBatch{}
+	records := 0
+	for _, batch := range l.batches {
+		if batch.Sequence <= after {
+			continue
+		}
+		if records > 0 && records+len(batch.Records) > replicationMaxRecords {
+			break
+		}
+		batches = append(batches, batch)
+		records += len(batch.Records)
+	}
+	return batches, l.updated, nil
+}
+
+func (l *replicationLog) sawFollower(name string, seq int64, leaderSeq int64) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.followers[name] = FollowerInfo{
+		Sequence: seq,
+		Lag:      leaderSeq - seq,
+		LastSeen: time.Now().UTC(),
+	}
+}
+
+// follower is the replication state of a follower collection.
+type follower struct {
+	name   string // collection name
+	leader string // leader API address
+
+	lock        sync.Mutex
+	leaderSeq   int64
+	lastContact time.Time
+	lastError   string
+
+	cancel  context.CancelFunc
+	stopped chan struct{}
+}
+
+// ReplicationSequence returns the sequence number of the last write batch.
+func (c *EventCollection) ReplicationSequence() int64 {
+	return atomic.LoadInt64(&c.replSeq)
+}
+
+// loadReplicationState loads the sequence number and the log of
+// write batches of the collection.
+func (c *EventCollection) loadReplicationState() error {
+	cur, err := c.col.NewCursor()
+	if err != nil {
+		return err
+	}
+	seq := int64(0)
+	val, err := cur.Get(replicationSeqKey)
+	if err == nil {
+		seq, err = strconv.ParseInt(val, 10, 64)
+	}
+	if err != nil && err != lm2.ErrKeyNotFound {
+		return err
+	}
+
+	lookup, err := c.col.NewCursor()
+	if err != nil {
+		return err
+	}
+	batches := []ReplicationBatch{}
+	cur.Seek(replicationLogKeyPrefix)
+	for cur.Next() {
+		if cur.Key() < replicationLogKeyPrefix {
+			continue
+		}
+		if !strings.HasPrefix(cur.Key(), replicationLogKeyPrefix) {
+			break
+		}
+		batch := ReplicationBatch{}
+		err = json.Unmarshal([]byte(cur.Value()), &batch)
+		if err != nil {
+			return err
+		}
+		records := []ReplicationRecord{}
+		for _, record := range batch.Records {
+			if !record.Deleted {
+				record.Value, err = lookup.Get(string(record.Key))
+				if err == lm2.ErrKeyNotFound {
+					// A later batch deletes the event.
+					continue
+				}
+				if err != nil {
+					return err
+				}
+			}
+			records = append(records, record)
+		}
+		batch.Records = records
+		batches = append(batches, batch)
+	}
+	if err = cur.Err(); err != nil {
+		return err
+	}
+	atomic.StoreInt64(&c.replSeq, seq)
+	c.replLog.reset(batches)
+	return nil
+}
+
+func replicationLogKey(seq int64) string {
+	return fmt.Sprintf("%s%020d", replicationLogKeyPrefix, seq)
+}
+
+// logBatch adds batch and the sequence number of the collection to
+// wb. Batches that no longer fit in the log are removed. Once wb is
+// written, commitBatch must be called.
+func (c *EventCollection) logBatch(wb *lm2.WriteBatch, batch ReplicationBatch) error {
+	for _, seq := range c.replLog.overflow(batch) {
+		wb.Delete(replicationLogKey(seq))
+	}
+	logged := batch
+	logged.Records = make([]ReplicationRecord, len(batch.Records))
+	for i, record := range batch.Records {
+		logged.Records[i] = ReplicationRecord{Key: record.Key, Deleted: record.Deleted}
+	}
+	data, err := json.Marshal(logged)
+	if err != nil {
+		return err
+	}
+	wb.Set(replicationLogKey(batch.Sequence), string(data))
+	wb.Set(replicationSeqKey, strconv.FormatInt(batch.Sequence, 10))
+	return nil
+}
+
+// trimLoggedBatch returns a batch stored by logBatch without the records
+// of events with keys before minKey.
+func trimLoggedBatch(data string, minKey string) string {
+	batch := ReplicationBatch{}
+	if json.Unmarshal([]byte(data), &batch) != nil {
+		return data
+	}
+	batch.Records = recordsFrom(batch.Records, minKey)
+	trimmed, err := json.Marshal(batch)
+	if err != nil {
+		return data
+	}
+	return string(trimmed)
+}
+
+// commitBatch makes a batch written with logBatch available to followers.
+func (c *EventCollection) commitBatch(batch ReplicationBatch) {
+	atomic.StoreInt64(&c.replSeq, batch.Sequence)
+	c.replLog.add(batch)
+}
+
+func (c *EventCollection) isFollower() bool {
+	c.followerLock.Lock()
+	defer c.followerLock.Unlock()
+	return c.follower != nil
+}
+
+// ReplicationInfo returns the replication state of the collection.
+func (c *EventCollection) ReplicationInfo() ReplicationInfo {
+	seq := c.ReplicationSequence()
+	c.followerLock.Lock()
+	f := c.follower
+	c.followerLock.Unlock()
+
+	if f == nil {
+		info := ReplicationInfo{Role: "leader", Sequence: seq}
+		c.replLog.lock.Lock()
+		if len(c.replLog.followers) > 0 {
+			info.Followers = map[string]FollowerInfo{}
+			for name, follower := range c.replLog.followers {
+				follower.Lag = seq - follower.Sequence
+				info.Followers[name] = follower
+			}
+		}
+		c.replLog.lock.Unlock()
+		return info
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	info := ReplicationInfo{
+		Role:           "follower",
+		Sequence:       seq,
+		Leader:         f.leader,
+		LeaderSequence: f.leaderSeq,
+		Lag:            f.leaderSeq - seq,
+		LastError:      f.lastError,
+	}
+	if !f.lastContact.IsZero() {
+		lastContact := f.lastContact
+		info.LastContact = &lastContact
+	}
+	return info
+}
+
+// Replicate returns the write batches after the sequence number after,
+// waiting up to wait for new batches if there are none.
+// name identifies the follower for lag reporting.
+func (c *EventCollection) Replicate(name string, after int64, wait time.Duration) (ReplicationResponse, error) {
+	if c.isFollower() {
+		return ReplicationResponse{}, ErrNotLeader
+	}
+
+	// Read checkpoints first. They're written after their events are
+	// stored, so they can't be ahead of the batches read below.
+	checkpoints := map[string]json.RawMessage{}
+	for _, source := range c.Sources() {
+		data, err := ioutil.ReadFile(sourceStateFile(source))
+		if err == nil {
+			checkpoints[source] = data
+		}
+	}
+
+	timeout := time.After(wait)
+	for {
+		seq := c.ReplicationSequence()
+		batches, updated, err := c.replLog.since(after, seq)
+		if err != nil {
+			return ReplicationResponse{}, err
+		}
+		if len(batches) > 0 || wait <= 0 {
+			resp := ReplicationResponse{Sequence: seq, Batches: batches}
+			caughtUp := after == seq
+			if len(batches) > 0 {
+				caughtUp = batches[len(batches)-1].Sequence == seq
+			}
+			if caughtUp && len(checkpoints) > 0 {
+				resp.Checkpoints = checkpoints
+			}
+			c.replLog.sawFollower(name, after, seq)
+			return resp, nil
+		}
+		select {
+		case <-updated:
+		case <-timeout:
+			wait = 0
+		}
+	}
+}
+
+// applyBatch stores a write batch of the leader. Records that are
+// already stored are skipped, and so are deleted records that aren't.
+func (c *EventCollection) applyBatch(batch ReplicationBatch) error {
+	if batch.DeleteBefore != nil {
+		return c.applyDeleteBefore(batch)
+	}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	c.countLock.Lock()
+	defer c.countLock.Unlock()
+
+	if batch.Sequence <= c.ReplicationSequence() {
+		return nil
+	}
+	if batch.Archive != nil {
+		// Events are deleted only once their segments are in the catalog.
+		err := c.saveArchiveCatalog(batch.Archive.Segments)
+		if err != nil {
+			return err
+		}
+	}
+
+	cur, err := c.col.NewCursor()
+	if err != nil {
+		return err
+	}
+	wb := lm2.NewWriteBatch()
+	count := c.EventCount()
+	fch := fieldChanges{}
+	for _, record := range batch.Records {
+		key := string(record.Key)
+		if len(key) == 0 || key[0] != eventKeyPrefix {
+			return errors.New("invalid replicated key")
+		}
+		value, err := cur.Get(key)
+		if err != nil && err != lm2.ErrKeyNotFound {
+			return err
+		}
+		exists := err == nil
+		if exists != record.Deleted {
+			continue
+		}
+		event := Event{}
+		if record.Deleted {
+			// The stored event has the index entries to remove.
+			err = json.Unmarshal([]byte(value), &event)
+			if err != nil {
+				return err
+			}
+			wb.Delete(key)
+			for _, indexKey := range indexEntries(c.indexes, event, key) {
+				wb.Delete(indexKey)
+			}
+			fch.remove(event)
+			count--
+			continue
+		}
+		err = json.Unmarshal([]byte(record.Value), &event)
+		if err != nil {
+			return err
+		}
+		ts, _, _, err := splitCollectionID(key)
+		if err != nil {
+			return err
+		}
+		wb.Set(key, record.Value)
+		for _, indexKey := range indexEntries(c.indexes, event, key) {
+			wb.Set(indexKey, "")
+		}
+		fch.store(event, ts)
+		count++
+	}
+	invalidateFieldCatalog(wb)
+	if count < 0 {
+		count = 0
+	}
+	wb.Set(eventCountKey, strconv.FormatInt(count, 10))
+	err = c.logBatch(wb, batch)
+	if err != nil {
+		return err
+	}
+	_, err = c.col.Update(wb)
+	if err != nil {
+		return err
+	}
+	atomic.StoreInt64(&c.eventCount, count)
+	c.commitBatch(batch)
+	c.applyFieldChanges(fch)
+	return nil
+}
+
+// applyDeleteBefore removes the events a compaction of the leader
+// removed.
+func (c *EventCollection) applyDeleteBefore(batch ReplicationBatch) error {
+	c.snapshotLock.Lock()
+	defer c.snapshotLock.Unlock()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if batch.Sequence <= c.ReplicationSequence() {
+		return nil
+	}
+	return c.compact(string(batch.DeleteBefore), &batch)
+}
+
+// Follow makes the collection a read-only follower of the collection
+// with the same name on the leader at the given API address.
+func (c *EventCollection) Follow(name string, leader string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	f := &follower{
+		name:    name,
+		leader:  leader,
+		cancel:  cancel,
+		stopped: make(chan struct{}),
+	}
+	c.followerLock.Lock()
+	c.follower = f
+	c.followerLock.Unlock()
+
+	go func() {
+		defer close(f.stopped)
+		log.Printf("Following collection %s on %s", name, leader)
+		for ctx.Err() == nil {
+			err := c.pollLeader(ctx, f)
+			f.lock.Lock()
+			if err != nil {
+				f.lastError = err.Error()
+			} else {
+				f.lastError = ""
+				f.lastContact = time.Now().UTC()
+			}
+			f.lock.Unlock()
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Replication of collection %s from %s: %v", name, leader, err)
+				select {
+				case <-time.After(replicationRetryDelay):
+				case <-ctx.Done():
+				}
+			}
+		}
+	}()
+}
+
+// StopFollowing stops replication without promoting the collection.
+func (c *EventCollection) StopFollowing() {
+	c.followerLock.Lock()
+	f := c.follower
+	c.followerLock.Unlock()
+	if f != nil {
+		f.cancel()
+		<-f.stopped
+	}
+}
+
+// Promote stops following the leader and makes the collection writable.
+func (c *EventCollection) Promote() error {
+	c.followerLock.Lock()
+	f := c.follower
+	c.followerLock.Unlock()
+	if f == nil {
+		return ErrNotFollower
+	}
+	f.cancel()
+	<-f.stopped
+
+	c.followerLock.Lock()
+	c.follower = nil
+	close(c.promoted)
+	c.followerLock.Unlock()
+	log.Printf("Collection %s promoted to leader at sequence %d", f.name, c.ReplicationSequence())
+	return nil
+}
+
+// WaitLeader blocks until the collection is a leader. It returns false
+// if done is closed first.
+func (c *EventCollection) WaitLeader(done chan struct{}) bool {
+	if !c.isFollower() {
+		return true
+	}
+	select {
+	case <-c.promoted:
+		return true
+	case <-done:
+		return false
+	}
+}
+
+func (c *EventCollection) pollLeader(ctx context.Context, f *follower) error {
+	params := url.Values{
+		"after":    {strconv.FormatInt(c.ReplicationSequence(), 10)},
+		"wait":     {strconv.Itoa(int(replicationWait / time.Second))},
+		"follower": {ReplicationNodeID},
+	}
+	req, err := http.NewRequest("GET", collectionURL(f.leader, f.name, "replication")+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := followerClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusGone {
+		return c.resync(ctx, f)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("leader returned %s", resp.Status)
+	}
+
+	replResp := ReplicationResponse{}
+	err = json.NewDecoder(resp.Body).Decode(&replResp)
+	if err != nil {
+		return err
+	}
+	for _, batch := range replResp.Batches {
+		err = c.applyBatch(batch)
+		if err != nil {
+			return err
+		}
+	}
+	for source, checkpoint := range replResp.Checkpoints {
+		err = ioutil.WriteFile(sourceStateFile(source), checkpoint, 0600)
+		if err != nil {
+			return err
+		}
+	}
+	f.lock.Lock()
+	f.leaderSeq = replResp.Sequence
+	f.lock.Unlock()
+	return nil
+}
+
+// resync replaces the collection with a snapshot of the leader.
+func (c *EventCollection) resync(ctx context.Context, f *follower) error {
+	log.Printf("Collection %s is too far behind %s; restoring a snapshot", f.name, f.leader)
+	req, err := http.NewRequest("GET", collectionURL(f.leader, f.name, "snapshot"), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("leader returned %s for snapshot", resp.Status)
+	}
+	return RestoreSnapshotTar(resp.Body, f.name)
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Cistern/cistern/internal/query"
+)
+
+func TestReplication(t *testing.T) {
+	leader, err := CreateEventCollection("/tmp/test_cistern_leader.lm2", defaultCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer leader.col.Destroy()
+	follower, err := CreateEventCollection("/tmp/test_cistern_follower.lm2", defaultCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer follower.col.Destroy()
+
+	err = leader.StoreEvents(testEvents[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = leader.StoreEvents(testEvents[2:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Storing the same events again doesn't create a batch.
+	err = leader.StoreEvents(testEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seq := leader.ReplicationSequence(); seq != 2 {
+		t.Fatalf("expected leader sequence 2 but got %d", seq)
+	}
+
+	resp, err := leader.Replicate("follower", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Batches) != 2 || resp.Sequence != 2 {
+		t.Fatalf("expected 2 batches up to sequence 2 but got %+v", resp)
+	}
+	// Send the batches the way followers receive them.
+	body, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp = ReplicationResponse{}
+	err = json.Unmarshal(body, &resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = leader.Replicate("follower", 3, 0); err != ErrResyncNeeded {
+		t.Errorf("expected ErrResyncNeeded but got %v", err)
+	}
+
+	// The leader address is unreachable, so batches are applied by hand.
+	follower.Follow("follower", "127.0.0.1:1")
+	if _, err = follower.StoreNewEvents(testEvents); err != ErrReadOnly {
+		t.Errorf("expected ErrReadOnly but got %v", err)
+	}
+	if _, err = follower.Replicate("other", 0, 0); err != ErrNotLeader {
+		t.Errorf("expected ErrNotLeader but got %v", err)
+	}
+	for _, batch := range resp.Batches {
+		err = follower.applyBatch(batch)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Applying a batch again is a no-op.
+	err = follower.applyBatch(resp.Batches[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if follower.eventCount != leader.eventCount {
+		t.Errorf("expected %d events on the follower but got %d", leader.eventCount, follower.eventCount)
+	}
+	info, err := follower.Info("follower")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Replication.Role != "follower" || info.Replication.Sequence != 2 {
+		t.Errorf("expected follower at sequence 2 but got %+v", info.Replication)
+	}
+	newest, _ := time.Parse(time.RFC3339, "2017-08-01T04:20:00Z")
+	if info.Newest == nil || !info.Newest.Equal(newest) {
+		t.Errorf("expected newest event at %v but got %v", newest, info.Newest)
+	}
+
+	err = follower.Promote()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = follower.Promote(); err != ErrNotFollower {
+		t.Errorf("expected ErrNotFollower but got %v", err)
+	}
+	if !follower.WaitLeader(nil) {
+		t.Error("expected promoted collection to be a leader")
+	}
+	stored, err := follower.StoreNewEvents(testEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored != 0 {
+		t.Errorf("expected replicated events to be duplicates but %d were stored", stored)
+	}
+}
+
+func TestReplicationDeletes(t *testing.T) {
+	leader, err := CreateEventCollection("/tmp/test_cistern_leader_deletes.lm2", defaultCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { leader.col.Destroy() }()
+	follower, err := CreateEventCollection("/tmp/test_cistern_follower_deletes.lm2", defaultCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer follower.col.Destroy()
+	follower.Follow("follower", "127.0.0.1:1")
+	defer follower.StopFollowing()
+
+	err = leader.StoreEvents(testEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Archiving removes events from the leader.
+	cur, err := leader.col.NewCursor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := []archiveRecord{}
+	cur.Seek(string(eventKeyPrefix))
+	for cur.Next() && len(records) < 2 {
+		if cur.Key()[0] == eventKeyPrefix {
+			records = append(records, archiveRecord{key: cur.Key(), value: cur.Value()})
+		}
+	}
+	err = leader.removeArchived(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The log of batches survives a restart.
+	leader.Close()
+	leader, err = OpenEventCollection(leader.filename, defaultCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := leader.Replicate("follower", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Batches) != 2 || resp.Sequence != 2 {
+		t.Fatalf("expected 2 batches up to sequence 2 but got %+v", resp)
+	}
+	// Events deleted by a later batch are left out.
+	if len(resp.Batches[0].Records) != len(testEvents)-2 || resp.Batches[0].Records[0].Value == "" {
+		t.Errorf("expected the events left of the first batch but got %+v", resp.Batches[0])
+	}
+	for _, batch := range resp.Batches {
+		err = follower.applyBatch(batch)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if count := follower.EventCount(); count != int64(len(testEvents)-2) {
+		t.Errorf("expected %d events on the follower but got %d", len(testEvents)-2, count)
+	}
+
+	// Compactions remove events from followers too.
+	leader.SetRetention(30)
+	err = leader.Compact()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = leader.Replicate("follower", 2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Batches) != 1 || resp.Batches[0].DeleteBefore == nil {
+		t.Fatalf("expected a batch that deletes events but got %+v", resp)
+	}
+	err = follower.applyBatch(resp.Batches[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := follower.EventCount(); count != 0 || follower.ReplicationSequence() != 3 {
+		t.Errorf("expected no events at sequence 3 on the follower but got %d at %d",
+			count, follower.ReplicationSequence())
+	}
+	result, err := follower.Query(query.Desc{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Events) != 0 {
+		t.Errorf("expected no events on the follower but got %d", len(result.Events))
+	}
+}
@@ -0,0 +1,474 @@
+package main
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Preetam/lm2"
+)
+
+const (
+	snapshotManifestFile = "manifest.json"
+	snapshotDataFile     = "data.lm2"
+	snapshotArchiveFile  = "archive.json"
+	snapshotSourcesDir   = "sources"
+)
+
+var (
+	ErrHasSources         = errors.New("cistern: collection has attached sources")
+	ErrInvalidSnapshotDir = errors.New("cistern: snapshot directory must be a relative path inside the data directory")
+)
+
+// SnapshotManifest describes the contents of a snapshot.
+type SnapshotManifest struct {
+	Collection string    `json:"collection"`
+	Created    time.Time `json:"created"`
+	Events     int64     `json:"events"`
+	Sources    []string  `json:"sources,omitempty"`
+}
+
+// Snapshot writes a consistent point-in-time copy of the collection, its
+// archive catalog and the checkpoints of its sources to dir, which must
+// not exist yet.
+func (c *EventCollection) Snapshot(name string, dir string) error {
+	err := os.Mkdir(dir, 0700)
+	if err != nil {
+		return err
+	}
+
+	// Compacting closes the file the cursor reads, and archiving
+	// removes events the catalog read here doesn't have, so neither
+	// runs until the copy is done.
+	c.snapshotLock.RLock()
+	defer c.snapshotLock.RUnlock()
+
+	// Hold the write lock while taking the cursor and reading checkpoints
+	// so no batch is stored in between. Checkpoints are written after
+	// their events are stored, so a snapshot never has a checkpoint ahead
+	// of its data.
+	c.lock.Lock()
+	sources := c.Sources()
+	checkpoints := map[string][]byte{}
+	for _, source := range sources {
+		data, err := ioutil.ReadFile(sourceStateFile(source))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			c.lock.Unlock()
+			return err
+		}
+		checkpoints[source] = data
+	}
+	// Segments are added to the catalog before their events are removed,
+	// so every event is either in the copy or in a cataloged segment.
+	catalog, err := ioutil.ReadFile(c.filename + archiveCatalogSuffix)
+	if err != nil && !os.IsNotExist(err) {
+		c.lock.Unlock()
+		return err
+	}
+	cur, err := c.col.NewCursor()
+	events := c.EventCount()
+	c.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	copied, err := copyCollection(cur, filepath.Join(dir, snapshotDataFile))
+	if err != nil {
+		return err
+	}
+	if copied != events {
+		return fmt.Errorf("cistern: snapshot copied %d of %d events", copied, events)
+	}
+
+	if catalog != nil {
+		err = ioutil.WriteFile(filepath.Join(dir, snapshotArchiveFile), catalog, 0600)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(checkpoints) > 0 {
+		err = os.Mkdir(filepath.Join(dir, snapshotSourcesDir), 0700)
+		if err != nil {
+			return err
+		}
+	}
+	for source, data := range checkpoints {
+		err = ioutil.WriteFile(snapshotStateFile(dir, source), data, 0600)
+		if err != nil {
+			return err
+		}
+	}
+
+	manifest, err := json.Marshal(SnapshotManifest{
+		Collection: name,
+		Created:    time.Now().UTC(),
+		Events:     events,
+		Sources:    sources,
+	})
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, snapshotManifestFile), manifest, 0600)
+}
+
+// SnapshotTar writes a snapshot of the collection to w as a tar stream.
+func (c *EventCollection) SnapshotTar(name string, w io.Writer) error {
+	tmpDir, err := ioutil.TempDir(DataDir, ".snapshot-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	dir := filepath.Join(tmpDir, url.PathEscape(name))
+	err = c.Snapshot(name, dir)
+	if err != nil {
+		return err
+	}
+	return writeTar(dir, w)
+}
+
+// copyCollection writes every record visible to cur to a new lm2 file.
+// It returns the number of events copied.
+func copyCollection(cur *lm2.Cursor, filename string) (int64, error) {
+	col, err := lm2.NewCollection(filename, 10)
+	if err != nil {
+		return 0, err
+	}
+	defer col.Close()
+
+	const batchSize = 1000
+	wb := lm2.NewWriteBatch()
+	pending := 0
+	events := int64(0)
+	for cur.Next() {
+		if cur.Key()[0] == eventKeyPrefix {
+			events++
+		}
+		wb.Set(cur.Key(), cur.Value())
+		pending++
+		if pending == batchSize {
+			_, err = col.Update(wb)
+			if err != nil {
+				return events, err
+			}
+			wb = lm2.NewWriteBatch()
+			pending = 0
+		}
+	}
+	if err = cur.Err(); err != nil {
+		return events, err
+	}
+	if pending > 0 {
+		_, err = col.Update(wb)
+	}
+	return events, err
+}
+
+// snapshotDir returns the path of the snapshot directory dir, which the
+// API takes relative to the data directory. Absolute paths and paths
+// that leave the data directory are rejected.
+func snapshotDir(dir string) (string, error) {
+	if dir == "" || filepath.IsAbs(dir) {
+		return "", ErrInvalidSnapshotDir
+	}
+	for _, element := range strings.Split(filepath.ToSlash(dir), "/") {
+		if element == ".." {
+			return "", ErrInvalidSnapshotDir
+		}
+	}
+	clean := filepath.Clean(dir)
+	if clean == "." {
+		return "", ErrInvalidSnapshotDir
+	}
+	return filepath.Join(DataDir, clean), nil
+}
+
+// RestoreSnapshot restores the snapshot in dir as the collection name.
+// An existing collection is replaced, unless it has attached sources.
+// Source checkpoints are only restored under the original collection name.
+func RestoreSnapshot(dir string, name string) error {
+	manifest, err := readSnapshotManifest(dir)
+	if err != nil {
+		return err
+	}
+
+	collectionsLock.Lock()
+	collection := Collections[name]
+	collectionsLock.Unlock()
+
+	if collection != nil {
+		if len(collection.Sources()) > 0 {
+			return ErrHasSources
+		}
+		err = collection.restore(filepath.Join(dir, snapshotDataFile))
+		if err != nil {
+			return err
+		}
+	} else {
+		filename := filepath.Join(DataDir, name+".lm2")
+		// A leftover WAL would be replayed onto the restored file.
+		os.Remove(filename + ".wal")
+		err = copyFile(filepath.Join(dir, snapshotDataFile), filename)
+		if err != nil {
+			return err
+		}
+		collection, err = OpenEventCollection(filename, defaultCacheSize)
+		if err != nil {
+			return err
+		}
+		collection.SetRetention(DefaultRetention)
+		collectionsLock.Lock()
+		Collections[name] = collection
+		collectionsLock.Unlock()
+	}
+	err = collection.restoreArchiveCatalog(dir)
+	if err != nil {
+		return err
+	}
+
+	if name == manifest.Collection {
+		err = restoreCheckpoints(dir, manifest.Sources)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RestoreSnapshotTar restores a snapshot read from a tar stream.
+func RestoreSnapshotTar(r io.Reader, name string) error {
+	tmpDir, err := ioutil.TempDir(DataDir, ".restore-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	dir, err := readTar(r, tmpDir)
+	if err != nil {
+		return err
+	}
+	return RestoreSnapshot(dir, name)
+}
+
+// restore replaces the collection data with the lm2 file at filename.
+func (c *EventCollection) restore(filename string) error {
+	c.snapshotLock.Lock()
+	defer c.snapshotLock.Unlock()
+	c.lock.Lock()
+	c.col.Close()
+	err := copyFile(filename, c.filename+".restore")
+	if err == nil {
+		os.Remove(c.filename + ".wal")
+		err = os.Rename(c.filename+".restore", c.filename)
+	}
+	if err != nil {
+		c.lock.Unlock()
+		return err
+	}
+	col, err := lm2.OpenCollection(c.filename, c.cacheSize)
+	if err != nil {
+		c.lock.Unlock()
+		return err
+	}
+	c.col = col
+	err = c.loadEventCount()
+	if err == nil {
+		err = c.loadReplicationState()
+	}
+	if err == nil {
+		err = c.loadFieldCatalog()
+	}
+	indexes := c.indexes
+	c.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// Build any indexes the snapshot doesn't have.
+	return c.SetIndexes(indexes)
+}
+
+// restoreArchiveCatalog replaces the archive catalog of the collection
+// with the catalog in the snapshot in dir.
+func (c *EventCollection) restoreArchiveCatalog(dir string) error {
+	catalog := archiveCatalog{}
+	data, err := ioutil.ReadFile(filepath.Join(dir, snapshotArchiveFile))
+	if err == nil {
+		err = json.Unmarshal(data, &catalog)
+	}
+	if os.IsNotExist(err) && c.archive == nil {
+		// Nothing was archived.
+		err = os.Remove(c.filename + archiveCatalogSuffix)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return c.saveArchiveCatalog(catalog.Segments)
+}
+
+func restoreCheckpoints(dir string, sources []string) error {
+	for _, source := range sources {
+		data, err := ioutil.ReadFile(snapshotStateFile(dir, source))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		err = ioutil.WriteFile(sourceStateFile(source), data, 0600)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshotStateFile returns the path of a source checkpoint in a snapshot.
+// Source names may contain slashes, so they're escaped.
+func snapshotStateFile(dir string, source string) string {
+	return filepath.Join(dir, snapshotSourcesDir, url.PathEscape(source)+".state")
+}
+
+func readSnapshotManifest(dir string) (SnapshotManifest, error) {
+	manifest := SnapshotManifest{}
+	data, err := ioutil.ReadFile(filepath.Join(dir, snapshotManifestFile))
+	if err != nil {
+		return manifest, err
+	}
+	err = json.Unmarshal(data, &manifest)
+	return manifest, err
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if err != nil {
+		out.Close()
+		return err
+	}
+	err = out.Sync()
+	if err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// writeTar writes the regular files under dir to w. Paths in the
+// archive are prefixed with the base name of dir.
+func writeTar(dir string, w io.Writer) error {
+	tw := tar.NewWriter(w)
+	base := filepath.Dir(dir)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(base, path)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		err = tw.WriteHeader(header)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// readTar extracts a snapshot tar stream into dir and returns the
+// path of the snapshot directory.
+func readTar(r io.Reader, dir string) (string, error) {
+	tr := tar.NewReader(r)
+	snapshotDir := ""
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		name := filepath.Clean(filepath.FromSlash(header.Name))
+		if filepath.IsAbs(name) || strings.HasPrefix(name, "..") {
+			return "", errors.New("invalid path in snapshot: " + header.Name)
+		}
+		path := filepath.Join(dir, name)
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(path, 0700)
+		case tar.TypeReg:
+			err = os.MkdirAll(filepath.Dir(path), 0700)
+			if err == nil {
+				err = writeFile(path, tr)
+			}
+			if filepath.Base(name) == snapshotManifestFile {
+				snapshotDir = filepath.Dir(path)
+			}
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	if snapshotDir == "" {
+		return "", errors.New("snapshot manifest not found")
+	}
+	return snapshotDir, nil
+}
+
+func writeFile(path string, r io.Reader) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
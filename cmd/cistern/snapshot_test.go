@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Cistern/cistern/internal/query"
+	"github.com/Preetam/lm2"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	dataDir, err := ioutil.TempDir("", "cistern-snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataDir)
+	defer func(dir string) { DataDir = dir }(DataDir)
+	DataDir = dataDir
+
+	ec, err := CreateEventCollection(filepath.Join(dataDir, "flowlogs.lm2"), defaultCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ec.Close()
+	err = ec.StoreEvents(testEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec.AttachSource("vpc-flowlogs")
+	err = ioutil.WriteFile(sourceStateFile("vpc-flowlogs"), []byte(`{"last_timestamp":5}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	err = ec.SnapshotTar("flowlogs", buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = RestoreSnapshotTar(buf, "restored")
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := Collections["restored"]
+	defer delete(Collections, "restored")
+	defer restored.Close()
+
+	if restored.EventCount() != int64(len(testEvents)) {
+		t.Errorf("expected %d events but got %d", len(testEvents), restored.EventCount())
+	}
+	result, err := restored.Query(query.Desc{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Events) != len(testEvents) {
+		t.Errorf("expected %d events but got %d", len(testEvents), len(result.Events))
+	}
+}
+
+func TestSnapshotDuringCompaction(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cistern-snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ec, err := CreateEventCollection(filepath.Join(dir, "flowlogs.lm2"), defaultCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ec.Close()
+	const n = 5000
+	start := time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC)
+	events := []Event{}
+	for i := 0; i < n; i++ {
+		events = append(events, Event{"_tag": "a", "_ts": start.Add(time.Duration(i) * time.Second).Format(time.RFC3339), "i": i})
+	}
+	err = ec.StoreEvents(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Compacting closes the file snapshots read, so it waits for them.
+	done := make(chan struct{})
+	compacted := make(chan error)
+	go func() {
+		for {
+			select {
+			case <-done:
+				compacted <- nil
+				return
+			default:
+			}
+			if err := ec.Compact(); err != nil {
+				compacted <- err
+				return
+			}
+		}
+	}()
+	for i := 0; i < 5; i++ {
+		snapshotDir := filepath.Join(dir, "snapshot"+strconv.Itoa(i))
+		err = ec.Snapshot("flowlogs", snapshotDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		col, err := lm2.OpenCollection(filepath.Join(snapshotDir, snapshotDataFile), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cur, err := col.NewCursor()
+		if err != nil {
+			t.Fatal(err)
+		}
+		copied := 0
+		for cur.Next() {
+			if cur.Key()[0] == eventKeyPrefix {
+				copied++
+			}
+		}
+		col.Close()
+		if copied != n {
+			t.Fatalf("expected %d events in the snapshot but got %d", n, copied)
+		}
+	}
+	close(done)
+	if err = <-compacted; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSnapshotDir(t *testing.T) {
+	defer func(dir string) { DataDir = dir }(DataDir)
+	DataDir = "/var/lib/cistern"
+
+	testCases := []struct {
+		dir      string
+		expected string
+	}{
+		{"snapshots/flowlogs", "/var/lib/cistern/snapshots/flowlogs"},
+		{"./flowlogs/", "/var/lib/cistern/flowlogs"},
+		{"", ""},
+		{".", ""},
+		{"/tmp/flowlogs", ""},
+		{"..", ""},
+		{"../flowlogs", ""},
+		{"snapshots/../../flowlogs", ""},
+		{"snapshots/../flowlogs", ""},
+	}
+	for _, c := range testCases {
+		dir, err := snapshotDir(c.dir)
+		if c.expected == "" {
+			if err != ErrInvalidSnapshotDir {
+				t.Errorf("%q: expected ErrInvalidSnapshotDir but got %q, %v", c.dir, dir, err)
+			}
+			continue
+		}
+		if err != nil || dir != c.expected {
+			t.Errorf("%q: expected %q but got %q, %v", c.dir, c.expected, dir, err)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"time"
+
+	"github.com/Cistern/cistern/internal/query"
+	"github.com/Preetam/lm2"
+)
+
+// QueryStats describes the work done by a query.
+type QueryStats struct {
+	KeysScanned   int64  `json:"keys_scanned"`
+	EventsDecoded int64  `json:"events_decoded"`
+	EventsMatched int64  `json:"events_matched"`
+	Groups        int64  `json:"groups"`
+	BytesRead     int64  `json:"bytes_read"`
+	Index         string `json:"index,omitempty"`
+	Segments      int    `json:"segments"`
+	// Records read and cache hits and misses of the collection during
+	// the query. Concurrent queries share these counters.
+	RecordsRead uint64 `json:"records_read"`
+	CacheHits   uint64 `json:"cache_hits"`
+	CacheMisses uint64 `json:"cache_misses"`
+	// Phases is the wall time of each phase of the query in
+	// milliseconds.
+	Phases map[string]float64 `json:"phases_ms"`
+
+	colStats lm2.Stats
+}
+
+func newQueryStats(colStats lm2.Stats) *QueryStats {
+	return &QueryStats{
+		Phases:   map[string]float64{},
+		colStats: colStats,
+	}
+}
+
+// phase records the time since start as the wall time of a phase and
+// returns the start of the next one.
+func (s *QueryStats) phase(name string, start time.Time) time.Time {
+	now := time.Now()
+	s.Phases[name] += float64(now.Sub(start)) / float64(time.Millisecond)
+	return now
+}
+
+// finish records the last phase and the collection stats since the
+// query started.
+func (s *QueryStats) finish(colStats lm2.Stats, name string, start time.Time) *QueryStats {
+	s.phase(name, start)
+	s.RecordsRead = colStats.RecordsRead - s.colStats.RecordsRead
+	s.CacheHits = colStats.CacheHits - s.colStats.CacheHits
+	s.CacheMisses = colStats.CacheMisses - s.colStats.CacheMisses
+	return s
+}
+
+// add adds the stats of a partial query on another node. Nodes run in
+// parallel, so phases take the longest time.
+func (s *QueryStats) add(other *QueryStats) {
+	if other == nil {
+		return
+	}
+	s.KeysScanned += other.KeysScanned
+	s.EventsDecoded += other.EventsDecoded
+	s.EventsMatched += other.EventsMatched
+	s.Groups += other.Groups
+	s.BytesRead += other.BytesRead
+	s.Segments += other.Segments
+	s.RecordsRead += other.RecordsRead
+	s.CacheHits += other.CacheHits
+	s.CacheMisses += other.CacheMisses
+	if s.Index == "" {
+		s.Index = other.Index
+	}
+	for name, ms := range other.Phases {
+		if ms > s.Phases[name] {
+			s.Phases[name] = ms
+		}
+	}
+}
+
+// QueryExplain describes how a query would run.
+type QueryExplain struct {
+	// Scan is "index" if events are read through an index, or "range"
+	// if every event in the time range is read.
+	Scan       string    `json:"scan"`
+	Index      string    `json:"index,omitempty"`
+	Reverse    bool      `json:"reverse"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	Segments   int       `json:"segments"`
+	Filters    int       `json:"filters"`
+	Aggregates []string  `json:"aggregates,omitempty"`
+	GroupBy    []string  `json:"group_by,omitempty"`
+	// Order is how results are ordered: "key" or "reverse key" for
+	// events in the order they're scanned, "sort" or "top-n" for
+	// sorted events, and "groups" for summary rows.
+	Order string `json:"order"`
+}
+
+// explain describes how desc would be executed by plan without running
+// it.
+func (c *EventCollection) explain(desc query.Desc, plan *queryPlan, reverse bool) *QueryExplain {
+	e := &QueryExplain{
+		Scan:    "range",
+		Reverse: reverse,
+		Start:   desc.TimeRange.Start,
+		End:     desc.TimeRange.End,
+		Filters: len(desc.Filters),
+		Order:   "key",
+	}
+	if filter, ok := c.indexedFilter(desc.Filters); ok {
+		e.Scan = "index"
+		e.Index = filter.Column
+	}
+	if c.archive != nil {
+		startTs := formatTs(toMicrosecondTime(desc.TimeRange.Start))
+		endTs := formatTs(toMicrosecondTime(desc.TimeRange.End))
+		e.Segments = len(c.archive.segmentsBetween(
+			string(eventKeyPrefix)+string(startTs[:]),
+			string(eventKeyPrefix)+string(endTs[:])+"\xff"))
+	}
+	for _, call := range plan.aggregates {
+		e.Aggregates = append(e.Aggregates, call.key)
+	}
+	for _, column := range desc.GroupBy {
+		e.GroupBy = append(e.GroupBy, exprString(columnExpr(column)))
+	}
+	switch {
+	case plan.aggregated:
+		e.Order = "groups"
+	case reverse:
+		e.Order = "reverse key"
+	case len(desc.OrderBy) > 0 && desc.Limit > 0:
+		e.Order = "top-n"
+	case len(desc.OrderBy) > 0:
+		e.Order = "sort"
+	}
+	return e
+}
@@ -1,10 +1,17 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"log"
+	"os"
 	"regexp"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Preetam/lm2"
@@ -12,143 +19,587 @@ import (
 
 var (
 	ErrDoesNotExist = errors.New("cistern: does not exist")
+	ErrLowDisk      = errors.New("cistern: low disk space")
+
+	errInvalidTag = invalidEventError("invalid tag")
 
 	eventIDTagRegexp = regexp.MustCompile("^[a-zA-Z0-9_./-]{1,256}$")
 
+	// collectionNameRegexp matches names that are safe to use as file names.
+	collectionNameRegexp = regexp.MustCompile("^[a-zA-Z0-9_-][a-zA-Z0-9_.-]{0,255}$")
+
 	minTimestamp = time.Unix(0, 0)
+	// maxTimestamp is the latest time that can be marshaled to JSON.
+	maxTimestamp = time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC)
 )
 
 const (
 	eventKeyPrefix byte = 'e'
+
+	defaultCacheSize = 10000000000
+
+	// eventCountKey holds the number of stored events. It lives in the
+	// reserved '_' key space, which sorts before all event keys.
+	eventCountKey = "_count"
+
+	// recordOverhead is the approximate number of bytes lm2 uses
+	// for each record in addition to the key and value.
+	recordOverhead = 48
+	// fileOverhead is the size of the lm2 file header.
+	fileOverhead = 512
 )
 
-// Event represents a flat JSON event object.
+// lowDisk is set to 1 when the data directory, or another directory
+// with collection files, is low on disk space.
+// Ingestion is paused while it is set.
+var lowDisk int32
+
+// Event represents a JSON event object. Nested fields are
+// addressed with paths; see Lookup.
 type Event map[string]interface{}
 
+// EventStore stores events. It returns the number of events
+// that weren't already stored.
+type EventStore interface {
+	StoreNewEvents(events []Event) (int, error)
+}
+
 type EventCollection struct {
-	filename  string
-	col       *lm2.Collection
-	retention int // event retention in days
-	lock      sync.RWMutex
+	filename   string
+	cacheSize  int
+	col        *lm2.Collection
+	indexes    []string
+	flatten    bool  // flatten nested fields at ingest
+	retention  int   // event retention in days
+	maxBytes   int64 // maximum disk usage in bytes
+	maxEvents  int64 // maximum number of events
+	eventCount int64
+	countLock  sync.Mutex
+	lock       sync.RWMutex
+	// snapshotLock is read locked by snapshots while they copy the
+	// collection, and locked by whatever replaces its data or moves
+	// events to the archive. It is taken before lock.
+	snapshotLock sync.RWMutex
+
+	sources     []string // names of the sources feeding the collection
+	sourcesLock sync.Mutex
+
+	replSeq      int64 // sequence number of the last write batch
+	replLog      *replicationLog
+	follower     *follower // set while following a leader
+	followerLock sync.Mutex
+	promoted     chan struct{}
+
+	archive *archive // set if old events are archived
+
+	fieldCatalog *fieldCatalog
 }
 
-func OpenEventCollection(filename string) (*EventCollection, error) {
-	col, err := lm2.OpenCollection(filename, 10000000000)
+func newEventCollection(filename string, cacheSize int, col *lm2.Collection) *EventCollection {
+	return &EventCollection{
+		filename:  filename,
+		cacheSize: cacheSize,
+		col:       col,
+		replLog:   newReplicationLog(),
+		promoted:  make(chan struct{}),
+
+		fieldCatalog: newFieldCatalog(),
+	}
+}
+
+func OpenEventCollection(filename string, cacheSize int) (*EventCollection, error) {
+	col, err := lm2.OpenCollection(filename, cacheSize)
 	if err != nil {
 		if err == lm2.ErrDoesNotExist {
 			return nil, ErrDoesNotExist
 		}
 		return nil, err
 	}
-	return &EventCollection{
-		filename: filename,
-		col:      col,
-	}, nil
+	c := newEventCollection(filename, cacheSize, col)
+	err = c.loadEventCount()
+	if err == nil {
+		err = c.loadReplicationState()
+	}
+	if err == nil {
+		err = c.loadFieldCatalog()
+	}
+	if err != nil {
+		col.Close()
+		return nil, err
+	}
+	return c, nil
 }
 
-func CreateEventCollection(filename string) (*EventCollection, error) {
-	col, err := lm2.NewCollection(filename, 10000000000)
+func CreateEventCollection(filename string, cacheSize int) (*EventCollection, error) {
+	col, err := lm2.NewCollection(filename, cacheSize)
 	if err != nil {
 		return nil, err
 	}
-	return &EventCollection{
-		filename: filename,
-		col:      col,
-	}, nil
+	return newEventCollection(filename, cacheSize, col), nil
+}
+
+// OpenOrCreateEventCollection opens the collection at filename,
+// creating it if it doesn't exist.
+func OpenOrCreateEventCollection(filename string, cacheSize int) (*EventCollection, error) {
+	c, err := OpenEventCollection(filename, cacheSize)
+	if err == ErrDoesNotExist {
+		c, err = CreateEventCollection(filename, cacheSize)
+	}
+	return c, err
+}
+
+// Close stops replication, writes the field catalog and closes the collection.
+func (c *EventCollection) Close() {
+	c.StopFollowing()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	err := c.flushFieldCatalog()
+	if err != nil {
+		log.Printf("Couldn't write the field catalog of %s: %v", c.filename, err)
+	}
+	c.col.Close()
+}
+
+// SetFlatten sets whether nested objects and arrays of new events are
+// flattened into top-level fields named by their paths.
+func (c *EventCollection) SetFlatten(flatten bool) {
+	c.flatten = flatten
 }
 
 func (c *EventCollection) SetRetention(days int) {
 	c.retention = days
 }
 
+// SetMaxBytes sets the maximum disk usage of the collection.
+// Zero means no limit.
+func (c *EventCollection) SetMaxBytes(maxBytes int64) {
+	c.maxBytes = maxBytes
+}
+
+// SetMaxEvents sets the maximum number of events kept in the collection.
+// Zero means no limit.
+func (c *EventCollection) SetMaxEvents(maxEvents int64) {
+	c.maxEvents = maxEvents
+}
+
+// AttachSource records that the named source stores events
+// in the collection.
+func (c *EventCollection) AttachSource(name string) {
+	c.sourcesLock.Lock()
+	defer c.sourcesLock.Unlock()
+	c.sources = append(c.sources, name)
+}
+
+// Sources returns the names of the sources attached to the collection.
+func (c *EventCollection) Sources() []string {
+	c.sourcesLock.Lock()
+	defer c.sourcesLock.Unlock()
+	return append([]string(nil), c.sources...)
+}
+
+// EventCount returns the number of events stored in the collection.
+func (c *EventCollection) EventCount() int64 {
+	return atomic.LoadInt64(&c.eventCount)
+}
+
+// DiskUsage returns the number of bytes used by the collection's
+// data file and WAL.
+func (c *EventCollection) DiskUsage() (int64, error) {
+	usage := int64(0)
+	for _, filename := range []string{c.filename, c.filename + ".wal"} {
+		info, err := os.Stat(filename)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return 0, err
+		}
+		usage += info.Size()
+	}
+	return usage, nil
+}
+
+// ExceedsLimits returns true if the collection is over its
+// size or event count limits.
+func (c *EventCollection) ExceedsLimits() bool {
+	if c.maxEvents > 0 && c.EventCount() > c.maxEvents {
+		return true
+	}
+	if c.maxBytes > 0 {
+		usage, err := c.DiskUsage()
+		if err == nil && usage > c.maxBytes {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *EventCollection) loadEventCount() error {
+	cur, err := c.col.NewCursor()
+	if err != nil {
+		return err
+	}
+	val, err := cur.Get(eventCountKey)
+	if err != nil {
+		if err == lm2.ErrKeyNotFound {
+			return nil
+		}
+		return err
+	}
+	count, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return err
+	}
+	atomic.StoreInt64(&c.eventCount, count)
+	return nil
+}
+
+// StoreEvents stores events in the collection. Events that are already
+// stored are skipped, so batches can safely be stored again.
 func (c *EventCollection) StoreEvents(events []Event) error {
+	_, err := c.StoreNewEvents(events)
+	return err
+}
+
+// StoreNewEvents stores events in the collection and returns the number
+// of events that weren't already stored.
+//
+// An event is identified by its timestamp, tag and _hash. Sources set
+// _hash to an ID they provide; events without one are identified by a
+// hash of their content. If different events end up with the same key,
+// the later one is stored under the same key with a "~n" suffix, so no
+// event is ever overwritten.
+func (c *EventCollection) StoreNewEvents(events []Event) (int, error) {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
 
+	if atomic.LoadInt32(&lowDisk) == 1 {
+		return 0, ErrLowDisk
+	}
+	if c.isFollower() {
+		return 0, ErrReadOnly
+	}
+
 	// Validate tags
 	for _, e := range events {
 		tag, ok := e["_tag"].(string)
 		if !ok {
-			return errors.New("invalid tag")
+			return 0, errInvalidTag
 		}
 		if !eventIDTagRegexp.MatchString(tag) {
-			return errors.New("invalid tag")
+			return 0, errInvalidTag
 		}
 	}
 
+	// Writers are serialized so that looking up existing events and
+	// updating the count happen together with the write.
+	c.countLock.Lock()
+	defer c.countLock.Unlock()
+
+	cur, err := c.col.NewCursor()
+	if err != nil {
+		return 0, err
+	}
+
 	wb := lm2.NewWriteBatch()
+	batch := map[string]string{}
+	records := []ReplicationRecord{}
+	fch := fieldChanges{}
+	stored := 0
 	for _, event := range events {
+		if c.flatten {
+			event = flattenEvent(event)
+		}
 		delete(event, "_id")
-		tag := event["_tag"].(string)
+		baseKey, value, err := eventKey(event)
+		if err != nil {
+			return 0, err
+		}
 
-		marshalled, err := json.Marshal(event)
+		key, exists, err := findEvent(cur, batch, baseKey, value)
 		if err != nil {
-			return err
+			return 0, err
+		}
+		if exists {
+			continue
+		}
+
+		ts, _, _, err := splitCollectionID(key)
+		if err != nil {
+			return 0, err
 		}
+		batch[key] = value
+		records = append(records, ReplicationRecord{Key: []byte(key), Value: value})
+		wb.Set(key, value)
+		for _, indexKey := range indexEntries(c.indexes, event, key) {
+			wb.Set(indexKey, "")
+		}
+		fch.store(event, ts)
+		stored++
+	}
+
+	if stored == 0 {
+		return 0, nil
+	}
+	invalidateFieldCatalog(wb)
+	count := c.EventCount() + int64(stored)
+	wb.Set(eventCountKey, strconv.FormatInt(count, 10))
+	replBatch := ReplicationBatch{Sequence: c.ReplicationSequence() + 1, Records: records}
+	err = c.logBatch(wb, replBatch)
+	if err != nil {
+		return 0, err
+	}
 
-		var ts int64
-		if tsVal, ok := event["_ts"]; ok {
-			if tsString, ok := tsVal.(string); ok {
-				timeTs, err := time.Parse(time.RFC3339Nano, tsString)
-				if err != nil {
-					return errors.New("ts is not formatted per RFC 3339")
-				}
-				if timeTs.Before(minTimestamp) {
-					return errors.New("ts before Unix epoch")
-				}
-				ts = toMicrosecondTime(timeTs)
-			} else {
-				return errors.New("ts is not a string")
+	_, err = c.col.Update(wb)
+	if err != nil {
+		return 0, err
+	}
+	atomic.StoreInt64(&c.eventCount, count)
+	c.commitBatch(replBatch)
+	c.applyFieldChanges(fch)
+
+	return stored, nil
+}
+
+// invalidEventError is returned for events that can't be stored
+// because of their content, as opposed to failures of the server.
+type invalidEventError string
+
+func (e invalidEventError) Error() string {
+	return string(e)
+}
+
+// eventKey returns the key and marshaled value of an event.
+func eventKey(event Event) (string, string, error) {
+	tag := event["_tag"].(string)
+
+	marshalled, err := json.Marshal(event)
+	if err != nil {
+		return "", "", err
+	}
+
+	var ts int64
+	if tsVal, ok := event["_ts"]; ok {
+		if tsString, ok := tsVal.(string); ok {
+			timeTs, err := time.Parse(time.RFC3339Nano, tsString)
+			if err != nil {
+				return "", "", invalidEventError("ts is not formatted per RFC 3339")
+			}
+			if timeTs.Before(minTimestamp) {
+				return "", "", invalidEventError("ts before Unix epoch")
 			}
+			ts = toMicrosecondTime(timeTs)
 		} else {
-			return errors.New("missing event ts")
+			return "", "", invalidEventError("ts is not a string")
 		}
+	} else {
+		return "", "", invalidEventError("missing event ts")
+	}
+
+	hash := ""
+	if hashValue, ok := event["_hash"]; ok {
+		if hashString, ok := hashValue.(string); ok {
+			hash = hashString
+		}
+	}
+	if strings.Contains(hash, "|") {
+		return "", "", invalidEventError("invalid hash")
+	}
+	if hash == "" {
+		hash = contentHash(marshalled)
+	}
+
+	formattedTs := formatTs(ts)
+	key := string(eventKeyPrefix) + string(formattedTs[:]) + "|" + tag + "|" + hash
+	return key, string(marshalled), nil
+}
+
+// contentHash identifies an event by its marshaled content. Map keys
+// are marshaled in sorted order, so equal events have equal hashes.
+func contentHash(marshalled []byte) string {
+	sum := sha256.Sum256(marshalled)
+	return hex.EncodeToString(sum[:8])
+}
 
-		hash := ""
-		if hashValue, ok := event["_hash"]; ok {
-			if hashString, ok := hashValue.(string); ok {
-				hash = hashString
+// findEvent returns the key to store an event with the given base key
+// and value under, and whether the event is already stored there.
+// batch holds the events that are about to be stored.
+func findEvent(cur *lm2.Cursor, batch map[string]string, baseKey string, value string) (string, bool, error) {
+	for i := 0; ; i++ {
+		key := baseKey
+		if i > 0 {
+			key += "~" + strconv.Itoa(i)
+		}
+
+		existing, ok := batch[key]
+		if !ok {
+			var err error
+			existing, err = cur.Get(key)
+			if err == lm2.ErrKeyNotFound {
+				return key, false, nil
+			}
+			if err != nil {
+				return "", false, err
 			}
 		}
+		if existing == value {
+			return key, true, nil
+		}
+	}
+}
+
+// Compact removes events outside of the retention period and evicts the
+// oldest events until the collection is within its size and event count limits.
+func (c *EventCollection) Compact() error {
+	c.snapshotLock.Lock()
+	defer c.snapshotLock.Unlock()
+	c.lock.Lock()
+	defer c.lock.Unlock()
 
-		formattedTs := formatTs(ts)
-		idStr := string(eventKeyPrefix) + string(formattedTs[:]) + "|" + tag + "|" + hash
-		wb.Set(idStr, string(marshalled))
+	minTs := minTimestamp
+	if c.retention > 0 {
+		minTs = time.Now().Add(-1 * time.Duration(c.retention) * 24 * time.Hour)
 	}
+	// Sizes are estimates, so a collection can still be over its size
+	// limit after compacting. It is compacted again for a size smaller
+	// by as much as it was over.
+	maxBytes := c.maxBytes
+	for i := 0; ; i++ {
+		err := c.compactWithin(minTs, maxBytes)
+		if err != nil || c.maxBytes <= 0 || i == maxCompactions-1 {
+			return err
+		}
+		usage, err := c.DiskUsage()
+		if err != nil || usage <= c.maxBytes {
+			return err
+		}
+		maxBytes = int64(float64(maxBytes) * float64(c.maxBytes) / float64(usage))
+	}
+}
 
-	_, err := c.col.Update(wb)
+// maxCompactions is the most times Compact compacts to get the
+// collection under its size limit.
+const maxCompactions = 3
+
+// compactWithin removes events before minTs and evicts the oldest events
+// until the collection is estimated to take at most maxBytes and is
+// within its event count limit. The caller must hold the collection
+// write lock.
+func (c *EventCollection) compactWithin(minTs time.Time, maxBytes int64) error {
+	minKey, err := c.evictionKey(minTs, maxBytes)
 	if err != nil {
 		return err
 	}
+	formattedTs := formatTs(toMicrosecondTime(minTs))
+	if retentionKey := string(eventKeyPrefix) + string(formattedTs[:]); retentionKey > minKey {
+		minKey = retentionKey
+	}
+	if !c.isFollower() {
+		// The batch is written before compacting, which reclaims the
+		// space it takes in the write-ahead log.
+		err = c.logDeleteBefore(minKey)
+		if err != nil {
+			return err
+		}
+	}
+	return c.compact(minKey, nil)
+}
+
+// logDeleteBefore sends followers a batch that removes the events with
+// keys before minKey, if there are any.
+func (c *EventCollection) logDeleteBefore(minKey string) error {
+	cur, err := c.col.NewCursor()
+	if err != nil {
+		return err
+	}
+	oldest := ""
+	cur.Seek(string(eventKeyPrefix))
+	for cur.Next() {
+		if cur.Key() < string(eventKeyPrefix) {
+			continue
+		}
+		if cur.Key()[0] == eventKeyPrefix {
+			oldest = cur.Key()
+		}
+		break
+	}
+	if err = cur.Err(); err != nil {
+		return err
+	}
+	if oldest == "" || oldest >= minKey {
+		return nil
+	}
 
+	wb := lm2.NewWriteBatch()
+	batch := ReplicationBatch{Sequence: c.ReplicationSequence() + 1, DeleteBefore: []byte(minKey)}
+	err = c.logBatch(wb, batch)
+	if err != nil {
+		return err
+	}
+	_, err = c.col.Update(wb)
+	if err != nil {
+		return err
+	}
+	c.commitBatch(batch)
 	return nil
 }
 
-func (c *EventCollection) Compact() error {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-
-	minTs := time.Now().Add(-1 * time.Duration(c.retention) * 24 * time.Hour)
+// compact rewrites the collection without the events with keys before
+// minKey. batch is the compaction of the leader when a follower applies
+// it. The caller must hold the collection write lock.
+func (c *EventCollection) compact(minKey string, batch *ReplicationBatch) error {
+	count := int64(0)
+	// The field catalog is rebuilt from the kept events, which also
+	// drops what it counted of removed events.
+	fc := newFieldCatalog()
 	err := c.col.CompactFunc(func(key string, value string) (string, string, bool) {
-		if key[0] != eventKeyPrefix {
+		if key == eventCountKey || key == fieldCatalogBuiltKey || strings.HasPrefix(key, fieldCatalogKeyPrefix) {
+			return "", "", false
+		}
+		if key[0] == legacyIndexKeyPrefix || strings.HasPrefix(key, legacyIndexBuiltKeyPrefix) {
+			return "", "", false
+		}
+		if strings.HasPrefix(key, indexBuiltKeyPrefix) {
+			if !c.isIndexed(key[len(indexBuiltKeyPrefix):]) {
+				return "", "", false
+			}
 			return key, value, true
 		}
+		if strings.HasPrefix(key, replicationLogKeyPrefix) {
+			return key, trimLoggedBatch(value, minKey), true
+		}
 
-		ts, _, _, err := splitCollectionID(key)
-		if err != nil {
+		eventKey := key
+		if field, indexedKey, ok := splitIndexKey(key); ok {
+			// Drop entries of removed indexes and evicted events.
+			if !c.isIndexed(field) {
+				return "", "", false
+			}
+			eventKey = indexedKey
+		} else if key[0] != eventKeyPrefix {
 			return key, value, true
 		}
-		if fromMicrosecondTime(ts).Before(minTs) {
+
+		if eventKey < minKey {
 			return "", "", false
 		}
 
+		if eventKey == key {
+			count++
+			ts, tag, _, err := splitCollectionID(key)
+			event := Event{}
+			if err == nil && json.Unmarshal([]byte(value), &event) == nil {
+				event["_tag"] = tag
+				fc.observe(event, ts)
+			}
+		}
 		return key, value, true
 	})
 	if err != nil {
 		return err
 	}
 
-	col, err := lm2.OpenCollection(c.filename, 10000000000)
+	col, err := lm2.OpenCollection(c.filename, c.cacheSize)
 	if err != nil {
 		if err == lm2.ErrDoesNotExist {
 			return ErrDoesNotExist
@@ -156,5 +607,122 @@ func (c *EventCollection) Compact() error {
 		return err
 	}
 	c.col = col
+	c.replLog.removeBefore(minKey)
+	// The catalog is written like other changes. Until then, it's
+	// rebuilt on open.
+	c.fieldCatalog.replace(fc)
+
+	wb := lm2.NewWriteBatch()
+	wb.Set(eventCountKey, strconv.FormatInt(count, 10))
+	if batch != nil {
+		err = c.logBatch(wb, *batch)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = c.col.Update(wb)
+	if err != nil {
+		return err
+	}
+	atomic.StoreInt64(&c.eventCount, count)
+	if batch != nil {
+		c.commitBatch(*batch)
+	}
 	return nil
 }
+
+// recordBytes returns the approximate size of a record in an lm2 file.
+// lm2 writes a record again for each skip list level above the first,
+// which about one in ten records has. Levels are random, so this allows
+// for a quarter more.
+func recordBytes(key, value string) int64 {
+	return int64(len(key)+len(value)+recordOverhead) * 5 / 4
+}
+
+// evictionKey returns the smallest event key that can be kept without
+// exceeding maxBytes or the event count limit of the collection. Events
+// at or after minTs are considered.
+func (c *EventCollection) evictionKey(minTs time.Time, maxBytes int64) (string, error) {
+	if maxBytes <= 0 && c.maxEvents <= 0 {
+		return "", nil
+	}
+
+	formattedTs := formatTs(toMicrosecondTime(minTs))
+	startKey := string(eventKeyPrefix) + string(formattedTs[:])
+
+	// First pass: total size of the records compacting keeps, and
+	// count of the events.
+	cur, err := c.col.NewCursor()
+	if err != nil {
+		return "", err
+	}
+	// Events in the replication log take space there too.
+	logBytes, logRecordBytes := c.replLog.storedBytes()
+	totalBytes := int64(fileOverhead) + logBytes
+	totalEvents := int64(0)
+	for cur.Next() {
+		key := cur.Key()
+		switch {
+		case key == "" || strings.HasPrefix(key, replicationLogKeyPrefix):
+			// Log records are counted in logBytes.
+			continue
+		case key[0] == legacyIndexKeyPrefix || strings.HasPrefix(key, legacyIndexBuiltKeyPrefix):
+			continue
+		case key[0] == eventKeyPrefix:
+			if key < startKey {
+				continue
+			}
+			totalEvents++
+		default:
+			if field, eventKey, ok := splitIndexKey(key); ok && (eventKey < startKey || !c.isIndexed(field)) {
+				continue
+			}
+		}
+		totalBytes += recordBytes(key, cur.Value()) + logRecordBytes[key]
+	}
+	if err = cur.Err(); err != nil {
+		return "", err
+	}
+
+	excessBytes := int64(0)
+	if maxBytes > 0 && totalBytes > maxBytes {
+		excessBytes = totalBytes - maxBytes
+	}
+	excessEvents := int64(0)
+	if c.maxEvents > 0 && totalEvents > c.maxEvents {
+		excessEvents = totalEvents - c.maxEvents
+	}
+	if excessBytes == 0 && excessEvents == 0 {
+		return "", nil
+	}
+
+	// Second pass: drop the oldest events until we're within the limits.
+	cur, err = c.col.NewCursor()
+	if err != nil {
+		return "", err
+	}
+	cur.Seek(startKey)
+	for cur.Next() {
+		if cur.Key() < startKey || cur.Key()[0] != eventKeyPrefix {
+			continue
+		}
+		if excessBytes <= 0 && excessEvents <= 0 {
+			return cur.Key(), nil
+		}
+		excessBytes -= recordBytes(cur.Key(), cur.Value()) + logRecordBytes[cur.Key()]
+		// The index entries of the event go with it.
+		event := Event{}
+		if json.Unmarshal([]byte(cur.Value()), &event) == nil {
+			for _, indexKey := range indexEntries(c.indexes, event, cur.Key()) {
+				excessBytes -= recordBytes(indexKey, "")
+			}
+		}
+		excessEvents--
+	}
+	if err = cur.Err(); err != nil {
+		return "", err
+	}
+
+	// Everything has to go.
+	return string(eventKeyPrefix + 1), nil
+}
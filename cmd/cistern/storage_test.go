@@ -0,0 +1,210 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cistern/cistern/internal/query"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+func TestMaxEvents(t *testing.T) {
+	ec, err := CreateEventCollection("/tmp/test_cistern_max_events.lm2", defaultCacheSize)
+	defer ec.col.Destroy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ec.StoreEvents(testEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ec.EventCount() != int64(len(testEvents)) {
+		t.Errorf("expected %d events but got %d", len(testEvents), ec.EventCount())
+	}
+
+	const maxEvents = 3
+	ec.SetMaxEvents(maxEvents)
+	if !ec.ExceedsLimits() {
+		t.Fatal("expected collection to exceed its limits")
+	}
+	err = ec.Compact()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ec.EventCount() != maxEvents {
+		t.Errorf("expected %d events but got %d", maxEvents, ec.EventCount())
+	}
+
+	result, err := ec.Query(query.Desc{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Events) != maxEvents {
+		t.Fatalf("expected %d events but got %d", maxEvents, len(result.Events))
+	}
+	// The oldest events should have been evicted.
+	expected, _ := time.Parse(time.RFC3339, testEvents[len(testEvents)-maxEvents]["_ts"].(string))
+	if ts := result.Events[0]["_ts"].(time.Time); !ts.Equal(expected) {
+		t.Errorf("expected oldest event at %v but got %v", expected, ts)
+	}
+}
+
+func TestMaxBytes(t *testing.T) {
+	ec, err := CreateEventCollection("/tmp/test_cistern_max_bytes.lm2", defaultCacheSize)
+	defer ec.col.Destroy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ec.StoreEvents(testEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The limit leaves room for the replication log.
+	const maxBytes = 2048
+	ec.SetMaxBytes(maxBytes)
+	err = ec.Compact()
+	if err != nil {
+		t.Fatal(err)
+	}
+	usage, err := ec.DiskUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage > maxBytes {
+		t.Errorf("expected at most %d bytes but got %d", maxBytes, usage)
+	}
+	if ec.EventCount() == 0 || ec.EventCount() == int64(len(testEvents)) {
+		t.Errorf("expected some events to be evicted, got %d", ec.EventCount())
+	}
+}
+
+func TestMaxBytesIndexed(t *testing.T) {
+	ec, err := CreateEventCollection("/tmp/test_cistern_max_bytes_indexed.lm2", defaultCacheSize)
+	defer ec.col.Destroy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ec.SetIndexes([]string{"source_address", "dest_address", "protocol"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := []Event{}
+	start := time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 500; i++ {
+		event := Event{}
+		for k, v := range testEvents[i%len(testEvents)] {
+			event[k] = v
+		}
+		event["_ts"] = start.Add(time.Duration(i) * time.Second).Format(time.RFC3339)
+		events = append(events, event)
+	}
+	err = ec.StoreEvents(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Evicting events also frees their index entries, so one compaction
+	// gets the collection under its limit.
+	const maxBytes = 64 << 10
+	ec.SetMaxBytes(maxBytes)
+	err = ec.Compact()
+	if err != nil {
+		t.Fatal(err)
+	}
+	usage, err := ec.DiskUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage > maxBytes {
+		t.Errorf("expected at most %d bytes but got %d", maxBytes, usage)
+	}
+	if ec.EventCount() == 0 || ec.EventCount() == int64(len(events)) {
+		t.Errorf("expected some events to be evicted, got %d", ec.EventCount())
+	}
+}
+
+func TestEventIdentity(t *testing.T) {
+	ec, err := CreateEventCollection("/tmp/test_cistern_event_identity.lm2", defaultCacheSize)
+	defer ec.col.Destroy()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two flow records of the same stream with the same start time.
+	stream := "eni-6fd9facc-all"
+	logEvents := []*cloudwatchlogs.FilteredLogEvent{}
+	for i, message := range []string{
+		testValidLog,
+		strings.Replace(testValidLog, " 80 60462 ", " 80 60463 ", 1),
+		testNoDataLog,
+	} {
+		logEvents = append(logEvents, &cloudwatchlogs.FilteredLogEvent{
+			EventId:       aws.String(strconv.Itoa(i)),
+			LogStreamName: aws.String(stream),
+			Message:       aws.String(message),
+			Timestamp:     aws.Int64(1500182400000),
+		})
+	}
+	events := []Event{}
+	for _, e := range logEvents {
+		event, err := flowLogEvent(e)
+		if err != nil {
+			continue
+		}
+		events = append(events, event)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 flow log events but got %d", len(events))
+	}
+
+	stored, err := ec.StoreNewEvents(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored != 2 || ec.EventCount() != 2 {
+		t.Errorf("expected 2 stored events but got %d (count %d)", stored, ec.EventCount())
+	}
+
+	// Storing the batch again is a no-op.
+	stored, err = ec.StoreNewEvents(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored != 0 || ec.EventCount() != 2 {
+		t.Errorf("expected no new events but got %d (count %d)", stored, ec.EventCount())
+	}
+
+	// Different events with the same key are both kept.
+	pushed := []Event{
+		{"_tag": "push", "_ts": "2017-08-01T03:20:00Z", "_hash": "a", "value": 1},
+		{"_tag": "push", "_ts": "2017-08-01T03:20:00Z", "_hash": "a", "value": 2},
+		{"_tag": "push", "_ts": "2017-08-01T03:20:00Z", "value": 3},
+	}
+	stored, err = ec.StoreNewEvents(pushed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored != 3 {
+		t.Errorf("expected 3 stored events but got %d", stored)
+	}
+
+	result, err := ec.Query(query.Desc{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Events) != 5 {
+		t.Errorf("expected 5 events but got %d", len(result.Events))
+	}
+
+	verify, err := ec.VerifyEvents(logEvents, flowLogEvent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verify.SourceEvents != 3 || verify.Stored != 2 || verify.Invalid != 1 || verify.Missing != 0 {
+		t.Errorf("unexpected verify result %+v", verify)
+	}
+}
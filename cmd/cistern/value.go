@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValueType is the type of a Value.
+type ValueType int
+
+// Value types in the order values of different types sort in.
+// Ints and floats are both numbers and compare with each other.
+const (
+	TypeNull ValueType = iota
+	TypeBool
+	TypeInt
+	TypeFloat
+	TypeString
+	TypeIP
+	TypeTimestamp
+	TypeDuration
+)
+
+func (t ValueType) String() string {
+	switch t {
+	case TypeNull:
+		return "null"
+	case TypeBool:
+		return "bool"
+	case TypeInt:
+		return "int"
+	case TypeFloat:
+		return "float"
+	case TypeString:
+		return "string"
+	case TypeIP:
+		return "ip"
+	case TypeTimestamp:
+		return "timestamp"
+	case TypeDuration:
+		return "duration"
+	}
+	return "?"
+}
+
+// Value is a typed event or query value.
+//
+// Events are JSON, so IPs, timestamps and durations arrive as strings.
+// When two values of different types are compared, the less specific
+// one is coerced to the type of the other if possible, e.g. the string
+// "10.0.0.1" to an IP, "3" to a number or "1.5s" to a duration. Values
+// that can't be coerced are ordered by type.
+type Value struct {
+	typ ValueType
+	b   bool
+	i   int64 // ints and durations
+	f   float64
+	s   string
+	ip  net.IP
+	t   time.Time
+}
+
+// valueOf returns the typed value of a decoded JSON or Go value.
+// Strings stay strings; see inferValue.
+func valueOf(v interface{}) Value {
+	switch v := v.(type) {
+	case nil:
+		return Value{typ: TypeNull}
+	case Value:
+		return v
+	case bool:
+		return Value{typ: TypeBool, b: v}
+	case int:
+		return Value{typ: TypeInt, i: int64(v)}
+	case int64:
+		return Value{typ: TypeInt, i: v}
+	case float64:
+		return Value{typ: TypeFloat, f: v}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return Value{typ: TypeInt, i: i}
+		}
+		f, _ := v.Float64()
+		return Value{typ: TypeFloat, f: f}
+	case string:
+		return Value{typ: TypeString, s: v}
+	case net.IP:
+		return Value{typ: TypeIP, ip: v}
+	case time.Time:
+		return Value{typ: TypeTimestamp, t: v}
+	case time.Duration:
+		return Value{typ: TypeDuration, i: int64(v)}
+	}
+	// Objects and arrays compare as their JSON.
+	return Value{typ: TypeString, s: stringValue(v)}
+}
+
+// inferValue is like valueOf, but strings that are IPs, RFC 3339
+// timestamps or durations with units get those types.
+func inferValue(v interface{}) Value {
+	value := valueOf(v)
+	if value.typ != TypeString {
+		return value
+	}
+	for _, typ := range []ValueType{TypeIP, TypeTimestamp, TypeDuration} {
+		if typed, ok := value.coerce(typ); ok {
+			return typed
+		}
+	}
+	return value
+}
+
+// coerce converts the value to typ if it can be represented as one.
+// Nothing is coerced to a string, so numbers never compare as text.
+func (v Value) coerce(typ ValueType) (Value, bool) {
+	if v.typ == typ {
+		return v, true
+	}
+	switch typ {
+	case TypeFloat:
+		switch v.typ {
+		case TypeInt:
+			return Value{typ: TypeFloat, f: float64(v.i)}, true
+		case TypeString:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v.s), 64)
+			if err == nil {
+				return Value{typ: TypeFloat, f: f}, true
+			}
+		}
+	case TypeBool:
+		if v.typ == TypeString {
+			b, err := strconv.ParseBool(v.s)
+			if err == nil {
+				return Value{typ: TypeBool, b: b}, true
+			}
+		}
+	case TypeIP:
+		if v.typ == TypeString {
+			if ip := net.ParseIP(v.s); ip != nil {
+				return Value{typ: TypeIP, ip: ip}, true
+			}
+		}
+	case TypeTimestamp:
+		if v.typ == TypeString {
+			t, err := time.Parse(time.RFC3339Nano, v.s)
+			if err == nil {
+				return Value{typ: TypeTimestamp, t: t}, true
+			}
+		}
+	case TypeDuration:
+		// Plain numbers like "5" aren't durations.
+		if v.typ == TypeString && strings.IndexFunc(v.s, isUnitChar) >= 0 {
+			d, err := time.ParseDuration(v.s)
+			if err == nil {
+				return Value{typ: TypeDuration, i: int64(d)}, true
+			}
+		}
+	}
+	return v, false
+}
+
+func isUnitChar(r rune) bool {
+	return r == 'h' || r == 'm' || r == 's' || r == 'u' || r == 'µ' || r == 'n'
+}
+
+// number returns the value as a float for aggregation. Durations
+// are in seconds.
+func (v Value) number() (float64, bool) {
+	switch v.typ {
+	case TypeInt:
+		return float64(v.i), true
+	case TypeFloat:
+		return v.f, true
+	case TypeDuration:
+		return time.Duration(v.i).Seconds(), true
+	case TypeString:
+		if f, ok := v.coerce(TypeFloat); ok {
+			return f.f, true
+		}
+	}
+	return 0, false
+}
+
+// compareValues returns -1, 0 or 1 if a is less than, equal to or
+// greater than b.
+func compareValues(a, b Value) int {
+	// Ints only compare exactly with ints.
+	if a.typ == TypeInt && b.typ != TypeInt {
+		a, _ = a.coerce(TypeFloat)
+	}
+	if b.typ == TypeInt && a.typ != TypeInt {
+		b, _ = b.coerce(TypeFloat)
+	}
+	if a.typ != b.typ {
+		if coerced, ok := a.coerce(b.typ); ok {
+			a = coerced
+		} else if coerced, ok := b.coerce(a.typ); ok {
+			b = coerced
+		}
+	}
+	if a.typ != b.typ {
+		return compareInts(int64(a.typ), int64(b.typ))
+	}
+
+	switch a.typ {
+	case TypeNull:
+		return 0
+	case TypeBool:
+		if a.b == b.b {
+			return 0
+		}
+		if !a.b {
+			return -1
+		}
+		return 1
+	case TypeInt, TypeDuration:
+		return compareInts(a.i, b.i)
+	case TypeFloat:
+		return compareFloats(a.f, b.f)
+	case TypeString:
+		return strings.Compare(a.s, b.s)
+	case TypeIP:
+		// IPv4 addresses sort before IPv6 addresses.
+		a4, b4 := a.ip.To4(), b.ip.To4()
+		if (a4 == nil) != (b4 == nil) {
+			if a4 != nil {
+				return -1
+			}
+			return 1
+		}
+		return bytes.Compare(a.ip.To16(), b.ip.To16())
+	case TypeTimestamp:
+		if a.t.Equal(b.t) {
+			return 0
+		}
+		if a.t.Before(b.t) {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+func compareInts(a, b int64) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
+
+// compareFloats orders NaN before all other floats.
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	case a == b:
+		return 0
+	case math.IsNaN(a) && math.IsNaN(b):
+		return 0
+	case math.IsNaN(a):
+		return -1
+	}
+	return 1
+}
+
+// compareInterfaces compares two event values, inferring the types
+// of strings.
+func compareInterfaces(a, b interface{}) int {
+	return compareValues(inferValue(a), inferValue(b))
+}
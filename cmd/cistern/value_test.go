@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Cistern/cistern/internal/query"
+)
+
+func TestCompareValues(t *testing.T) {
+	testCases := []struct {
+		a, b     interface{}
+		expected int
+	}{
+		{1, 1.0, 0},
+		{int64(2), 1.5, 1},
+		{json.Number("443"), "443", 0},
+		{json.Number("1.5"), 2, -1},
+		{"10.0.0.2", "10.0.0.10", -1},
+		{"10.0.0.2", "::1", -1},
+		{"2017-08-01T03:20:00Z", "2017-08-01T04:20:00+01:00", 0},
+		{"2017-08-01T03:20:00Z", "2017-08-01T03:20:01Z", -1},
+		{"1500ms", "1.5s", 0},
+		{"90s", "1m", 1},
+		{time.Minute, "59s", 1},
+		{"true", true, 0},
+		{nil, 0, -1},
+		{"abc", 1, 1},
+		{"b", "a", 1},
+	}
+	for _, c := range testCases {
+		if got := compareInterfaces(c.a, c.b); got != c.expected {
+			t.Errorf("compare(%#v, %#v): expected %d but got %d", c.a, c.b, c.expected, got)
+		}
+		if got := compareInterfaces(c.b, c.a); got != -c.expected {
+			t.Errorf("compare(%#v, %#v): expected %d but got %d", c.b, c.a, -c.expected, got)
+		}
+	}
+}
+
+func TestTypedFilters(t *testing.T) {
+	event := Event{
+		"source_address": "10.0.0.10",
+		"port":           json.Number("443"),
+		"latency":        "250ms",
+	}
+	testCases := []struct {
+		filter   Filter
+		expected bool
+	}{
+		{EqualsFilter("source_address", "10.0.0.10"), true},
+		{GreaterThanFilter("source_address", "10.0.0.9"), true},
+		{EqualsFilter("port", 443.0), true},
+		{EqualsFilter("port", "443"), true},
+		{LessThanFilter("port", 1024), true},
+		{GreaterThanFilter("latency", "1s"), false},
+		{LessThanOrEqualFilter("latency", "0.25s"), true},
+	}
+	for i, c := range testCases {
+		if got := c.filter.Filter(event); got != c.expected {
+			t.Errorf("case %d: expected %v but got %v", i, c.expected, got)
+		}
+	}
+}
+
+func TestTypedAggregates(t *testing.T) {
+	ec, err := CreateEventCollection("/tmp/test_cistern_value.lm2", defaultCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { ec.col.Destroy() }()
+
+	err = ec.StoreEvents([]Event{
+		{"_tag": "a", "_ts": "2017-08-01T03:20:00Z", "bytes": 100},
+		{"_tag": "a", "_ts": "2017-08-01T03:21:00Z", "bytes": "50"},
+		{"_tag": "a", "_ts": "2017-08-01T03:22:00Z", "bytes": "unknown"},
+		{"_tag": "a", "_ts": "2017-08-01T03:23:00Z"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ec.Query(query.Desc{
+		Columns: []query.ColumnDesc{
+			{Aggregate: "sum", Name: "bytes"},
+			{Aggregate: "min", Name: "bytes"},
+			{Aggregate: "count", Name: "bytes"},
+			{Aggregate: "max", Name: "missing"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Summary) != 1 {
+		t.Fatalf("expected one summary row but got %v", result.Summary)
+	}
+	summary := result.Summary[0]
+	if summary["sum(bytes)"] != 150.0 || summary["min(bytes)"] != 50.0 || summary["count(bytes)"] != 4.0 {
+		t.Errorf("expected non-numeric values to be skipped but got %v", summary)
+	}
+	if summary["max(missing)"] != nil {
+		t.Errorf("expected a null aggregate but got %v", summary["max(missing)"])
+	}
+}
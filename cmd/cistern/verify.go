@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+// maxMissingIDs is the maximum number of missing event IDs reported.
+const maxMissingIDs = 100
+
+var ErrUnknownSource = errors.New("cistern: unknown source")
+
+// VerifyResult compares the events of a source in a time range with the
+// events stored in a collection.
+type VerifyResult struct {
+	Source       string    `json:"source"`
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+	Checkpoint   time.Time `json:"checkpoint"` // events after this haven't been captured yet
+	SourceEvents int       `json:"source_events"`
+	Invalid      int       `json:"invalid"` // events that couldn't be converted
+	Stored       int       `json:"stored"`
+	Missing      int       `json:"missing"`
+	MissingIDs   []string  `json:"missing_ids,omitempty"`
+}
+
+// VerifySource fetches the events of a source attached to the collection
+// between start and end again and checks that every one of them is stored.
+func VerifySource(collectionName string, source string, start time.Time, end time.Time) (VerifyResult, error) {
+	collectionsLock.Lock()
+	collection := Collections[collectionName]
+	collectionsLock.Unlock()
+	if collection == nil {
+		return VerifyResult{}, ErrDoesNotExist
+	}
+
+	attached := false
+	for _, name := range collection.Sources() {
+		if name == source {
+			attached = true
+		}
+	}
+	newConverter, ok := logSourceConverter(source)
+	if !attached || !ok {
+		return VerifyResult{}, ErrUnknownSource
+	}
+	state := &LogState{filename: sourceStateFile(source)}
+	state.Load()
+
+	// Don't use NewCloudWatchLog, which writes the checkpoint.
+	cwl := &CloudWatchLog{
+		svc:          cloudwatchlogs.New(session.Must(session.NewSession())),
+		logGroupName: source,
+	}
+	logEvents, err := cwl.GetLogEventsRange(start.UnixNano()/1e6, end.UnixNano()/1e6)
+	if err != nil {
+		return VerifyResult{}, err
+	}
+
+	result, err := collection.VerifyEvents(logEvents, newConverter(state))
+	if err != nil {
+		return result, err
+	}
+	result.Source = source
+	result.Start = start.UTC()
+	result.End = end.UTC()
+	result.Checkpoint = time.Unix(state.LastTimestamp/1000, (state.LastTimestamp%1000)*1e6).UTC()
+	return result, nil
+}
+
+// VerifyEvents checks which of the source events are stored in the collection.
+func (c *EventCollection) VerifyEvents(logEvents []*cloudwatchlogs.FilteredLogEvent, convert logEventConverter) (VerifyResult, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	result := VerifyResult{}
+	cur, err := c.col.NewCursor()
+	if err != nil {
+		return result, err
+	}
+	for _, e := range logEvents {
+		result.SourceEvents++
+		event, err := convert(e)
+		if err != nil {
+			result.Invalid++
+			continue
+		}
+		if c.flatten {
+			event = flattenEvent(event)
+		}
+		baseKey, value, err := eventKey(event)
+		if err != nil {
+			result.Invalid++
+			continue
+		}
+		_, exists, err := findEvent(cur, map[string]string{}, baseKey, value)
+		if err != nil {
+			return result, err
+		}
+		if exists {
+			result.Stored++
+			continue
+		}
+		result.Missing++
+		if len(result.MissingIDs) < maxMissingIDs && e.EventId != nil {
+			result.MissingIDs = append(result.MissingIDs, *e.EventId)
+		}
+	}
+	return result, nil
+}
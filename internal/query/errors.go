@@ -0,0 +1,324 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// SyntaxError describes where a query fails to parse.
+type SyntaxError struct {
+	// Offset is the position of the error in runes from the start
+	// of the query. Line and Column start at 1.
+	Offset int `json:"offset"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	// Near is the text at the error, or empty at the end of the
+	// query.
+	Near string `json:"near,omitempty"`
+	// Expected lists the tokens that could continue the query at
+	// the error.
+	Expected []string `json:"expected,omitempty"`
+}
+
+func (e *SyntaxError) Error() string {
+	near := "at end of query"
+	if e.Near != "" {
+		near = fmt.Sprintf("near %q", e.Near)
+	}
+	msg := fmt.Sprintf("syntax error at line %d, column %d %s", e.Line, e.Column, near)
+	if len(e.Expected) > 0 {
+		msg += ": expected " + strings.Join(e.Expected, ", ")
+	}
+	return msg
+}
+
+// expectedTokens are the tokens a SyntaxError may list, with examples
+// of them. The parser only reports how far it got, so a token is
+// expected if the query up to there continued with one of its examples
+// parses, possibly once completed with one of exampleCompletions and
+// closing brackets.
+var expectedTokens = []struct {
+	name     string
+	examples []string
+}{
+	{"EXPLAIN", []string{"EXPLAIN SELECT a"}},
+	{"SELECT", []string{"SELECT a"}},
+	{"GROUP BY", []string{"GROUP BY a"}},
+	{"FILTER", []string{"FILTER a = 1"}},
+	{"HAVING", []string{"HAVING a = 1"}},
+	{"SINCE", []string{"SINCE 1m"}},
+	{"UNTIL", []string{"UNTIL 1m"}},
+	{"BETWEEN", []string{"BETWEEN 1m AND 1m", "BETWEEN 1 AND 1"}},
+	{"ORDER BY", []string{"ORDER BY a"}},
+	{"LIMIT", []string{"LIMIT 1"}},
+	{"TOP", []string{"TOP 1"}},
+	{"POINT SIZE", []string{"POINT SIZE 1m"}},
+	{"AS", []string{"AS a"}},
+	{"ASC", []string{"ASC"}},
+	{"DESC", []string{"DESC"}},
+	{"AND", []string{"AND a = 1", "AND 1"}},
+	{"OR", []string{"OR a = 1"}},
+	{"NOT", []string{"NOT a = 1", "NOT IN (1)"}},
+	{"IN", []string{"IN (1)", "IN CIDR (1)"}},
+	{"matches", []string{`matches "a"`}},
+	{"NOW", []string{"NOW"}},
+	{"=", []string{"= 1"}},
+	{"!=", []string{"!= 1"}},
+	{"<", []string{"< 1"}},
+	{"<=", []string{"<= 1"}},
+	{">", []string{"> 1"}},
+	{">=", []string{">= 1"}},
+	{"+", []string{"+ 1"}},
+	{"-", []string{"- 1"}},
+	{"*", []string{"* 1"}},
+	{"/", []string{"/ 1"}},
+	{"%", []string{"% 1"}},
+	{",", []string{", a", ", 1"}},
+	{"(", []string{"(a)", "(1)", "(a = 1)"}},
+	{")", []string{")"}},
+	{".", []string{".a"}},
+	{"[", []string{"[0]"}},
+	{"identifier", []string{"a"}},
+	{"number", []string{"1"}},
+	{"duration", []string{"1m"}},
+	{"string", []string{`"a"`}},
+}
+
+// exampleCompletions complete examples that start a filter or a range.
+var exampleCompletions = []string{"", " = 1", " AND 1"}
+
+const (
+	// maxSuggestionLength is the length in runes of the longest query
+	// a SyntaxError lists expected tokens for.
+	maxSuggestionLength = 512
+	// maxSuggestionParses limits the parses of continued prefixes of
+	// the query that finding the expected tokens may take.
+	maxSuggestionParses = 1000
+	// maxSuggestionTime limits the time they may take. Parses of
+	// deeply nested queries are slow.
+	maxSuggestionTime = 50 * time.Millisecond
+	// maxSuggestionSteps limits how many tokens the error may move
+	// away from where the parser stopped.
+	maxSuggestionSteps = 4
+)
+
+// newSyntaxError returns the SyntaxError for a query the parser failed
+// to parse with err. The error is after the furthest rule the parser
+// matched, moved by a few tokens to the end of the longest prefix of
+// the query that expected tokens continue. Literal words like SELECT
+// are not rules, and rules matched in branches that failed may end
+// past the error. Long queries, or queries that take too many parses
+// or too long to find the expected tokens, get no expected tokens.
+func newSyntaxError(query string, err *parseError) *SyntaxError {
+	buffer := []rune(query)
+	offset := int(err.max.end)
+	if offset > len(buffer) {
+		offset = len(buffer)
+	}
+	var expected []string
+	if len(buffer) <= maxSuggestionLength {
+		s := &suggester{p: &parser{}, deadline: time.Now().Add(maxSuggestionTime)}
+		s.p.Init()
+		offset, expected = s.suggest(buffer, offset)
+	}
+
+	// The error is at the first token after the prefix.
+	for offset < len(buffer) && unicode.IsSpace(buffer[offset]) {
+		offset++
+	}
+	e := &SyntaxError{
+		Offset:   offset,
+		Line:     1,
+		Column:   1,
+		Expected: expected,
+	}
+	if rest := strings.Fields(string(buffer[offset:])); len(rest) > 0 {
+		e.Near = rest[0]
+	}
+	for _, r := range buffer[:offset] {
+		if r == '\n' {
+			e.Line++
+			e.Column = 1
+		} else {
+			e.Column++
+		}
+	}
+	return e
+}
+
+// suggester finds the expected tokens of a query that failed to parse.
+type suggester struct {
+	p        *parser
+	parses   int
+	deadline time.Time
+}
+
+// suggest returns the offset of the error in buffer, starting from
+// where the parser stopped, and the tokens expected there. It returns
+// offset and no tokens if it runs out of parses or time.
+func (s *suggester) suggest(buffer []rune, offset int) (int, []string) {
+	start := offset
+	for steps := 0; !s.continued(string(buffer[:offset])); steps++ {
+		if offset == 0 || steps == maxSuggestionSteps || s.exhausted() {
+			return start, nil
+		}
+		offset = previousBoundary(buffer, offset)
+	}
+	for steps := 0; steps < maxSuggestionSteps && offset < len(buffer); steps++ {
+		end := nextBoundary(buffer, offset)
+		if !s.continued(string(buffer[:end])) {
+			break
+		}
+		offset = end
+	}
+	expected := s.continuations(string(buffer[:offset]))
+	if s.exhausted() {
+		return start, nil
+	}
+	return offset, expected
+}
+
+// exhausted returns true once the suggester has run out of parses or
+// time.
+func (s *suggester) exhausted() bool {
+	return s.parses >= maxSuggestionParses || time.Now().After(s.deadline)
+}
+
+// continued returns true if an expected token continues prefix.
+func (s *suggester) continued(prefix string) bool {
+	for _, token := range expectedTokens {
+		if s.continues(prefix, token.examples) {
+			return true
+		}
+	}
+	return false
+}
+
+// continuations returns the expected tokens that continue prefix.
+func (s *suggester) continuations(prefix string) []string {
+	expected := []string{}
+	identifier := false
+	for _, token := range expectedTokens {
+		if s.continues(prefix, token.examples) {
+			if token.name == "identifier" {
+				identifier = true
+			}
+			expected = append(expected, token.name)
+		}
+	}
+	if !identifier {
+		return expected
+	}
+	// Words like DESC are also identifiers.
+	filtered := expected[:0]
+	for _, name := range expected {
+		if name == "identifier" || !isIdentifier(name) {
+			filtered = append(filtered, name)
+		}
+	}
+	return filtered
+}
+
+// continues returns true if prefix continued with one of the examples
+// parses, or false once the suggester is exhausted.
+func (s *suggester) continues(prefix string, examples []string) bool {
+	for _, example := range examples {
+		text := prefix
+		if runeClass(lastRune(prefix)) == wordRune && runeClass([]rune(example)[0]) == wordRune {
+			text += " "
+		}
+		text += example
+		for _, completion := range exampleCompletions {
+			if s.exhausted() {
+				return false
+			}
+			s.parses++
+			s.p.Buffer = text + completion + closingBrackets(text)
+			s.p.Reset()
+			if s.p.Parse() == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// closingBrackets returns the brackets that close the ones left open
+// in text.
+func closingBrackets(text string) string {
+	open := []rune{}
+	quoted := false
+	for _, r := range text {
+		switch {
+		case r == '"' || r == '\'':
+			quoted = !quoted
+		case quoted:
+		case r == '(':
+			open = append(open, ')')
+		case r == '[':
+			open = append(open, ']')
+		case (r == ')' || r == ']') && len(open) > 0:
+			open = open[:len(open)-1]
+		}
+	}
+	closing := ""
+	for i := len(open) - 1; i >= 0; i-- {
+		closing += string(open[i])
+	}
+	return closing
+}
+
+// previousBoundary returns the offset of the last change of rune class
+// in buffer before offset, or 0.
+func previousBoundary(buffer []rune, offset int) int {
+	offset--
+	for offset > 0 && runeClass(buffer[offset-1]) == runeClass(buffer[offset]) {
+		offset--
+	}
+	return offset
+}
+
+// nextBoundary returns the offset of the first change of rune class in
+// buffer after offset, or the end of buffer.
+func nextBoundary(buffer []rune, offset int) int {
+	end := offset + 1
+	for end < len(buffer) && runeClass(buffer[end-1]) == runeClass(buffer[end]) {
+		end++
+	}
+	return end
+}
+
+const (
+	spaceRune = iota
+	wordRune
+	otherRune
+)
+
+func runeClass(r rune) int {
+	switch {
+	case unicode.IsSpace(r):
+		return spaceRune
+	case r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+		return wordRune
+	}
+	return otherRune
+}
+
+func lastRune(s string) rune {
+	if s == "" {
+		return ' '
+	}
+	r := []rune(s)
+	return r[len(r)-1]
+}
+
+func isIdentifier(s string) bool {
+	for _, r := range s {
+		if runeClass(r) != wordRune {
+			return false
+		}
+	}
+	return true
+}
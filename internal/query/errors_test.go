@@ -0,0 +1,95 @@
+package query
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyntaxError(t *testing.T) {
+	testCases := []struct {
+		query    string
+		line     int
+		column   int
+		near     string
+		expected []string
+	}{
+		{"SELECT", 1, 7, "", []string{"identifier"}},
+		{"SELECT sum(bytes", 1, 17, "", []string{")", ","}},
+		{"SELECT a FILTER (a = 1 OR b = 2", 1, 32, "", []string{"AND", "OR", ")"}},
+		{"SELECT a\nFILTER a = = 1", 2, 12, "=", []string{"number", "string"}},
+		{"SELECT bytes AS", 1, 16, "", []string{"identifier"}},
+		{"SELECT count(a) GROUP BY b TOP", 1, 31, "", []string{"number"}},
+		{"SELECT a b", 1, 10, "b", []string{",", "FILTER", "AS"}},
+		{"SELECT a ORDER BY b DESC ASC", 1, 26, "ASC", []string{",", "LIMIT"}},
+		{"SELECT µs,", 1, 8, "µs,", []string{"identifier"}},
+	}
+
+	for _, c := range testCases {
+		_, err := Parse(c.query)
+		serr, ok := err.(*SyntaxError)
+		if !ok {
+			t.Errorf("%q: expected a syntax error but got %v", c.query, err)
+			continue
+		}
+		if serr.Line != c.line || serr.Column != c.column || serr.Near != c.near {
+			t.Errorf("%q: expected an error at line %d column %d near %q but got %v", c.query, c.line, c.column, c.near, serr)
+		}
+		for _, token := range c.expected {
+			if !contains(serr.Expected, token) {
+				t.Errorf("%q: expected %q in %v", c.query, token, serr.Expected)
+			}
+		}
+	}
+}
+
+func TestSyntaxErrorExpected(t *testing.T) {
+	_, err := Parse("SELECT a FILTER a = 1 OR")
+	serr, ok := err.(*SyntaxError)
+	if !ok {
+		t.Fatalf("expected a syntax error but got %v", err)
+	}
+	// DESC is an identifier, and LIMIT can't follow OR.
+	for _, token := range []string{"DESC", "LIMIT", ")"} {
+		if contains(serr.Expected, token) {
+			t.Errorf("unexpected %q in %v", token, serr.Expected)
+		}
+	}
+}
+
+func TestSyntaxErrorLimits(t *testing.T) {
+	// Long queries get no expected tokens.
+	query := `SELECT a FILTER b = "` + strings.Repeat(`"x "`, maxSuggestionLength)
+	_, err := Parse(query)
+	serr, ok := err.(*SyntaxError)
+	if !ok {
+		t.Fatalf("expected a syntax error but got %v", err)
+	}
+	if serr.Column != 25 || len(serr.Expected) != 0 {
+		t.Errorf("expected an error at column 25 without expected tokens but got %v", serr)
+	}
+
+	// Shorter ones take a bounded number of parses.
+	query = query[:maxSuggestionLength]
+	p := &parser{Buffer: query}
+	p.Init()
+	perr, ok := p.Parse().(*parseError)
+	if !ok {
+		t.Fatalf("expected a parse error for %q", query)
+	}
+	s := &suggester{p: &parser{}, deadline: time.Now().Add(time.Minute)}
+	s.p.Init()
+	s.suggest([]rune(query), int(perr.max.end))
+	if s.parses > maxSuggestionParses {
+		t.Errorf("expected at most %d parses but got %d", maxSuggestionParses, s.parses)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -2,13 +2,23 @@ package query
 
 import (
 	"encoding/json"
+	"fmt"
+	"math"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type expression struct {
 	query          Desc
 	currentSection string
+	filters        []Filter
+	exprs          []Expr
+	values         []string
+
+	now  time.Time // the time relative times are relative to
+	time time.Time // the current time of a time range
+	err  error     // set by actions that fail
 }
 
 func (e *expression) AddColumn() {
@@ -22,66 +32,274 @@ func (e *expression) AddColumn() {
 	}
 }
 
-func (e *expression) SetColumnName(name string) {
+func (e *expression) currentColumn() *ColumnDesc {
 	switch e.currentSection {
 	case "columns":
-		e.query.Columns[len(e.query.Columns)-1].Name = name
+		return &e.query.Columns[len(e.query.Columns)-1]
 	case "group by":
-		e.query.GroupBy[len(e.query.GroupBy)-1].Name = name
+		return &e.query.GroupBy[len(e.query.GroupBy)-1]
 	case "order by":
-		e.query.OrderBy[len(e.query.OrderBy)-1].Name = name
+		return &e.query.OrderBy[len(e.query.OrderBy)-1]
 	}
+	return nil
 }
 
-func (e *expression) SetColumnAggregate(aggregate string) {
-	switch e.currentSection {
-	case "columns":
-		e.query.Columns[len(e.query.Columns)-1].Aggregate = aggregate
-	case "group by":
-		e.query.GroupBy[len(e.query.GroupBy)-1].Aggregate = aggregate
-	case "order by":
-		e.query.OrderBy[len(e.query.OrderBy)-1].Aggregate = aggregate
+// SetColumnExpr sets the current column to the parsed expression.
+// Fields and aggregates of fields keep their simple form, like
+// {Aggregate: "sum", Name: "bytes"}; other columns are named by their
+// text without whitespace.
+func (e *expression) SetColumnExpr(text string) {
+	expr := e.popExpr()
+	column := e.currentColumn()
+	switch {
+	case expr.isField():
+		column.Name = expr.Column
+	case expr.Func != "" && len(expr.Operands) == 1 && expr.Operands[0].isField():
+		column.Aggregate = expr.Func
+		column.Name = expr.Operands[0].Column
+	default:
+		column.Name = strings.Join(strings.Fields(text), "")
+		column.Expr = &expr
+	}
+}
+
+func (e *expression) SetColumnAlias(alias string) {
+	e.currentColumn().Alias = alias
+}
+
+// Arithmetic expressions are built on a stack like filters.
+
+func (e *expression) popExpr() Expr {
+	expr := e.exprs[len(e.exprs)-1]
+	e.exprs = e.exprs[:len(e.exprs)-1]
+	return expr
+}
+
+func (e *expression) PushField(name string) {
+	e.exprs = append(e.exprs, Expr{Column: name})
+}
+
+func (e *expression) PushLiteral(text string) {
+	f, _ := strconv.ParseFloat(text, 64)
+	e.exprs = append(e.exprs, Expr{Value: f})
+}
+
+// PushDuration pushes a duration literal like 1d. Its value is the
+// duration as a string, like "24h0m0s".
+func (e *expression) PushDuration(text string) {
+	d, err := parseOffset(text)
+	if err != nil {
+		// Keep the stack balanced for the actions that follow.
+		e.err = err
+		e.exprs = append(e.exprs, Expr{})
+		return
 	}
+	e.exprs = append(e.exprs, Expr{Value: d.String()})
+}
+
+func (e *expression) BinaryExpr(op string) {
+	b, a := e.popExpr(), e.popExpr()
+	e.exprs = append(e.exprs, Expr{Op: op, Operands: []Expr{a, b}})
+}
+
+func (e *expression) NegateExpr() {
+	a := e.popExpr()
+	e.exprs = append(e.exprs, Expr{Op: "-", Operands: []Expr{a}})
+}
+
+func (e *expression) StartCall(name string) {
+	e.exprs = append(e.exprs, Expr{Func: name})
+}
+
+func (e *expression) AddArgument() {
+	arg := e.popExpr()
+	call := &e.exprs[len(e.exprs)-1]
+	call.Operands = append(call.Operands, arg)
+}
+
+// Filter expressions are built on a stack. Comparisons are pushed,
+// boolean operators replace their operands with the combined filter
+// and each complete expression is popped into the query's filters.
+
+func (e *expression) PushFilter() {
+	e.filters = append(e.filters, Filter{})
+}
+
+func (e *expression) popFilter() Filter {
+	f := e.filters[len(e.filters)-1]
+	e.filters = e.filters[:len(e.filters)-1]
+	return f
 }
 
 func (e *expression) AddFilter() {
-	e.query.Filters = append(e.query.Filters, Filter{})
+	f := e.popFilter()
+	filters := &e.query.Filters
+	if e.currentSection == "having" {
+		filters = &e.query.Having
+	}
+	if f.And != nil {
+		// Filters are ANDed anyway.
+		*filters = append(*filters, f.And...)
+		return
+	}
+	*filters = append(*filters, f)
+}
+
+func (e *expression) And() {
+	b, a := e.popFilter(), e.popFilter()
+	if a.And != nil {
+		a.And = append(a.And, b)
+		e.filters = append(e.filters, a)
+		return
+	}
+	e.filters = append(e.filters, Filter{And: []Filter{a, b}})
 }
 
-func (e *expression) SetFilterColumn(column string) {
-	e.query.Filters[len(e.query.Filters)-1].Column = column
+func (e *expression) Or() {
+	b, a := e.popFilter(), e.popFilter()
+	if a.Or != nil {
+		a.Or = append(a.Or, b)
+		e.filters = append(e.filters, a)
+		return
+	}
+	e.filters = append(e.filters, Filter{Or: []Filter{a, b}})
+}
+
+func (e *expression) Not() {
+	f := e.popFilter()
+	e.filters = append(e.filters, Filter{Not: &f})
+}
+
+func (e *expression) SetFilterColumn(text string) {
+	expr := e.popExpr()
+	filter := &e.filters[len(e.filters)-1]
+	if expr.isField() {
+		filter.Column = expr.Column
+		return
+	}
+	filter.Column = strings.Join(strings.Fields(text), "")
+	filter.Expr = &expr
 }
 
 func (e *expression) SetFilterCondition(condition string) {
-	e.query.Filters[len(e.query.Filters)-1].Condition = condition
+	// Multi-word conditions like "NOT  IN" are normalized to "not in".
+	condition = strings.ToLower(strings.Join(strings.Fields(condition), " "))
+	e.filters[len(e.filters)-1].Condition = condition
 }
 
 func (e *expression) SetFilterValue(value string) {
-	e.query.Filters[len(e.query.Filters)-1].Value = json.RawMessage(value)
+	e.filters[len(e.filters)-1].Value = json.RawMessage(value)
+}
+
+// AddFilterValue adds a value of a list, which is set as
+// the filter's value as a JSON array by SetFilterValues.
+func (e *expression) AddFilterValue(value string) {
+	e.values = append(e.values, value)
+}
+
+func (e *expression) SetFilterValues() {
+	e.SetFilterValue("[" + strings.Join(e.values, ",") + "]")
+	e.values = nil
+}
+
+// SetAbsoluteTime sets the current time of a time range to a
+// quoted timestamp.
+func (e *expression) SetAbsoluteTime(quoted string) {
+	text, err := strconv.Unquote(quoted)
+	if err == nil {
+		e.time, err = time.Parse(time.RFC3339Nano, text)
+		if err != nil {
+			e.time, err = time.Parse("2006-01-02", text)
+		}
+	}
+	if err != nil {
+		e.err = fmt.Errorf("invalid time %s", quoted)
+	}
+}
+
+// SetRelativeTime sets the current time of a time range to the time
+// the query is parsed plus an offset like "-6h" or "+1d".
+func (e *expression) SetRelativeTime(offset string) {
+	offset = strings.Join(strings.Fields(offset), "")
+	d, err := parseOffset(offset)
+	if err != nil {
+		e.err = err
+		return
+	}
+	e.time = e.now.Add(d)
+}
+
+func (e *expression) SetStart() {
+	e.query.TimeRange.Start = e.time
+}
+
+func (e *expression) SetEnd() {
+	e.query.TimeRange.End = e.time
+}
+
+// parseOffset parses a duration, which may be in days (d) or weeks (w).
+func parseOffset(s string) (time.Duration, error) {
+	unit := time.Duration(0)
+	switch {
+	case strings.HasSuffix(s, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(s, "w"):
+		unit = 7 * 24 * time.Hour
+	default:
+		return time.ParseDuration(s)
+	}
+	n, err := strconv.ParseFloat(s[:len(s)-1], 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.Abs(n*float64(unit)) > math.MaxInt64 {
+		return 0, fmt.Errorf("time: invalid duration %q", s)
+	}
+	return time.Duration(n * float64(unit)), nil
 }
 
 func (e *expression) SetDescending() {
-	e.query.Descending = true
+	e.currentColumn().Descending = true
+}
+
+func (e *expression) SetExplain() {
+	e.query.Explain = true
 }
 
 func (e *expression) SetLimit(num string) {
 	e.query.Limit, _ = strconv.Atoi(num)
 }
 
+func (e *expression) SetTop(num string) {
+	e.query.Top, _ = strconv.Atoi(num)
+}
+
 func (e *expression) SetPointSize(num string) {
 	dur, _ := time.ParseDuration(num)
 	e.query.PointSize = int64(dur) / 1e3
 }
 
+// Parse parses a query. Relative times are relative to the current time.
 func Parse(query string) (*Desc, error) {
+	return parse(query, time.Now())
+}
+
+func parse(query string, now time.Time) (*Desc, error) {
 	p := &parser{
 		Buffer: query,
 	}
 	p.Init()
+	p.now = now
 	err := p.Parse()
 	if err != nil {
+		if perr, ok := err.(*parseError); ok {
+			return nil, newSyntaxError(query, perr)
+		}
 		return nil, err
 	}
 	p.Execute()
+	if p.err != nil {
+		return nil, p.err
+	}
 	return &p.query, nil
 }
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"reflect"
 	"testing"
+	"time"
 )
 
 type testCase struct {
@@ -121,23 +122,270 @@ func TestParse(t *testing.T) {
 				},
 				OrderBy: []ColumnDesc{
 					{Name: "source_addr"},
+					{Aggregate: "sum", Name: "bytes", Descending: true},
+				},
+				Limit: 100,
+			},
+		},
+		{
+			query: `SELECT count(userIdentity.arn) GROUP BY resources[0].ARN FILTER requestParameters.bucketName = "logs"`,
+			expected: &Desc{
+				Columns: []ColumnDesc{
+					{Aggregate: "count", Name: "userIdentity.arn"},
+				},
+				GroupBy: []ColumnDesc{
+					{Name: "resources[0].ARN"},
+				},
+				Filters: []Filter{
+					{Column: "requestParameters.bucketName", Condition: "=", Value: json.RawMessage(`"logs"`)},
+				},
+			},
+		},
+		{
+			query: `SELECT count(_id) FILTER (dest_port = 22 OR dest_port = 3389) AND NOT action = "ACCEPT"`,
+			expected: &Desc{
+				Columns: []ColumnDesc{
+					{Aggregate: "count", Name: "_id"},
+				},
+				Filters: []Filter{
+					{Or: []Filter{
+						{Column: "dest_port", Condition: "=", Value: json.RawMessage(`22`)},
+						{Column: "dest_port", Condition: "=", Value: json.RawMessage(`3389`)},
+					}},
+					{Not: &Filter{Column: "action", Condition: "=", Value: json.RawMessage(`"ACCEPT"`)}},
+				},
+			},
+		},
+		{
+			query: `SELECT notes FILTER a = 1 or b = 2 and not not c = 3, order_id = 4`,
+			expected: &Desc{
+				Columns: []ColumnDesc{
+					{Name: "notes"},
+				},
+				Filters: []Filter{
+					{Or: []Filter{
+						{Column: "a", Condition: "=", Value: json.RawMessage(`1`)},
+						{And: []Filter{
+							{Column: "b", Condition: "=", Value: json.RawMessage(`2`)},
+							{Not: &Filter{Not: &Filter{Column: "c", Condition: "=", Value: json.RawMessage(`3`)}}},
+						}},
+					}},
+					{Column: "order_id", Condition: "=", Value: json.RawMessage(`4`)},
+				},
+			},
+		},
+		{
+			query: `SELECT _id FILTER dest_port in (22, 3389, 5900) source_address NOT IN CIDR "10.0.0.0/8" protocol not in ("tcp") bytes between 100 AND 1e3 packets >= 2 packets <= 5`,
+			expected: &Desc{
+				Columns: []ColumnDesc{
+					{Name: "_id"},
+				},
+				Filters: []Filter{
+					{Column: "dest_port", Condition: "in", Value: json.RawMessage(`[22,3389,5900]`)},
+					{Column: "source_address", Condition: "not in cidr", Value: json.RawMessage(`"10.0.0.0/8"`)},
+					{Column: "protocol", Condition: "not in", Value: json.RawMessage(`["tcp"]`)},
+					{Column: "bytes", Condition: "between", Value: json.RawMessage(`[100,1e3]`)},
+					{Column: "packets", Condition: ">=", Value: json.RawMessage(`2`)},
+					{Column: "packets", Condition: "<=", Value: json.RawMessage(`5`)},
+				},
+			},
+		},
+		{
+			query: `SELECT _id FILTER bytes BETWEEN 1 AND 5 AND inside = 1`,
+			expected: &Desc{
+				Columns: []ColumnDesc{
+					{Name: "_id"},
+				},
+				Filters: []Filter{
+					{Column: "bytes", Condition: "between", Value: json.RawMessage(`[1,5]`)},
+					{Column: "inside", Condition: "=", Value: json.RawMessage(`1`)},
+				},
+			},
+		},
+		{
+			query: "SELECT 1, (bytes), -packets, bytes * 8 AS bits",
+			expected: &Desc{
+				Columns: []ColumnDesc{
+					{Name: "1", Expr: &Expr{Value: 1.0}},
+					{Name: "bytes"},
+					{Name: "-packets", Expr: &Expr{Op: "-", Operands: []Expr{{Column: "packets"}}}},
+					{Name: "bytes*8", Alias: "bits", Expr: &Expr{Op: "*", Operands: []Expr{{Column: "bytes"}, {Value: 8.0}}}},
+				},
+			},
+		},
+		{
+			query: "SELECT sum(bytes) / count(_id) AS avg_size, round(sum(bytes * 8) / 1e6, 2), p95(bytes) GROUP BY source_address ORDER BY avg_size DESC",
+			expected: &Desc{
+				Columns: []ColumnDesc{
+					{
+						Name:  "sum(bytes)/count(_id)",
+						Alias: "avg_size",
+						Expr: &Expr{Op: "/", Operands: []Expr{
+							{Func: "sum", Operands: []Expr{{Column: "bytes"}}},
+							{Func: "count", Operands: []Expr{{Column: "_id"}}},
+						}},
+					},
+					{
+						Name: "round(sum(bytes*8)/1e6,2)",
+						Expr: &Expr{Func: "round", Operands: []Expr{
+							{Op: "/", Operands: []Expr{
+								{Func: "sum", Operands: []Expr{{Op: "*", Operands: []Expr{{Column: "bytes"}, {Value: 8.0}}}}},
+								{Value: 1e6},
+							}},
+							{Value: 2.0},
+						}},
+					},
+					{Aggregate: "p95", Name: "bytes"},
+				},
+				GroupBy: []ColumnDesc{
+					{Name: "source_address"},
+				},
+				OrderBy: []ColumnDesc{
+					{Name: "avg_size", Descending: true},
+				},
+			},
+		},
+		{
+			query: "SELECT a - b - c, a + b * c, (a + b) % 2",
+			expected: &Desc{
+				Columns: []ColumnDesc{
+					{Name: "a-b-c", Expr: &Expr{Op: "-", Operands: []Expr{
+						{Op: "-", Operands: []Expr{{Column: "a"}, {Column: "b"}}},
+						{Column: "c"},
+					}}},
+					{Name: "a+b*c", Expr: &Expr{Op: "+", Operands: []Expr{
+						{Column: "a"},
+						{Op: "*", Operands: []Expr{{Column: "b"}, {Column: "c"}}},
+					}}},
+					{Name: "(a+b)%2", Expr: &Expr{Op: "%", Operands: []Expr{
+						{Op: "+", Operands: []Expr{{Column: "a"}, {Column: "b"}}},
+						{Value: 2.0},
+					}}},
+				},
+			},
+		},
+		{
+			query: "SELECT count(_id) GROUP BY source_address FILTER bytes / packets > 1000 HAVING count_distinct(dest_port) > 100 OR avg_size <= 5 ORDER BY count(_id) DESC",
+			expected: &Desc{
+				Columns: []ColumnDesc{
+					{Aggregate: "count", Name: "_id"},
+				},
+				GroupBy: []ColumnDesc{
+					{Name: "source_address"},
+				},
+				Filters: []Filter{
+					{
+						Column:    "bytes/packets",
+						Expr:      &Expr{Op: "/", Operands: []Expr{{Column: "bytes"}, {Column: "packets"}}},
+						Condition: ">",
+						Value:     json.RawMessage(`1000`),
+					},
+				},
+				Having: []Filter{
+					{Or: []Filter{
+						{
+							Column:    "count_distinct(dest_port)",
+							Expr:      &Expr{Func: "count_distinct", Operands: []Expr{{Column: "dest_port"}}},
+							Condition: ">",
+							Value:     json.RawMessage(`100`),
+						},
+						{Column: "avg_size", Condition: "<=", Value: json.RawMessage(`5`)},
+					}},
+				},
+				OrderBy: []ColumnDesc{
+					{Aggregate: "count", Name: "_id", Descending: true},
+				},
+			},
+		},
+		{
+			query: "SELECT count(_id) GROUP BY bucket(_ts, 1d) AS day, hour_of_day(_ts), cidr(source_address, 24)",
+			expected: &Desc{
+				Columns: []ColumnDesc{
+					{Aggregate: "count", Name: "_id"},
+				},
+				GroupBy: []ColumnDesc{
+					{
+						Name:  "bucket(_ts,1d)",
+						Alias: "day",
+						Expr:  &Expr{Func: "bucket", Operands: []Expr{{Column: "_ts"}, {Value: "24h0m0s"}}},
+					},
+					{Aggregate: "hour_of_day", Name: "_ts"},
+					{
+						Name: "cidr(source_address,24)",
+						Expr: &Expr{Func: "cidr", Operands: []Expr{{Column: "source_address"}, {Value: 24.0}}},
+					},
+				},
+			},
+		},
+		{
+			query: "SELECT sum(bytes) GROUP BY source_address TOP 5 POINT SIZE 1m",
+			expected: &Desc{
+				Columns: []ColumnDesc{
 					{Aggregate: "sum", Name: "bytes"},
 				},
-				Limit:      100,
-				Descending: true,
+				GroupBy: []ColumnDesc{
+					{Name: "source_address"},
+				},
+				Top:       5,
+				PointSize: 60000000,
+			},
+		},
+		{
+			query: "SELECT _id ORDER BY bytes DESC, protocol ASC, bytes / packets desc LIMIT 5",
+			expected: &Desc{
+				Columns: []ColumnDesc{
+					{Name: "_id"},
+				},
+				OrderBy: []ColumnDesc{
+					{Name: "bytes", Descending: true},
+					{Name: "protocol"},
+					{
+						Name:       "bytes/packets",
+						Expr:       &Expr{Op: "/", Operands: []Expr{{Column: "bytes"}, {Column: "packets"}}},
+						Descending: true,
+					},
+				},
+				Limit: 5,
+			},
+		},
+		{
+			query: "EXPLAIN SELECT count(_id) GROUP BY protocol",
+			expected: &Desc{
+				Columns: []ColumnDesc{
+					{Aggregate: "count", Name: "_id"},
+				},
+				GroupBy: []ColumnDesc{
+					{Name: "protocol"},
+				},
+				Explain: true,
 			},
 		},
 
 		// Invalid
 
 		{query: "SELECT"},
-		{query: "SELECT 1"},
 		{query: "GROUP"},
 		{query: "SELECT 1(bytes), foo"},
 		{query: "SELECT sum(bytes) LIMIT 1 POINT SIZE -5"},
 		{query: "SELECT sum(bytes) LIMIT -1 POINT SIZE -5"},
 		{query: "SELECT sum(bytes) GROUP BY min(source_addr), dest_addr ORDER"},
-		{query: "SELECT (bytes)"},
+		{query: "SELECT bytes AS"},
+		{query: "SELECT bytes /"},
+		{query: "SELECT sum(bytes"},
+		{query: "SELECT foo..bar"},
+		{query: "SELECT foo[x]"},
+		{query: "SELECT foo.0"},
+		{query: "SELECT a FILTER a = 1 OR"},
+		{query: "SELECT a FILTER NOT"},
+		{query: "SELECT a FILTER (a = 1 OR b = 2"},
+		{query: "SELECT a FILTER a in ()"},
+		{query: "SELECT a FILTER a between 1"},
+		{query: "SELECT count(a) HAVING"},
+		{query: "SELECT count(a) GROUP BY b LIMIT 1 TOP 5"},
+		{query: "SELECT a EXPLAIN"},
+		{query: "SELECT count(a) GROUP BY b TOP"},
+		{query: "SELECT a ORDER BY b DESC ASC"},
+		{query: "SELECT count(a) HAVING count(a) > 1 FILTER a = 1"},
 	}
 
 	for _, c := range testCases {
@@ -155,3 +403,60 @@ func TestParse(t *testing.T) {
 		}
 	}
 }
+
+func TestParseTimeRange(t *testing.T) {
+	now := time.Date(2017, 8, 1, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		query      string
+		start, end time.Time
+	}{
+		{"SELECT a SINCE 6h", now.Add(-6 * time.Hour), time.Time{}},
+		{"SELECT a since 1.5d", now.Add(-36 * time.Hour), time.Time{}},
+		{"SELECT a UNTIL now-1h", time.Time{}, now.Add(-time.Hour)},
+		{"SELECT a SINCE 1w UNTIL now - 30m", now.Add(-7 * 24 * time.Hour), now.Add(-30 * time.Minute)},
+		{"SELECT a SINCE \"2017-07-01\" UNTIL now", time.Date(2017, 7, 1, 0, 0, 0, 0, time.UTC), now},
+		{
+			"SELECT count(a) GROUP BY b FILTER a > 1 BETWEEN \"2017-08-01T00:00:00Z\" AND \"2017-08-01T01:00:00+01:00\" LIMIT 5",
+			time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC), time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, c := range testCases {
+		desc, err := parse(c.query, now)
+		if err != nil {
+			t.Errorf("Error parsing \"%s\": %s", c.query, err)
+			continue
+		}
+		if !desc.TimeRange.Start.Equal(c.start) || !desc.TimeRange.End.Equal(c.end) {
+			t.Errorf("%s: expected %v to %v but got %v to %v",
+				c.query, c.start, c.end, desc.TimeRange.Start, desc.TimeRange.End)
+		}
+	}
+
+	for _, q := range []string{
+		"SELECT a SINCE",
+		"SELECT a SINCE 6",
+		"SELECT a SINCE \"yesterday\"",
+		"SELECT a UNTIL now-",
+		"SELECT a BETWEEN 1h",
+		"SELECT a SINCE 1h LIMIT 1 UNTIL now",
+		"SELECT a SINCE 99999999999999d",
+	} {
+		if _, err := parse(q, now); err == nil {
+			t.Errorf("%s: expected an error", q)
+		}
+	}
+}
+
+func TestParseDurationOverflow(t *testing.T) {
+	for _, q := range []string{
+		"SELECT 99999999999999h",
+		"SELECT 99999999999999w",
+		"SELECT -99999999999999d + 1",
+		"SELECT count(_id) GROUP BY bucket(_ts, 99999999999999h)",
+		"SELECT a FILTER a > 99999999999999h",
+	} {
+		if _, err := Parse(q); err == nil {
+			t.Errorf("%s: expected an error", q)
+		}
+	}
+}
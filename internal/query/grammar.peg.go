@@ -18,19 +18,40 @@ const (
 	ruleColumnExpr
 	ruleGroupExpr
 	ruleFilterExpr
+	ruleHavingExpr
+	ruleTimeRangeExpr
+	ruleSinceExpr
+	ruleUntilExpr
+	ruleTimeValue
+	ruleTimeOffset
 	ruleOrderByExpr
+	ruleOrderColumn
+	ruleExplainExpr
 	ruleLimitExpr
+	ruleTopExpr
 	rulePointSizeExpr
 	ruleColumns
 	ruleColumn
-	ruleColumnAggregation
+	ruleColumnAlias
+	ruleExpr
+	ruleTerm
+	ruleFactor
+	ruleFunctionCall
 	ruleLogicExpr
+	ruleAndExpr
+	ruleNotExpr
+	rulePrimaryExpr
+	ruleRangeCondition
+	ruleListCondition
 	ruleOPERATOR
 	ruleFilterKey
 	ruleFilterCondition
 	ruleFilterValue
+	ruleValueList
+	ruleListValue
 	ruleValue
 	ruleDescending
+	ruleAscending
 	ruleString
 	ruleStringChar
 	ruleEscape
@@ -46,16 +67,29 @@ const (
 	ruleFloat
 	ruleDuration
 	ruleIdentifier
+	rulePathElem
+	ruleIdStart
 	ruleIdChar
 	ruleKeyword
 	rule_
 	ruleLPAR
 	ruleRPAR
 	ruleCOMMA
+	rulePLUS
+	ruleMINUS
+	ruleTIMES
+	ruleDIVIDE
+	ruleMODULO
+	ruleAS
+	ruleAND
+	ruleOR
+	ruleNOT
+	ruleIN
+	ruleCIDR
+	ruleBETWEEN
 	ruleAction0
 	ruleAction1
 	ruleAction2
-	rulePegText
 	ruleAction3
 	ruleAction4
 	ruleAction5
@@ -65,8 +99,44 @@ const (
 	ruleAction9
 	ruleAction10
 	ruleAction11
+	rulePegText
 	ruleAction12
 	ruleAction13
+	ruleAction14
+	ruleAction15
+	ruleAction16
+	ruleAction17
+	ruleAction18
+	ruleAction19
+	ruleAction20
+	ruleAction21
+	ruleAction22
+	ruleAction23
+	ruleAction24
+	ruleAction25
+	ruleAction26
+	ruleAction27
+	ruleAction28
+	ruleAction29
+	ruleAction30
+	ruleAction31
+	ruleAction32
+	ruleAction33
+	ruleAction34
+	ruleAction35
+	ruleAction36
+	ruleAction37
+	ruleAction38
+	ruleAction39
+	ruleAction40
+	ruleAction41
+	ruleAction42
+	ruleAction43
+	ruleAction44
+	ruleAction45
+	ruleAction46
+	ruleAction47
+	ruleAction48
 )
 
 var rul3s = [...]string{
@@ -75,19 +145,40 @@ var rul3s = [...]string{
 	"ColumnExpr",
 	"GroupExpr",
 	"FilterExpr",
+	"HavingExpr",
+	"TimeRangeExpr",
+	"SinceExpr",
+	"UntilExpr",
+	"TimeValue",
+	"TimeOffset",
 	"OrderByExpr",
+	"OrderColumn",
+	"ExplainExpr",
 	"LimitExpr",
+	"TopExpr",
 	"PointSizeExpr",
 	"Columns",
 	"Column",
-	"ColumnAggregation",
+	"ColumnAlias",
+	"Expr",
+	"Term",
+	"Factor",
+	"FunctionCall",
 	"LogicExpr",
+	"AndExpr",
+	"NotExpr",
+	"PrimaryExpr",
+	"RangeCondition",
+	"ListCondition",
 	"OPERATOR",
 	"FilterKey",
 	"FilterCondition",
 	"FilterValue",
+	"ValueList",
+	"ListValue",
 	"Value",
 	"Descending",
+	"Ascending",
 	"String",
 	"StringChar",
 	"Escape",
@@ -103,16 +194,29 @@ var rul3s = [...]string{
 	"Float",
 	"Duration",
 	"Identifier",
+	"PathElem",
+	"IdStart",
 	"IdChar",
 	"Keyword",
 	"_",
 	"LPAR",
 	"RPAR",
 	"COMMA",
+	"PLUS",
+	"MINUS",
+	"TIMES",
+	"DIVIDE",
+	"MODULO",
+	"AS",
+	"AND",
+	"OR",
+	"NOT",
+	"IN",
+	"CIDR",
+	"BETWEEN",
 	"Action0",
 	"Action1",
 	"Action2",
-	"PegText",
 	"Action3",
 	"Action4",
 	"Action5",
@@ -122,8 +226,44 @@ var rul3s = [...]string{
 	"Action9",
 	"Action10",
 	"Action11",
+	"PegText",
 	"Action12",
 	"Action13",
+	"Action14",
+	"Action15",
+	"Action16",
+	"Action17",
+	"Action18",
+	"Action19",
+	"Action20",
+	"Action21",
+	"Action22",
+	"Action23",
+	"Action24",
+	"Action25",
+	"Action26",
+	"Action27",
+	"Action28",
+	"Action29",
+	"Action30",
+	"Action31",
+	"Action32",
+	"Action33",
+	"Action34",
+	"Action35",
+	"Action36",
+	"Action37",
+	"Action38",
+	"Action39",
+	"Action40",
+	"Action41",
+	"Action42",
+	"Action43",
+	"Action44",
+	"Action45",
+	"Action46",
+	"Action47",
+	"Action48",
 }
 
 type token32 struct {
@@ -240,7 +380,7 @@ type parser struct {
 
 	Buffer string
 	buffer []rune
-	rules  [54]func() bool
+	rules  [124]func() bool
 	parse  func(rule ...int) error
 	reset  func()
 	Pretty bool
@@ -337,28 +477,98 @@ func (p *parser) Execute() {
 		case ruleAction1:
 			p.currentSection = "group by"
 		case ruleAction2:
-			p.currentSection = "order by"
+			p.currentSection = "filter"
 		case ruleAction3:
-			p.SetLimit(text)
+			p.AddFilter()
 		case ruleAction4:
-			p.SetPointSize(text)
+			p.AddFilter()
 		case ruleAction5:
-			p.AddColumn()
+			p.currentSection = "having"
 		case ruleAction6:
-			p.SetColumnName(text)
+			p.AddFilter()
 		case ruleAction7:
-			p.SetColumnAggregate(text)
+			p.AddFilter()
 		case ruleAction8:
-			p.SetColumnName(text)
+			p.SetStart()
 		case ruleAction9:
-			p.AddFilter()
+			p.SetEnd()
 		case ruleAction10:
-			p.SetFilterColumn(text)
+			p.SetStart()
 		case ruleAction11:
-			p.SetFilterCondition(text)
+			p.SetEnd()
 		case ruleAction12:
-			p.SetFilterValue(text)
+			p.SetAbsoluteTime(text)
 		case ruleAction13:
+			p.SetRelativeTime("0s")
+		case ruleAction14:
+			p.SetRelativeTime(text)
+		case ruleAction15:
+			p.SetRelativeTime("-" + text)
+		case ruleAction16:
+			p.currentSection = "order by"
+		case ruleAction17:
+			p.SetExplain()
+		case ruleAction18:
+			p.SetLimit(text)
+		case ruleAction19:
+			p.SetTop(text)
+		case ruleAction20:
+			p.SetPointSize(text)
+		case ruleAction21:
+			p.AddColumn()
+		case ruleAction22:
+			p.SetColumnExpr(text)
+		case ruleAction23:
+			p.SetColumnAlias(text)
+		case ruleAction24:
+			p.BinaryExpr("+")
+		case ruleAction25:
+			p.BinaryExpr("-")
+		case ruleAction26:
+			p.BinaryExpr("*")
+		case ruleAction27:
+			p.BinaryExpr("/")
+		case ruleAction28:
+			p.BinaryExpr("%")
+		case ruleAction29:
+			p.PushDuration(text)
+		case ruleAction30:
+			p.PushLiteral(text)
+		case ruleAction31:
+			p.NegateExpr()
+		case ruleAction32:
+			p.PushField(text)
+		case ruleAction33:
+			p.StartCall(text)
+		case ruleAction34:
+			p.AddArgument()
+		case ruleAction35:
+			p.AddArgument()
+		case ruleAction36:
+			p.Or()
+		case ruleAction37:
+			p.And()
+		case ruleAction38:
+			p.Not()
+		case ruleAction39:
+			p.PushFilter()
+		case ruleAction40:
+			p.SetFilterCondition("between")
+		case ruleAction41:
+			p.SetFilterValues()
+		case ruleAction42:
+			p.SetFilterCondition(text)
+		case ruleAction43:
+			p.SetFilterColumn(text)
+		case ruleAction44:
+			p.SetFilterCondition(text)
+		case ruleAction45:
+			p.SetFilterValue(text)
+		case ruleAction46:
+			p.SetFilterValues()
+		case ruleAction47:
+			p.AddFilterValue(text)
+		case ruleAction48:
 			p.SetDescending()
 
 		}
@@ -434,7 +644,7 @@ func (p *parser) Init() {
 
 	_rules = [...]func() bool{
 		nil,
-		/* 0 Query <- <(_ ColumnExpr? _ GroupExpr? _ FilterExpr? _ OrderByExpr? _ LimitExpr? _ PointSizeExpr? _ !.)> */
+		/* 0 Query <- <(_ ExplainExpr? _ ColumnExpr? _ GroupExpr? _ FilterExpr? _ HavingExpr? _ TimeRangeExpr? _ OrderByExpr? _ (LimitExpr / TopExpr)? _ PointSizeExpr? _ !.)> */
 		func() bool {
 			position0, tokenIndex0 := position, tokenIndex
 			{
@@ -444,7 +654,7 @@ func (p *parser) Init() {
 				}
 				{
 					position2, tokenIndex2 := position, tokenIndex
-					if !_rules[ruleColumnExpr]() {
+					if !_rules[ruleExplainExpr]() {
 						goto l2
 					}
 					goto l3
@@ -457,7 +667,7 @@ func (p *parser) Init() {
 				}
 				{
 					position4, tokenIndex4 := position, tokenIndex
-					if !_rules[ruleGroupExpr]() {
+					if !_rules[ruleColumnExpr]() {
 						goto l4
 					}
 					goto l5
@@ -470,7 +680,7 @@ func (p *parser) Init() {
 				}
 				{
 					position6, tokenIndex6 := position, tokenIndex
-					if !_rules[ruleFilterExpr]() {
+					if !_rules[ruleGroupExpr]() {
 						goto l6
 					}
 					goto l7
@@ -483,7 +693,7 @@ func (p *parser) Init() {
 				}
 				{
 					position8, tokenIndex8 := position, tokenIndex
-					if !_rules[ruleOrderByExpr]() {
+					if !_rules[ruleFilterExpr]() {
 						goto l8
 					}
 					goto l9
@@ -496,7 +706,7 @@ func (p *parser) Init() {
 				}
 				{
 					position10, tokenIndex10 := position, tokenIndex
-					if !_rules[ruleLimitExpr]() {
+					if !_rules[ruleHavingExpr]() {
 						goto l10
 					}
 					goto l11
@@ -509,7 +719,7 @@ func (p *parser) Init() {
 				}
 				{
 					position12, tokenIndex12 := position, tokenIndex
-					if !_rules[rulePointSizeExpr]() {
+					if !_rules[ruleTimeRangeExpr]() {
 						goto l12
 					}
 					goto l13
@@ -522,13 +732,62 @@ func (p *parser) Init() {
 				}
 				{
 					position14, tokenIndex14 := position, tokenIndex
-					if !matchDot() {
+					if !_rules[ruleOrderByExpr]() {
 						goto l14
 					}
-					goto l0
+					goto l15
 				l14:
 					position, tokenIndex = position14, tokenIndex14
 				}
+			l15:
+				if !_rules[rule_]() {
+					goto l0
+				}
+				{
+					position16, tokenIndex16 := position, tokenIndex
+					{
+						position18, tokenIndex18 := position, tokenIndex
+						if !_rules[ruleLimitExpr]() {
+							goto l19
+						}
+						goto l18
+					l19:
+						position, tokenIndex = position18, tokenIndex18
+						if !_rules[ruleTopExpr]() {
+							goto l16
+						}
+					}
+				l18:
+					goto l17
+				l16:
+					position, tokenIndex = position16, tokenIndex16
+				}
+			l17:
+				if !_rules[rule_]() {
+					goto l0
+				}
+				{
+					position20, tokenIndex20 := position, tokenIndex
+					if !_rules[rulePointSizeExpr]() {
+						goto l20
+					}
+					goto l21
+				l20:
+					position, tokenIndex = position20, tokenIndex20
+				}
+			l21:
+				if !_rules[rule_]() {
+					goto l0
+				}
+				{
+					position22, tokenIndex22 := position, tokenIndex
+					if !matchDot() {
+						goto l22
+					}
+					goto l0
+				l22:
+					position, tokenIndex = position22, tokenIndex22
+				}
 				add(ruleQuery, position1)
 			}
 			return true
@@ -538,2440 +797,5015 @@ func (p *parser) Init() {
 		},
 		/* 1 ColumnExpr <- <(('s' / 'S') ('e' / 'E') ('l' / 'L') ('e' / 'E') ('c' / 'C') ('t' / 'T') _ Action0 Columns)> */
 		func() bool {
-			position15, tokenIndex15 := position, tokenIndex
+			position23, tokenIndex23 := position, tokenIndex
 			{
-				position16 := position
+				position24 := position
 				{
-					position17, tokenIndex17 := position, tokenIndex
+					position25, tokenIndex25 := position, tokenIndex
 					if buffer[position] != rune('s') {
-						goto l18
+						goto l26
 					}
 					position++
-					goto l17
-				l18:
-					position, tokenIndex = position17, tokenIndex17
+					goto l25
+				l26:
+					position, tokenIndex = position25, tokenIndex25
 					if buffer[position] != rune('S') {
-						goto l15
+						goto l23
 					}
 					position++
 				}
-			l17:
+			l25:
 				{
-					position19, tokenIndex19 := position, tokenIndex
+					position27, tokenIndex27 := position, tokenIndex
 					if buffer[position] != rune('e') {
-						goto l20
+						goto l28
 					}
 					position++
-					goto l19
-				l20:
-					position, tokenIndex = position19, tokenIndex19
+					goto l27
+				l28:
+					position, tokenIndex = position27, tokenIndex27
 					if buffer[position] != rune('E') {
-						goto l15
+						goto l23
 					}
 					position++
 				}
-			l19:
+			l27:
 				{
-					position21, tokenIndex21 := position, tokenIndex
+					position29, tokenIndex29 := position, tokenIndex
 					if buffer[position] != rune('l') {
-						goto l22
+						goto l30
 					}
 					position++
-					goto l21
-				l22:
-					position, tokenIndex = position21, tokenIndex21
+					goto l29
+				l30:
+					position, tokenIndex = position29, tokenIndex29
 					if buffer[position] != rune('L') {
-						goto l15
+						goto l23
 					}
 					position++
 				}
-			l21:
+			l29:
 				{
-					position23, tokenIndex23 := position, tokenIndex
+					position31, tokenIndex31 := position, tokenIndex
 					if buffer[position] != rune('e') {
-						goto l24
+						goto l32
 					}
 					position++
-					goto l23
-				l24:
-					position, tokenIndex = position23, tokenIndex23
+					goto l31
+				l32:
+					position, tokenIndex = position31, tokenIndex31
 					if buffer[position] != rune('E') {
-						goto l15
+						goto l23
 					}
 					position++
 				}
-			l23:
+			l31:
 				{
-					position25, tokenIndex25 := position, tokenIndex
+					position33, tokenIndex33 := position, tokenIndex
 					if buffer[position] != rune('c') {
-						goto l26
+						goto l34
 					}
 					position++
-					goto l25
-				l26:
-					position, tokenIndex = position25, tokenIndex25
+					goto l33
+				l34:
+					position, tokenIndex = position33, tokenIndex33
 					if buffer[position] != rune('C') {
-						goto l15
+						goto l23
 					}
 					position++
 				}
-			l25:
+			l33:
 				{
-					position27, tokenIndex27 := position, tokenIndex
+					position35, tokenIndex35 := position, tokenIndex
 					if buffer[position] != rune('t') {
-						goto l28
+						goto l36
 					}
 					position++
-					goto l27
-				l28:
-					position, tokenIndex = position27, tokenIndex27
+					goto l35
+				l36:
+					position, tokenIndex = position35, tokenIndex35
 					if buffer[position] != rune('T') {
-						goto l15
+						goto l23
 					}
 					position++
 				}
-			l27:
+			l35:
 				if !_rules[rule_]() {
-					goto l15
+					goto l23
 				}
 				if !_rules[ruleAction0]() {
-					goto l15
+					goto l23
 				}
 				if !_rules[ruleColumns]() {
-					goto l15
+					goto l23
 				}
-				add(ruleColumnExpr, position16)
+				add(ruleColumnExpr, position24)
 			}
 			return true
-		l15:
-			position, tokenIndex = position15, tokenIndex15
+		l23:
+			position, tokenIndex = position23, tokenIndex23
 			return false
 		},
 		/* 2 GroupExpr <- <(('g' / 'G') ('r' / 'R') ('o' / 'O') ('u' / 'U') ('p' / 'P') ' ' ('b' / 'B') ('y' / 'Y') _ Action1 Columns)> */
 		func() bool {
-			position29, tokenIndex29 := position, tokenIndex
+			position37, tokenIndex37 := position, tokenIndex
 			{
-				position30 := position
+				position38 := position
 				{
-					position31, tokenIndex31 := position, tokenIndex
+					position39, tokenIndex39 := position, tokenIndex
 					if buffer[position] != rune('g') {
-						goto l32
+						goto l40
 					}
 					position++
-					goto l31
-				l32:
-					position, tokenIndex = position31, tokenIndex31
+					goto l39
+				l40:
+					position, tokenIndex = position39, tokenIndex39
 					if buffer[position] != rune('G') {
-						goto l29
+						goto l37
 					}
 					position++
 				}
-			l31:
+			l39:
 				{
-					position33, tokenIndex33 := position, tokenIndex
+					position41, tokenIndex41 := position, tokenIndex
 					if buffer[position] != rune('r') {
-						goto l34
+						goto l42
 					}
 					position++
-					goto l33
-				l34:
-					position, tokenIndex = position33, tokenIndex33
+					goto l41
+				l42:
+					position, tokenIndex = position41, tokenIndex41
 					if buffer[position] != rune('R') {
-						goto l29
+						goto l37
 					}
 					position++
 				}
-			l33:
+			l41:
 				{
-					position35, tokenIndex35 := position, tokenIndex
+					position43, tokenIndex43 := position, tokenIndex
 					if buffer[position] != rune('o') {
-						goto l36
+						goto l44
 					}
 					position++
-					goto l35
-				l36:
-					position, tokenIndex = position35, tokenIndex35
+					goto l43
+				l44:
+					position, tokenIndex = position43, tokenIndex43
 					if buffer[position] != rune('O') {
-						goto l29
+						goto l37
 					}
 					position++
 				}
-			l35:
+			l43:
 				{
-					position37, tokenIndex37 := position, tokenIndex
+					position45, tokenIndex45 := position, tokenIndex
 					if buffer[position] != rune('u') {
-						goto l38
+						goto l46
 					}
 					position++
-					goto l37
-				l38:
-					position, tokenIndex = position37, tokenIndex37
+					goto l45
+				l46:
+					position, tokenIndex = position45, tokenIndex45
 					if buffer[position] != rune('U') {
-						goto l29
+						goto l37
 					}
 					position++
 				}
-			l37:
+			l45:
 				{
-					position39, tokenIndex39 := position, tokenIndex
+					position47, tokenIndex47 := position, tokenIndex
 					if buffer[position] != rune('p') {
-						goto l40
+						goto l48
 					}
 					position++
-					goto l39
-				l40:
-					position, tokenIndex = position39, tokenIndex39
+					goto l47
+				l48:
+					position, tokenIndex = position47, tokenIndex47
 					if buffer[position] != rune('P') {
-						goto l29
+						goto l37
 					}
 					position++
 				}
-			l39:
+			l47:
 				if buffer[position] != rune(' ') {
-					goto l29
+					goto l37
 				}
 				position++
 				{
-					position41, tokenIndex41 := position, tokenIndex
+					position49, tokenIndex49 := position, tokenIndex
 					if buffer[position] != rune('b') {
-						goto l42
+						goto l50
 					}
 					position++
-					goto l41
-				l42:
-					position, tokenIndex = position41, tokenIndex41
+					goto l49
+				l50:
+					position, tokenIndex = position49, tokenIndex49
 					if buffer[position] != rune('B') {
-						goto l29
+						goto l37
 					}
 					position++
 				}
-			l41:
+			l49:
 				{
-					position43, tokenIndex43 := position, tokenIndex
+					position51, tokenIndex51 := position, tokenIndex
 					if buffer[position] != rune('y') {
-						goto l44
+						goto l52
 					}
 					position++
-					goto l43
-				l44:
-					position, tokenIndex = position43, tokenIndex43
+					goto l51
+				l52:
+					position, tokenIndex = position51, tokenIndex51
 					if buffer[position] != rune('Y') {
-						goto l29
+						goto l37
 					}
 					position++
 				}
-			l43:
+			l51:
 				if !_rules[rule_]() {
-					goto l29
+					goto l37
 				}
 				if !_rules[ruleAction1]() {
-					goto l29
+					goto l37
 				}
 				if !_rules[ruleColumns]() {
-					goto l29
+					goto l37
 				}
-				add(ruleGroupExpr, position30)
+				add(ruleGroupExpr, position38)
 			}
 			return true
-		l29:
-			position, tokenIndex = position29, tokenIndex29
+		l37:
+			position, tokenIndex = position37, tokenIndex37
 			return false
 		},
-		/* 3 FilterExpr <- <(('f' / 'F') ('i' / 'I') ('l' / 'L') ('t' / 'T') ('e' / 'E') ('r' / 'R') _ LogicExpr (_ COMMA? LogicExpr)*)> */
+		/* 3 FilterExpr <- <(('f' / 'F') ('i' / 'I') ('l' / 'L') ('t' / 'T') ('e' / 'E') ('r' / 'R') _ Action2 LogicExpr Action3 (_ COMMA? LogicExpr Action4)*)> */
 		func() bool {
-			position45, tokenIndex45 := position, tokenIndex
+			position53, tokenIndex53 := position, tokenIndex
 			{
-				position46 := position
+				position54 := position
 				{
-					position47, tokenIndex47 := position, tokenIndex
+					position55, tokenIndex55 := position, tokenIndex
 					if buffer[position] != rune('f') {
-						goto l48
+						goto l56
 					}
 					position++
-					goto l47
-				l48:
-					position, tokenIndex = position47, tokenIndex47
+					goto l55
+				l56:
+					position, tokenIndex = position55, tokenIndex55
 					if buffer[position] != rune('F') {
-						goto l45
+						goto l53
 					}
 					position++
 				}
-			l47:
+			l55:
 				{
-					position49, tokenIndex49 := position, tokenIndex
+					position57, tokenIndex57 := position, tokenIndex
 					if buffer[position] != rune('i') {
-						goto l50
+						goto l58
 					}
 					position++
-					goto l49
-				l50:
-					position, tokenIndex = position49, tokenIndex49
+					goto l57
+				l58:
+					position, tokenIndex = position57, tokenIndex57
 					if buffer[position] != rune('I') {
-						goto l45
+						goto l53
 					}
 					position++
 				}
-			l49:
+			l57:
 				{
-					position51, tokenIndex51 := position, tokenIndex
+					position59, tokenIndex59 := position, tokenIndex
 					if buffer[position] != rune('l') {
-						goto l52
+						goto l60
 					}
 					position++
-					goto l51
-				l52:
-					position, tokenIndex = position51, tokenIndex51
+					goto l59
+				l60:
+					position, tokenIndex = position59, tokenIndex59
 					if buffer[position] != rune('L') {
-						goto l45
+						goto l53
 					}
 					position++
 				}
-			l51:
+			l59:
 				{
-					position53, tokenIndex53 := position, tokenIndex
+					position61, tokenIndex61 := position, tokenIndex
 					if buffer[position] != rune('t') {
-						goto l54
+						goto l62
 					}
 					position++
-					goto l53
-				l54:
-					position, tokenIndex = position53, tokenIndex53
+					goto l61
+				l62:
+					position, tokenIndex = position61, tokenIndex61
 					if buffer[position] != rune('T') {
-						goto l45
+						goto l53
 					}
 					position++
 				}
-			l53:
+			l61:
 				{
-					position55, tokenIndex55 := position, tokenIndex
+					position63, tokenIndex63 := position, tokenIndex
 					if buffer[position] != rune('e') {
-						goto l56
+						goto l64
 					}
 					position++
-					goto l55
-				l56:
-					position, tokenIndex = position55, tokenIndex55
+					goto l63
+				l64:
+					position, tokenIndex = position63, tokenIndex63
 					if buffer[position] != rune('E') {
-						goto l45
+						goto l53
 					}
 					position++
 				}
-			l55:
+			l63:
 				{
-					position57, tokenIndex57 := position, tokenIndex
+					position65, tokenIndex65 := position, tokenIndex
 					if buffer[position] != rune('r') {
-						goto l58
+						goto l66
 					}
 					position++
-					goto l57
-				l58:
-					position, tokenIndex = position57, tokenIndex57
+					goto l65
+				l66:
+					position, tokenIndex = position65, tokenIndex65
 					if buffer[position] != rune('R') {
-						goto l45
+						goto l53
 					}
 					position++
 				}
-			l57:
+			l65:
 				if !_rules[rule_]() {
-					goto l45
+					goto l53
+				}
+				if !_rules[ruleAction2]() {
+					goto l53
 				}
 				if !_rules[ruleLogicExpr]() {
-					goto l45
+					goto l53
 				}
-			l59:
+				if !_rules[ruleAction3]() {
+					goto l53
+				}
+			l67:
 				{
-					position60, tokenIndex60 := position, tokenIndex
+					position68, tokenIndex68 := position, tokenIndex
 					if !_rules[rule_]() {
-						goto l60
+						goto l68
 					}
 					{
-						position61, tokenIndex61 := position, tokenIndex
+						position69, tokenIndex69 := position, tokenIndex
 						if !_rules[ruleCOMMA]() {
-							goto l61
+							goto l69
 						}
-						goto l62
-					l61:
-						position, tokenIndex = position61, tokenIndex61
+						goto l70
+					l69:
+						position, tokenIndex = position69, tokenIndex69
 					}
-				l62:
+				l70:
 					if !_rules[ruleLogicExpr]() {
-						goto l60
+						goto l68
 					}
-					goto l59
-				l60:
-					position, tokenIndex = position60, tokenIndex60
+					if !_rules[ruleAction4]() {
+						goto l68
+					}
+					goto l67
+				l68:
+					position, tokenIndex = position68, tokenIndex68
 				}
-				add(ruleFilterExpr, position46)
+				add(ruleFilterExpr, position54)
 			}
 			return true
-		l45:
-			position, tokenIndex = position45, tokenIndex45
+		l53:
+			position, tokenIndex = position53, tokenIndex53
 			return false
 		},
-		/* 4 OrderByExpr <- <(('o' / 'O') ('r' / 'R') ('d' / 'D') ('e' / 'E') ('r' / 'R') ' ' ('b' / 'B') ('y' / 'Y') _ Action2 Columns Descending?)> */
+		/* 4 HavingExpr <- <(('h' / 'H') ('a' / 'A') ('v' / 'V') ('i' / 'I') ('n' / 'N') ('g' / 'G') _ Action5 LogicExpr Action6 (_ COMMA? LogicExpr Action7)*)> */
 		func() bool {
-			position63, tokenIndex63 := position, tokenIndex
+			position71, tokenIndex71 := position, tokenIndex
 			{
-				position64 := position
-				{
-					position65, tokenIndex65 := position, tokenIndex
-					if buffer[position] != rune('o') {
-						goto l66
-					}
-					position++
-					goto l65
-				l66:
-					position, tokenIndex = position65, tokenIndex65
-					if buffer[position] != rune('O') {
-						goto l63
-					}
-					position++
-				}
-			l65:
-				{
-					position67, tokenIndex67 := position, tokenIndex
-					if buffer[position] != rune('r') {
-						goto l68
-					}
-					position++
-					goto l67
-				l68:
-					position, tokenIndex = position67, tokenIndex67
-					if buffer[position] != rune('R') {
-						goto l63
-					}
-					position++
-				}
-			l67:
-				{
-					position69, tokenIndex69 := position, tokenIndex
-					if buffer[position] != rune('d') {
-						goto l70
-					}
-					position++
-					goto l69
-				l70:
-					position, tokenIndex = position69, tokenIndex69
-					if buffer[position] != rune('D') {
-						goto l63
-					}
-					position++
-				}
-			l69:
-				{
-					position71, tokenIndex71 := position, tokenIndex
-					if buffer[position] != rune('e') {
-						goto l72
-					}
-					position++
-					goto l71
-				l72:
-					position, tokenIndex = position71, tokenIndex71
-					if buffer[position] != rune('E') {
-						goto l63
-					}
-					position++
-				}
-			l71:
+				position72 := position
 				{
 					position73, tokenIndex73 := position, tokenIndex
-					if buffer[position] != rune('r') {
+					if buffer[position] != rune('h') {
 						goto l74
 					}
 					position++
 					goto l73
 				l74:
 					position, tokenIndex = position73, tokenIndex73
-					if buffer[position] != rune('R') {
-						goto l63
+					if buffer[position] != rune('H') {
+						goto l71
 					}
 					position++
 				}
 			l73:
-				if buffer[position] != rune(' ') {
-					goto l63
-				}
-				position++
 				{
 					position75, tokenIndex75 := position, tokenIndex
-					if buffer[position] != rune('b') {
+					if buffer[position] != rune('a') {
 						goto l76
 					}
 					position++
 					goto l75
 				l76:
 					position, tokenIndex = position75, tokenIndex75
-					if buffer[position] != rune('B') {
-						goto l63
+					if buffer[position] != rune('A') {
+						goto l71
 					}
 					position++
 				}
 			l75:
 				{
 					position77, tokenIndex77 := position, tokenIndex
-					if buffer[position] != rune('y') {
+					if buffer[position] != rune('v') {
 						goto l78
 					}
 					position++
 					goto l77
 				l78:
 					position, tokenIndex = position77, tokenIndex77
-					if buffer[position] != rune('Y') {
-						goto l63
+					if buffer[position] != rune('V') {
+						goto l71
 					}
 					position++
 				}
 			l77:
-				if !_rules[rule_]() {
-					goto l63
-				}
-				if !_rules[ruleAction2]() {
-					goto l63
-				}
-				if !_rules[ruleColumns]() {
-					goto l63
-				}
 				{
 					position79, tokenIndex79 := position, tokenIndex
-					if !_rules[ruleDescending]() {
-						goto l79
+					if buffer[position] != rune('i') {
+						goto l80
 					}
-					goto l80
-				l79:
+					position++
+					goto l79
+				l80:
 					position, tokenIndex = position79, tokenIndex79
+					if buffer[position] != rune('I') {
+						goto l71
+					}
+					position++
 				}
-			l80:
-				add(ruleOrderByExpr, position64)
-			}
-			return true
-		l63:
-			position, tokenIndex = position63, tokenIndex63
-			return false
-		},
-		/* 5 LimitExpr <- <(('l' / 'L') ('i' / 'I') ('m' / 'M') ('i' / 'I') ('t' / 'T') _ <Unsigned> Action3)> */
-		func() bool {
-			position81, tokenIndex81 := position, tokenIndex
-			{
-				position82 := position
+			l79:
+				{
+					position81, tokenIndex81 := position, tokenIndex
+					if buffer[position] != rune('n') {
+						goto l82
+					}
+					position++
+					goto l81
+				l82:
+					position, tokenIndex = position81, tokenIndex81
+					if buffer[position] != rune('N') {
+						goto l71
+					}
+					position++
+				}
+			l81:
 				{
 					position83, tokenIndex83 := position, tokenIndex
-					if buffer[position] != rune('l') {
+					if buffer[position] != rune('g') {
 						goto l84
 					}
 					position++
 					goto l83
 				l84:
 					position, tokenIndex = position83, tokenIndex83
-					if buffer[position] != rune('L') {
-						goto l81
+					if buffer[position] != rune('G') {
+						goto l71
 					}
 					position++
 				}
 			l83:
-				{
-					position85, tokenIndex85 := position, tokenIndex
-					if buffer[position] != rune('i') {
-						goto l86
-					}
-					position++
-					goto l85
-				l86:
-					position, tokenIndex = position85, tokenIndex85
-					if buffer[position] != rune('I') {
-						goto l81
-					}
-					position++
+				if !_rules[rule_]() {
+					goto l71
+				}
+				if !_rules[ruleAction5]() {
+					goto l71
+				}
+				if !_rules[ruleLogicExpr]() {
+					goto l71
+				}
+				if !_rules[ruleAction6]() {
+					goto l71
 				}
 			l85:
 				{
-					position87, tokenIndex87 := position, tokenIndex
-					if buffer[position] != rune('m') {
+					position86, tokenIndex86 := position, tokenIndex
+					if !_rules[rule_]() {
+						goto l86
+					}
+					{
+						position87, tokenIndex87 := position, tokenIndex
+						if !_rules[ruleCOMMA]() {
+							goto l87
+						}
 						goto l88
+					l87:
+						position, tokenIndex = position87, tokenIndex87
 					}
-					position++
-					goto l87
 				l88:
-					position, tokenIndex = position87, tokenIndex87
-					if buffer[position] != rune('M') {
-						goto l81
-					}
-					position++
-				}
-			l87:
-				{
-					position89, tokenIndex89 := position, tokenIndex
-					if buffer[position] != rune('i') {
-						goto l90
+					if !_rules[ruleLogicExpr]() {
+						goto l86
 					}
-					position++
-					goto l89
-				l90:
-					position, tokenIndex = position89, tokenIndex89
-					if buffer[position] != rune('I') {
-						goto l81
+					if !_rules[ruleAction7]() {
+						goto l86
 					}
-					position++
+					goto l85
+				l86:
+					position, tokenIndex = position86, tokenIndex86
 				}
-			l89:
+				add(ruleHavingExpr, position72)
+			}
+			return true
+		l71:
+			position, tokenIndex = position71, tokenIndex71
+			return false
+		},
+		/* 5 TimeRangeExpr <- <((SinceExpr (_ UntilExpr)?) / UntilExpr / (('b' / 'B') ('e' / 'E') ('t' / 'T') ('w' / 'W') ('e' / 'E') ('e' / 'E') ('n' / 'N') _ TimeValue Action8 AND TimeValue Action9))> */
+		func() bool {
+			position89, tokenIndex89 := position, tokenIndex
+			{
+				position90 := position
 				{
 					position91, tokenIndex91 := position, tokenIndex
-					if buffer[position] != rune('t') {
+					if !_rules[ruleSinceExpr]() {
 						goto l92
 					}
-					position++
+					{
+						position93, tokenIndex93 := position, tokenIndex
+						if !_rules[rule_]() {
+							goto l93
+						}
+						if !_rules[ruleUntilExpr]() {
+							goto l93
+						}
+						goto l94
+					l93:
+						position, tokenIndex = position93, tokenIndex93
+					}
+				l94:
 					goto l91
 				l92:
 					position, tokenIndex = position91, tokenIndex91
-					if buffer[position] != rune('T') {
-						goto l81
+					if !_rules[ruleUntilExpr]() {
+						goto l95
 					}
-					position++
-				}
-			l91:
-				if !_rules[rule_]() {
-					goto l81
-				}
-				{
-					position93 := position
-					if !_rules[ruleUnsigned]() {
-						goto l81
+					goto l91
+				l95:
+					position, tokenIndex = position91, tokenIndex91
+					{
+						position96, tokenIndex96 := position, tokenIndex
+						if buffer[position] != rune('b') {
+							goto l97
+						}
+						position++
+						goto l96
+					l97:
+						position, tokenIndex = position96, tokenIndex96
+						if buffer[position] != rune('B') {
+							goto l89
+						}
+						position++
+					}
+				l96:
+					{
+						position98, tokenIndex98 := position, tokenIndex
+						if buffer[position] != rune('e') {
+							goto l99
+						}
+						position++
+						goto l98
+					l99:
+						position, tokenIndex = position98, tokenIndex98
+						if buffer[position] != rune('E') {
+							goto l89
+						}
+						position++
+					}
+				l98:
+					{
+						position100, tokenIndex100 := position, tokenIndex
+						if buffer[position] != rune('t') {
+							goto l101
+						}
+						position++
+						goto l100
+					l101:
+						position, tokenIndex = position100, tokenIndex100
+						if buffer[position] != rune('T') {
+							goto l89
+						}
+						position++
+					}
+				l100:
+					{
+						position102, tokenIndex102 := position, tokenIndex
+						if buffer[position] != rune('w') {
+							goto l103
+						}
+						position++
+						goto l102
+					l103:
+						position, tokenIndex = position102, tokenIndex102
+						if buffer[position] != rune('W') {
+							goto l89
+						}
+						position++
+					}
+				l102:
+					{
+						position104, tokenIndex104 := position, tokenIndex
+						if buffer[position] != rune('e') {
+							goto l105
+						}
+						position++
+						goto l104
+					l105:
+						position, tokenIndex = position104, tokenIndex104
+						if buffer[position] != rune('E') {
+							goto l89
+						}
+						position++
+					}
+				l104:
+					{
+						position106, tokenIndex106 := position, tokenIndex
+						if buffer[position] != rune('e') {
+							goto l107
+						}
+						position++
+						goto l106
+					l107:
+						position, tokenIndex = position106, tokenIndex106
+						if buffer[position] != rune('E') {
+							goto l89
+						}
+						position++
+					}
+				l106:
+					{
+						position108, tokenIndex108 := position, tokenIndex
+						if buffer[position] != rune('n') {
+							goto l109
+						}
+						position++
+						goto l108
+					l109:
+						position, tokenIndex = position108, tokenIndex108
+						if buffer[position] != rune('N') {
+							goto l89
+						}
+						position++
+					}
+				l108:
+					if !_rules[rule_]() {
+						goto l89
+					}
+					if !_rules[ruleTimeValue]() {
+						goto l89
+					}
+					if !_rules[ruleAction8]() {
+						goto l89
+					}
+					if !_rules[ruleAND]() {
+						goto l89
+					}
+					if !_rules[ruleTimeValue]() {
+						goto l89
+					}
+					if !_rules[ruleAction9]() {
+						goto l89
 					}
-					add(rulePegText, position93)
-				}
-				if !_rules[ruleAction3]() {
-					goto l81
 				}
-				add(ruleLimitExpr, position82)
+			l91:
+				add(ruleTimeRangeExpr, position90)
 			}
 			return true
-		l81:
-			position, tokenIndex = position81, tokenIndex81
+		l89:
+			position, tokenIndex = position89, tokenIndex89
 			return false
 		},
-		/* 6 PointSizeExpr <- <(('p' / 'P') ('o' / 'O') ('i' / 'I') ('n' / 'N') ('t' / 'T') ' ' ('s' / 'S') ('i' / 'I') ('z' / 'Z') ('e' / 'E') _ <Duration> Action4)> */
+		/* 6 SinceExpr <- <(('s' / 'S') ('i' / 'I') ('n' / 'N') ('c' / 'C') ('e' / 'E') _ TimeValue Action10)> */
 		func() bool {
-			position94, tokenIndex94 := position, tokenIndex
+			position110, tokenIndex110 := position, tokenIndex
 			{
-				position95 := position
+				position111 := position
 				{
-					position96, tokenIndex96 := position, tokenIndex
-					if buffer[position] != rune('p') {
-						goto l97
-					}
-					position++
-					goto l96
-				l97:
-					position, tokenIndex = position96, tokenIndex96
-					if buffer[position] != rune('P') {
-						goto l94
-					}
-					position++
-				}
-			l96:
-				{
-					position98, tokenIndex98 := position, tokenIndex
-					if buffer[position] != rune('o') {
-						goto l99
+					position112, tokenIndex112 := position, tokenIndex
+					if buffer[position] != rune('s') {
+						goto l113
 					}
 					position++
-					goto l98
-				l99:
-					position, tokenIndex = position98, tokenIndex98
-					if buffer[position] != rune('O') {
-						goto l94
+					goto l112
+				l113:
+					position, tokenIndex = position112, tokenIndex112
+					if buffer[position] != rune('S') {
+						goto l110
 					}
 					position++
 				}
-			l98:
+			l112:
 				{
-					position100, tokenIndex100 := position, tokenIndex
+					position114, tokenIndex114 := position, tokenIndex
 					if buffer[position] != rune('i') {
-						goto l101
+						goto l115
 					}
 					position++
-					goto l100
-				l101:
-					position, tokenIndex = position100, tokenIndex100
+					goto l114
+				l115:
+					position, tokenIndex = position114, tokenIndex114
 					if buffer[position] != rune('I') {
-						goto l94
+						goto l110
 					}
 					position++
 				}
-			l100:
+			l114:
 				{
-					position102, tokenIndex102 := position, tokenIndex
+					position116, tokenIndex116 := position, tokenIndex
 					if buffer[position] != rune('n') {
-						goto l103
+						goto l117
 					}
 					position++
-					goto l102
-				l103:
-					position, tokenIndex = position102, tokenIndex102
+					goto l116
+				l117:
+					position, tokenIndex = position116, tokenIndex116
 					if buffer[position] != rune('N') {
-						goto l94
-					}
-					position++
-				}
-			l102:
-				{
-					position104, tokenIndex104 := position, tokenIndex
-					if buffer[position] != rune('t') {
-						goto l105
-					}
-					position++
-					goto l104
-				l105:
-					position, tokenIndex = position104, tokenIndex104
-					if buffer[position] != rune('T') {
-						goto l94
-					}
-					position++
-				}
-			l104:
-				if buffer[position] != rune(' ') {
-					goto l94
-				}
-				position++
-				{
-					position106, tokenIndex106 := position, tokenIndex
-					if buffer[position] != rune('s') {
-						goto l107
-					}
-					position++
-					goto l106
-				l107:
-					position, tokenIndex = position106, tokenIndex106
-					if buffer[position] != rune('S') {
-						goto l94
-					}
-					position++
-				}
-			l106:
-				{
-					position108, tokenIndex108 := position, tokenIndex
-					if buffer[position] != rune('i') {
-						goto l109
-					}
-					position++
-					goto l108
-				l109:
-					position, tokenIndex = position108, tokenIndex108
-					if buffer[position] != rune('I') {
-						goto l94
+						goto l110
 					}
 					position++
 				}
-			l108:
+			l116:
 				{
-					position110, tokenIndex110 := position, tokenIndex
-					if buffer[position] != rune('z') {
-						goto l111
+					position118, tokenIndex118 := position, tokenIndex
+					if buffer[position] != rune('c') {
+						goto l119
 					}
 					position++
-					goto l110
-				l111:
-					position, tokenIndex = position110, tokenIndex110
-					if buffer[position] != rune('Z') {
-						goto l94
+					goto l118
+				l119:
+					position, tokenIndex = position118, tokenIndex118
+					if buffer[position] != rune('C') {
+						goto l110
 					}
 					position++
 				}
-			l110:
+			l118:
 				{
-					position112, tokenIndex112 := position, tokenIndex
+					position120, tokenIndex120 := position, tokenIndex
 					if buffer[position] != rune('e') {
-						goto l113
+						goto l121
 					}
 					position++
-					goto l112
-				l113:
-					position, tokenIndex = position112, tokenIndex112
+					goto l120
+				l121:
+					position, tokenIndex = position120, tokenIndex120
 					if buffer[position] != rune('E') {
-						goto l94
+						goto l110
 					}
 					position++
 				}
-			l112:
+			l120:
 				if !_rules[rule_]() {
-					goto l94
+					goto l110
 				}
-				{
-					position114 := position
-					if !_rules[ruleDuration]() {
-						goto l94
-					}
-					add(rulePegText, position114)
+				if !_rules[ruleTimeValue]() {
+					goto l110
 				}
-				if !_rules[ruleAction4]() {
-					goto l94
+				if !_rules[ruleAction10]() {
+					goto l110
 				}
-				add(rulePointSizeExpr, position95)
+				add(ruleSinceExpr, position111)
 			}
 			return true
-		l94:
-			position, tokenIndex = position94, tokenIndex94
+		l110:
+			position, tokenIndex = position110, tokenIndex110
 			return false
 		},
-		/* 7 Columns <- <(Column (COMMA Column)*)> */
+		/* 7 UntilExpr <- <(('u' / 'U') ('n' / 'N') ('t' / 'T') ('i' / 'I') ('l' / 'L') _ TimeValue Action11)> */
 		func() bool {
-			position115, tokenIndex115 := position, tokenIndex
+			position122, tokenIndex122 := position, tokenIndex
 			{
-				position116 := position
-				if !_rules[ruleColumn]() {
-					goto l115
-				}
-			l117:
+				position123 := position
 				{
-					position118, tokenIndex118 := position, tokenIndex
-					if !_rules[ruleCOMMA]() {
-						goto l118
+					position124, tokenIndex124 := position, tokenIndex
+					if buffer[position] != rune('u') {
+						goto l125
 					}
-					if !_rules[ruleColumn]() {
-						goto l118
+					position++
+					goto l124
+				l125:
+					position, tokenIndex = position124, tokenIndex124
+					if buffer[position] != rune('U') {
+						goto l122
 					}
-					goto l117
-				l118:
-					position, tokenIndex = position118, tokenIndex118
-				}
-				add(ruleColumns, position116)
-			}
-			return true
-		l115:
-			position, tokenIndex = position115, tokenIndex115
-			return false
-		},
-		/* 8 Column <- <(Action5 (ColumnAggregation / (<Identifier> _ Action6)))> */
-		func() bool {
-			position119, tokenIndex119 := position, tokenIndex
-			{
-				position120 := position
-				if !_rules[ruleAction5]() {
-					goto l119
+					position++
 				}
+			l124:
 				{
-					position121, tokenIndex121 := position, tokenIndex
-					if !_rules[ruleColumnAggregation]() {
-						goto l122
-					}
-					goto l121
-				l122:
-					position, tokenIndex = position121, tokenIndex121
-					{
-						position123 := position
-						if !_rules[ruleIdentifier]() {
-							goto l119
-						}
-						add(rulePegText, position123)
-					}
-					if !_rules[rule_]() {
-						goto l119
+					position126, tokenIndex126 := position, tokenIndex
+					if buffer[position] != rune('n') {
+						goto l127
 					}
-					if !_rules[ruleAction6]() {
-						goto l119
+					position++
+					goto l126
+				l127:
+					position, tokenIndex = position126, tokenIndex126
+					if buffer[position] != rune('N') {
+						goto l122
 					}
+					position++
 				}
-			l121:
-				add(ruleColumn, position120)
-			}
-			return true
-		l119:
-			position, tokenIndex = position119, tokenIndex119
-			return false
-		},
-		/* 9 ColumnAggregation <- <(<Identifier> Action7 LPAR <Identifier> RPAR Action8)> */
-		func() bool {
-			position124, tokenIndex124 := position, tokenIndex
-			{
-				position125 := position
+			l126:
 				{
-					position126 := position
-					if !_rules[ruleIdentifier]() {
-						goto l124
+					position128, tokenIndex128 := position, tokenIndex
+					if buffer[position] != rune('t') {
+						goto l129
 					}
-					add(rulePegText, position126)
-				}
-				if !_rules[ruleAction7]() {
-					goto l124
-				}
-				if !_rules[ruleLPAR]() {
-					goto l124
-				}
-				{
-					position127 := position
-					if !_rules[ruleIdentifier]() {
-						goto l124
+					position++
+					goto l128
+				l129:
+					position, tokenIndex = position128, tokenIndex128
+					if buffer[position] != rune('T') {
+						goto l122
 					}
-					add(rulePegText, position127)
-				}
-				if !_rules[ruleRPAR]() {
-					goto l124
-				}
-				if !_rules[ruleAction8]() {
-					goto l124
+					position++
 				}
-				add(ruleColumnAggregation, position125)
-			}
-			return true
-		l124:
-			position, tokenIndex = position124, tokenIndex124
-			return false
-		},
-		/* 10 LogicExpr <- <((LPAR LogicExpr RPAR) / (Action9 FilterKey _ FilterCondition _ FilterValue))> */
-		func() bool {
-			position128, tokenIndex128 := position, tokenIndex
-			{
-				position129 := position
+			l128:
 				{
 					position130, tokenIndex130 := position, tokenIndex
-					if !_rules[ruleLPAR]() {
-						goto l131
-					}
-					if !_rules[ruleLogicExpr]() {
-						goto l131
-					}
-					if !_rules[ruleRPAR]() {
+					if buffer[position] != rune('i') {
 						goto l131
 					}
+					position++
 					goto l130
 				l131:
 					position, tokenIndex = position130, tokenIndex130
-					if !_rules[ruleAction9]() {
-						goto l128
-					}
-					if !_rules[ruleFilterKey]() {
-						goto l128
-					}
-					if !_rules[rule_]() {
-						goto l128
-					}
-					if !_rules[ruleFilterCondition]() {
-						goto l128
+					if buffer[position] != rune('I') {
+						goto l122
 					}
-					if !_rules[rule_]() {
-						goto l128
+					position++
+				}
+			l130:
+				{
+					position132, tokenIndex132 := position, tokenIndex
+					if buffer[position] != rune('l') {
+						goto l133
 					}
-					if !_rules[ruleFilterValue]() {
-						goto l128
+					position++
+					goto l132
+				l133:
+					position, tokenIndex = position132, tokenIndex132
+					if buffer[position] != rune('L') {
+						goto l122
 					}
+					position++
 				}
-			l130:
-				add(ruleLogicExpr, position129)
+			l132:
+				if !_rules[rule_]() {
+					goto l122
+				}
+				if !_rules[ruleTimeValue]() {
+					goto l122
+				}
+				if !_rules[ruleAction11]() {
+					goto l122
+				}
+				add(ruleUntilExpr, position123)
 			}
 			return true
-		l128:
-			position, tokenIndex = position128, tokenIndex128
+		l122:
+			position, tokenIndex = position122, tokenIndex122
 			return false
 		},
-		/* 11 OPERATOR <- <('=' / ('!' '=') / '<' / ('<' '=') / '>' / ('>' '=') / (('m' / 'M') ('a' / 'A') ('t' / 'T') ('c' / 'C') ('h' / 'H') ('e' / 'E') ('s' / 'S')))> */
+		/* 8 TimeValue <- <((<String> Action12) / (('n' / 'N') ('o' / 'O') ('w' / 'W') !IdChar Action13 (_ <(('-' / '+') _ TimeOffset)> Action14)?) / (<TimeOffset> Action15))> */
 		func() bool {
-			position132, tokenIndex132 := position, tokenIndex
+			position134, tokenIndex134 := position, tokenIndex
 			{
-				position133 := position
+				position135 := position
 				{
-					position134, tokenIndex134 := position, tokenIndex
-					if buffer[position] != rune('=') {
-						goto l135
-					}
-					position++
-					goto l134
-				l135:
-					position, tokenIndex = position134, tokenIndex134
-					if buffer[position] != rune('!') {
-						goto l136
-					}
-					position++
-					if buffer[position] != rune('=') {
-						goto l136
+					position136, tokenIndex136 := position, tokenIndex
+					{
+						position138 := position
+						if !_rules[ruleString]() {
+							goto l137
+						}
+						add(rulePegText, position138)
 					}
-					position++
-					goto l134
-				l136:
-					position, tokenIndex = position134, tokenIndex134
-					if buffer[position] != rune('<') {
+					if !_rules[ruleAction12]() {
 						goto l137
 					}
-					position++
-					goto l134
+					goto l136
 				l137:
-					position, tokenIndex = position134, tokenIndex134
-					if buffer[position] != rune('<') {
-						goto l138
-					}
-					position++
-					if buffer[position] != rune('=') {
-						goto l138
-					}
-					position++
-					goto l134
-				l138:
-					position, tokenIndex = position134, tokenIndex134
-					if buffer[position] != rune('>') {
-						goto l139
-					}
-					position++
-					goto l134
-				l139:
-					position, tokenIndex = position134, tokenIndex134
-					if buffer[position] != rune('>') {
-						goto l140
-					}
-					position++
-					if buffer[position] != rune('=') {
+					position, tokenIndex = position136, tokenIndex136
+					{
+						position140, tokenIndex140 := position, tokenIndex
+						if buffer[position] != rune('n') {
+							goto l141
+						}
+						position++
 						goto l140
+					l141:
+						position, tokenIndex = position140, tokenIndex140
+						if buffer[position] != rune('N') {
+							goto l139
+						}
+						position++
 					}
-					position++
-					goto l134
 				l140:
-					position, tokenIndex = position134, tokenIndex134
 					{
-						position141, tokenIndex141 := position, tokenIndex
-						if buffer[position] != rune('m') {
-							goto l142
+						position142, tokenIndex142 := position, tokenIndex
+						if buffer[position] != rune('o') {
+							goto l143
 						}
 						position++
-						goto l141
-					l142:
-						position, tokenIndex = position141, tokenIndex141
-						if buffer[position] != rune('M') {
-							goto l132
+						goto l142
+					l143:
+						position, tokenIndex = position142, tokenIndex142
+						if buffer[position] != rune('O') {
+							goto l139
 						}
 						position++
 					}
-				l141:
+				l142:
 					{
-						position143, tokenIndex143 := position, tokenIndex
-						if buffer[position] != rune('a') {
-							goto l144
+						position144, tokenIndex144 := position, tokenIndex
+						if buffer[position] != rune('w') {
+							goto l145
 						}
 						position++
-						goto l143
-					l144:
-						position, tokenIndex = position143, tokenIndex143
-						if buffer[position] != rune('A') {
-							goto l132
+						goto l144
+					l145:
+						position, tokenIndex = position144, tokenIndex144
+						if buffer[position] != rune('W') {
+							goto l139
 						}
 						position++
 					}
-				l143:
+				l144:
 					{
-						position145, tokenIndex145 := position, tokenIndex
-						if buffer[position] != rune('t') {
+						position146, tokenIndex146 := position, tokenIndex
+						if !_rules[ruleIdChar]() {
 							goto l146
 						}
-						position++
-						goto l145
+						goto l139
 					l146:
-						position, tokenIndex = position145, tokenIndex145
-						if buffer[position] != rune('T') {
-							goto l132
-						}
-						position++
+						position, tokenIndex = position146, tokenIndex146
+					}
+					if !_rules[ruleAction13]() {
+						goto l139
 					}
-				l145:
 					{
 						position147, tokenIndex147 := position, tokenIndex
-						if buffer[position] != rune('c') {
-							goto l148
-						}
-						position++
-						goto l147
-					l148:
-						position, tokenIndex = position147, tokenIndex147
-						if buffer[position] != rune('C') {
-							goto l132
+						if !_rules[rule_]() {
+							goto l147
 						}
-						position++
-					}
-				l147:
-					{
-						position149, tokenIndex149 := position, tokenIndex
-						if buffer[position] != rune('h') {
-							goto l150
+						{
+							position149 := position
+							{
+								position150, tokenIndex150 := position, tokenIndex
+								if buffer[position] != rune('-') {
+									goto l151
+								}
+								position++
+								goto l150
+							l151:
+								position, tokenIndex = position150, tokenIndex150
+								if buffer[position] != rune('+') {
+									goto l147
+								}
+								position++
+							}
+						l150:
+							if !_rules[rule_]() {
+								goto l147
+							}
+							if !_rules[ruleTimeOffset]() {
+								goto l147
+							}
+							add(rulePegText, position149)
 						}
-						position++
-						goto l149
-					l150:
-						position, tokenIndex = position149, tokenIndex149
-						if buffer[position] != rune('H') {
-							goto l132
+						if !_rules[ruleAction14]() {
+							goto l147
 						}
-						position++
+						goto l148
+					l147:
+						position, tokenIndex = position147, tokenIndex147
 					}
-				l149:
+				l148:
+					goto l136
+				l139:
+					position, tokenIndex = position136, tokenIndex136
 					{
-						position151, tokenIndex151 := position, tokenIndex
-						if buffer[position] != rune('e') {
-							goto l152
-						}
-						position++
-						goto l151
-					l152:
-						position, tokenIndex = position151, tokenIndex151
-						if buffer[position] != rune('E') {
-							goto l132
+						position152 := position
+						if !_rules[ruleTimeOffset]() {
+							goto l134
 						}
-						position++
+						add(rulePegText, position152)
 					}
-				l151:
-					{
-						position153, tokenIndex153 := position, tokenIndex
-						if buffer[position] != rune('s') {
-							goto l154
-						}
-						position++
-						goto l153
-					l154:
-						position, tokenIndex = position153, tokenIndex153
-						if buffer[position] != rune('S') {
-							goto l132
-						}
-						position++
+					if !_rules[ruleAction15]() {
+						goto l134
 					}
-				l153:
 				}
-			l134:
-				add(ruleOPERATOR, position133)
+			l136:
+				add(ruleTimeValue, position135)
 			}
 			return true
-		l132:
-			position, tokenIndex = position132, tokenIndex132
+		l134:
+			position, tokenIndex = position134, tokenIndex134
 			return false
 		},
-		/* 12 FilterKey <- <(<Identifier> Action10)> */
+		/* 9 TimeOffset <- <(Unsigned ('.' Unsigned)? (('n' 's') / ('u' 's') / ('µ' 's') / ('m' 's') / 's' / 'm' / 'h' / 'd' / 'w'))> */
 		func() bool {
-			position155, tokenIndex155 := position, tokenIndex
+			position153, tokenIndex153 := position, tokenIndex
 			{
-				position156 := position
+				position154 := position
+				if !_rules[ruleUnsigned]() {
+					goto l153
+				}
 				{
-					position157 := position
-					if !_rules[ruleIdentifier]() {
+					position155, tokenIndex155 := position, tokenIndex
+					if buffer[position] != rune('.') {
 						goto l155
 					}
-					add(rulePegText, position157)
-				}
-				if !_rules[ruleAction10]() {
-					goto l155
+					position++
+					if !_rules[ruleUnsigned]() {
+						goto l155
+					}
+					goto l156
+				l155:
+					position, tokenIndex = position155, tokenIndex155
 				}
-				add(ruleFilterKey, position156)
-			}
-			return true
-		l155:
-			position, tokenIndex = position155, tokenIndex155
-			return false
-		},
-		/* 13 FilterCondition <- <(<OPERATOR> Action11)> */
-		func() bool {
-			position158, tokenIndex158 := position, tokenIndex
-			{
-				position159 := position
+			l156:
 				{
-					position160 := position
-					if !_rules[ruleOPERATOR]() {
+					position157, tokenIndex157 := position, tokenIndex
+					if buffer[position] != rune('n') {
 						goto l158
 					}
-					add(rulePegText, position160)
-				}
-				if !_rules[ruleAction11]() {
-					goto l158
-				}
-				add(ruleFilterCondition, position159)
-			}
-			return true
-		l158:
-			position, tokenIndex = position158, tokenIndex158
-			return false
-		},
-		/* 14 FilterValue <- <(<Value> Action12)> */
-		func() bool {
-			position161, tokenIndex161 := position, tokenIndex
-			{
-				position162 := position
-				{
-					position163 := position
-					if !_rules[ruleValue]() {
+					position++
+					if buffer[position] != rune('s') {
+						goto l158
+					}
+					position++
+					goto l157
+				l158:
+					position, tokenIndex = position157, tokenIndex157
+					if buffer[position] != rune('u') {
+						goto l159
+					}
+					position++
+					if buffer[position] != rune('s') {
+						goto l159
+					}
+					position++
+					goto l157
+				l159:
+					position, tokenIndex = position157, tokenIndex157
+					if buffer[position] != rune('µ') {
+						goto l160
+					}
+					position++
+					if buffer[position] != rune('s') {
+						goto l160
+					}
+					position++
+					goto l157
+				l160:
+					position, tokenIndex = position157, tokenIndex157
+					if buffer[position] != rune('m') {
 						goto l161
 					}
-					add(rulePegText, position163)
-				}
-				if !_rules[ruleAction12]() {
-					goto l161
+					position++
+					if buffer[position] != rune('s') {
+						goto l161
+					}
+					position++
+					goto l157
+				l161:
+					position, tokenIndex = position157, tokenIndex157
+					if buffer[position] != rune('s') {
+						goto l162
+					}
+					position++
+					goto l157
+				l162:
+					position, tokenIndex = position157, tokenIndex157
+					if buffer[position] != rune('m') {
+						goto l163
+					}
+					position++
+					goto l157
+				l163:
+					position, tokenIndex = position157, tokenIndex157
+					if buffer[position] != rune('h') {
+						goto l164
+					}
+					position++
+					goto l157
+				l164:
+					position, tokenIndex = position157, tokenIndex157
+					if buffer[position] != rune('d') {
+						goto l165
+					}
+					position++
+					goto l157
+				l165:
+					position, tokenIndex = position157, tokenIndex157
+					if buffer[position] != rune('w') {
+						goto l153
+					}
+					position++
 				}
-				add(ruleFilterValue, position162)
+			l157:
+				add(ruleTimeOffset, position154)
 			}
 			return true
-		l161:
-			position, tokenIndex = position161, tokenIndex161
+		l153:
+			position, tokenIndex = position153, tokenIndex153
 			return false
 		},
-		/* 15 Value <- <(Float / Integer / String)> */
+		/* 10 OrderByExpr <- <(('o' / 'O') ('r' / 'R') ('d' / 'D') ('e' / 'E') ('r' / 'R') ' ' ('b' / 'B') ('y' / 'Y') _ Action16 OrderColumn (COMMA OrderColumn)*)> */
 		func() bool {
-			position164, tokenIndex164 := position, tokenIndex
+			position166, tokenIndex166 := position, tokenIndex
 			{
-				position165 := position
+				position167 := position
 				{
-					position166, tokenIndex166 := position, tokenIndex
-					if !_rules[ruleFloat]() {
-						goto l167
+					position168, tokenIndex168 := position, tokenIndex
+					if buffer[position] != rune('o') {
+						goto l169
 					}
-					goto l166
-				l167:
-					position, tokenIndex = position166, tokenIndex166
-					if !_rules[ruleInteger]() {
-						goto l168
+					position++
+					goto l168
+				l169:
+					position, tokenIndex = position168, tokenIndex168
+					if buffer[position] != rune('O') {
+						goto l166
 					}
-					goto l166
-				l168:
-					position, tokenIndex = position166, tokenIndex166
-					if !_rules[ruleString]() {
-						goto l164
+					position++
+				}
+			l168:
+				{
+					position170, tokenIndex170 := position, tokenIndex
+					if buffer[position] != rune('r') {
+						goto l171
+					}
+					position++
+					goto l170
+				l171:
+					position, tokenIndex = position170, tokenIndex170
+					if buffer[position] != rune('R') {
+						goto l166
 					}
+					position++
 				}
-			l166:
-				add(ruleValue, position165)
-			}
-			return true
-		l164:
-			position, tokenIndex = position164, tokenIndex164
-			return false
-		},
-		/* 16 Descending <- <(('d' / 'D') ('e' / 'E') ('s' / 'S') ('c' / 'C') Action13)> */
-		func() bool {
-			position169, tokenIndex169 := position, tokenIndex
-			{
-				position170 := position
+			l170:
 				{
-					position171, tokenIndex171 := position, tokenIndex
+					position172, tokenIndex172 := position, tokenIndex
 					if buffer[position] != rune('d') {
-						goto l172
+						goto l173
 					}
 					position++
-					goto l171
-				l172:
-					position, tokenIndex = position171, tokenIndex171
+					goto l172
+				l173:
+					position, tokenIndex = position172, tokenIndex172
 					if buffer[position] != rune('D') {
-						goto l169
+						goto l166
 					}
 					position++
 				}
-			l171:
+			l172:
 				{
-					position173, tokenIndex173 := position, tokenIndex
+					position174, tokenIndex174 := position, tokenIndex
 					if buffer[position] != rune('e') {
-						goto l174
+						goto l175
 					}
 					position++
-					goto l173
-				l174:
-					position, tokenIndex = position173, tokenIndex173
+					goto l174
+				l175:
+					position, tokenIndex = position174, tokenIndex174
 					if buffer[position] != rune('E') {
-						goto l169
+						goto l166
 					}
 					position++
 				}
-			l173:
+			l174:
 				{
-					position175, tokenIndex175 := position, tokenIndex
-					if buffer[position] != rune('s') {
-						goto l176
+					position176, tokenIndex176 := position, tokenIndex
+					if buffer[position] != rune('r') {
+						goto l177
 					}
 					position++
-					goto l175
-				l176:
-					position, tokenIndex = position175, tokenIndex175
-					if buffer[position] != rune('S') {
-						goto l169
+					goto l176
+				l177:
+					position, tokenIndex = position176, tokenIndex176
+					if buffer[position] != rune('R') {
+						goto l166
 					}
 					position++
 				}
-			l175:
+			l176:
+				if buffer[position] != rune(' ') {
+					goto l166
+				}
+				position++
 				{
-					position177, tokenIndex177 := position, tokenIndex
-					if buffer[position] != rune('c') {
-						goto l178
+					position178, tokenIndex178 := position, tokenIndex
+					if buffer[position] != rune('b') {
+						goto l179
 					}
 					position++
-					goto l177
-				l178:
-					position, tokenIndex = position177, tokenIndex177
-					if buffer[position] != rune('C') {
-						goto l169
+					goto l178
+				l179:
+					position, tokenIndex = position178, tokenIndex178
+					if buffer[position] != rune('B') {
+						goto l166
 					}
 					position++
 				}
-			l177:
-				if !_rules[ruleAction13]() {
-					goto l169
-				}
-				add(ruleDescending, position170)
-			}
-			return true
-		l169:
-			position, tokenIndex = position169, tokenIndex169
-			return false
-		},
-		/* 17 String <- <('"' <StringChar*> '"')+> */
-		func() bool {
-			position179, tokenIndex179 := position, tokenIndex
-			{
-				position180 := position
-				if buffer[position] != rune('"') {
-					goto l179
-				}
-				position++
+			l178:
 				{
-					position183 := position
-				l184:
-					{
-						position185, tokenIndex185 := position, tokenIndex
-						if !_rules[ruleStringChar]() {
-							goto l185
-						}
-						goto l184
-					l185:
-						position, tokenIndex = position185, tokenIndex185
+					position180, tokenIndex180 := position, tokenIndex
+					if buffer[position] != rune('y') {
+						goto l181
+					}
+					position++
+					goto l180
+				l181:
+					position, tokenIndex = position180, tokenIndex180
+					if buffer[position] != rune('Y') {
+						goto l166
 					}
-					add(rulePegText, position183)
+					position++
 				}
-				if buffer[position] != rune('"') {
-					goto l179
+			l180:
+				if !_rules[rule_]() {
+					goto l166
 				}
-				position++
-			l181:
+				if !_rules[ruleAction16]() {
+					goto l166
+				}
+				if !_rules[ruleOrderColumn]() {
+					goto l166
+				}
+			l182:
 				{
-					position182, tokenIndex182 := position, tokenIndex
-					if buffer[position] != rune('"') {
-						goto l182
-					}
-					position++
-					{
-						position186 := position
-					l187:
-						{
-							position188, tokenIndex188 := position, tokenIndex
-							if !_rules[ruleStringChar]() {
-								goto l188
-							}
-							goto l187
-						l188:
-							position, tokenIndex = position188, tokenIndex188
-						}
-						add(rulePegText, position186)
+					position183, tokenIndex183 := position, tokenIndex
+					if !_rules[ruleCOMMA]() {
+						goto l183
 					}
-					if buffer[position] != rune('"') {
-						goto l182
+					if !_rules[ruleOrderColumn]() {
+						goto l183
 					}
-					position++
-					goto l181
-				l182:
-					position, tokenIndex = position182, tokenIndex182
+					goto l182
+				l183:
+					position, tokenIndex = position183, tokenIndex183
 				}
-				add(ruleString, position180)
+				add(ruleOrderByExpr, position167)
 			}
 			return true
-		l179:
-			position, tokenIndex = position179, tokenIndex179
+		l166:
+			position, tokenIndex = position166, tokenIndex166
 			return false
 		},
-		/* 18 StringChar <- <(Escape / (!('"' / '\n' / '\\') .))> */
+		/* 11 OrderColumn <- <(Column (Descending / Ascending)?)> */
 		func() bool {
-			position189, tokenIndex189 := position, tokenIndex
+			position184, tokenIndex184 := position, tokenIndex
 			{
-				position190 := position
+				position185 := position
+				if !_rules[ruleColumn]() {
+					goto l184
+				}
 				{
-					position191, tokenIndex191 := position, tokenIndex
-					if !_rules[ruleEscape]() {
-						goto l192
-					}
-					goto l191
-				l192:
-					position, tokenIndex = position191, tokenIndex191
+					position186, tokenIndex186 := position, tokenIndex
 					{
-						position193, tokenIndex193 := position, tokenIndex
-						{
-							position194, tokenIndex194 := position, tokenIndex
-							if buffer[position] != rune('"') {
-								goto l195
-							}
-							position++
-							goto l194
-						l195:
-							position, tokenIndex = position194, tokenIndex194
-							if buffer[position] != rune('\n') {
-								goto l196
-							}
-							position++
-							goto l194
-						l196:
-							position, tokenIndex = position194, tokenIndex194
-							if buffer[position] != rune('\\') {
-								goto l193
-							}
-							position++
+						position188, tokenIndex188 := position, tokenIndex
+						if !_rules[ruleDescending]() {
+							goto l189
+						}
+						goto l188
+					l189:
+						position, tokenIndex = position188, tokenIndex188
+						if !_rules[ruleAscending]() {
+							goto l186
 						}
-					l194:
-						goto l189
-					l193:
-						position, tokenIndex = position193, tokenIndex193
-					}
-					if !matchDot() {
-						goto l189
 					}
+				l188:
+					goto l187
+				l186:
+					position, tokenIndex = position186, tokenIndex186
 				}
-			l191:
-				add(ruleStringChar, position190)
+			l187:
+				add(ruleOrderColumn, position185)
 			}
 			return true
-		l189:
-			position, tokenIndex = position189, tokenIndex189
+		l184:
+			position, tokenIndex = position184, tokenIndex184
 			return false
 		},
-		/* 19 Escape <- <(SimpleEscape / OctalEscape / HexEscape / UniversalCharacter)> */
+		/* 12 ExplainExpr <- <(('e' / 'E') ('x' / 'X') ('p' / 'P') ('l' / 'L') ('a' / 'A') ('i' / 'I') ('n' / 'N') !IdChar _ Action17)> */
 		func() bool {
-			position197, tokenIndex197 := position, tokenIndex
+			position190, tokenIndex190 := position, tokenIndex
 			{
-				position198 := position
+				position191 := position
 				{
-					position199, tokenIndex199 := position, tokenIndex
-					if !_rules[ruleSimpleEscape]() {
-						goto l200
+					position192, tokenIndex192 := position, tokenIndex
+					if buffer[position] != rune('e') {
+						goto l193
 					}
-					goto l199
-				l200:
-					position, tokenIndex = position199, tokenIndex199
-					if !_rules[ruleOctalEscape]() {
-						goto l201
+					position++
+					goto l192
+				l193:
+					position, tokenIndex = position192, tokenIndex192
+					if buffer[position] != rune('E') {
+						goto l190
 					}
-					goto l199
-				l201:
-					position, tokenIndex = position199, tokenIndex199
-					if !_rules[ruleHexEscape]() {
-						goto l202
+					position++
+				}
+			l192:
+				{
+					position194, tokenIndex194 := position, tokenIndex
+					if buffer[position] != rune('x') {
+						goto l195
 					}
-					goto l199
-				l202:
-					position, tokenIndex = position199, tokenIndex199
-					if !_rules[ruleUniversalCharacter]() {
-						goto l197
+					position++
+					goto l194
+				l195:
+					position, tokenIndex = position194, tokenIndex194
+					if buffer[position] != rune('X') {
+						goto l190
 					}
+					position++
 				}
-			l199:
-				add(ruleEscape, position198)
-			}
-			return true
-		l197:
-			position, tokenIndex = position197, tokenIndex197
-			return false
-		},
-		/* 20 SimpleEscape <- <('\\' ('\'' / '"' / '?' / '\\' / 'a' / 'b' / 'f' / 'n' / 'r' / 't' / 'v'))> */
-		func() bool {
-			position203, tokenIndex203 := position, tokenIndex
-			{
-				position204 := position
-				if buffer[position] != rune('\\') {
-					goto l203
-				}
-				position++
+			l194:
 				{
-					position205, tokenIndex205 := position, tokenIndex
-					if buffer[position] != rune('\'') {
-						goto l206
+					position196, tokenIndex196 := position, tokenIndex
+					if buffer[position] != rune('p') {
+						goto l197
 					}
 					position++
-					goto l205
-				l206:
-					position, tokenIndex = position205, tokenIndex205
-					if buffer[position] != rune('"') {
-						goto l207
+					goto l196
+				l197:
+					position, tokenIndex = position196, tokenIndex196
+					if buffer[position] != rune('P') {
+						goto l190
 					}
 					position++
-					goto l205
-				l207:
-					position, tokenIndex = position205, tokenIndex205
-					if buffer[position] != rune('?') {
-						goto l208
+				}
+			l196:
+				{
+					position198, tokenIndex198 := position, tokenIndex
+					if buffer[position] != rune('l') {
+						goto l199
 					}
 					position++
-					goto l205
-				l208:
-					position, tokenIndex = position205, tokenIndex205
-					if buffer[position] != rune('\\') {
-						goto l209
+					goto l198
+				l199:
+					position, tokenIndex = position198, tokenIndex198
+					if buffer[position] != rune('L') {
+						goto l190
 					}
 					position++
-					goto l205
-				l209:
-					position, tokenIndex = position205, tokenIndex205
+				}
+			l198:
+				{
+					position200, tokenIndex200 := position, tokenIndex
 					if buffer[position] != rune('a') {
-						goto l210
+						goto l201
 					}
 					position++
-					goto l205
-				l210:
-					position, tokenIndex = position205, tokenIndex205
-					if buffer[position] != rune('b') {
-						goto l211
+					goto l200
+				l201:
+					position, tokenIndex = position200, tokenIndex200
+					if buffer[position] != rune('A') {
+						goto l190
 					}
 					position++
-					goto l205
-				l211:
-					position, tokenIndex = position205, tokenIndex205
-					if buffer[position] != rune('f') {
-						goto l212
+				}
+			l200:
+				{
+					position202, tokenIndex202 := position, tokenIndex
+					if buffer[position] != rune('i') {
+						goto l203
 					}
 					position++
-					goto l205
-				l212:
-					position, tokenIndex = position205, tokenIndex205
-					if buffer[position] != rune('n') {
-						goto l213
+					goto l202
+				l203:
+					position, tokenIndex = position202, tokenIndex202
+					if buffer[position] != rune('I') {
+						goto l190
 					}
 					position++
-					goto l205
-				l213:
-					position, tokenIndex = position205, tokenIndex205
-					if buffer[position] != rune('r') {
-						goto l214
+				}
+			l202:
+				{
+					position204, tokenIndex204 := position, tokenIndex
+					if buffer[position] != rune('n') {
+						goto l205
 					}
 					position++
-					goto l205
-				l214:
-					position, tokenIndex = position205, tokenIndex205
-					if buffer[position] != rune('t') {
-						goto l215
+					goto l204
+				l205:
+					position, tokenIndex = position204, tokenIndex204
+					if buffer[position] != rune('N') {
+						goto l190
 					}
 					position++
-					goto l205
-				l215:
-					position, tokenIndex = position205, tokenIndex205
-					if buffer[position] != rune('v') {
-						goto l203
+				}
+			l204:
+				{
+					position206, tokenIndex206 := position, tokenIndex
+					if !_rules[ruleIdChar]() {
+						goto l206
 					}
-					position++
+					goto l190
+				l206:
+					position, tokenIndex = position206, tokenIndex206
+				}
+				if !_rules[rule_]() {
+					goto l190
+				}
+				if !_rules[ruleAction17]() {
+					goto l190
 				}
-			l205:
-				add(ruleSimpleEscape, position204)
+				add(ruleExplainExpr, position191)
 			}
 			return true
-		l203:
-			position, tokenIndex = position203, tokenIndex203
+		l190:
+			position, tokenIndex = position190, tokenIndex190
 			return false
 		},
-		/* 21 OctalEscape <- <('\\' [0-7] [0-7]? [0-7]?)> */
+		/* 13 LimitExpr <- <(('l' / 'L') ('i' / 'I') ('m' / 'M') ('i' / 'I') ('t' / 'T') _ <Unsigned> Action18)> */
 		func() bool {
-			position216, tokenIndex216 := position, tokenIndex
+			position207, tokenIndex207 := position, tokenIndex
 			{
-				position217 := position
-				if buffer[position] != rune('\\') {
-					goto l216
-				}
-				position++
-				if c := buffer[position]; c < rune('0') || c > rune('7') {
-					goto l216
-				}
-				position++
+				position208 := position
 				{
-					position218, tokenIndex218 := position, tokenIndex
-					if c := buffer[position]; c < rune('0') || c > rune('7') {
-						goto l218
+					position209, tokenIndex209 := position, tokenIndex
+					if buffer[position] != rune('l') {
+						goto l210
+					}
+					position++
+					goto l209
+				l210:
+					position, tokenIndex = position209, tokenIndex209
+					if buffer[position] != rune('L') {
+						goto l207
 					}
 					position++
-					goto l219
-				l218:
-					position, tokenIndex = position218, tokenIndex218
 				}
-			l219:
+			l209:
 				{
-					position220, tokenIndex220 := position, tokenIndex
-					if c := buffer[position]; c < rune('0') || c > rune('7') {
-						goto l220
+					position211, tokenIndex211 := position, tokenIndex
+					if buffer[position] != rune('i') {
+						goto l212
+					}
+					position++
+					goto l211
+				l212:
+					position, tokenIndex = position211, tokenIndex211
+					if buffer[position] != rune('I') {
+						goto l207
 					}
 					position++
-					goto l221
-				l220:
-					position, tokenIndex = position220, tokenIndex220
-				}
-			l221:
-				add(ruleOctalEscape, position217)
-			}
-			return true
-		l216:
-			position, tokenIndex = position216, tokenIndex216
-			return false
-		},
-		/* 22 HexEscape <- <('\\' 'x' HexDigit+)> */
-		func() bool {
-			position222, tokenIndex222 := position, tokenIndex
-			{
-				position223 := position
-				if buffer[position] != rune('\\') {
-					goto l222
 				}
-				position++
-				if buffer[position] != rune('x') {
-					goto l222
+			l211:
+				{
+					position213, tokenIndex213 := position, tokenIndex
+					if buffer[position] != rune('m') {
+						goto l214
+					}
+					position++
+					goto l213
+				l214:
+					position, tokenIndex = position213, tokenIndex213
+					if buffer[position] != rune('M') {
+						goto l207
+					}
+					position++
 				}
-				position++
-				if !_rules[ruleHexDigit]() {
-					goto l222
+			l213:
+				{
+					position215, tokenIndex215 := position, tokenIndex
+					if buffer[position] != rune('i') {
+						goto l216
+					}
+					position++
+					goto l215
+				l216:
+					position, tokenIndex = position215, tokenIndex215
+					if buffer[position] != rune('I') {
+						goto l207
+					}
+					position++
 				}
-			l224:
+			l215:
 				{
-					position225, tokenIndex225 := position, tokenIndex
-					if !_rules[ruleHexDigit]() {
-						goto l225
+					position217, tokenIndex217 := position, tokenIndex
+					if buffer[position] != rune('t') {
+						goto l218
 					}
-					goto l224
-				l225:
-					position, tokenIndex = position225, tokenIndex225
+					position++
+					goto l217
+				l218:
+					position, tokenIndex = position217, tokenIndex217
+					if buffer[position] != rune('T') {
+						goto l207
+					}
+					position++
+				}
+			l217:
+				if !_rules[rule_]() {
+					goto l207
+				}
+				{
+					position219 := position
+					if !_rules[ruleUnsigned]() {
+						goto l207
+					}
+					add(rulePegText, position219)
 				}
-				add(ruleHexEscape, position223)
+				if !_rules[ruleAction18]() {
+					goto l207
+				}
+				add(ruleLimitExpr, position208)
 			}
 			return true
-		l222:
-			position, tokenIndex = position222, tokenIndex222
+		l207:
+			position, tokenIndex = position207, tokenIndex207
 			return false
 		},
-		/* 23 UniversalCharacter <- <(('\\' 'u' HexQuad) / ('\\' 'U' HexQuad HexQuad))> */
+		/* 14 TopExpr <- <(('t' / 'T') ('o' / 'O') ('p' / 'P') _ <Unsigned> Action19)> */
 		func() bool {
-			position226, tokenIndex226 := position, tokenIndex
+			position220, tokenIndex220 := position, tokenIndex
 			{
-				position227 := position
+				position221 := position
 				{
-					position228, tokenIndex228 := position, tokenIndex
-					if buffer[position] != rune('\\') {
-						goto l229
+					position222, tokenIndex222 := position, tokenIndex
+					if buffer[position] != rune('t') {
+						goto l223
 					}
 					position++
-					if buffer[position] != rune('u') {
-						goto l229
+					goto l222
+				l223:
+					position, tokenIndex = position222, tokenIndex222
+					if buffer[position] != rune('T') {
+						goto l220
 					}
 					position++
-					if !_rules[ruleHexQuad]() {
-						goto l229
-					}
-					goto l228
-				l229:
-					position, tokenIndex = position228, tokenIndex228
-					if buffer[position] != rune('\\') {
-						goto l226
+				}
+			l222:
+				{
+					position224, tokenIndex224 := position, tokenIndex
+					if buffer[position] != rune('o') {
+						goto l225
 					}
 					position++
-					if buffer[position] != rune('U') {
-						goto l226
+					goto l224
+				l225:
+					position, tokenIndex = position224, tokenIndex224
+					if buffer[position] != rune('O') {
+						goto l220
 					}
 					position++
-					if !_rules[ruleHexQuad]() {
-						goto l226
+				}
+			l224:
+				{
+					position226, tokenIndex226 := position, tokenIndex
+					if buffer[position] != rune('p') {
+						goto l227
 					}
-					if !_rules[ruleHexQuad]() {
-						goto l226
+					position++
+					goto l226
+				l227:
+					position, tokenIndex = position226, tokenIndex226
+					if buffer[position] != rune('P') {
+						goto l220
 					}
+					position++
 				}
-			l228:
-				add(ruleUniversalCharacter, position227)
-			}
-			return true
-		l226:
-			position, tokenIndex = position226, tokenIndex226
-			return false
-		},
-		/* 24 HexQuad <- <(HexDigit HexDigit HexDigit HexDigit)> */
-		func() bool {
-			position230, tokenIndex230 := position, tokenIndex
-			{
-				position231 := position
-				if !_rules[ruleHexDigit]() {
-					goto l230
-				}
-				if !_rules[ruleHexDigit]() {
-					goto l230
+			l226:
+				if !_rules[rule_]() {
+					goto l220
 				}
-				if !_rules[ruleHexDigit]() {
-					goto l230
+				{
+					position228 := position
+					if !_rules[ruleUnsigned]() {
+						goto l220
+					}
+					add(rulePegText, position228)
 				}
-				if !_rules[ruleHexDigit]() {
-					goto l230
+				if !_rules[ruleAction19]() {
+					goto l220
 				}
-				add(ruleHexQuad, position231)
+				add(ruleTopExpr, position221)
 			}
 			return true
-		l230:
-			position, tokenIndex = position230, tokenIndex230
+		l220:
+			position, tokenIndex = position220, tokenIndex220
 			return false
 		},
-		/* 25 HexDigit <- <([a-f] / [A-F] / [0-9])> */
+		/* 15 PointSizeExpr <- <(('p' / 'P') ('o' / 'O') ('i' / 'I') ('n' / 'N') ('t' / 'T') ' ' ('s' / 'S') ('i' / 'I') ('z' / 'Z') ('e' / 'E') _ <Duration> Action20)> */
 		func() bool {
-			position232, tokenIndex232 := position, tokenIndex
+			position229, tokenIndex229 := position, tokenIndex
 			{
-				position233 := position
+				position230 := position
 				{
-					position234, tokenIndex234 := position, tokenIndex
-					if c := buffer[position]; c < rune('a') || c > rune('f') {
-						goto l235
+					position231, tokenIndex231 := position, tokenIndex
+					if buffer[position] != rune('p') {
+						goto l232
 					}
 					position++
-					goto l234
-				l235:
-					position, tokenIndex = position234, tokenIndex234
-					if c := buffer[position]; c < rune('A') || c > rune('F') {
+					goto l231
+				l232:
+					position, tokenIndex = position231, tokenIndex231
+					if buffer[position] != rune('P') {
+						goto l229
+					}
+					position++
+				}
+			l231:
+				{
+					position233, tokenIndex233 := position, tokenIndex
+					if buffer[position] != rune('o') {
+						goto l234
+					}
+					position++
+					goto l233
+				l234:
+					position, tokenIndex = position233, tokenIndex233
+					if buffer[position] != rune('O') {
+						goto l229
+					}
+					position++
+				}
+			l233:
+				{
+					position235, tokenIndex235 := position, tokenIndex
+					if buffer[position] != rune('i') {
 						goto l236
 					}
 					position++
-					goto l234
+					goto l235
 				l236:
-					position, tokenIndex = position234, tokenIndex234
-					if c := buffer[position]; c < rune('0') || c > rune('9') {
-						goto l232
+					position, tokenIndex = position235, tokenIndex235
+					if buffer[position] != rune('I') {
+						goto l229
 					}
 					position++
 				}
-			l234:
-				add(ruleHexDigit, position233)
-			}
-			return true
-		l232:
-			position, tokenIndex = position232, tokenIndex232
-			return false
-		},
-		/* 26 Unsigned <- <[0-9]+> */
-		func() bool {
-			position237, tokenIndex237 := position, tokenIndex
-			{
-				position238 := position
-				if c := buffer[position]; c < rune('0') || c > rune('9') {
+			l235:
+				{
+					position237, tokenIndex237 := position, tokenIndex
+					if buffer[position] != rune('n') {
+						goto l238
+					}
+					position++
 					goto l237
+				l238:
+					position, tokenIndex = position237, tokenIndex237
+					if buffer[position] != rune('N') {
+						goto l229
+					}
+					position++
 				}
-				position++
-			l239:
+			l237:
 				{
-					position240, tokenIndex240 := position, tokenIndex
-					if c := buffer[position]; c < rune('0') || c > rune('9') {
+					position239, tokenIndex239 := position, tokenIndex
+					if buffer[position] != rune('t') {
 						goto l240
 					}
 					position++
 					goto l239
 				l240:
-					position, tokenIndex = position240, tokenIndex240
+					position, tokenIndex = position239, tokenIndex239
+					if buffer[position] != rune('T') {
+						goto l229
+					}
+					position++
 				}
-				add(ruleUnsigned, position238)
-			}
-			return true
-		l237:
-			position, tokenIndex = position237, tokenIndex237
-			return false
-		},
-		/* 27 Sign <- <('-' / '+')> */
-		func() bool {
-			position241, tokenIndex241 := position, tokenIndex
-			{
-				position242 := position
+			l239:
+				if buffer[position] != rune(' ') {
+					goto l229
+				}
+				position++
+				{
+					position241, tokenIndex241 := position, tokenIndex
+					if buffer[position] != rune('s') {
+						goto l242
+					}
+					position++
+					goto l241
+				l242:
+					position, tokenIndex = position241, tokenIndex241
+					if buffer[position] != rune('S') {
+						goto l229
+					}
+					position++
+				}
+			l241:
 				{
 					position243, tokenIndex243 := position, tokenIndex
-					if buffer[position] != rune('-') {
+					if buffer[position] != rune('i') {
 						goto l244
 					}
 					position++
 					goto l243
 				l244:
 					position, tokenIndex = position243, tokenIndex243
-					if buffer[position] != rune('+') {
-						goto l241
+					if buffer[position] != rune('I') {
+						goto l229
 					}
 					position++
 				}
 			l243:
-				add(ruleSign, position242)
+				{
+					position245, tokenIndex245 := position, tokenIndex
+					if buffer[position] != rune('z') {
+						goto l246
+					}
+					position++
+					goto l245
+				l246:
+					position, tokenIndex = position245, tokenIndex245
+					if buffer[position] != rune('Z') {
+						goto l229
+					}
+					position++
+				}
+			l245:
+				{
+					position247, tokenIndex247 := position, tokenIndex
+					if buffer[position] != rune('e') {
+						goto l248
+					}
+					position++
+					goto l247
+				l248:
+					position, tokenIndex = position247, tokenIndex247
+					if buffer[position] != rune('E') {
+						goto l229
+					}
+					position++
+				}
+			l247:
+				if !_rules[rule_]() {
+					goto l229
+				}
+				{
+					position249 := position
+					if !_rules[ruleDuration]() {
+						goto l229
+					}
+					add(rulePegText, position249)
+				}
+				if !_rules[ruleAction20]() {
+					goto l229
+				}
+				add(rulePointSizeExpr, position230)
 			}
 			return true
-		l241:
-			position, tokenIndex = position241, tokenIndex241
+		l229:
+			position, tokenIndex = position229, tokenIndex229
 			return false
 		},
-		/* 28 Integer <- <<(Sign? Unsigned)>> */
+		/* 16 Columns <- <(Column (COMMA Column)*)> */
 		func() bool {
-			position245, tokenIndex245 := position, tokenIndex
+			position250, tokenIndex250 := position, tokenIndex
 			{
-				position246 := position
+				position251 := position
+				if !_rules[ruleColumn]() {
+					goto l250
+				}
+			l252:
 				{
-					position247 := position
-					{
-						position248, tokenIndex248 := position, tokenIndex
-						if !_rules[ruleSign]() {
-							goto l248
-						}
-						goto l249
-					l248:
-						position, tokenIndex = position248, tokenIndex248
+					position253, tokenIndex253 := position, tokenIndex
+					if !_rules[ruleCOMMA]() {
+						goto l253
 					}
-				l249:
-					if !_rules[ruleUnsigned]() {
-						goto l245
+					if !_rules[ruleColumn]() {
+						goto l253
 					}
-					add(rulePegText, position247)
+					goto l252
+				l253:
+					position, tokenIndex = position253, tokenIndex253
 				}
-				add(ruleInteger, position246)
+				add(ruleColumns, position251)
 			}
 			return true
-		l245:
-			position, tokenIndex = position245, tokenIndex245
+		l250:
+			position, tokenIndex = position250, tokenIndex250
 			return false
 		},
-		/* 29 Float <- <(Integer ('.' Unsigned)? (('e' / 'E') Integer)?)> */
+		/* 17 Column <- <(Action21 <Expr> Action22 _ ColumnAlias?)> */
 		func() bool {
-			position250, tokenIndex250 := position, tokenIndex
+			position254, tokenIndex254 := position, tokenIndex
 			{
-				position251 := position
-				if !_rules[ruleInteger]() {
-					goto l250
+				position255 := position
+				if !_rules[ruleAction21]() {
+					goto l254
 				}
 				{
-					position252, tokenIndex252 := position, tokenIndex
-					if buffer[position] != rune('.') {
-						goto l252
+					position256 := position
+					if !_rules[ruleExpr]() {
+						goto l254
 					}
-					position++
-					if !_rules[ruleUnsigned]() {
-						goto l252
+					add(rulePegText, position256)
+				}
+				if !_rules[ruleAction22]() {
+					goto l254
+				}
+				if !_rules[rule_]() {
+					goto l254
+				}
+				{
+					position257, tokenIndex257 := position, tokenIndex
+					if !_rules[ruleColumnAlias]() {
+						goto l257
+					}
+					goto l258
+				l257:
+					position, tokenIndex = position257, tokenIndex257
+				}
+			l258:
+				add(ruleColumn, position255)
+			}
+			return true
+		l254:
+			position, tokenIndex = position254, tokenIndex254
+			return false
+		},
+		/* 18 ColumnAlias <- <(AS <Identifier> _ Action23)> */
+		func() bool {
+			position259, tokenIndex259 := position, tokenIndex
+			{
+				position260 := position
+				if !_rules[ruleAS]() {
+					goto l259
+				}
+				{
+					position261 := position
+					if !_rules[ruleIdentifier]() {
+						goto l259
 					}
-					goto l253
-				l252:
-					position, tokenIndex = position252, tokenIndex252
+					add(rulePegText, position261)
+				}
+				if !_rules[rule_]() {
+					goto l259
+				}
+				if !_rules[ruleAction23]() {
+					goto l259
 				}
-			l253:
+				add(ruleColumnAlias, position260)
+			}
+			return true
+		l259:
+			position, tokenIndex = position259, tokenIndex259
+			return false
+		},
+		/* 19 Expr <- <(Term ((PLUS Term Action24) / (MINUS Term Action25))*)> */
+		func() bool {
+			position262, tokenIndex262 := position, tokenIndex
+			{
+				position263 := position
+				if !_rules[ruleTerm]() {
+					goto l262
+				}
+			l264:
 				{
-					position254, tokenIndex254 := position, tokenIndex
+					position265, tokenIndex265 := position, tokenIndex
 					{
-						position256, tokenIndex256 := position, tokenIndex
-						if buffer[position] != rune('e') {
-							goto l257
+						position266, tokenIndex266 := position, tokenIndex
+						if !_rules[rulePLUS]() {
+							goto l267
 						}
-						position++
-						goto l256
-					l257:
-						position, tokenIndex = position256, tokenIndex256
-						if buffer[position] != rune('E') {
-							goto l254
+						if !_rules[ruleTerm]() {
+							goto l267
 						}
-						position++
-					}
-				l256:
-					if !_rules[ruleInteger]() {
-						goto l254
-					}
-					goto l255
-				l254:
-					position, tokenIndex = position254, tokenIndex254
+						if !_rules[ruleAction24]() {
+							goto l267
+						}
+						goto l266
+					l267:
+						position, tokenIndex = position266, tokenIndex266
+						if !_rules[ruleMINUS]() {
+							goto l265
+						}
+						if !_rules[ruleTerm]() {
+							goto l265
+						}
+						if !_rules[ruleAction25]() {
+							goto l265
+						}
+					}
+				l266:
+					goto l264
+				l265:
+					position, tokenIndex = position265, tokenIndex265
+				}
+				add(ruleExpr, position263)
+			}
+			return true
+		l262:
+			position, tokenIndex = position262, tokenIndex262
+			return false
+		},
+		/* 20 Term <- <(Factor ((TIMES Factor Action26) / (DIVIDE Factor Action27) / (MODULO Factor Action28))*)> */
+		func() bool {
+			position268, tokenIndex268 := position, tokenIndex
+			{
+				position269 := position
+				if !_rules[ruleFactor]() {
+					goto l268
+				}
+			l270:
+				{
+					position271, tokenIndex271 := position, tokenIndex
+					{
+						position272, tokenIndex272 := position, tokenIndex
+						if !_rules[ruleTIMES]() {
+							goto l273
+						}
+						if !_rules[ruleFactor]() {
+							goto l273
+						}
+						if !_rules[ruleAction26]() {
+							goto l273
+						}
+						goto l272
+					l273:
+						position, tokenIndex = position272, tokenIndex272
+						if !_rules[ruleDIVIDE]() {
+							goto l274
+						}
+						if !_rules[ruleFactor]() {
+							goto l274
+						}
+						if !_rules[ruleAction27]() {
+							goto l274
+						}
+						goto l272
+					l274:
+						position, tokenIndex = position272, tokenIndex272
+						if !_rules[ruleMODULO]() {
+							goto l271
+						}
+						if !_rules[ruleFactor]() {
+							goto l271
+						}
+						if !_rules[ruleAction28]() {
+							goto l271
+						}
+					}
+				l272:
+					goto l270
+				l271:
+					position, tokenIndex = position271, tokenIndex271
+				}
+				add(ruleTerm, position269)
+			}
+			return true
+		l268:
+			position, tokenIndex = position268, tokenIndex268
+			return false
+		},
+		/* 21 Factor <- <((LPAR Expr RPAR) / FunctionCall / (<TimeOffset> !IdChar Action29) / (<Float> Action30) / (MINUS Factor Action31) / (<Identifier> Action32))> */
+		func() bool {
+			position275, tokenIndex275 := position, tokenIndex
+			{
+				position276 := position
+				{
+					position277, tokenIndex277 := position, tokenIndex
+					if !_rules[ruleLPAR]() {
+						goto l278
+					}
+					if !_rules[ruleExpr]() {
+						goto l278
+					}
+					if !_rules[ruleRPAR]() {
+						goto l278
+					}
+					goto l277
+				l278:
+					position, tokenIndex = position277, tokenIndex277
+					if !_rules[ruleFunctionCall]() {
+						goto l279
+					}
+					goto l277
+				l279:
+					position, tokenIndex = position277, tokenIndex277
+					{
+						position281 := position
+						if !_rules[ruleTimeOffset]() {
+							goto l280
+						}
+						add(rulePegText, position281)
+					}
+					{
+						position282, tokenIndex282 := position, tokenIndex
+						if !_rules[ruleIdChar]() {
+							goto l282
+						}
+						goto l280
+					l282:
+						position, tokenIndex = position282, tokenIndex282
+					}
+					if !_rules[ruleAction29]() {
+						goto l280
+					}
+					goto l277
+				l280:
+					position, tokenIndex = position277, tokenIndex277
+					{
+						position284 := position
+						if !_rules[ruleFloat]() {
+							goto l283
+						}
+						add(rulePegText, position284)
+					}
+					if !_rules[ruleAction30]() {
+						goto l283
+					}
+					goto l277
+				l283:
+					position, tokenIndex = position277, tokenIndex277
+					if !_rules[ruleMINUS]() {
+						goto l285
+					}
+					if !_rules[ruleFactor]() {
+						goto l285
+					}
+					if !_rules[ruleAction31]() {
+						goto l285
+					}
+					goto l277
+				l285:
+					position, tokenIndex = position277, tokenIndex277
+					{
+						position286 := position
+						if !_rules[ruleIdentifier]() {
+							goto l275
+						}
+						add(rulePegText, position286)
+					}
+					if !_rules[ruleAction32]() {
+						goto l275
+					}
+				}
+			l277:
+				add(ruleFactor, position276)
+			}
+			return true
+		l275:
+			position, tokenIndex = position275, tokenIndex275
+			return false
+		},
+		/* 22 FunctionCall <- <(<Identifier> Action33 LPAR (Expr Action34 (COMMA Expr Action35)*)? RPAR)> */
+		func() bool {
+			position287, tokenIndex287 := position, tokenIndex
+			{
+				position288 := position
+				{
+					position289 := position
+					if !_rules[ruleIdentifier]() {
+						goto l287
+					}
+					add(rulePegText, position289)
+				}
+				if !_rules[ruleAction33]() {
+					goto l287
+				}
+				if !_rules[ruleLPAR]() {
+					goto l287
+				}
+				{
+					position290, tokenIndex290 := position, tokenIndex
+					if !_rules[ruleExpr]() {
+						goto l290
+					}
+					if !_rules[ruleAction34]() {
+						goto l290
+					}
+				l292:
+					{
+						position293, tokenIndex293 := position, tokenIndex
+						if !_rules[ruleCOMMA]() {
+							goto l293
+						}
+						if !_rules[ruleExpr]() {
+							goto l293
+						}
+						if !_rules[ruleAction35]() {
+							goto l293
+						}
+						goto l292
+					l293:
+						position, tokenIndex = position293, tokenIndex293
+					}
+					goto l291
+				l290:
+					position, tokenIndex = position290, tokenIndex290
+				}
+			l291:
+				if !_rules[ruleRPAR]() {
+					goto l287
+				}
+				add(ruleFunctionCall, position288)
+			}
+			return true
+		l287:
+			position, tokenIndex = position287, tokenIndex287
+			return false
+		},
+		/* 23 LogicExpr <- <(AndExpr (OR AndExpr Action36)*)> */
+		func() bool {
+			position294, tokenIndex294 := position, tokenIndex
+			{
+				position295 := position
+				if !_rules[ruleAndExpr]() {
+					goto l294
+				}
+			l296:
+				{
+					position297, tokenIndex297 := position, tokenIndex
+					if !_rules[ruleOR]() {
+						goto l297
+					}
+					if !_rules[ruleAndExpr]() {
+						goto l297
+					}
+					if !_rules[ruleAction36]() {
+						goto l297
+					}
+					goto l296
+				l297:
+					position, tokenIndex = position297, tokenIndex297
+				}
+				add(ruleLogicExpr, position295)
+			}
+			return true
+		l294:
+			position, tokenIndex = position294, tokenIndex294
+			return false
+		},
+		/* 24 AndExpr <- <(NotExpr (AND NotExpr Action37)*)> */
+		func() bool {
+			position298, tokenIndex298 := position, tokenIndex
+			{
+				position299 := position
+				if !_rules[ruleNotExpr]() {
+					goto l298
+				}
+			l300:
+				{
+					position301, tokenIndex301 := position, tokenIndex
+					if !_rules[ruleAND]() {
+						goto l301
+					}
+					if !_rules[ruleNotExpr]() {
+						goto l301
+					}
+					if !_rules[ruleAction37]() {
+						goto l301
+					}
+					goto l300
+				l301:
+					position, tokenIndex = position301, tokenIndex301
+				}
+				add(ruleAndExpr, position299)
+			}
+			return true
+		l298:
+			position, tokenIndex = position298, tokenIndex298
+			return false
+		},
+		/* 25 NotExpr <- <((NOT NotExpr Action38) / PrimaryExpr)> */
+		func() bool {
+			position302, tokenIndex302 := position, tokenIndex
+			{
+				position303 := position
+				{
+					position304, tokenIndex304 := position, tokenIndex
+					if !_rules[ruleNOT]() {
+						goto l305
+					}
+					if !_rules[ruleNotExpr]() {
+						goto l305
+					}
+					if !_rules[ruleAction38]() {
+						goto l305
+					}
+					goto l304
+				l305:
+					position, tokenIndex = position304, tokenIndex304
+					if !_rules[rulePrimaryExpr]() {
+						goto l302
+					}
+				}
+			l304:
+				add(ruleNotExpr, position303)
+			}
+			return true
+		l302:
+			position, tokenIndex = position302, tokenIndex302
+			return false
+		},
+		/* 26 PrimaryExpr <- <((LPAR LogicExpr RPAR) / (Action39 FilterKey _ (RangeCondition / ListCondition / (FilterCondition _ FilterValue))))> */
+		func() bool {
+			position306, tokenIndex306 := position, tokenIndex
+			{
+				position307 := position
+				{
+					position308, tokenIndex308 := position, tokenIndex
+					if !_rules[ruleLPAR]() {
+						goto l309
+					}
+					if !_rules[ruleLogicExpr]() {
+						goto l309
+					}
+					if !_rules[ruleRPAR]() {
+						goto l309
+					}
+					goto l308
+				l309:
+					position, tokenIndex = position308, tokenIndex308
+					if !_rules[ruleAction39]() {
+						goto l306
+					}
+					if !_rules[ruleFilterKey]() {
+						goto l306
+					}
+					if !_rules[rule_]() {
+						goto l306
+					}
+					{
+						position310, tokenIndex310 := position, tokenIndex
+						if !_rules[ruleRangeCondition]() {
+							goto l311
+						}
+						goto l310
+					l311:
+						position, tokenIndex = position310, tokenIndex310
+						if !_rules[ruleListCondition]() {
+							goto l312
+						}
+						goto l310
+					l312:
+						position, tokenIndex = position310, tokenIndex310
+						if !_rules[ruleFilterCondition]() {
+							goto l306
+						}
+						if !_rules[rule_]() {
+							goto l306
+						}
+						if !_rules[ruleFilterValue]() {
+							goto l306
+						}
+					}
+				l310:
+				}
+			l308:
+				add(rulePrimaryExpr, position307)
+			}
+			return true
+		l306:
+			position, tokenIndex = position306, tokenIndex306
+			return false
+		},
+		/* 27 RangeCondition <- <(BETWEEN Action40 ListValue AND ListValue Action41)> */
+		func() bool {
+			position313, tokenIndex313 := position, tokenIndex
+			{
+				position314 := position
+				if !_rules[ruleBETWEEN]() {
+					goto l313
+				}
+				if !_rules[ruleAction40]() {
+					goto l313
+				}
+				if !_rules[ruleListValue]() {
+					goto l313
+				}
+				if !_rules[ruleAND]() {
+					goto l313
+				}
+				if !_rules[ruleListValue]() {
+					goto l313
+				}
+				if !_rules[ruleAction41]() {
+					goto l313
+				}
+				add(ruleRangeCondition, position314)
+			}
+			return true
+		l313:
+			position, tokenIndex = position313, tokenIndex313
+			return false
+		},
+		/* 28 ListCondition <- <(<(NOT? IN CIDR?)> Action42 (ValueList / FilterValue))> */
+		func() bool {
+			position315, tokenIndex315 := position, tokenIndex
+			{
+				position316 := position
+				{
+					position317 := position
+					{
+						position318, tokenIndex318 := position, tokenIndex
+						if !_rules[ruleNOT]() {
+							goto l318
+						}
+						goto l319
+					l318:
+						position, tokenIndex = position318, tokenIndex318
+					}
+				l319:
+					if !_rules[ruleIN]() {
+						goto l315
+					}
+					{
+						position320, tokenIndex320 := position, tokenIndex
+						if !_rules[ruleCIDR]() {
+							goto l320
+						}
+						goto l321
+					l320:
+						position, tokenIndex = position320, tokenIndex320
+					}
+				l321:
+					add(rulePegText, position317)
+				}
+				if !_rules[ruleAction42]() {
+					goto l315
+				}
+				{
+					position322, tokenIndex322 := position, tokenIndex
+					if !_rules[ruleValueList]() {
+						goto l323
+					}
+					goto l322
+				l323:
+					position, tokenIndex = position322, tokenIndex322
+					if !_rules[ruleFilterValue]() {
+						goto l315
+					}
+				}
+			l322:
+				add(ruleListCondition, position316)
+			}
+			return true
+		l315:
+			position, tokenIndex = position315, tokenIndex315
+			return false
+		},
+		/* 29 OPERATOR <- <(('<' '=') / ('>' '=') / ('!' '=') / '=' / '<' / '>' / (('m' / 'M') ('a' / 'A') ('t' / 'T') ('c' / 'C') ('h' / 'H') ('e' / 'E') ('s' / 'S')))> */
+		func() bool {
+			position324, tokenIndex324 := position, tokenIndex
+			{
+				position325 := position
+				{
+					position326, tokenIndex326 := position, tokenIndex
+					if buffer[position] != rune('<') {
+						goto l327
+					}
+					position++
+					if buffer[position] != rune('=') {
+						goto l327
+					}
+					position++
+					goto l326
+				l327:
+					position, tokenIndex = position326, tokenIndex326
+					if buffer[position] != rune('>') {
+						goto l328
+					}
+					position++
+					if buffer[position] != rune('=') {
+						goto l328
+					}
+					position++
+					goto l326
+				l328:
+					position, tokenIndex = position326, tokenIndex326
+					if buffer[position] != rune('!') {
+						goto l329
+					}
+					position++
+					if buffer[position] != rune('=') {
+						goto l329
+					}
+					position++
+					goto l326
+				l329:
+					position, tokenIndex = position326, tokenIndex326
+					if buffer[position] != rune('=') {
+						goto l330
+					}
+					position++
+					goto l326
+				l330:
+					position, tokenIndex = position326, tokenIndex326
+					if buffer[position] != rune('<') {
+						goto l331
+					}
+					position++
+					goto l326
+				l331:
+					position, tokenIndex = position326, tokenIndex326
+					if buffer[position] != rune('>') {
+						goto l332
+					}
+					position++
+					goto l326
+				l332:
+					position, tokenIndex = position326, tokenIndex326
+					{
+						position333, tokenIndex333 := position, tokenIndex
+						if buffer[position] != rune('m') {
+							goto l334
+						}
+						position++
+						goto l333
+					l334:
+						position, tokenIndex = position333, tokenIndex333
+						if buffer[position] != rune('M') {
+							goto l324
+						}
+						position++
+					}
+				l333:
+					{
+						position335, tokenIndex335 := position, tokenIndex
+						if buffer[position] != rune('a') {
+							goto l336
+						}
+						position++
+						goto l335
+					l336:
+						position, tokenIndex = position335, tokenIndex335
+						if buffer[position] != rune('A') {
+							goto l324
+						}
+						position++
+					}
+				l335:
+					{
+						position337, tokenIndex337 := position, tokenIndex
+						if buffer[position] != rune('t') {
+							goto l338
+						}
+						position++
+						goto l337
+					l338:
+						position, tokenIndex = position337, tokenIndex337
+						if buffer[position] != rune('T') {
+							goto l324
+						}
+						position++
+					}
+				l337:
+					{
+						position339, tokenIndex339 := position, tokenIndex
+						if buffer[position] != rune('c') {
+							goto l340
+						}
+						position++
+						goto l339
+					l340:
+						position, tokenIndex = position339, tokenIndex339
+						if buffer[position] != rune('C') {
+							goto l324
+						}
+						position++
+					}
+				l339:
+					{
+						position341, tokenIndex341 := position, tokenIndex
+						if buffer[position] != rune('h') {
+							goto l342
+						}
+						position++
+						goto l341
+					l342:
+						position, tokenIndex = position341, tokenIndex341
+						if buffer[position] != rune('H') {
+							goto l324
+						}
+						position++
+					}
+				l341:
+					{
+						position343, tokenIndex343 := position, tokenIndex
+						if buffer[position] != rune('e') {
+							goto l344
+						}
+						position++
+						goto l343
+					l344:
+						position, tokenIndex = position343, tokenIndex343
+						if buffer[position] != rune('E') {
+							goto l324
+						}
+						position++
+					}
+				l343:
+					{
+						position345, tokenIndex345 := position, tokenIndex
+						if buffer[position] != rune('s') {
+							goto l346
+						}
+						position++
+						goto l345
+					l346:
+						position, tokenIndex = position345, tokenIndex345
+						if buffer[position] != rune('S') {
+							goto l324
+						}
+						position++
+					}
+				l345:
+				}
+			l326:
+				add(ruleOPERATOR, position325)
+			}
+			return true
+		l324:
+			position, tokenIndex = position324, tokenIndex324
+			return false
+		},
+		/* 30 FilterKey <- <(<Expr> Action43)> */
+		func() bool {
+			position347, tokenIndex347 := position, tokenIndex
+			{
+				position348 := position
+				{
+					position349 := position
+					if !_rules[ruleExpr]() {
+						goto l347
+					}
+					add(rulePegText, position349)
+				}
+				if !_rules[ruleAction43]() {
+					goto l347
+				}
+				add(ruleFilterKey, position348)
+			}
+			return true
+		l347:
+			position, tokenIndex = position347, tokenIndex347
+			return false
+		},
+		/* 31 FilterCondition <- <(<OPERATOR> Action44)> */
+		func() bool {
+			position350, tokenIndex350 := position, tokenIndex
+			{
+				position351 := position
+				{
+					position352 := position
+					if !_rules[ruleOPERATOR]() {
+						goto l350
+					}
+					add(rulePegText, position352)
+				}
+				if !_rules[ruleAction44]() {
+					goto l350
+				}
+				add(ruleFilterCondition, position351)
+			}
+			return true
+		l350:
+			position, tokenIndex = position350, tokenIndex350
+			return false
+		},
+		/* 32 FilterValue <- <(<Value> Action45)> */
+		func() bool {
+			position353, tokenIndex353 := position, tokenIndex
+			{
+				position354 := position
+				{
+					position355 := position
+					if !_rules[ruleValue]() {
+						goto l353
+					}
+					add(rulePegText, position355)
+				}
+				if !_rules[ruleAction45]() {
+					goto l353
+				}
+				add(ruleFilterValue, position354)
+			}
+			return true
+		l353:
+			position, tokenIndex = position353, tokenIndex353
+			return false
+		},
+		/* 33 ValueList <- <(LPAR ListValue (COMMA ListValue)* RPAR Action46)> */
+		func() bool {
+			position356, tokenIndex356 := position, tokenIndex
+			{
+				position357 := position
+				if !_rules[ruleLPAR]() {
+					goto l356
+				}
+				if !_rules[ruleListValue]() {
+					goto l356
+				}
+			l358:
+				{
+					position359, tokenIndex359 := position, tokenIndex
+					if !_rules[ruleCOMMA]() {
+						goto l359
+					}
+					if !_rules[ruleListValue]() {
+						goto l359
+					}
+					goto l358
+				l359:
+					position, tokenIndex = position359, tokenIndex359
+				}
+				if !_rules[ruleRPAR]() {
+					goto l356
+				}
+				if !_rules[ruleAction46]() {
+					goto l356
+				}
+				add(ruleValueList, position357)
+			}
+			return true
+		l356:
+			position, tokenIndex = position356, tokenIndex356
+			return false
+		},
+		/* 34 ListValue <- <(<Value> Action47)> */
+		func() bool {
+			position360, tokenIndex360 := position, tokenIndex
+			{
+				position361 := position
+				{
+					position362 := position
+					if !_rules[ruleValue]() {
+						goto l360
+					}
+					add(rulePegText, position362)
+				}
+				if !_rules[ruleAction47]() {
+					goto l360
+				}
+				add(ruleListValue, position361)
+			}
+			return true
+		l360:
+			position, tokenIndex = position360, tokenIndex360
+			return false
+		},
+		/* 35 Value <- <(Float / Integer / String)> */
+		func() bool {
+			position363, tokenIndex363 := position, tokenIndex
+			{
+				position364 := position
+				{
+					position365, tokenIndex365 := position, tokenIndex
+					if !_rules[ruleFloat]() {
+						goto l366
+					}
+					goto l365
+				l366:
+					position, tokenIndex = position365, tokenIndex365
+					if !_rules[ruleInteger]() {
+						goto l367
+					}
+					goto l365
+				l367:
+					position, tokenIndex = position365, tokenIndex365
+					if !_rules[ruleString]() {
+						goto l363
+					}
+				}
+			l365:
+				add(ruleValue, position364)
+			}
+			return true
+		l363:
+			position, tokenIndex = position363, tokenIndex363
+			return false
+		},
+		/* 36 Descending <- <(('d' / 'D') ('e' / 'E') ('s' / 'S') ('c' / 'C') !IdChar _ Action48)> */
+		func() bool {
+			position368, tokenIndex368 := position, tokenIndex
+			{
+				position369 := position
+				{
+					position370, tokenIndex370 := position, tokenIndex
+					if buffer[position] != rune('d') {
+						goto l371
+					}
+					position++
+					goto l370
+				l371:
+					position, tokenIndex = position370, tokenIndex370
+					if buffer[position] != rune('D') {
+						goto l368
+					}
+					position++
+				}
+			l370:
+				{
+					position372, tokenIndex372 := position, tokenIndex
+					if buffer[position] != rune('e') {
+						goto l373
+					}
+					position++
+					goto l372
+				l373:
+					position, tokenIndex = position372, tokenIndex372
+					if buffer[position] != rune('E') {
+						goto l368
+					}
+					position++
+				}
+			l372:
+				{
+					position374, tokenIndex374 := position, tokenIndex
+					if buffer[position] != rune('s') {
+						goto l375
+					}
+					position++
+					goto l374
+				l375:
+					position, tokenIndex = position374, tokenIndex374
+					if buffer[position] != rune('S') {
+						goto l368
+					}
+					position++
+				}
+			l374:
+				{
+					position376, tokenIndex376 := position, tokenIndex
+					if buffer[position] != rune('c') {
+						goto l377
+					}
+					position++
+					goto l376
+				l377:
+					position, tokenIndex = position376, tokenIndex376
+					if buffer[position] != rune('C') {
+						goto l368
+					}
+					position++
+				}
+			l376:
+				{
+					position378, tokenIndex378 := position, tokenIndex
+					if !_rules[ruleIdChar]() {
+						goto l378
+					}
+					goto l368
+				l378:
+					position, tokenIndex = position378, tokenIndex378
+				}
+				if !_rules[rule_]() {
+					goto l368
+				}
+				if !_rules[ruleAction48]() {
+					goto l368
+				}
+				add(ruleDescending, position369)
+			}
+			return true
+		l368:
+			position, tokenIndex = position368, tokenIndex368
+			return false
+		},
+		/* 37 Ascending <- <(('a' / 'A') ('s' / 'S') ('c' / 'C') !IdChar _)> */
+		func() bool {
+			position379, tokenIndex379 := position, tokenIndex
+			{
+				position380 := position
+				{
+					position381, tokenIndex381 := position, tokenIndex
+					if buffer[position] != rune('a') {
+						goto l382
+					}
+					position++
+					goto l381
+				l382:
+					position, tokenIndex = position381, tokenIndex381
+					if buffer[position] != rune('A') {
+						goto l379
+					}
+					position++
+				}
+			l381:
+				{
+					position383, tokenIndex383 := position, tokenIndex
+					if buffer[position] != rune('s') {
+						goto l384
+					}
+					position++
+					goto l383
+				l384:
+					position, tokenIndex = position383, tokenIndex383
+					if buffer[position] != rune('S') {
+						goto l379
+					}
+					position++
+				}
+			l383:
+				{
+					position385, tokenIndex385 := position, tokenIndex
+					if buffer[position] != rune('c') {
+						goto l386
+					}
+					position++
+					goto l385
+				l386:
+					position, tokenIndex = position385, tokenIndex385
+					if buffer[position] != rune('C') {
+						goto l379
+					}
+					position++
+				}
+			l385:
+				{
+					position387, tokenIndex387 := position, tokenIndex
+					if !_rules[ruleIdChar]() {
+						goto l387
+					}
+					goto l379
+				l387:
+					position, tokenIndex = position387, tokenIndex387
+				}
+				if !_rules[rule_]() {
+					goto l379
+				}
+				add(ruleAscending, position380)
+			}
+			return true
+		l379:
+			position, tokenIndex = position379, tokenIndex379
+			return false
+		},
+		/* 38 String <- <('"' <StringChar*> '"')+> */
+		func() bool {
+			position388, tokenIndex388 := position, tokenIndex
+			{
+				position389 := position
+				if buffer[position] != rune('"') {
+					goto l388
+				}
+				position++
+				{
+					position392 := position
+				l393:
+					{
+						position394, tokenIndex394 := position, tokenIndex
+						if !_rules[ruleStringChar]() {
+							goto l394
+						}
+						goto l393
+					l394:
+						position, tokenIndex = position394, tokenIndex394
+					}
+					add(rulePegText, position392)
+				}
+				if buffer[position] != rune('"') {
+					goto l388
+				}
+				position++
+			l390:
+				{
+					position391, tokenIndex391 := position, tokenIndex
+					if buffer[position] != rune('"') {
+						goto l391
+					}
+					position++
+					{
+						position395 := position
+					l396:
+						{
+							position397, tokenIndex397 := position, tokenIndex
+							if !_rules[ruleStringChar]() {
+								goto l397
+							}
+							goto l396
+						l397:
+							position, tokenIndex = position397, tokenIndex397
+						}
+						add(rulePegText, position395)
+					}
+					if buffer[position] != rune('"') {
+						goto l391
+					}
+					position++
+					goto l390
+				l391:
+					position, tokenIndex = position391, tokenIndex391
+				}
+				add(ruleString, position389)
+			}
+			return true
+		l388:
+			position, tokenIndex = position388, tokenIndex388
+			return false
+		},
+		/* 39 StringChar <- <(Escape / (!('"' / '\n' / '\\') .))> */
+		func() bool {
+			position398, tokenIndex398 := position, tokenIndex
+			{
+				position399 := position
+				{
+					position400, tokenIndex400 := position, tokenIndex
+					if !_rules[ruleEscape]() {
+						goto l401
+					}
+					goto l400
+				l401:
+					position, tokenIndex = position400, tokenIndex400
+					{
+						position402, tokenIndex402 := position, tokenIndex
+						{
+							position403, tokenIndex403 := position, tokenIndex
+							if buffer[position] != rune('"') {
+								goto l404
+							}
+							position++
+							goto l403
+						l404:
+							position, tokenIndex = position403, tokenIndex403
+							if buffer[position] != rune('\n') {
+								goto l405
+							}
+							position++
+							goto l403
+						l405:
+							position, tokenIndex = position403, tokenIndex403
+							if buffer[position] != rune('\\') {
+								goto l402
+							}
+							position++
+						}
+					l403:
+						goto l398
+					l402:
+						position, tokenIndex = position402, tokenIndex402
+					}
+					if !matchDot() {
+						goto l398
+					}
+				}
+			l400:
+				add(ruleStringChar, position399)
+			}
+			return true
+		l398:
+			position, tokenIndex = position398, tokenIndex398
+			return false
+		},
+		/* 40 Escape <- <(SimpleEscape / OctalEscape / HexEscape / UniversalCharacter)> */
+		func() bool {
+			position406, tokenIndex406 := position, tokenIndex
+			{
+				position407 := position
+				{
+					position408, tokenIndex408 := position, tokenIndex
+					if !_rules[ruleSimpleEscape]() {
+						goto l409
+					}
+					goto l408
+				l409:
+					position, tokenIndex = position408, tokenIndex408
+					if !_rules[ruleOctalEscape]() {
+						goto l410
+					}
+					goto l408
+				l410:
+					position, tokenIndex = position408, tokenIndex408
+					if !_rules[ruleHexEscape]() {
+						goto l411
+					}
+					goto l408
+				l411:
+					position, tokenIndex = position408, tokenIndex408
+					if !_rules[ruleUniversalCharacter]() {
+						goto l406
+					}
+				}
+			l408:
+				add(ruleEscape, position407)
+			}
+			return true
+		l406:
+			position, tokenIndex = position406, tokenIndex406
+			return false
+		},
+		/* 41 SimpleEscape <- <('\\' ('\'' / '"' / '?' / '\\' / 'a' / 'b' / 'f' / 'n' / 'r' / 't' / 'v'))> */
+		func() bool {
+			position412, tokenIndex412 := position, tokenIndex
+			{
+				position413 := position
+				if buffer[position] != rune('\\') {
+					goto l412
+				}
+				position++
+				{
+					position414, tokenIndex414 := position, tokenIndex
+					if buffer[position] != rune('\'') {
+						goto l415
+					}
+					position++
+					goto l414
+				l415:
+					position, tokenIndex = position414, tokenIndex414
+					if buffer[position] != rune('"') {
+						goto l416
+					}
+					position++
+					goto l414
+				l416:
+					position, tokenIndex = position414, tokenIndex414
+					if buffer[position] != rune('?') {
+						goto l417
+					}
+					position++
+					goto l414
+				l417:
+					position, tokenIndex = position414, tokenIndex414
+					if buffer[position] != rune('\\') {
+						goto l418
+					}
+					position++
+					goto l414
+				l418:
+					position, tokenIndex = position414, tokenIndex414
+					if buffer[position] != rune('a') {
+						goto l419
+					}
+					position++
+					goto l414
+				l419:
+					position, tokenIndex = position414, tokenIndex414
+					if buffer[position] != rune('b') {
+						goto l420
+					}
+					position++
+					goto l414
+				l420:
+					position, tokenIndex = position414, tokenIndex414
+					if buffer[position] != rune('f') {
+						goto l421
+					}
+					position++
+					goto l414
+				l421:
+					position, tokenIndex = position414, tokenIndex414
+					if buffer[position] != rune('n') {
+						goto l422
+					}
+					position++
+					goto l414
+				l422:
+					position, tokenIndex = position414, tokenIndex414
+					if buffer[position] != rune('r') {
+						goto l423
+					}
+					position++
+					goto l414
+				l423:
+					position, tokenIndex = position414, tokenIndex414
+					if buffer[position] != rune('t') {
+						goto l424
+					}
+					position++
+					goto l414
+				l424:
+					position, tokenIndex = position414, tokenIndex414
+					if buffer[position] != rune('v') {
+						goto l412
+					}
+					position++
+				}
+			l414:
+				add(ruleSimpleEscape, position413)
+			}
+			return true
+		l412:
+			position, tokenIndex = position412, tokenIndex412
+			return false
+		},
+		/* 42 OctalEscape <- <('\\' [0-7] [0-7]? [0-7]?)> */
+		func() bool {
+			position425, tokenIndex425 := position, tokenIndex
+			{
+				position426 := position
+				if buffer[position] != rune('\\') {
+					goto l425
+				}
+				position++
+				if c := buffer[position]; c < rune('0') || c > rune('7') {
+					goto l425
+				}
+				position++
+				{
+					position427, tokenIndex427 := position, tokenIndex
+					if c := buffer[position]; c < rune('0') || c > rune('7') {
+						goto l427
+					}
+					position++
+					goto l428
+				l427:
+					position, tokenIndex = position427, tokenIndex427
+				}
+			l428:
+				{
+					position429, tokenIndex429 := position, tokenIndex
+					if c := buffer[position]; c < rune('0') || c > rune('7') {
+						goto l429
+					}
+					position++
+					goto l430
+				l429:
+					position, tokenIndex = position429, tokenIndex429
+				}
+			l430:
+				add(ruleOctalEscape, position426)
+			}
+			return true
+		l425:
+			position, tokenIndex = position425, tokenIndex425
+			return false
+		},
+		/* 43 HexEscape <- <('\\' 'x' HexDigit+)> */
+		func() bool {
+			position431, tokenIndex431 := position, tokenIndex
+			{
+				position432 := position
+				if buffer[position] != rune('\\') {
+					goto l431
+				}
+				position++
+				if buffer[position] != rune('x') {
+					goto l431
+				}
+				position++
+				if !_rules[ruleHexDigit]() {
+					goto l431
+				}
+			l433:
+				{
+					position434, tokenIndex434 := position, tokenIndex
+					if !_rules[ruleHexDigit]() {
+						goto l434
+					}
+					goto l433
+				l434:
+					position, tokenIndex = position434, tokenIndex434
+				}
+				add(ruleHexEscape, position432)
+			}
+			return true
+		l431:
+			position, tokenIndex = position431, tokenIndex431
+			return false
+		},
+		/* 44 UniversalCharacter <- <(('\\' 'u' HexQuad) / ('\\' 'U' HexQuad HexQuad))> */
+		func() bool {
+			position435, tokenIndex435 := position, tokenIndex
+			{
+				position436 := position
+				{
+					position437, tokenIndex437 := position, tokenIndex
+					if buffer[position] != rune('\\') {
+						goto l438
+					}
+					position++
+					if buffer[position] != rune('u') {
+						goto l438
+					}
+					position++
+					if !_rules[ruleHexQuad]() {
+						goto l438
+					}
+					goto l437
+				l438:
+					position, tokenIndex = position437, tokenIndex437
+					if buffer[position] != rune('\\') {
+						goto l435
+					}
+					position++
+					if buffer[position] != rune('U') {
+						goto l435
+					}
+					position++
+					if !_rules[ruleHexQuad]() {
+						goto l435
+					}
+					if !_rules[ruleHexQuad]() {
+						goto l435
+					}
+				}
+			l437:
+				add(ruleUniversalCharacter, position436)
+			}
+			return true
+		l435:
+			position, tokenIndex = position435, tokenIndex435
+			return false
+		},
+		/* 45 HexQuad <- <(HexDigit HexDigit HexDigit HexDigit)> */
+		func() bool {
+			position439, tokenIndex439 := position, tokenIndex
+			{
+				position440 := position
+				if !_rules[ruleHexDigit]() {
+					goto l439
+				}
+				if !_rules[ruleHexDigit]() {
+					goto l439
+				}
+				if !_rules[ruleHexDigit]() {
+					goto l439
+				}
+				if !_rules[ruleHexDigit]() {
+					goto l439
+				}
+				add(ruleHexQuad, position440)
+			}
+			return true
+		l439:
+			position, tokenIndex = position439, tokenIndex439
+			return false
+		},
+		/* 46 HexDigit <- <([a-f] / [A-F] / [0-9])> */
+		func() bool {
+			position441, tokenIndex441 := position, tokenIndex
+			{
+				position442 := position
+				{
+					position443, tokenIndex443 := position, tokenIndex
+					if c := buffer[position]; c < rune('a') || c > rune('f') {
+						goto l444
+					}
+					position++
+					goto l443
+				l444:
+					position, tokenIndex = position443, tokenIndex443
+					if c := buffer[position]; c < rune('A') || c > rune('F') {
+						goto l445
+					}
+					position++
+					goto l443
+				l445:
+					position, tokenIndex = position443, tokenIndex443
+					if c := buffer[position]; c < rune('0') || c > rune('9') {
+						goto l441
+					}
+					position++
+				}
+			l443:
+				add(ruleHexDigit, position442)
+			}
+			return true
+		l441:
+			position, tokenIndex = position441, tokenIndex441
+			return false
+		},
+		/* 47 Unsigned <- <[0-9]+> */
+		func() bool {
+			position446, tokenIndex446 := position, tokenIndex
+			{
+				position447 := position
+				if c := buffer[position]; c < rune('0') || c > rune('9') {
+					goto l446
+				}
+				position++
+			l448:
+				{
+					position449, tokenIndex449 := position, tokenIndex
+					if c := buffer[position]; c < rune('0') || c > rune('9') {
+						goto l449
+					}
+					position++
+					goto l448
+				l449:
+					position, tokenIndex = position449, tokenIndex449
+				}
+				add(ruleUnsigned, position447)
+			}
+			return true
+		l446:
+			position, tokenIndex = position446, tokenIndex446
+			return false
+		},
+		/* 48 Sign <- <('-' / '+')> */
+		func() bool {
+			position450, tokenIndex450 := position, tokenIndex
+			{
+				position451 := position
+				{
+					position452, tokenIndex452 := position, tokenIndex
+					if buffer[position] != rune('-') {
+						goto l453
+					}
+					position++
+					goto l452
+				l453:
+					position, tokenIndex = position452, tokenIndex452
+					if buffer[position] != rune('+') {
+						goto l450
+					}
+					position++
+				}
+			l452:
+				add(ruleSign, position451)
+			}
+			return true
+		l450:
+			position, tokenIndex = position450, tokenIndex450
+			return false
+		},
+		/* 49 Integer <- <<(Sign? Unsigned)>> */
+		func() bool {
+			position454, tokenIndex454 := position, tokenIndex
+			{
+				position455 := position
+				{
+					position456 := position
+					{
+						position457, tokenIndex457 := position, tokenIndex
+						if !_rules[ruleSign]() {
+							goto l457
+						}
+						goto l458
+					l457:
+						position, tokenIndex = position457, tokenIndex457
+					}
+				l458:
+					if !_rules[ruleUnsigned]() {
+						goto l454
+					}
+					add(rulePegText, position456)
+				}
+				add(ruleInteger, position455)
+			}
+			return true
+		l454:
+			position, tokenIndex = position454, tokenIndex454
+			return false
+		},
+		/* 50 Float <- <(Integer ('.' Unsigned)? (('e' / 'E') Integer)?)> */
+		func() bool {
+			position459, tokenIndex459 := position, tokenIndex
+			{
+				position460 := position
+				if !_rules[ruleInteger]() {
+					goto l459
+				}
+				{
+					position461, tokenIndex461 := position, tokenIndex
+					if buffer[position] != rune('.') {
+						goto l461
+					}
+					position++
+					if !_rules[ruleUnsigned]() {
+						goto l461
+					}
+					goto l462
+				l461:
+					position, tokenIndex = position461, tokenIndex461
+				}
+			l462:
+				{
+					position463, tokenIndex463 := position, tokenIndex
+					{
+						position465, tokenIndex465 := position, tokenIndex
+						if buffer[position] != rune('e') {
+							goto l466
+						}
+						position++
+						goto l465
+					l466:
+						position, tokenIndex = position465, tokenIndex465
+						if buffer[position] != rune('E') {
+							goto l463
+						}
+						position++
+					}
+				l465:
+					if !_rules[ruleInteger]() {
+						goto l463
+					}
+					goto l464
+				l463:
+					position, tokenIndex = position463, tokenIndex463
+				}
+			l464:
+				add(ruleFloat, position460)
+			}
+			return true
+		l459:
+			position, tokenIndex = position459, tokenIndex459
+			return false
+		},
+		/* 51 Duration <- <(Integer ('.' Unsigned)? (('n' 's') / ('u' 's') / ('µ' 's') / ('m' 's') / 's' / 'm' / 'h'))> */
+		func() bool {
+			position467, tokenIndex467 := position, tokenIndex
+			{
+				position468 := position
+				if !_rules[ruleInteger]() {
+					goto l467
+				}
+				{
+					position469, tokenIndex469 := position, tokenIndex
+					if buffer[position] != rune('.') {
+						goto l469
+					}
+					position++
+					if !_rules[ruleUnsigned]() {
+						goto l469
+					}
+					goto l470
+				l469:
+					position, tokenIndex = position469, tokenIndex469
+				}
+			l470:
+				{
+					position471, tokenIndex471 := position, tokenIndex
+					if buffer[position] != rune('n') {
+						goto l472
+					}
+					position++
+					if buffer[position] != rune('s') {
+						goto l472
+					}
+					position++
+					goto l471
+				l472:
+					position, tokenIndex = position471, tokenIndex471
+					if buffer[position] != rune('u') {
+						goto l473
+					}
+					position++
+					if buffer[position] != rune('s') {
+						goto l473
+					}
+					position++
+					goto l471
+				l473:
+					position, tokenIndex = position471, tokenIndex471
+					if buffer[position] != rune('µ') {
+						goto l474
+					}
+					position++
+					if buffer[position] != rune('s') {
+						goto l474
+					}
+					position++
+					goto l471
+				l474:
+					position, tokenIndex = position471, tokenIndex471
+					if buffer[position] != rune('m') {
+						goto l475
+					}
+					position++
+					if buffer[position] != rune('s') {
+						goto l475
+					}
+					position++
+					goto l471
+				l475:
+					position, tokenIndex = position471, tokenIndex471
+					if buffer[position] != rune('s') {
+						goto l476
+					}
+					position++
+					goto l471
+				l476:
+					position, tokenIndex = position471, tokenIndex471
+					if buffer[position] != rune('m') {
+						goto l477
+					}
+					position++
+					goto l471
+				l477:
+					position, tokenIndex = position471, tokenIndex471
+					if buffer[position] != rune('h') {
+						goto l467
+					}
+					position++
+				}
+			l471:
+				add(ruleDuration, position468)
+			}
+			return true
+		l467:
+			position, tokenIndex = position467, tokenIndex467
+			return false
+		},
+		/* 52 Identifier <- <(!Keyword <(IdStart IdChar* PathElem*)>)> */
+		func() bool {
+			position478, tokenIndex478 := position, tokenIndex
+			{
+				position479 := position
+				{
+					position480, tokenIndex480 := position, tokenIndex
+					if !_rules[ruleKeyword]() {
+						goto l480
+					}
+					goto l478
+				l480:
+					position, tokenIndex = position480, tokenIndex480
+				}
+				{
+					position481 := position
+					if !_rules[ruleIdStart]() {
+						goto l478
+					}
+				l482:
+					{
+						position483, tokenIndex483 := position, tokenIndex
+						if !_rules[ruleIdChar]() {
+							goto l483
+						}
+						goto l482
+					l483:
+						position, tokenIndex = position483, tokenIndex483
+					}
+				l484:
+					{
+						position485, tokenIndex485 := position, tokenIndex
+						if !_rules[rulePathElem]() {
+							goto l485
+						}
+						goto l484
+					l485:
+						position, tokenIndex = position485, tokenIndex485
+					}
+					add(rulePegText, position481)
+				}
+				add(ruleIdentifier, position479)
+			}
+			return true
+		l478:
+			position, tokenIndex = position478, tokenIndex478
+			return false
+		},
+		/* 53 PathElem <- <(('.' IdStart IdChar*) / ('[' Unsigned ']'))> */
+		func() bool {
+			position486, tokenIndex486 := position, tokenIndex
+			{
+				position487 := position
+				{
+					position488, tokenIndex488 := position, tokenIndex
+					if buffer[position] != rune('.') {
+						goto l489
+					}
+					position++
+					if !_rules[ruleIdStart]() {
+						goto l489
+					}
+				l490:
+					{
+						position491, tokenIndex491 := position, tokenIndex
+						if !_rules[ruleIdChar]() {
+							goto l491
+						}
+						goto l490
+					l491:
+						position, tokenIndex = position491, tokenIndex491
+					}
+					goto l488
+				l489:
+					position, tokenIndex = position488, tokenIndex488
+					if buffer[position] != rune('[') {
+						goto l486
+					}
+					position++
+					if !_rules[ruleUnsigned]() {
+						goto l486
+					}
+					if buffer[position] != rune(']') {
+						goto l486
+					}
+					position++
+				}
+			l488:
+				add(rulePathElem, position487)
+			}
+			return true
+		l486:
+			position, tokenIndex = position486, tokenIndex486
+			return false
+		},
+		/* 54 IdStart <- <([a-z] / [A-Z] / '_')> */
+		func() bool {
+			position492, tokenIndex492 := position, tokenIndex
+			{
+				position493 := position
+				{
+					position494, tokenIndex494 := position, tokenIndex
+					if c := buffer[position]; c < rune('a') || c > rune('z') {
+						goto l495
+					}
+					position++
+					goto l494
+				l495:
+					position, tokenIndex = position494, tokenIndex494
+					if c := buffer[position]; c < rune('A') || c > rune('Z') {
+						goto l496
+					}
+					position++
+					goto l494
+				l496:
+					position, tokenIndex = position494, tokenIndex494
+					if buffer[position] != rune('_') {
+						goto l492
+					}
+					position++
+				}
+			l494:
+				add(ruleIdStart, position493)
+			}
+			return true
+		l492:
+			position, tokenIndex = position492, tokenIndex492
+			return false
+		},
+		/* 55 IdChar <- <([a-z] / [A-Z] / [0-9] / '_')> */
+		func() bool {
+			position497, tokenIndex497 := position, tokenIndex
+			{
+				position498 := position
+				{
+					position499, tokenIndex499 := position, tokenIndex
+					if c := buffer[position]; c < rune('a') || c > rune('z') {
+						goto l500
+					}
+					position++
+					goto l499
+				l500:
+					position, tokenIndex = position499, tokenIndex499
+					if c := buffer[position]; c < rune('A') || c > rune('Z') {
+						goto l501
+					}
+					position++
+					goto l499
+				l501:
+					position, tokenIndex = position499, tokenIndex499
+					if c := buffer[position]; c < rune('0') || c > rune('9') {
+						goto l502
+					}
+					position++
+					goto l499
+				l502:
+					position, tokenIndex = position499, tokenIndex499
+					if buffer[position] != rune('_') {
+						goto l497
+					}
+					position++
+				}
+			l499:
+				add(ruleIdChar, position498)
+			}
+			return true
+		l497:
+			position, tokenIndex = position497, tokenIndex497
+			return false
+		},
+		/* 56 Keyword <- <((('s' 'e' 'l' 'e' 'c' 't') / ('g' 'r' 'o' 'u' 'p' ' ' 'b' 'y') / ('f' 'i' 'l' 't' 'e' 'r' 's') / ('o' 'r' 'd' 'e' 'r' ' ' 'b' 'y') / ('d' 'e' 's' 'c') / ('l' 'i' 'm' 'i' 't') / ('h' 'a' 'v' 'i' 'n' 'g')) !(IdChar / '.' / '['))> */
+		func() bool {
+			position503, tokenIndex503 := position, tokenIndex
+			{
+				position504 := position
+				{
+					position505, tokenIndex505 := position, tokenIndex
+					if buffer[position] != rune('s') {
+						goto l506
+					}
+					position++
+					if buffer[position] != rune('e') {
+						goto l506
+					}
+					position++
+					if buffer[position] != rune('l') {
+						goto l506
+					}
+					position++
+					if buffer[position] != rune('e') {
+						goto l506
+					}
+					position++
+					if buffer[position] != rune('c') {
+						goto l506
+					}
+					position++
+					if buffer[position] != rune('t') {
+						goto l506
+					}
+					position++
+					goto l505
+				l506:
+					position, tokenIndex = position505, tokenIndex505
+					if buffer[position] != rune('g') {
+						goto l507
+					}
+					position++
+					if buffer[position] != rune('r') {
+						goto l507
+					}
+					position++
+					if buffer[position] != rune('o') {
+						goto l507
+					}
+					position++
+					if buffer[position] != rune('u') {
+						goto l507
+					}
+					position++
+					if buffer[position] != rune('p') {
+						goto l507
+					}
+					position++
+					if buffer[position] != rune(' ') {
+						goto l507
+					}
+					position++
+					if buffer[position] != rune('b') {
+						goto l507
+					}
+					position++
+					if buffer[position] != rune('y') {
+						goto l507
+					}
+					position++
+					goto l505
+				l507:
+					position, tokenIndex = position505, tokenIndex505
+					if buffer[position] != rune('f') {
+						goto l508
+					}
+					position++
+					if buffer[position] != rune('i') {
+						goto l508
+					}
+					position++
+					if buffer[position] != rune('l') {
+						goto l508
+					}
+					position++
+					if buffer[position] != rune('t') {
+						goto l508
+					}
+					position++
+					if buffer[position] != rune('e') {
+						goto l508
+					}
+					position++
+					if buffer[position] != rune('r') {
+						goto l508
+					}
+					position++
+					if buffer[position] != rune('s') {
+						goto l508
+					}
+					position++
+					goto l505
+				l508:
+					position, tokenIndex = position505, tokenIndex505
+					if buffer[position] != rune('o') {
+						goto l509
+					}
+					position++
+					if buffer[position] != rune('r') {
+						goto l509
+					}
+					position++
+					if buffer[position] != rune('d') {
+						goto l509
+					}
+					position++
+					if buffer[position] != rune('e') {
+						goto l509
+					}
+					position++
+					if buffer[position] != rune('r') {
+						goto l509
+					}
+					position++
+					if buffer[position] != rune(' ') {
+						goto l509
+					}
+					position++
+					if buffer[position] != rune('b') {
+						goto l509
+					}
+					position++
+					if buffer[position] != rune('y') {
+						goto l509
+					}
+					position++
+					goto l505
+				l509:
+					position, tokenIndex = position505, tokenIndex505
+					if buffer[position] != rune('d') {
+						goto l510
+					}
+					position++
+					if buffer[position] != rune('e') {
+						goto l510
+					}
+					position++
+					if buffer[position] != rune('s') {
+						goto l510
+					}
+					position++
+					if buffer[position] != rune('c') {
+						goto l510
+					}
+					position++
+					goto l505
+				l510:
+					position, tokenIndex = position505, tokenIndex505
+					if buffer[position] != rune('l') {
+						goto l511
+					}
+					position++
+					if buffer[position] != rune('i') {
+						goto l511
+					}
+					position++
+					if buffer[position] != rune('m') {
+						goto l511
+					}
+					position++
+					if buffer[position] != rune('i') {
+						goto l511
+					}
+					position++
+					if buffer[position] != rune('t') {
+						goto l511
+					}
+					position++
+					goto l505
+				l511:
+					position, tokenIndex = position505, tokenIndex505
+					if buffer[position] != rune('h') {
+						goto l503
+					}
+					position++
+					if buffer[position] != rune('a') {
+						goto l503
+					}
+					position++
+					if buffer[position] != rune('v') {
+						goto l503
+					}
+					position++
+					if buffer[position] != rune('i') {
+						goto l503
+					}
+					position++
+					if buffer[position] != rune('n') {
+						goto l503
+					}
+					position++
+					if buffer[position] != rune('g') {
+						goto l503
+					}
+					position++
+				}
+			l505:
+				{
+					position512, tokenIndex512 := position, tokenIndex
+					{
+						position513, tokenIndex513 := position, tokenIndex
+						if !_rules[ruleIdChar]() {
+							goto l514
+						}
+						goto l513
+					l514:
+						position, tokenIndex = position513, tokenIndex513
+						if buffer[position] != rune('.') {
+							goto l515
+						}
+						position++
+						goto l513
+					l515:
+						position, tokenIndex = position513, tokenIndex513
+						if buffer[position] != rune('[') {
+							goto l512
+						}
+						position++
+					}
+				l513:
+					goto l503
+				l512:
+					position, tokenIndex = position512, tokenIndex512
+				}
+				add(ruleKeyword, position504)
+			}
+			return true
+		l503:
+			position, tokenIndex = position503, tokenIndex503
+			return false
+		},
+		/* 57 _ <- <(' ' / '\t' / ('\r' '\n') / '\n' / '\r')*> */
+		func() bool {
+			{
+				position517 := position
+			l518:
+				{
+					position519, tokenIndex519 := position, tokenIndex
+					{
+						position520, tokenIndex520 := position, tokenIndex
+						if buffer[position] != rune(' ') {
+							goto l521
+						}
+						position++
+						goto l520
+					l521:
+						position, tokenIndex = position520, tokenIndex520
+						if buffer[position] != rune('\t') {
+							goto l522
+						}
+						position++
+						goto l520
+					l522:
+						position, tokenIndex = position520, tokenIndex520
+						if buffer[position] != rune('\r') {
+							goto l523
+						}
+						position++
+						if buffer[position] != rune('\n') {
+							goto l523
+						}
+						position++
+						goto l520
+					l523:
+						position, tokenIndex = position520, tokenIndex520
+						if buffer[position] != rune('\n') {
+							goto l524
+						}
+						position++
+						goto l520
+					l524:
+						position, tokenIndex = position520, tokenIndex520
+						if buffer[position] != rune('\r') {
+							goto l519
+						}
+						position++
+					}
+				l520:
+					goto l518
+				l519:
+					position, tokenIndex = position519, tokenIndex519
+				}
+				add(rule_, position517)
+			}
+			return true
+		},
+		/* 58 LPAR <- <(_ '(' _)> */
+		func() bool {
+			position525, tokenIndex525 := position, tokenIndex
+			{
+				position526 := position
+				if !_rules[rule_]() {
+					goto l525
+				}
+				if buffer[position] != rune('(') {
+					goto l525
+				}
+				position++
+				if !_rules[rule_]() {
+					goto l525
+				}
+				add(ruleLPAR, position526)
+			}
+			return true
+		l525:
+			position, tokenIndex = position525, tokenIndex525
+			return false
+		},
+		/* 59 RPAR <- <(_ ')' _)> */
+		func() bool {
+			position527, tokenIndex527 := position, tokenIndex
+			{
+				position528 := position
+				if !_rules[rule_]() {
+					goto l527
+				}
+				if buffer[position] != rune(')') {
+					goto l527
+				}
+				position++
+				if !_rules[rule_]() {
+					goto l527
+				}
+				add(ruleRPAR, position528)
+			}
+			return true
+		l527:
+			position, tokenIndex = position527, tokenIndex527
+			return false
+		},
+		/* 60 COMMA <- <(_ ',' _)> */
+		func() bool {
+			position529, tokenIndex529 := position, tokenIndex
+			{
+				position530 := position
+				if !_rules[rule_]() {
+					goto l529
+				}
+				if buffer[position] != rune(',') {
+					goto l529
+				}
+				position++
+				if !_rules[rule_]() {
+					goto l529
+				}
+				add(ruleCOMMA, position530)
+			}
+			return true
+		l529:
+			position, tokenIndex = position529, tokenIndex529
+			return false
+		},
+		/* 61 PLUS <- <(_ '+' _)> */
+		func() bool {
+			position531, tokenIndex531 := position, tokenIndex
+			{
+				position532 := position
+				if !_rules[rule_]() {
+					goto l531
+				}
+				if buffer[position] != rune('+') {
+					goto l531
+				}
+				position++
+				if !_rules[rule_]() {
+					goto l531
+				}
+				add(rulePLUS, position532)
+			}
+			return true
+		l531:
+			position, tokenIndex = position531, tokenIndex531
+			return false
+		},
+		/* 62 MINUS <- <(_ '-' _)> */
+		func() bool {
+			position533, tokenIndex533 := position, tokenIndex
+			{
+				position534 := position
+				if !_rules[rule_]() {
+					goto l533
+				}
+				if buffer[position] != rune('-') {
+					goto l533
+				}
+				position++
+				if !_rules[rule_]() {
+					goto l533
+				}
+				add(ruleMINUS, position534)
+			}
+			return true
+		l533:
+			position, tokenIndex = position533, tokenIndex533
+			return false
+		},
+		/* 63 TIMES <- <(_ '*' _)> */
+		func() bool {
+			position535, tokenIndex535 := position, tokenIndex
+			{
+				position536 := position
+				if !_rules[rule_]() {
+					goto l535
+				}
+				if buffer[position] != rune('*') {
+					goto l535
+				}
+				position++
+				if !_rules[rule_]() {
+					goto l535
+				}
+				add(ruleTIMES, position536)
+			}
+			return true
+		l535:
+			position, tokenIndex = position535, tokenIndex535
+			return false
+		},
+		/* 64 DIVIDE <- <(_ '/' _)> */
+		func() bool {
+			position537, tokenIndex537 := position, tokenIndex
+			{
+				position538 := position
+				if !_rules[rule_]() {
+					goto l537
+				}
+				if buffer[position] != rune('/') {
+					goto l537
+				}
+				position++
+				if !_rules[rule_]() {
+					goto l537
+				}
+				add(ruleDIVIDE, position538)
+			}
+			return true
+		l537:
+			position, tokenIndex = position537, tokenIndex537
+			return false
+		},
+		/* 65 MODULO <- <(_ '%' _)> */
+		func() bool {
+			position539, tokenIndex539 := position, tokenIndex
+			{
+				position540 := position
+				if !_rules[rule_]() {
+					goto l539
+				}
+				if buffer[position] != rune('%') {
+					goto l539
+				}
+				position++
+				if !_rules[rule_]() {
+					goto l539
 				}
-			l255:
-				add(ruleFloat, position251)
+				add(ruleMODULO, position540)
 			}
 			return true
-		l250:
-			position, tokenIndex = position250, tokenIndex250
+		l539:
+			position, tokenIndex = position539, tokenIndex539
 			return false
 		},
-		/* 30 Duration <- <(Integer ('.' Unsigned)? (('n' 's') / ('u' 's') / ('µ' 's') / ('m' 's') / 's' / 'm' / 'h'))> */
+		/* 66 AS <- <(_ (('a' / 'A') ('s' / 'S')) !IdChar _)> */
 		func() bool {
-			position258, tokenIndex258 := position, tokenIndex
+			position541, tokenIndex541 := position, tokenIndex
 			{
-				position259 := position
-				if !_rules[ruleInteger]() {
-					goto l258
+				position542 := position
+				if !_rules[rule_]() {
+					goto l541
 				}
 				{
-					position260, tokenIndex260 := position, tokenIndex
-					if buffer[position] != rune('.') {
-						goto l260
+					position543, tokenIndex543 := position, tokenIndex
+					if buffer[position] != rune('a') {
+						goto l544
 					}
 					position++
-					if !_rules[ruleUnsigned]() {
-						goto l260
+					goto l543
+				l544:
+					position, tokenIndex = position543, tokenIndex543
+					if buffer[position] != rune('A') {
+						goto l541
 					}
-					goto l261
-				l260:
-					position, tokenIndex = position260, tokenIndex260
+					position++
 				}
-			l261:
+			l543:
 				{
-					position262, tokenIndex262 := position, tokenIndex
-					if buffer[position] != rune('n') {
-						goto l263
-					}
-					position++
+					position545, tokenIndex545 := position, tokenIndex
 					if buffer[position] != rune('s') {
-						goto l263
-					}
-					position++
-					goto l262
-				l263:
-					position, tokenIndex = position262, tokenIndex262
-					if buffer[position] != rune('u') {
-						goto l264
+						goto l546
 					}
 					position++
-					if buffer[position] != rune('s') {
-						goto l264
+					goto l545
+				l546:
+					position, tokenIndex = position545, tokenIndex545
+					if buffer[position] != rune('S') {
+						goto l541
 					}
 					position++
-					goto l262
-				l264:
-					position, tokenIndex = position262, tokenIndex262
-					if buffer[position] != rune('µ') {
-						goto l265
+				}
+			l545:
+				{
+					position547, tokenIndex547 := position, tokenIndex
+					if !_rules[ruleIdChar]() {
+						goto l547
 					}
-					position++
-					if buffer[position] != rune('s') {
-						goto l265
+					goto l541
+				l547:
+					position, tokenIndex = position547, tokenIndex547
+				}
+				if !_rules[rule_]() {
+					goto l541
+				}
+				add(ruleAS, position542)
+			}
+			return true
+		l541:
+			position, tokenIndex = position541, tokenIndex541
+			return false
+		},
+		/* 67 AND <- <(_ (('a' / 'A') ('n' / 'N') ('d' / 'D')) !IdChar _)> */
+		func() bool {
+			position548, tokenIndex548 := position, tokenIndex
+			{
+				position549 := position
+				if !_rules[rule_]() {
+					goto l548
+				}
+				{
+					position550, tokenIndex550 := position, tokenIndex
+					if buffer[position] != rune('a') {
+						goto l551
 					}
 					position++
-					goto l262
-				l265:
-					position, tokenIndex = position262, tokenIndex262
-					if buffer[position] != rune('m') {
-						goto l266
+					goto l550
+				l551:
+					position, tokenIndex = position550, tokenIndex550
+					if buffer[position] != rune('A') {
+						goto l548
 					}
 					position++
-					if buffer[position] != rune('s') {
-						goto l266
+				}
+			l550:
+				{
+					position552, tokenIndex552 := position, tokenIndex
+					if buffer[position] != rune('n') {
+						goto l553
 					}
 					position++
-					goto l262
-				l266:
-					position, tokenIndex = position262, tokenIndex262
-					if buffer[position] != rune('s') {
-						goto l267
+					goto l552
+				l553:
+					position, tokenIndex = position552, tokenIndex552
+					if buffer[position] != rune('N') {
+						goto l548
 					}
 					position++
-					goto l262
-				l267:
-					position, tokenIndex = position262, tokenIndex262
-					if buffer[position] != rune('m') {
-						goto l268
+				}
+			l552:
+				{
+					position554, tokenIndex554 := position, tokenIndex
+					if buffer[position] != rune('d') {
+						goto l555
 					}
 					position++
-					goto l262
-				l268:
-					position, tokenIndex = position262, tokenIndex262
-					if buffer[position] != rune('h') {
-						goto l258
+					goto l554
+				l555:
+					position, tokenIndex = position554, tokenIndex554
+					if buffer[position] != rune('D') {
+						goto l548
 					}
 					position++
 				}
-			l262:
-				add(ruleDuration, position259)
-			}
-			return true
-		l258:
-			position, tokenIndex = position258, tokenIndex258
-			return false
-		},
-		/* 31 Identifier <- <(!Keyword <(([a-z] / [A-Z] / '_') IdChar*)>)> */
-		func() bool {
-			position269, tokenIndex269 := position, tokenIndex
-			{
-				position270 := position
+			l554:
 				{
-					position271, tokenIndex271 := position, tokenIndex
-					if !_rules[ruleKeyword]() {
-						goto l271
+					position556, tokenIndex556 := position, tokenIndex
+					if !_rules[ruleIdChar]() {
+						goto l556
 					}
-					goto l269
-				l271:
-					position, tokenIndex = position271, tokenIndex271
+					goto l548
+				l556:
+					position, tokenIndex = position556, tokenIndex556
 				}
-				{
-					position272 := position
-					{
-						position273, tokenIndex273 := position, tokenIndex
-						if c := buffer[position]; c < rune('a') || c > rune('z') {
-							goto l274
-						}
-						position++
-						goto l273
-					l274:
-						position, tokenIndex = position273, tokenIndex273
-						if c := buffer[position]; c < rune('A') || c > rune('Z') {
-							goto l275
-						}
-						position++
-						goto l273
-					l275:
-						position, tokenIndex = position273, tokenIndex273
-						if buffer[position] != rune('_') {
-							goto l269
-						}
-						position++
-					}
-				l273:
-				l276:
-					{
-						position277, tokenIndex277 := position, tokenIndex
-						if !_rules[ruleIdChar]() {
-							goto l277
-						}
-						goto l276
-					l277:
-						position, tokenIndex = position277, tokenIndex277
-					}
-					add(rulePegText, position272)
+				if !_rules[rule_]() {
+					goto l548
 				}
-				add(ruleIdentifier, position270)
+				add(ruleAND, position549)
 			}
 			return true
-		l269:
-			position, tokenIndex = position269, tokenIndex269
+		l548:
+			position, tokenIndex = position548, tokenIndex548
 			return false
 		},
-		/* 32 IdChar <- <([a-z] / [A-Z] / [0-9] / '_')> */
+		/* 68 OR <- <(_ (('o' / 'O') ('r' / 'R')) !IdChar _)> */
 		func() bool {
-			position278, tokenIndex278 := position, tokenIndex
+			position557, tokenIndex557 := position, tokenIndex
 			{
-				position279 := position
+				position558 := position
+				if !_rules[rule_]() {
+					goto l557
+				}
 				{
-					position280, tokenIndex280 := position, tokenIndex
-					if c := buffer[position]; c < rune('a') || c > rune('z') {
-						goto l281
+					position559, tokenIndex559 := position, tokenIndex
+					if buffer[position] != rune('o') {
+						goto l560
 					}
 					position++
-					goto l280
-				l281:
-					position, tokenIndex = position280, tokenIndex280
-					if c := buffer[position]; c < rune('A') || c > rune('Z') {
-						goto l282
+					goto l559
+				l560:
+					position, tokenIndex = position559, tokenIndex559
+					if buffer[position] != rune('O') {
+						goto l557
 					}
 					position++
-					goto l280
-				l282:
-					position, tokenIndex = position280, tokenIndex280
-					if c := buffer[position]; c < rune('0') || c > rune('9') {
-						goto l283
+				}
+			l559:
+				{
+					position561, tokenIndex561 := position, tokenIndex
+					if buffer[position] != rune('r') {
+						goto l562
 					}
 					position++
-					goto l280
-				l283:
-					position, tokenIndex = position280, tokenIndex280
-					if buffer[position] != rune('_') {
-						goto l278
+					goto l561
+				l562:
+					position, tokenIndex = position561, tokenIndex561
+					if buffer[position] != rune('R') {
+						goto l557
 					}
 					position++
 				}
-			l280:
-				add(ruleIdChar, position279)
+			l561:
+				{
+					position563, tokenIndex563 := position, tokenIndex
+					if !_rules[ruleIdChar]() {
+						goto l563
+					}
+					goto l557
+				l563:
+					position, tokenIndex = position563, tokenIndex563
+				}
+				if !_rules[rule_]() {
+					goto l557
+				}
+				add(ruleOR, position558)
 			}
 			return true
-		l278:
-			position, tokenIndex = position278, tokenIndex278
+		l557:
+			position, tokenIndex = position557, tokenIndex557
 			return false
 		},
-		/* 33 Keyword <- <((('s' 'e' 'l' 'e' 'c' 't') / ('g' 'r' 'o' 'u' 'p' ' ' 'b' 'y') / ('f' 'i' 'l' 't' 'e' 'r' 's') / ('o' 'r' 'd' 'e' 'r' ' ' 'b' 'y') / ('d' 'e' 's' 'c') / ('l' 'i' 'm' 'i' 't')) !IdChar)> */
+		/* 69 NOT <- <(_ (('n' / 'N') ('o' / 'O') ('t' / 'T')) !IdChar _)> */
 		func() bool {
-			position284, tokenIndex284 := position, tokenIndex
+			position564, tokenIndex564 := position, tokenIndex
 			{
-				position285 := position
+				position565 := position
+				if !_rules[rule_]() {
+					goto l564
+				}
 				{
-					position286, tokenIndex286 := position, tokenIndex
-					if buffer[position] != rune('s') {
-						goto l287
-					}
-					position++
-					if buffer[position] != rune('e') {
-						goto l287
+					position566, tokenIndex566 := position, tokenIndex
+					if buffer[position] != rune('n') {
+						goto l567
 					}
 					position++
-					if buffer[position] != rune('l') {
-						goto l287
+					goto l566
+				l567:
+					position, tokenIndex = position566, tokenIndex566
+					if buffer[position] != rune('N') {
+						goto l564
 					}
 					position++
-					if buffer[position] != rune('e') {
-						goto l287
+				}
+			l566:
+				{
+					position568, tokenIndex568 := position, tokenIndex
+					if buffer[position] != rune('o') {
+						goto l569
 					}
 					position++
-					if buffer[position] != rune('c') {
-						goto l287
+					goto l568
+				l569:
+					position, tokenIndex = position568, tokenIndex568
+					if buffer[position] != rune('O') {
+						goto l564
 					}
 					position++
+				}
+			l568:
+				{
+					position570, tokenIndex570 := position, tokenIndex
 					if buffer[position] != rune('t') {
-						goto l287
+						goto l571
 					}
 					position++
-					goto l286
-				l287:
-					position, tokenIndex = position286, tokenIndex286
-					if buffer[position] != rune('g') {
-						goto l288
+					goto l570
+				l571:
+					position, tokenIndex = position570, tokenIndex570
+					if buffer[position] != rune('T') {
+						goto l564
 					}
 					position++
-					if buffer[position] != rune('r') {
-						goto l288
+				}
+			l570:
+				{
+					position572, tokenIndex572 := position, tokenIndex
+					if !_rules[ruleIdChar]() {
+						goto l572
 					}
-					position++
-					if buffer[position] != rune('o') {
-						goto l288
+					goto l564
+				l572:
+					position, tokenIndex = position572, tokenIndex572
+				}
+				if !_rules[rule_]() {
+					goto l564
+				}
+				add(ruleNOT, position565)
+			}
+			return true
+		l564:
+			position, tokenIndex = position564, tokenIndex564
+			return false
+		},
+		/* 70 IN <- <(_ (('i' / 'I') ('n' / 'N')) !IdChar _)> */
+		func() bool {
+			position573, tokenIndex573 := position, tokenIndex
+			{
+				position574 := position
+				if !_rules[rule_]() {
+					goto l573
+				}
+				{
+					position575, tokenIndex575 := position, tokenIndex
+					if buffer[position] != rune('i') {
+						goto l576
 					}
 					position++
-					if buffer[position] != rune('u') {
-						goto l288
+					goto l575
+				l576:
+					position, tokenIndex = position575, tokenIndex575
+					if buffer[position] != rune('I') {
+						goto l573
 					}
 					position++
-					if buffer[position] != rune('p') {
-						goto l288
+				}
+			l575:
+				{
+					position577, tokenIndex577 := position, tokenIndex
+					if buffer[position] != rune('n') {
+						goto l578
 					}
 					position++
-					if buffer[position] != rune(' ') {
-						goto l288
+					goto l577
+				l578:
+					position, tokenIndex = position577, tokenIndex577
+					if buffer[position] != rune('N') {
+						goto l573
 					}
 					position++
-					if buffer[position] != rune('b') {
-						goto l288
+				}
+			l577:
+				{
+					position579, tokenIndex579 := position, tokenIndex
+					if !_rules[ruleIdChar]() {
+						goto l579
 					}
-					position++
-					if buffer[position] != rune('y') {
-						goto l288
+					goto l573
+				l579:
+					position, tokenIndex = position579, tokenIndex579
+				}
+				if !_rules[rule_]() {
+					goto l573
+				}
+				add(ruleIN, position574)
+			}
+			return true
+		l573:
+			position, tokenIndex = position573, tokenIndex573
+			return false
+		},
+		/* 71 CIDR <- <(_ (('c' / 'C') ('i' / 'I') ('d' / 'D') ('r' / 'R')) !IdChar _)> */
+		func() bool {
+			position580, tokenIndex580 := position, tokenIndex
+			{
+				position581 := position
+				if !_rules[rule_]() {
+					goto l580
+				}
+				{
+					position582, tokenIndex582 := position, tokenIndex
+					if buffer[position] != rune('c') {
+						goto l583
 					}
 					position++
-					goto l286
-				l288:
-					position, tokenIndex = position286, tokenIndex286
-					if buffer[position] != rune('f') {
-						goto l289
+					goto l582
+				l583:
+					position, tokenIndex = position582, tokenIndex582
+					if buffer[position] != rune('C') {
+						goto l580
 					}
 					position++
+				}
+			l582:
+				{
+					position584, tokenIndex584 := position, tokenIndex
 					if buffer[position] != rune('i') {
-						goto l289
+						goto l585
 					}
 					position++
-					if buffer[position] != rune('l') {
-						goto l289
+					goto l584
+				l585:
+					position, tokenIndex = position584, tokenIndex584
+					if buffer[position] != rune('I') {
+						goto l580
 					}
 					position++
-					if buffer[position] != rune('t') {
-						goto l289
+				}
+			l584:
+				{
+					position586, tokenIndex586 := position, tokenIndex
+					if buffer[position] != rune('d') {
+						goto l587
 					}
 					position++
-					if buffer[position] != rune('e') {
-						goto l289
+					goto l586
+				l587:
+					position, tokenIndex = position586, tokenIndex586
+					if buffer[position] != rune('D') {
+						goto l580
 					}
 					position++
+				}
+			l586:
+				{
+					position588, tokenIndex588 := position, tokenIndex
 					if buffer[position] != rune('r') {
-						goto l289
+						goto l589
 					}
 					position++
-					if buffer[position] != rune('s') {
-						goto l289
+					goto l588
+				l589:
+					position, tokenIndex = position588, tokenIndex588
+					if buffer[position] != rune('R') {
+						goto l580
 					}
 					position++
-					goto l286
-				l289:
-					position, tokenIndex = position286, tokenIndex286
-					if buffer[position] != rune('o') {
-						goto l290
+				}
+			l588:
+				{
+					position590, tokenIndex590 := position, tokenIndex
+					if !_rules[ruleIdChar]() {
+						goto l590
 					}
-					position++
-					if buffer[position] != rune('r') {
-						goto l290
+					goto l580
+				l590:
+					position, tokenIndex = position590, tokenIndex590
+				}
+				if !_rules[rule_]() {
+					goto l580
+				}
+				add(ruleCIDR, position581)
+			}
+			return true
+		l580:
+			position, tokenIndex = position580, tokenIndex580
+			return false
+		},
+		/* 72 BETWEEN <- <(_ (('b' / 'B') ('e' / 'E') ('t' / 'T') ('w' / 'W') ('e' / 'E') ('e' / 'E') ('n' / 'N')) !IdChar _)> */
+		func() bool {
+			position591, tokenIndex591 := position, tokenIndex
+			{
+				position592 := position
+				if !_rules[rule_]() {
+					goto l591
+				}
+				{
+					position593, tokenIndex593 := position, tokenIndex
+					if buffer[position] != rune('b') {
+						goto l594
 					}
 					position++
-					if buffer[position] != rune('d') {
-						goto l290
+					goto l593
+				l594:
+					position, tokenIndex = position593, tokenIndex593
+					if buffer[position] != rune('B') {
+						goto l591
 					}
 					position++
+				}
+			l593:
+				{
+					position595, tokenIndex595 := position, tokenIndex
 					if buffer[position] != rune('e') {
-						goto l290
+						goto l596
 					}
 					position++
-					if buffer[position] != rune('r') {
-						goto l290
+					goto l595
+				l596:
+					position, tokenIndex = position595, tokenIndex595
+					if buffer[position] != rune('E') {
+						goto l591
 					}
 					position++
-					if buffer[position] != rune(' ') {
-						goto l290
+				}
+			l595:
+				{
+					position597, tokenIndex597 := position, tokenIndex
+					if buffer[position] != rune('t') {
+						goto l598
 					}
 					position++
-					if buffer[position] != rune('b') {
-						goto l290
+					goto l597
+				l598:
+					position, tokenIndex = position597, tokenIndex597
+					if buffer[position] != rune('T') {
+						goto l591
 					}
 					position++
-					if buffer[position] != rune('y') {
-						goto l290
+				}
+			l597:
+				{
+					position599, tokenIndex599 := position, tokenIndex
+					if buffer[position] != rune('w') {
+						goto l600
 					}
 					position++
-					goto l286
-				l290:
-					position, tokenIndex = position286, tokenIndex286
-					if buffer[position] != rune('d') {
-						goto l291
+					goto l599
+				l600:
+					position, tokenIndex = position599, tokenIndex599
+					if buffer[position] != rune('W') {
+						goto l591
 					}
 					position++
+				}
+			l599:
+				{
+					position601, tokenIndex601 := position, tokenIndex
 					if buffer[position] != rune('e') {
-						goto l291
-					}
-					position++
-					if buffer[position] != rune('s') {
-						goto l291
-					}
-					position++
-					if buffer[position] != rune('c') {
-						goto l291
+						goto l602
 					}
 					position++
-					goto l286
-				l291:
-					position, tokenIndex = position286, tokenIndex286
-					if buffer[position] != rune('l') {
-						goto l284
+					goto l601
+				l602:
+					position, tokenIndex = position601, tokenIndex601
+					if buffer[position] != rune('E') {
+						goto l591
 					}
 					position++
-					if buffer[position] != rune('i') {
-						goto l284
+				}
+			l601:
+				{
+					position603, tokenIndex603 := position, tokenIndex
+					if buffer[position] != rune('e') {
+						goto l604
 					}
 					position++
-					if buffer[position] != rune('m') {
-						goto l284
+					goto l603
+				l604:
+					position, tokenIndex = position603, tokenIndex603
+					if buffer[position] != rune('E') {
+						goto l591
 					}
 					position++
-					if buffer[position] != rune('i') {
-						goto l284
+				}
+			l603:
+				{
+					position605, tokenIndex605 := position, tokenIndex
+					if buffer[position] != rune('n') {
+						goto l606
 					}
 					position++
-					if buffer[position] != rune('t') {
-						goto l284
+					goto l605
+				l606:
+					position, tokenIndex = position605, tokenIndex605
+					if buffer[position] != rune('N') {
+						goto l591
 					}
 					position++
 				}
-			l286:
+			l605:
 				{
-					position292, tokenIndex292 := position, tokenIndex
+					position607, tokenIndex607 := position, tokenIndex
 					if !_rules[ruleIdChar]() {
-						goto l292
+						goto l607
 					}
-					goto l284
-				l292:
-					position, tokenIndex = position292, tokenIndex292
+					goto l591
+				l607:
+					position, tokenIndex = position607, tokenIndex607
+				}
+				if !_rules[rule_]() {
+					goto l591
 				}
-				add(ruleKeyword, position285)
+				add(ruleBETWEEN, position592)
 			}
 			return true
-		l284:
-			position, tokenIndex = position284, tokenIndex284
+		l591:
+			position, tokenIndex = position591, tokenIndex591
 			return false
 		},
-		/* 34 _ <- <(' ' / '\t' / ('\r' '\n') / '\n' / '\r')*> */
+		/* 74 Action0 <- <{ p.currentSection = "columns" }> */
 		func() bool {
 			{
-				position294 := position
-			l295:
-				{
-					position296, tokenIndex296 := position, tokenIndex
-					{
-						position297, tokenIndex297 := position, tokenIndex
-						if buffer[position] != rune(' ') {
-							goto l298
-						}
-						position++
-						goto l297
-					l298:
-						position, tokenIndex = position297, tokenIndex297
-						if buffer[position] != rune('\t') {
-							goto l299
-						}
-						position++
-						goto l297
-					l299:
-						position, tokenIndex = position297, tokenIndex297
-						if buffer[position] != rune('\r') {
-							goto l300
-						}
-						position++
-						if buffer[position] != rune('\n') {
-							goto l300
-						}
-						position++
-						goto l297
-					l300:
-						position, tokenIndex = position297, tokenIndex297
-						if buffer[position] != rune('\n') {
-							goto l301
-						}
-						position++
-						goto l297
-					l301:
-						position, tokenIndex = position297, tokenIndex297
-						if buffer[position] != rune('\r') {
-							goto l296
-						}
-						position++
-					}
-				l297:
-					goto l295
-				l296:
-					position, tokenIndex = position296, tokenIndex296
-				}
-				add(rule_, position294)
+				add(ruleAction0, position)
 			}
 			return true
 		},
-		/* 35 LPAR <- <(_ '(' _)> */
+		/* 75 Action1 <- <{ p.currentSection = "group by" }> */
 		func() bool {
-			position302, tokenIndex302 := position, tokenIndex
 			{
-				position303 := position
-				if !_rules[rule_]() {
-					goto l302
-				}
-				if buffer[position] != rune('(') {
-					goto l302
-				}
-				position++
-				if !_rules[rule_]() {
-					goto l302
-				}
-				add(ruleLPAR, position303)
+				add(ruleAction1, position)
 			}
 			return true
-		l302:
-			position, tokenIndex = position302, tokenIndex302
-			return false
 		},
-		/* 36 RPAR <- <(_ ')' _)> */
+		/* 76 Action2 <- <{ p.currentSection = "filter" }> */
 		func() bool {
-			position304, tokenIndex304 := position, tokenIndex
 			{
-				position305 := position
-				if !_rules[rule_]() {
-					goto l304
-				}
-				if buffer[position] != rune(')') {
-					goto l304
-				}
-				position++
-				if !_rules[rule_]() {
-					goto l304
-				}
-				add(ruleRPAR, position305)
+				add(ruleAction2, position)
 			}
 			return true
-		l304:
-			position, tokenIndex = position304, tokenIndex304
-			return false
 		},
-		/* 37 COMMA <- <(_ ',' _)> */
+		/* 77 Action3 <- <{ p.AddFilter() }> */
 		func() bool {
-			position306, tokenIndex306 := position, tokenIndex
 			{
-				position307 := position
-				if !_rules[rule_]() {
-					goto l306
-				}
-				if buffer[position] != rune(',') {
-					goto l306
-				}
-				position++
-				if !_rules[rule_]() {
-					goto l306
-				}
-				add(ruleCOMMA, position307)
+				add(ruleAction3, position)
 			}
 			return true
-		l306:
-			position, tokenIndex = position306, tokenIndex306
-			return false
 		},
-		/* 39 Action0 <- <{ p.currentSection = "columns" }> */
+		/* 78 Action4 <- <{ p.AddFilter() }> */
 		func() bool {
 			{
-				add(ruleAction0, position)
+				add(ruleAction4, position)
 			}
 			return true
 		},
-		/* 40 Action1 <- <{ p.currentSection = "group by" }> */
+		/* 79 Action5 <- <{ p.currentSection = "having" }> */
 		func() bool {
 			{
-				add(ruleAction1, position)
+				add(ruleAction5, position)
 			}
 			return true
 		},
-		/* 41 Action2 <- <{ p.currentSection = "order by" }> */
+		/* 80 Action6 <- <{ p.AddFilter() }> */
 		func() bool {
 			{
-				add(ruleAction2, position)
+				add(ruleAction6, position)
+			}
+			return true
+		},
+		/* 81 Action7 <- <{ p.AddFilter() }> */
+		func() bool {
+			{
+				add(ruleAction7, position)
+			}
+			return true
+		},
+		/* 82 Action8 <- <{ p.SetStart() }> */
+		func() bool {
+			{
+				add(ruleAction8, position)
+			}
+			return true
+		},
+		/* 83 Action9 <- <{ p.SetEnd() }> */
+		func() bool {
+			{
+				add(ruleAction9, position)
+			}
+			return true
+		},
+		/* 84 Action10 <- <{ p.SetStart() }> */
+		func() bool {
+			{
+				add(ruleAction10, position)
+			}
+			return true
+		},
+		/* 85 Action11 <- <{ p.SetEnd() }> */
+		func() bool {
+			{
+				add(ruleAction11, position)
 			}
 			return true
 		},
 		nil,
-		/* 43 Action3 <- <{ p.SetLimit(text) }> */
+		/* 87 Action12 <- <{ p.SetAbsoluteTime(text) }> */
 		func() bool {
 			{
-				add(ruleAction3, position)
+				add(ruleAction12, position)
 			}
 			return true
 		},
-		/* 44 Action4 <- <{ p.SetPointSize(text) }> */
+		/* 88 Action13 <- <{ p.SetRelativeTime("0s") }> */
 		func() bool {
 			{
-				add(ruleAction4, position)
+				add(ruleAction13, position)
 			}
 			return true
 		},
-		/* 45 Action5 <- <{ p.AddColumn() }> */
+		/* 89 Action14 <- <{ p.SetRelativeTime(text) }> */
 		func() bool {
 			{
-				add(ruleAction5, position)
+				add(ruleAction14, position)
 			}
 			return true
 		},
-		/* 46 Action6 <- <{ p.SetColumnName(text) }> */
+		/* 90 Action15 <- <{ p.SetRelativeTime("-" + text) }> */
 		func() bool {
 			{
-				add(ruleAction6, position)
+				add(ruleAction15, position)
 			}
 			return true
 		},
-		/* 47 Action7 <- <{ p.SetColumnAggregate(text) }> */
+		/* 91 Action16 <- <{ p.currentSection = "order by" }> */
 		func() bool {
 			{
-				add(ruleAction7, position)
+				add(ruleAction16, position)
 			}
 			return true
 		},
-		/* 48 Action8 <- <{ p.SetColumnName(text)      }> */
+		/* 92 Action17 <- <{ p.SetExplain() }> */
 		func() bool {
 			{
-				add(ruleAction8, position)
+				add(ruleAction17, position)
 			}
 			return true
 		},
-		/* 49 Action9 <- <{ p.AddFilter() }> */
+		/* 93 Action18 <- <{ p.SetLimit(text) }> */
 		func() bool {
 			{
-				add(ruleAction9, position)
+				add(ruleAction18, position)
 			}
 			return true
 		},
-		/* 50 Action10 <- <{ p.SetFilterColumn(text) }> */
+		/* 94 Action19 <- <{ p.SetTop(text) }> */
 		func() bool {
 			{
-				add(ruleAction10, position)
+				add(ruleAction19, position)
 			}
 			return true
 		},
-		/* 51 Action11 <- <{ p.SetFilterCondition(text) }> */
+		/* 95 Action20 <- <{ p.SetPointSize(text) }> */
 		func() bool {
 			{
-				add(ruleAction11, position)
+				add(ruleAction20, position)
 			}
 			return true
 		},
-		/* 52 Action12 <- <{ p.SetFilterValue(text) }> */
+		/* 96 Action21 <- <{ p.AddColumn() }> */
 		func() bool {
 			{
-				add(ruleAction12, position)
+				add(ruleAction21, position)
 			}
 			return true
 		},
-		/* 53 Action13 <- <{ p.SetDescending() }> */
+		/* 97 Action22 <- <{ p.SetColumnExpr(text) }> */
 		func() bool {
 			{
-				add(ruleAction13, position)
+				add(ruleAction22, position)
+			}
+			return true
+		},
+		/* 98 Action23 <- <{ p.SetColumnAlias(text) }> */
+		func() bool {
+			{
+				add(ruleAction23, position)
+			}
+			return true
+		},
+		/* 99 Action24 <- <{ p.BinaryExpr("+") }> */
+		func() bool {
+			{
+				add(ruleAction24, position)
+			}
+			return true
+		},
+		/* 100 Action25 <- <{ p.BinaryExpr("-") }> */
+		func() bool {
+			{
+				add(ruleAction25, position)
+			}
+			return true
+		},
+		/* 101 Action26 <- <{ p.BinaryExpr("*") }> */
+		func() bool {
+			{
+				add(ruleAction26, position)
+			}
+			return true
+		},
+		/* 102 Action27 <- <{ p.BinaryExpr("/") }> */
+		func() bool {
+			{
+				add(ruleAction27, position)
+			}
+			return true
+		},
+		/* 103 Action28 <- <{ p.BinaryExpr("%") }> */
+		func() bool {
+			{
+				add(ruleAction28, position)
+			}
+			return true
+		},
+		/* 104 Action29 <- <{ p.PushDuration(text) }> */
+		func() bool {
+			{
+				add(ruleAction29, position)
+			}
+			return true
+		},
+		/* 105 Action30 <- <{ p.PushLiteral(text) }> */
+		func() bool {
+			{
+				add(ruleAction30, position)
+			}
+			return true
+		},
+		/* 106 Action31 <- <{ p.NegateExpr() }> */
+		func() bool {
+			{
+				add(ruleAction31, position)
+			}
+			return true
+		},
+		/* 107 Action32 <- <{ p.PushField(text) }> */
+		func() bool {
+			{
+				add(ruleAction32, position)
+			}
+			return true
+		},
+		/* 108 Action33 <- <{ p.StartCall(text) }> */
+		func() bool {
+			{
+				add(ruleAction33, position)
+			}
+			return true
+		},
+		/* 109 Action34 <- <{ p.AddArgument() }> */
+		func() bool {
+			{
+				add(ruleAction34, position)
+			}
+			return true
+		},
+		/* 110 Action35 <- <{ p.AddArgument() }> */
+		func() bool {
+			{
+				add(ruleAction35, position)
+			}
+			return true
+		},
+		/* 111 Action36 <- <{ p.Or() }> */
+		func() bool {
+			{
+				add(ruleAction36, position)
+			}
+			return true
+		},
+		/* 112 Action37 <- <{ p.And() }> */
+		func() bool {
+			{
+				add(ruleAction37, position)
+			}
+			return true
+		},
+		/* 113 Action38 <- <{ p.Not() }> */
+		func() bool {
+			{
+				add(ruleAction38, position)
+			}
+			return true
+		},
+		/* 114 Action39 <- <{ p.PushFilter() }> */
+		func() bool {
+			{
+				add(ruleAction39, position)
+			}
+			return true
+		},
+		/* 115 Action40 <- <{ p.SetFilterCondition("between") }> */
+		func() bool {
+			{
+				add(ruleAction40, position)
+			}
+			return true
+		},
+		/* 116 Action41 <- <{ p.SetFilterValues() }> */
+		func() bool {
+			{
+				add(ruleAction41, position)
+			}
+			return true
+		},
+		/* 117 Action42 <- <{ p.SetFilterCondition(text) }> */
+		func() bool {
+			{
+				add(ruleAction42, position)
+			}
+			return true
+		},
+		/* 118 Action43 <- <{ p.SetFilterColumn(text) }> */
+		func() bool {
+			{
+				add(ruleAction43, position)
+			}
+			return true
+		},
+		/* 119 Action44 <- <{ p.SetFilterCondition(text) }> */
+		func() bool {
+			{
+				add(ruleAction44, position)
+			}
+			return true
+		},
+		/* 120 Action45 <- <{ p.SetFilterValue(text) }> */
+		func() bool {
+			{
+				add(ruleAction45, position)
+			}
+			return true
+		},
+		/* 121 Action46 <- <{ p.SetFilterValues() }> */
+		func() bool {
+			{
+				add(ruleAction46, position)
+			}
+			return true
+		},
+		/* 122 Action47 <- <{ p.AddFilterValue(text) }> */
+		func() bool {
+			{
+				add(ruleAction47, position)
+			}
+			return true
+		},
+		/* 123 Action48 <- <{ p.SetDescending() }> */
+		func() bool {
+			{
+				add(ruleAction48, position)
 			}
 			return true
 		},
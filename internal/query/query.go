@@ -7,20 +7,58 @@ import (
 
 // Desc describes a query.
 type Desc struct {
-	Columns    []ColumnDesc `json:"columns,omitempty"`
-	TimeRange  TimeRange    `json:"time_range"`
-	GroupBy    []ColumnDesc `json:"group_by,omitempty"`
-	Filters    []Filter     `json:"filters,omitempty"`
-	PointSize  int64        `json:"point_size,omitempty"`
-	OrderBy    []ColumnDesc `json:"order_by,omitempty"`
-	Descending bool         `json:"descending"`
-	Limit      int          `json:"limit,omitempty"`
+	Columns   []ColumnDesc `json:"columns,omitempty"`
+	TimeRange TimeRange    `json:"time_range"`
+	GroupBy   []ColumnDesc `json:"group_by,omitempty"`
+	Filters   []Filter     `json:"filters,omitempty"`
+	Having    []Filter     `json:"having,omitempty"`
+	PointSize int64        `json:"point_size,omitempty"`
+	OrderBy   []ColumnDesc `json:"order_by,omitempty"`
+	Limit     int          `json:"limit,omitempty"`
+	Top       int          `json:"top,omitempty"` // like Limit, but other groups are folded into _other
+
+	// Partial asks for the mergeable state of aggregates instead of
+	// their values. Cluster coordinators set it for queries to nodes.
+	Partial bool `json:"partial,omitempty"`
+
+	// Continuation is a continuation token from the result of the
+	// previous page of a raw event query.
+	Continuation string `json:"continuation,omitempty"`
+
+	// Explain asks for the query plan instead of results.
+	Explain bool `json:"explain,omitempty"`
 }
 
-// ColumnDesc describes a column.
+// ColumnDesc describes a column. Simple columns are a field, or an
+// aggregate of a field, named by Name. Computed columns have an Expr
+// and are named by its text. Alias renames the column in results.
+// Descending reverses the order of ORDER BY columns.
 type ColumnDesc struct {
-	Name      string `json:"name"`
-	Aggregate string `json:"aggregate,omitempty"`
+	Name       string `json:"name"`
+	Aggregate  string `json:"aggregate,omitempty"`
+	Expr       *Expr  `json:"expr,omitempty"`
+	Alias      string `json:"alias,omitempty"`
+	Descending bool   `json:"descending,omitempty"`
+}
+
+// Expr is an expression computed from the fields of events or from
+// aggregates. It is a field, a literal, an arithmetic operation or
+// a function call.
+type Expr struct {
+	// Column is the field of a field expression.
+	Column string `json:"column,omitempty"`
+	// Value is the value of a literal.
+	Value interface{} `json:"value,omitempty"`
+	// Op is the operator (+, -, *, / or %) of an arithmetic operation
+	// on two operands, or "-" to negate a single operand.
+	Op string `json:"op,omitempty"`
+	// Func is the aggregate or scalar function applied to the operands.
+	Func     string `json:"func,omitempty"`
+	Operands []Expr `json:"operands,omitempty"`
+}
+
+func (e Expr) isField() bool {
+	return e.Column != "" && e.Op == "" && e.Func == ""
 }
 
 // TimeRange represents start and end timestamps.
@@ -29,11 +67,18 @@ type TimeRange struct {
 	End   time.Time `json:"end"`
 }
 
-// Filter represents a filter expression.
+// Filter represents a filter expression. A filter either compares
+// a column with a value or combines other filters with And, Or or Not.
+// The column of a comparison can be computed by Expr, in which case
+// Column is its text.
 type Filter struct {
-	Column    string      `json:"column"`
-	Condition string      `json:"condition"`
-	Value     interface{} `json:"value"`
+	Column    string      `json:"column,omitempty"`
+	Expr      *Expr       `json:"expr,omitempty"`
+	Condition string      `json:"condition,omitempty"`
+	Value     interface{} `json:"value,omitempty"`
+	And       []Filter    `json:"and,omitempty"`
+	Or        []Filter    `json:"or,omitempty"`
+	Not       *Filter     `json:"not,omitempty"`
 }
 
 func (d Desc) String() string {